@@ -0,0 +1,65 @@
+package alt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-export/v3"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// DeprecateAltFeature reads the alternate geometry file for 'id' labeled 'alt_label' from 'r', assigns an
+// `edtf:deprecated` property (set to the current date) and returns the updated, exported, body.
+func DeprecateAltFeature(ctx context.Context, r reader.Reader, id int64, alt_label string) ([]byte, error) {
+
+	alt_args, err := uri.NewAlternateURIArgsFromAltLabel(alt_label)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive URI args from alt label '%s', %w", alt_label, err)
+	}
+
+	alt_uri, err := uri.Id2RelPath(id, alt_args)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive alt URI for %d, %w", id, err)
+	}
+
+	// whosonfirst/go-whosonfirst-reader doesn't know how to work with alt files
+
+	alt_r, err := r.Read(ctx, alt_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s for reading, %w", alt_uri, err)
+	}
+
+	defer alt_r.Close()
+
+	alt_body, err := io.ReadAll(alt_r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", alt_uri, err)
+	}
+
+	now := time.Now()
+
+	alt_updates := map[string]any{
+		"properties.edtf:deprecated": now.Format("2006-01-02"),
+	}
+
+	new_alt_body, err := export.AssignProperties(ctx, alt_body, alt_updates)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to assign properties to %s, %w", alt_uri, err)
+	}
+
+	_, new_alt_body, err = export.Export(ctx, new_alt_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to export %s, %w", alt_uri, err)
+	}
+
+	return new_alt_body, nil
+}
@@ -75,29 +75,29 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 	}
 
 	assign_opts := &georeference.AssignReferencesOptions{
-		DepictionReader:    depiction_reader,
-		SubjectReader:      subject_reader,
-		WhosOnFirstReader:  whosonfirst_reader,
-		SFOMuseumReader:    sfomuseum_reader,
-		DepictionWriterURI: depiction_writer_uri,
-		SubjectWriterURI:   subject_writer_uri,
+		DepictionReader:     depiction_reader,
+		SubjectReader:       subject_reader,
+		WhosOnFirstReader:   whosonfirst_reader,
+		SFOMuseumReader:     sfomuseum_reader,
+		ReaderCacheURI:      opts.ReaderCacheURI,
+		DepictionWriterURI:  opts.DepictionWriterURI,
+		SubjectWriterURI:    opts.SubjectWriterURI,
+		Author:              opts.Author,
+		GitHubTemplates:     opts.GitHubTemplates,
+		ReferenceValidation: opts.ReferenceValidation,
+		DryRun:              opts.DryRun,
 	}
 
-	switch mode {
+	switch opts.Mode {
 	case "cli":
-
-		for _, id := range opts.Depictions {
-
-			_, err := georeference.AddReferences(ctx, assign_opts, id, opts.References...)
-
-			if err != nil {
-				return fmt.Errorf("Failed to georeference depiction %d, %w", id, err)
-			}
-		}
-
+		return runCommandLine(ctx, opts, assign_opts)
+	case "lambda":
+		return runLambda(ctx, opts, assign_opts)
+	case "server":
+		return runServer(ctx, opts, assign_opts)
+	case "batch":
+		return runBatch(ctx, opts, assign_opts)
 	default:
 		return fmt.Errorf("Invalid or unsupported mode")
 	}
-
-	return nil
 }
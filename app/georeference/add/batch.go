@@ -0,0 +1,83 @@
+package add
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/batch"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// BatchRecord defines a single line of JSONL-encoded input in "batch" mode.
+type BatchRecord struct {
+	// DepictionId is the ID of the depiction (image) to georeference.
+	DepictionId int64 `json:"depiction_id"`
+	// References is the list of `georeference.Reference` instances to assign to the depiction.
+	References []*georeference.Reference `json:"references"`
+	// Replace is a boolean flag signaling that existing references should be replaced rather than merged.
+	Replace bool `json:"replace,omitempty"`
+}
+
+func runBatch(ctx context.Context, opts *RunOptions, assign_opts *georeference.AssignReferencesOptions) error {
+
+	km := batch.NewKeyedMutex()
+
+	process := func(ctx context.Context, line []byte) (int64, *geo_writers.UpdateResult, error) {
+
+		var rec *BatchRecord
+
+		err := json.Unmarshal(line, &rec)
+
+		if err != nil {
+			return 0, nil, fmt.Errorf("Failed to decode line, %w", err)
+		}
+
+		if rec == nil || rec.DepictionId == 0 {
+			return 0, nil, fmt.Errorf("Line is missing depiction ID")
+		}
+
+		unlock, err := batch.LockSubject(ctx, km, assign_opts.DepictionReader, rec.DepictionId)
+
+		if err != nil {
+			return rec.DepictionId, nil, err
+		}
+
+		defer unlock()
+
+		var r *geo_writers.UpdateResult
+
+		if opts.Replace || rec.Replace {
+			r, err = georeference.AssignReferences(ctx, assign_opts, rec.DepictionId, rec.References...)
+		} else {
+			r, err = georeference.AddReferences(ctx, assign_opts, rec.DepictionId, rec.References...)
+		}
+
+		if err != nil {
+			return rec.DepictionId, nil, fmt.Errorf("Failed to georeference depiction %d, %w", rec.DepictionId, err)
+		}
+
+		return rec.DepictionId, r, nil
+	}
+
+	batch_opts := &batch.RunOptions{
+		Workers: opts.BatchWorkers,
+		Process: process,
+	}
+
+	summary, err := batch.RunWithPaths(ctx, batch_opts, opts.BatchInput, opts.BatchResults)
+
+	if err != nil {
+		return fmt.Errorf("Failed to process batch, %w", err)
+	}
+
+	slog.Info("Batch complete", "total", summary.Total, "succeeded", summary.Succeeded, "failed", summary.Failed)
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d lines failed", summary.Failed, summary.Total)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package add
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+func runCommandLine(ctx context.Context, opts *RunOptions, assign_opts *georeference.AssignReferencesOptions) error {
+
+	req := &Request{
+		Depictions: opts.Depictions,
+		References: opts.References,
+		Replace:    opts.Replace,
+	}
+
+	rsp, err := processRequest(ctx, assign_opts, req)
+
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintln(os.Stdout, string(rsp))
+	}
+
+	return nil
+}
@@ -8,9 +8,15 @@ import (
 
 	"github.com/sfomuseum/go-flags/flagset"
 	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
 )
 
 var mode string
+var server_uri string
+
+var batch_input string
+var batch_results string
+var batch_workers int
 var verbose bool
 
 var depiction_reader_uri string
@@ -21,17 +27,39 @@ var subject_writer_uri string
 
 var whosonfirst_reader_uri string
 var sfomuseum_reader_uri string
+var reader_cache_uri string
 
 var access_token_uri string
 
 var references multi.KeyValueString
+var gazetteer_iterator_uri string
+var gazetteer_sources multi.MultiString
 var depictions multi.MultiInt64
+var reference_validation string
+
+var author string
+
+var commit_message_template string
+var pull_request_title_template string
+var pull_request_description_template string
+var branch_template string
+var citation string
+var note string
+var confidence float64
+
+var replace bool
+var dry_run bool
 
 func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("reference")
 
-	fs.StringVar(&mode, "mode", "cli", "Valid options are: cli, lambda.")
+	fs.StringVar(&mode, "mode", "cli", "Valid options are: batch, cli, lambda, server.")
+	fs.StringVar(&server_uri, "server-uri", "http://localhost:8080", "The address to listen for requests on when -mode is \"server\".")
+
+	fs.StringVar(&batch_input, "batch-input", "-", "The path to a JSONL file, where each line is a {\"depiction_id\": ..., \"references\": [...]} object, to process when -mode is \"batch\". If \"-\" then input is read from STDIN.")
+	fs.StringVar(&batch_results, "batch-results", "-", "The path to write the JSONL-encoded result for each line of input to when -mode is \"batch\". If \"-\" then results are written to STDOUT.")
+	fs.IntVar(&batch_workers, "batch-workers", 4, "The maximum number of lines to process concurrently when -mode is \"batch\".")
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 
 	// Assumed to be something in sfomuseum-data-media-collection
@@ -50,11 +78,29 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
 
+	fs.StringVar(&reader_cache_uri, "reader-cache-uri", "", "An optional cache URI used to avoid reading the same Who's On First and SFO Museum records more than once. Valid options are: lru://?size={SIZE} (an in-memory cache), disk://{PATH} (an on-disk cache which is never expired).")
+
 	fs.StringVar(&access_token_uri, "access-token", "", "A valid gocloud.dev/runtimevar URI")
 
-	fs.Var(&references, "reference", "One or more {LABEL}={WHOSONFIRST_ID} key-value pairs denoting a place that is being (geo)referenced in a depiction.")
+	fs.Var(&references, "reference", "One or more {LABEL}={WHOSONFIRST_ID} (or {LABEL}=name:{NAME}) key-value pairs denoting a place that is being (geo)referenced in a depiction.")
+	fs.StringVar(&gazetteer_iterator_uri, "gazetteer-iterator-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to build the local gazetteer index for resolving place names.")
+	fs.Var(&gazetteer_sources, "gazetteer-source", "One or more sources (for example the path to a whosonfirst-data-admin repository) to index when resolving -reference values in the form of {LABEL}=name:{NAME}. Names may be qualified by a country code, placetype or ancestor name, for example \"name:Sydney, AU\".")
+	fs.StringVar(&reference_validation, "reference-validation", "warn", "How to handle references to deprecated, superseded or not current Who's On First records. Valid options are: follow (replace superseded records with the record they were superseded by), warn (record and store them as-is), refuse (fail the update).")
 	fs.Var(&depictions, "depiction-id", "One or more valid Who's On First IDs for the records being depicted (for example an object image).")
 
+	fs.StringVar(&author, "author", "", "The name of the person (or process) asserting the references. This is recorded in the provenance for each new or updated reference and used as the commit author for githubapi:// writers.")
+
+	fs.StringVar(&commit_message_template, "commit-message-template", github.DEFAULT_COMMIT_MESSAGE_TEMPLATE, "A text/template template used to derive commit messages for GitHub writers. Templates are passed a github.TemplateContext struct with Author, Action, WhosOnFirstId, SubjectId, Labels, Places, Timestamp and Path fields.")
+	fs.StringVar(&pull_request_title_template, "pull-request-title-template", github.DEFAULT_PULL_REQUEST_TITLE_TEMPLATE, "A text/template template used to derive pull request titles for githubapi-pr:// writers.")
+	fs.StringVar(&pull_request_description_template, "pull-request-description-template", github.DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE, "A text/template template used to derive pull request descriptions for githubapi-pr:// writers.")
+	fs.StringVar(&branch_template, "branch-template", github.DEFAULT_BRANCH_TEMPLATE, "A text/template template used to derive branch names for githubapi-pr:// writers.")
+	fs.StringVar(&citation, "citation", "", "An optional citation for the source or evidence of the references defined by the -reference flag.")
+	fs.StringVar(&note, "note", "", "An optional note about the references defined by the -reference flag.")
+	fs.Float64Var(&confidence, "confidence", 0.0, "An optional value between 0.0 and 1.0 indicating the confidence in the references defined by the -reference flag.")
+
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
+	fs.BoolVar(&replace, "replace", false, "Replace all the existing references for a depiction with those defined by the -reference flag rather than merging them.")
+
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "...\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options]\n", os.Args[0])
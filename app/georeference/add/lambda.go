@@ -0,0 +1,19 @@
+package add
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+func runLambda(ctx context.Context, opts *RunOptions, assign_opts *georeference.AssignReferencesOptions) error {
+
+	handler := func(ctx context.Context, req *Request) (json.RawMessage, error) {
+		return processRequest(ctx, assign_opts, req)
+	}
+
+	lambda.Start(handler)
+	return nil
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 
 	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-sfomuseum-geo/gazetteer"
 	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
 )
 
 // subject: a collection object, for example
@@ -14,6 +16,10 @@ import (
 
 type RunOptions struct {
 	Mode                 string
+	ServerURI            string
+	BatchInput           string
+	BatchResults         string
+	BatchWorkers         int
 	Verbose              bool
 	SubjectReaderURI     string
 	SubjectWriterURI     string
@@ -21,9 +27,15 @@ type RunOptions struct {
 	DepictionWriterURI   string
 	WhosOnFirstReaderURI string
 	SFOMuseumReaderURI   string
+	ReaderCacheURI       string
 	GitHubAccessTokenURI string
+	Author               string
+	GitHubTemplates      *github.Templates
 	References           []*georeference.Reference
+	ReferenceValidation  georeference.ValidationMode
 	Depictions           []int64
+	Replace              bool
+	DryRun               bool
 }
 
 func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
@@ -36,14 +48,81 @@ func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions,
 		return nil, fmt.Errorf("Failed to set flags from environment variables, %w", err)
 	}
 
-	refs, err := georeference.MultiKeyValueStringsToReferences(references)
+	var resolver georeference.NameResolver
+
+	if georeference.HasNameReferences(references) {
+
+		if len(gazetteer_sources) == 0 {
+			return nil, fmt.Errorf("One or more -gazetteer-source flags are required to resolve place names")
+		}
+
+		idx, err := gazetteer.NewIndexFromIterator(ctx, gazetteer_iterator_uri, gazetteer_sources...)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create gazetteer index, %w", err)
+		}
+
+		resolver = idx
+	}
+
+	refs, err := georeference.MultiKeyValueStringsToReferencesWithResolver(ctx, references, resolver)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to derive references from flags, %w", err)
 	}
 
+	validation_mode, err := georeference.ParseValidationMode(reference_validation)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid -reference-validation flag, %w", err)
+	}
+
+	p := &georeference.Provenance{
+		Citation: citation,
+		Note:     note,
+	}
+
+	// Only record a confidence value if the -confidence flag was set, so that an explicit 0.0 is not ignored
+
+	fs.Visit(func(f *flag.Flag) {
+
+		if f.Name == "confidence" {
+			p.Confidence = &confidence
+		}
+	})
+
+	err = p.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid provenance flags, %w", err)
+	}
+
+	if !p.IsZero() {
+
+		for _, r := range refs {
+			r.Provenance = p.Clone()
+		}
+	}
+
+	github_templates := &github.Templates{
+		CommitMessage:          commit_message_template,
+		PullRequestTitle:       pull_request_title_template,
+		PullRequestDescription: pull_request_description_template,
+		Branch:                 branch_template,
+	}
+
+	err = github_templates.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GitHub template flags, %w", err)
+	}
+
 	opts := &RunOptions{
 		Mode:                 mode,
+		ServerURI:            server_uri,
+		BatchInput:           batch_input,
+		BatchResults:         batch_results,
+		BatchWorkers:         batch_workers,
 		Verbose:              verbose,
 		SubjectReaderURI:     subject_reader_uri,
 		SubjectWriterURI:     subject_writer_uri,
@@ -51,9 +130,15 @@ func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions,
 		DepictionWriterURI:   depiction_writer_uri,
 		WhosOnFirstReaderURI: whosonfirst_reader_uri,
 		SFOMuseumReaderURI:   sfomuseum_reader_uri,
+		ReaderCacheURI:       reader_cache_uri,
 		GitHubAccessTokenURI: access_token_uri,
+		Author:               author,
+		GitHubTemplates:      github_templates,
 		Depictions:           depictions,
 		References:           refs,
+		ReferenceValidation:  validation_mode,
+		Replace:              replace,
+		DryRun:               dry_run,
 	}
 
 	return opts, nil
@@ -0,0 +1,57 @@
+package add
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/georeference/request"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// Request defines the JSON-encoded payload used to georeference one or more depictions in "lambda" and "server" mode.
+type Request struct {
+	// Depictions is the list of depiction (image) IDs to georeference.
+	Depictions []int64 `json:"depiction_ids"`
+	// References is the list of `georeference.Reference` instances to assign to each depiction.
+	References []*georeference.Reference `json:"references"`
+	// Replace is a boolean flag signaling that existing references should be replaced rather than merged.
+	Replace bool `json:"replace,omitempty"`
+}
+
+func processRequest(ctx context.Context, assign_opts *georeference.AssignReferencesOptions, req *Request) ([]byte, error) {
+
+	if req == nil || len(req.Depictions) == 0 {
+		return nil, &request.InvalidRequestError{Reason: "Request is missing depiction IDs"}
+	}
+
+	results := make([]*geo_writers.UpdateResult, len(req.Depictions))
+
+	for idx, id := range req.Depictions {
+
+		var r *geo_writers.UpdateResult
+		var err error
+
+		if req.Replace {
+			r, err = georeference.AssignReferences(ctx, assign_opts, id, req.References...)
+		} else {
+			r, err = georeference.AddReferences(ctx, assign_opts, id, req.References...)
+		}
+
+		if err != nil {
+			return nil, &request.PartialFailureError{
+				Completed: req.Depictions[:idx],
+				Failed:    id,
+				Err:       fmt.Errorf("Failed to georeference depiction %d, %w", id, err),
+			}
+		}
+
+		results[idx] = r
+	}
+
+	combined := georeference.CombineResults(assign_opts.DryRun, results...)
+	combined.Log(slog.Default())
+
+	return combined.Response()
+}
@@ -0,0 +1,18 @@
+package add
+
+import (
+	"context"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/georeference/request"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+func runServer(ctx context.Context, opts *RunOptions, assign_opts *georeference.AssignReferencesOptions) error {
+
+	process := func(ctx context.Context, req *Request) ([]byte, error) {
+		return processRequest(ctx, assign_opts, req)
+	}
+
+	handler := request.Handler(process, assign_opts.DryRun)
+	return request.Serve(ctx, opts.ServerURI, handler)
+}
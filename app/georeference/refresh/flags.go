@@ -0,0 +1,92 @@
+package refresh
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+)
+
+var verbose bool
+
+var depiction_reader_uri string
+var depiction_writer_uri string
+
+var subject_reader_uri string
+var subject_writer_uri string
+
+var whosonfirst_reader_uri string
+var sfomuseum_reader_uri string
+var reader_cache_uri string
+
+var access_token_uri string
+
+var place_ids multi.MultiInt64
+var depictions multi.MultiInt64
+
+var index_path string
+var iterator_uri string
+
+var default_geometry_feature_id int64
+var reference_validation string
+var author string
+
+var commit_message_template string
+var pull_request_title_template string
+var pull_request_description_template string
+var branch_template string
+
+var dry_run bool
+
+func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("refresh")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	// Assumed to be something in sfomuseum-data-media-collection
+
+	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&depiction_writer_uri, "depiction-writer-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-writer URI.")
+
+	// Assumed to be something in sfomuseum-data-collection
+
+	fs.StringVar(&subject_reader_uri, "subject-reader-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&subject_writer_uri, "subject-writer-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-writer URI.")
+
+	fs.StringVar(&whosonfirst_reader_uri, "whosonfirst-reader-uri", "https://data.whosonfirst.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&reader_cache_uri, "reader-cache-uri", "", "An optional cache URI used to avoid reading the same Who's On First and SFO Museum records more than once. Valid options are: lru://?size={SIZE} (an in-memory cache), disk://{PATH} (an on-disk cache which is never expired).")
+
+	fs.StringVar(&access_token_uri, "access-token", "", "A valid gocloud.dev/runtimevar URI")
+
+	fs.Var(&place_ids, "place-id", "One or more Who's On First place IDs whose (georeferenced) depictions should be refreshed.")
+	fs.Var(&depictions, "depiction-id", "One or more depiction IDs to refresh in addition to those derived from the -place-id flag.")
+
+	fs.StringVar(&index_path, "index", "", "The path to a JSON-encoded reverse index file (as produced by the sfomuseum-geo-reverse-index tool) used to find the depictions which reference -place-id. If empty the index will be built from the sources passed as arguments.")
+	fs.StringVar(&iterator_uri, "iterator-uri", "repo://?include=properties.georef:depicted=.*", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to find the depictions which reference -place-id if -index is empty.")
+
+	fs.Int64Var(&default_geometry_feature_id, "default-geometry-feature-id", 1729828959, "The WOF ID for the Feature whose centroid will be used as a default absent any references.")
+	fs.StringVar(&reference_validation, "reference-validation", "warn", "How to handle references to deprecated, superseded or not current Who's On First records. Valid options are: follow (replace superseded records with the record they were superseded by), warn (record and store them as-is), refuse (fail the update).")
+	fs.StringVar(&author, "author", "", "The name of the person (or process) refreshing the references. This is used as the commit author for githubapi:// writers.")
+
+	fs.StringVar(&commit_message_template, "commit-message-template", github.DEFAULT_COMMIT_MESSAGE_TEMPLATE, "A text/template template used to derive commit messages for GitHub writers. Templates are passed a github.TemplateContext struct with Author, Action, WhosOnFirstId, SubjectId, Labels, Places, Timestamp and Path fields.")
+	fs.StringVar(&pull_request_title_template, "pull-request-title-template", github.DEFAULT_PULL_REQUEST_TITLE_TEMPLATE, "A text/template template used to derive pull request titles for githubapi-pr:// writers.")
+	fs.StringVar(&pull_request_description_template, "pull-request-description-template", github.DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE, "A text/template template used to derive pull request descriptions for githubapi-pr:// writers.")
+	fs.StringVar(&branch_template, "branch-template", github.DEFAULT_BRANCH_TEMPLATE, "A text/template template used to derive branch names for githubapi-pr:// writers.")
+
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Refresh the georeferences for all the depictions which reference one or more Who's On First places and then recompile their subjects.\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] [iterator-source(N) iterator-source(N)]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
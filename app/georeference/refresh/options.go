@@ -0,0 +1,90 @@
+package refresh
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+)
+
+// subject: a collection object, for example
+// depiction: an image of a collection object, for example
+
+type RunOptions struct {
+	Verbose                  bool
+	SubjectReaderURI         string
+	SubjectWriterURI         string
+	DepictionReaderURI       string
+	DepictionWriterURI       string
+	WhosOnFirstReaderURI     string
+	SFOMuseumReaderURI       string
+	ReaderCacheURI           string
+	GitHubAccessTokenURI     string
+	PlaceIds                 []int64
+	Depictions               []int64
+	IndexPath                string
+	IteratorURI              string
+	IteratorSources          []string
+	DefaultGeometryFeatureId int64
+	ReferenceValidation      georeference.ValidationMode
+	Author                   string
+	GitHubTemplates          *github.Templates
+	DryRun                   bool
+}
+
+func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	err := flagset.SetFlagsFromEnvVars(fs, "SFOMUSEUM")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set flags from environment variables, %w", err)
+	}
+
+	validation_mode, err := georeference.ParseValidationMode(reference_validation)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid -reference-validation flag, %w", err)
+	}
+
+	github_templates := &github.Templates{
+		CommitMessage:          commit_message_template,
+		PullRequestTitle:       pull_request_title_template,
+		PullRequestDescription: pull_request_description_template,
+		Branch:                 branch_template,
+	}
+
+	err = github_templates.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GitHub template flags, %w", err)
+	}
+
+	opts := &RunOptions{
+		Verbose:                  verbose,
+		SubjectReaderURI:         subject_reader_uri,
+		SubjectWriterURI:         subject_writer_uri,
+		DepictionReaderURI:       depiction_reader_uri,
+		DepictionWriterURI:       depiction_writer_uri,
+		WhosOnFirstReaderURI:     whosonfirst_reader_uri,
+		SFOMuseumReaderURI:       sfomuseum_reader_uri,
+		ReaderCacheURI:           reader_cache_uri,
+		GitHubAccessTokenURI:     access_token_uri,
+		PlaceIds:                 place_ids,
+		Depictions:               depictions,
+		IndexPath:                index_path,
+		IteratorURI:              iterator_uri,
+		IteratorSources:          fs.Args(),
+		DefaultGeometryFeatureId: default_geometry_feature_id,
+		ReferenceValidation:      validation_mode,
+		Author:                   author,
+		GitHubTemplates:          github_templates,
+		DryRun:                   dry_run,
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,192 @@
+package refresh
+
+/*
+
+> ./bin/georef-refresh \
+	-place-id 102025263 \
+	-index /usr/local/data/reverse.json \
+	-whosonfirst-reader-uri repo:///usr/local/data/whosonfirst-data-admin-th \
+	-verbose
+
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/reverse"
+	"github.com/whosonfirst/go-reader/v2"
+	gh_writer "github.com/whosonfirst/go-writer-github/v3"
+)
+
+// Run executes the "refresh georeferences" application with a default `flag.FlagSet` instance.
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet(ctx)
+	return RunWithFlagSet(ctx, fs)
+}
+
+// RunWithFlagSet executes the "refresh georeferences" application with a `flag.FlagSet` instance defined by 'fs'.
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := RunOptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts, os.Stdout)
+}
+
+// RunWithOptions executes the "refresh georeferences" application with 'opts'. If 'opts.DryRun' is true the
+// JSON-encoded plan of changes is written to 'wr'.
+func RunWithOptions(ctx context.Context, opts *RunOptions, wr io.Writer) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	var err error
+
+	opts.DepictionWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.DepictionWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for depiction writer URI, %w", err)
+	}
+
+	opts.SubjectWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.SubjectWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for subject writer URI, %w", err)
+	}
+
+	depiction_ids, err := deriveDepictions(ctx, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if len(depiction_ids) == 0 {
+		slog.Info("No depictions to refresh")
+		return nil
+	}
+
+	depiction_reader, err := reader.NewReader(ctx, opts.DepictionReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create depiction reader, %w", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, opts.SubjectReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create subject reader, %w", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, opts.WhosOnFirstReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create whosonfirst reader, %w", err)
+	}
+
+	sfomuseum_reader, err := reader.NewReader(ctx, opts.SFOMuseumReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create architecture reader, %w", err)
+	}
+
+	assign_opts := &georeference.AssignReferencesOptions{
+		DepictionReader:          depiction_reader,
+		SubjectReader:            subject_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		ReaderCacheURI:           opts.ReaderCacheURI,
+		DepictionWriterURI:       opts.DepictionWriterURI,
+		SubjectWriterURI:         opts.SubjectWriterURI,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		Author:                   opts.Author,
+		GitHubTemplates:          opts.GitHubTemplates,
+		ReferenceValidation:      opts.ReferenceValidation,
+		DryRun:                   opts.DryRun,
+	}
+
+	slog.Debug("Refresh depictions", "count", len(depiction_ids))
+
+	rsp, err := georeference.RefreshReferences(ctx, assign_opts, depiction_ids...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to refresh georeferences, %w", err)
+	}
+
+	rsp.Log(slog.Default())
+
+	if opts.DryRun {
+
+		body, err := rsp.Response()
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive plan, %w", err)
+		}
+
+		_, err = fmt.Fprintln(wr, string(body))
+
+		if err != nil {
+			return fmt.Errorf("Failed to write plan, %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deriveDepictions returns the unique list of depiction IDs defined by 'opts.Depictions' and the depictions whose
+// `georef:depicted` property references any of 'opts.PlaceIds'.
+func deriveDepictions(ctx context.Context, opts *RunOptions) ([]int64, error) {
+
+	depiction_ids := slices.Clone(opts.Depictions)
+
+	if len(opts.PlaceIds) == 0 {
+		return depiction_ids, nil
+	}
+
+	var idx *reverse.Index
+	var err error
+
+	if opts.IndexPath != "" {
+
+		slog.Debug("Read reverse index", "path", opts.IndexPath)
+		idx, err = reverse.ReadIndexFromPath(opts.IndexPath)
+
+	} else {
+
+		if len(opts.IteratorSources) == 0 {
+			return nil, fmt.Errorf("Either -index or one or more iterator sources are required to find depictions for -place-id")
+		}
+
+		slog.Debug("Build reverse index", "sources", len(opts.IteratorSources))
+		idx, err = reverse.NewIndexFromIterator(ctx, opts.IteratorURI, opts.IteratorSources...)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load reverse index, %w", err)
+	}
+
+	for _, r := range idx.Lookup(opts.PlaceIds...) {
+
+		if r.Role != reverse.DepictionRole || !slices.Contains(r.Properties, geo.RESERVED_GEOREFERENCE_DEPICTED) {
+			continue
+		}
+
+		if !slices.Contains(depiction_ids, r.Id) {
+			depiction_ids = append(depiction_ids, r.Id)
+		}
+	}
+
+	return depiction_ids, nil
+}
@@ -0,0 +1,29 @@
+package remove
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+func runCommandLine(ctx context.Context, opts *RunOptions, assign_opts *georeference.AssignReferencesOptions) error {
+
+	req := &Request{
+		Depictions: opts.Depictions,
+		Labels:     opts.Labels,
+	}
+
+	rsp, err := processRequest(ctx, assign_opts, req)
+
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintln(os.Stdout, string(rsp))
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 )
 
 var mode string
+var server_uri string
 var verbose bool
 
 var depiction_reader_uri string
@@ -25,6 +26,9 @@ var sfomuseum_reader_uri string
 var access_token_uri string
 
 var depictions multi.MultiInt64
+var labels multi.MultiString
+
+var dry_run bool
 
 var default_geometry_feature_id int64
 
@@ -32,7 +36,8 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("reference")
 
-	fs.StringVar(&mode, "mode", "cli", "Valid options are: cli, lambda.")
+	fs.StringVar(&mode, "mode", "cli", "Valid options are: cli, lambda, server.")
+	fs.StringVar(&server_uri, "server-uri", "http://localhost:8080", "The address to listen for requests on when -mode is \"server\".")
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 
 	// Assumed to be something in sfomuseum-data-media-collection
@@ -56,6 +61,8 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 	fs.Int64Var(&default_geometry_feature_id, "default-geometry-feature-id", 1729828959, "The WOF ID for the Feature whose centroid will be used as a default absent any references.")
 
 	fs.Var(&depictions, "depiction-id", "One or more valid Who's On First IDs for the records being depicted (for example an object image).")
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
+	fs.Var(&labels, "label", "Zero or more georeference labels (for example \"sfomuseum:flightcover_via\") to remove. If empty then all the georeferences for a depiction will be removed.")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "...\n")
@@ -13,6 +13,7 @@ import (
 
 type RunOptions struct {
 	Mode                     string
+	ServerURI                string
 	Verbose                  bool
 	SubjectReaderURI         string
 	SubjectWriterURI         string
@@ -23,6 +24,8 @@ type RunOptions struct {
 	GitHubAccessTokenURI     string
 	DefaultGeometryFeatureId int64
 	Depictions               []int64
+	Labels                   []string
+	DryRun                   bool
 }
 
 func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
@@ -37,6 +40,7 @@ func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions,
 
 	opts := &RunOptions{
 		Mode:                     mode,
+		ServerURI:                server_uri,
 		Verbose:                  verbose,
 		SubjectReaderURI:         subject_reader_uri,
 		SubjectWriterURI:         subject_writer_uri,
@@ -47,6 +51,8 @@ func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions,
 		GitHubAccessTokenURI:     access_token_uri,
 		DefaultGeometryFeatureId: default_geometry_feature_id,
 		Depictions:               depictions,
+		Labels:                   labels,
+		DryRun:                   dry_run,
 	}
 
 	return opts, nil
@@ -82,23 +82,17 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 		DepictionWriterURI:       opts.DepictionWriterURI,
 		SubjectWriterURI:         opts.SubjectWriterURI,
 		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		DryRun:                   opts.DryRun,
 	}
 
-	switch mode {
+	switch opts.Mode {
 	case "cli":
-
-		for _, id := range opts.Depictions {
-
-			_, err := georeference.RemoveAllReferences(ctx, assign_opts, id)
-
-			if err != nil {
-				return fmt.Errorf("Failed to georeference depiction %d, %w", id, err)
-			}
-		}
-
+		return runCommandLine(ctx, opts, assign_opts)
+	case "lambda":
+		return runLambda(ctx, opts, assign_opts)
+	case "server":
+		return runServer(ctx, opts, assign_opts)
 	default:
 		return fmt.Errorf("Invalid or unsupported mode")
 	}
-
-	return nil
 }
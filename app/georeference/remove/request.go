@@ -0,0 +1,55 @@
+package remove
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/georeference/request"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// Request defines the JSON-encoded payload used to remove georeferences from one or more depictions in "lambda" and "server" mode.
+type Request struct {
+	// Depictions is the list of depiction (image) IDs to remove georeferences from.
+	Depictions []int64 `json:"depiction_ids"`
+	// Labels is the optional list of georeference labels to remove. If empty all the georeferences for each depiction are removed.
+	Labels []string `json:"labels,omitempty"`
+}
+
+func processRequest(ctx context.Context, assign_opts *georeference.AssignReferencesOptions, req *Request) ([]byte, error) {
+
+	if req == nil || len(req.Depictions) == 0 {
+		return nil, &request.InvalidRequestError{Reason: "Request is missing depiction IDs"}
+	}
+
+	results := make([]*geo_writers.UpdateResult, len(req.Depictions))
+
+	for idx, id := range req.Depictions {
+
+		var r *geo_writers.UpdateResult
+		var err error
+
+		if len(req.Labels) > 0 {
+			r, err = georeference.RemoveReferences(ctx, assign_opts, id, req.Labels...)
+		} else {
+			r, err = georeference.RemoveAllReferences(ctx, assign_opts, id)
+		}
+
+		if err != nil {
+			return nil, &request.PartialFailureError{
+				Completed: req.Depictions[:idx],
+				Failed:    id,
+				Err:       fmt.Errorf("Failed to remove georeferences for depiction %d, %w", id, err),
+			}
+		}
+
+		results[idx] = r
+	}
+
+	combined := georeference.CombineResults(assign_opts.DryRun, results...)
+	combined.Log(slog.Default())
+
+	return combined.Response()
+}
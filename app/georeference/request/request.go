@@ -0,0 +1,134 @@
+// Package request provides the HTTP handler and server shared by the "server" mode of the georeference applications.
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// InvalidRequestError is the error returned when a request can not be processed because it is malformed or incomplete.
+type InvalidRequestError struct {
+	// The reason the request is invalid.
+	Reason string
+}
+
+// Error returns a string representation of 'e'.
+func (e *InvalidRequestError) Error() string {
+	return e.Reason
+}
+
+// PartialFailureError is the error returned when a request spanning multiple records fails part way through, after
+// the updates for one or more records have already been written.
+type PartialFailureError struct {
+	// The IDs of the records which were updated before the failure.
+	Completed []int64 `json:"completed"`
+	// The ID of the record which failed to be updated.
+	Failed int64 `json:"failed"`
+	// The underlying error.
+	Err error `json:"-"`
+}
+
+// Error returns a string representation of 'e'.
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("Failed to update %d (after updating %v), %v", e.Failed, e.Completed, e.Err)
+}
+
+// Unwrap returns the underlying error for 'e'.
+func (e *PartialFailureError) Unwrap() error {
+	return e.Err
+}
+
+// ProcessFunc is a function for processing a decoded request returning the body to write to the client.
+type ProcessFunc[T any] func(ctx context.Context, req *T) ([]byte, error)
+
+// Handler returns a new `http.Handler` instance which decodes JSON-encoded POST requests and processes them using 'process'.
+// Requests which can not be decoded, or for which 'process' returns an `InvalidRequestError`, receive a 400 response. All
+// other errors receive a 500 response. A `PartialFailureError` error receives a 500 response whose JSON-encoded body lists
+// the records which were updated before the failure. If 'dry_run' is true successful responses are assigned an "application/json" content
+// type, otherwise "application/geo+json".
+func Handler[T any](process ProcessFunc[T], dry_run bool) http.Handler {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		ctx := req.Context()
+
+		if req.Method != http.MethodPost {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var process_req *T
+
+		dec := json.NewDecoder(req.Body)
+		err := dec.Decode(&process_req)
+
+		if err != nil {
+			slog.Error("Failed to decode request", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		body, err := process(ctx, process_req)
+
+		if err != nil {
+
+			var invalid_err *InvalidRequestError
+
+			if errors.As(err, &invalid_err) {
+				slog.Error("Invalid request", "error", err)
+				http.Error(rsp, fmt.Sprintf("Bad request, %s", invalid_err.Reason), http.StatusBadRequest)
+				return
+			}
+
+			var partial_err *PartialFailureError
+
+			if errors.As(err, &partial_err) {
+				slog.Error("Failed to process request", "error", err)
+				rsp.Header().Set("Content-Type", "application/json")
+				rsp.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(rsp).Encode(partial_err)
+				return
+			}
+
+			slog.Error("Failed to process request", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if dry_run {
+			rsp.Header().Set("Content-Type", "application/json")
+		} else {
+			rsp.Header().Set("Content-Type", "application/geo+json")
+		}
+
+		rsp.Write(body)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Serve listens for requests on the address defined by 'server_uri' and dispatches them to 'handler'.
+func Serve(ctx context.Context, server_uri string, handler http.Handler) error {
+
+	u, err := url.Parse(server_uri)
+
+	if err != nil {
+		return fmt.Errorf("Failed to parse server URI, %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+
+	s := &http.Server{
+		Addr:    u.Host,
+		Handler: mux,
+	}
+
+	slog.Info("Listening for requests", "address", server_uri)
+	return s.ListenAndServe()
+}
@@ -0,0 +1,104 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testRequest struct {
+	Ids []int64 `json:"ids"`
+}
+
+func TestHandler(t *testing.T) {
+
+	process := func(ctx context.Context, req *testRequest) ([]byte, error) {
+
+		if req == nil || len(req.Ids) == 0 {
+			return nil, &InvalidRequestError{Reason: "Request is missing IDs"}
+		}
+
+		if req.Ids[0] < 0 {
+			return nil, fmt.Errorf("Failed to write %d", req.Ids[0])
+		}
+
+		return []byte(`{"type":"FeatureCollection","features":[]}`), nil
+	}
+
+	handler := Handler(process, false)
+
+	tests := []struct {
+		Method string
+		Body   string
+		Status int
+	}{
+		{http.MethodPost, `{"ids":[1]}`, http.StatusOK},
+		{http.MethodPost, `{`, http.StatusBadRequest},
+		{http.MethodPost, `null`, http.StatusBadRequest},
+		{http.MethodPost, `{"ids":[]}`, http.StatusBadRequest},
+		{http.MethodPost, `{"ids":[-1]}`, http.StatusInternalServerError},
+		{http.MethodGet, ``, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+
+		req := httptest.NewRequest(test.Method, "/", bytes.NewBufferString(test.Body))
+		rsp := httptest.NewRecorder()
+
+		handler.ServeHTTP(rsp, req)
+
+		if rsp.Code != test.Status {
+			t.Fatalf("Expected status %d for %s '%s', got %d (%s)", test.Status, test.Method, test.Body, rsp.Code, rsp.Body.String())
+		}
+
+		if test.Status == http.StatusOK && rsp.Header().Get("Content-Type") != "application/geo+json" {
+			t.Fatalf("Unexpected content type, %s", rsp.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestHandlerPartialFailure(t *testing.T) {
+
+	process := func(ctx context.Context, req *testRequest) ([]byte, error) {
+
+		for idx, id := range req.Ids {
+
+			if id < 0 {
+				return nil, &PartialFailureError{
+					Completed: req.Ids[:idx],
+					Failed:    id,
+					Err:       fmt.Errorf("Failed to write %d", id),
+				}
+			}
+		}
+
+		return []byte(`{"type":"FeatureCollection","features":[]}`), nil
+	}
+
+	handler := Handler(process, false)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"ids":[1,2,-3]}`))
+	rsp := httptest.NewRecorder()
+
+	handler.ServeHTTP(rsp, req)
+
+	if rsp.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, rsp.Code)
+	}
+
+	var partial_err *PartialFailureError
+
+	err := json.Unmarshal(rsp.Body.Bytes(), &partial_err)
+
+	if err != nil {
+		t.Fatalf("Failed to decode response, %v", err)
+	}
+
+	if len(partial_err.Completed) != 2 || partial_err.Completed[0] != 1 || partial_err.Completed[1] != 2 || partial_err.Failed != -3 {
+		t.Fatalf("Unexpected response, %s", rsp.Body.String())
+	}
+}
@@ -0,0 +1,336 @@
+package recompile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/batch"
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// Summary defines the aggregate outcome of recompiling georeference data for subjects in "cli" mode.
+type Summary struct {
+	// The number of subjects whose georeference data was changed (or, for dry runs, would be changed).
+	Changed int `json:"changed"`
+	// The number of subjects whose georeference data was unchanged.
+	Unchanged int `json:"unchanged"`
+	// The number of subjects which failed to be recompiled.
+	Failed int `json:"failed"`
+	// The number of subjects which were skipped because they were already listed in the checkpoint file.
+	Skipped int `json:"skipped"`
+}
+
+func runCommandLine(ctx context.Context, opts *RunOptions, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_reader reader.Reader) error {
+
+	summary, err := recompileSubjects(ctx, opts, recompile_opts, subject_reader, os.Stdout)
+
+	if summary != nil {
+		slog.Info("Recompile complete", "changed", summary.Changed, "unchanged", summary.Unchanged, "failed", summary.Failed, "skipped", summary.Skipped)
+	}
+
+	return err
+}
+
+// subjectItem defines a subject to recompile in "cli" mode.
+type subjectItem struct {
+	// The ID of the subject.
+	id int64
+	// The label used to identify the subject in log messages.
+	label string
+	// The body of the subject. If nil it will be loaded from the subject reader.
+	body []byte
+}
+
+// recompileSubjects recompiles the georeference data for each of the subjects in 'opts.SubjectIds' and those emitted by
+// 'opts.IteratorSources' with at most 'opts.Workers' subjects processed concurrently. If 'opts.CheckpointPath' is not empty
+// then subjects already listed in that file are skipped and each subject that is recompiled successfully is appended to it.
+// For writers which buffer their writes until they are closed (for example `githubapi-pr://`) subjects are only appended to
+// the checkpoint file once the writer has been closed successfully. If 'opts.ContinueOnError' is true failures are logged and
+// processing continues; otherwise processing stops at the first failure and any buffered writes are discarded rather than
+// published. For dry runs the resulting `plan.Plan` is written to 'wr'.
+func recompileSubjects(ctx context.Context, opts *RunOptions, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_reader reader.Reader, wr io.Writer) (*Summary, error) {
+
+	if opts.DryRun && opts.CheckpointPath != "" {
+		return nil, fmt.Errorf("Checkpoints can not be used with dry runs")
+	}
+
+	subject_writer, update_plan, err := newSubjectWriter(ctx, opts, subject_reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint *batch.Checkpoint
+
+	if opts.CheckpointPath != "" {
+
+		checkpoint, err = batch.NewCheckpoint(opts.CheckpointPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create checkpoint, %w", err)
+		}
+
+		defer checkpoint.Close()
+
+		slog.Debug("Resume from checkpoint", "path", opts.CheckpointPath, "count", checkpoint.Count())
+	}
+
+	// Subjects written to a buffered writer are only recorded in the checkpoint once the writer has been closed
+	buffered := github.IsBufferedURI(opts.SubjectWriterURI)
+	pending := make([]int64, 0)
+
+	workers := opts.Workers
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	logger := slog.Default()
+	logger = logger.With("action", "recompile georeferences")
+
+	summary := new(Summary)
+	mu := new(sync.Mutex)
+
+	// fail records a failure for 'label'. It returns nil if 'opts.ContinueOnError' is true, otherwise an error which
+	// stops any further processing.
+	fail := func(label string, err error) error {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		summary.Failed += 1
+
+		logger.Error("Failed to recompile georeferences", "subject", label, "error", err)
+
+		if opts.ContinueOnError {
+			return nil
+		}
+
+		return fmt.Errorf("Failed to recompile georeferences for %s, %w", label, err)
+	}
+
+	recompile := func(ctx context.Context, item *subjectItem) error {
+
+		if checkpoint != nil && checkpoint.Done(item.id) {
+
+			logger.Debug("Subject already recompiled, skipping", "subject", item.label)
+
+			mu.Lock()
+			summary.Skipped += 1
+			mu.Unlock()
+
+			return nil
+		}
+
+		body := item.body
+
+		if body == nil {
+
+			b, err := wof_reader.LoadBytes(ctx, subject_reader, item.id)
+
+			if err != nil {
+				return fail(item.label, fmt.Errorf("Failed to read body, %w", err))
+			}
+
+			body = b
+		}
+
+		has_changed, _, err := recompileSubject(ctx, recompile_opts, subject_writer, body)
+
+		if err != nil {
+			return fail(item.label, err)
+		}
+
+		if checkpoint != nil && !buffered {
+
+			err := checkpoint.Record(item.id)
+
+			if err != nil {
+				return fail(item.label, err)
+			}
+		}
+
+		logger.Debug("Recompiled subject", "subject", item.label, "changed", has_changed)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if checkpoint != nil && buffered {
+			pending = append(pending, item.id)
+		}
+
+		if has_changed {
+			summary.Changed += 1
+		} else {
+			summary.Unchanged += 1
+		}
+
+		return nil
+	}
+
+	// The first error which stops processing
+	var run_err error
+
+	if len(opts.SubjectIds) > 0 {
+
+		logger.Debug("Recompile georeference data for specific record IDs", "count", len(opts.SubjectIds))
+
+		items := make([]*subjectItem, len(opts.SubjectIds))
+
+		for idx, id := range opts.SubjectIds {
+			items[idx] = &subjectItem{id: id, label: fmt.Sprintf("%d", id)}
+		}
+
+		run_err = fanout.Each(ctx, workers, items, recompile)
+	}
+
+	if len(opts.IteratorSources) > 0 && run_err == nil {
+		run_err = recompileIteratorSubjects(ctx, opts, workers, recompile, fail)
+	}
+
+	if run_err != nil {
+		discardSubjectWriter(ctx, subject_writer)
+		return summary, run_err
+	}
+
+	err = subject_writer.Close(ctx)
+
+	if err != nil {
+		return summary, fmt.Errorf("Failed to close subject writer, %w", err)
+	}
+
+	for _, id := range pending {
+
+		err := checkpoint.Record(id)
+
+		if err != nil {
+			return summary, fmt.Errorf("Failed to record %d in checkpoint, %w", id, err)
+		}
+	}
+
+	if update_plan != nil {
+
+		enc := json.NewEncoder(wr)
+		err = enc.Encode(update_plan)
+
+		if err != nil {
+			return summary, fmt.Errorf("Failed to encode plan, %w", err)
+		}
+	}
+
+	if summary.Failed > 0 {
+		return summary, fmt.Errorf("Failed to recompile georeferences for %d subject(s)", summary.Failed)
+	}
+
+	return summary, nil
+}
+
+// recompileIteratorSubjects passes each of the subjects emitted by 'opts.IteratorSources' to 'recompile', using `fanout.Each`,
+// in chunks so that the bodies of all the subjects are not held in memory at once. Iterator errors are passed to 'fail'.
+func recompileIteratorSubjects(ctx context.Context, opts *RunOptions, workers int, recompile func(context.Context, *subjectItem) error, fail func(string, error) error) error {
+
+	slog.Debug("Recompile georeference data from iterator", "iterator", opts.IteratorURI, "sources", len(opts.IteratorSources))
+
+	iter, err := iterate.NewIterator(ctx, opts.IteratorURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create new iterator, %w", err)
+	}
+
+	defer iter.Close()
+
+	chunk_size := workers * 10
+	items := make([]*subjectItem, 0, chunk_size)
+
+	for rec, err := range iter.Iterate(ctx, opts.IteratorSources...) {
+
+		if err != nil {
+
+			err = fail("iterator", fmt.Errorf("Iterator signaled an error, %w", err))
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		body, err := io.ReadAll(rec.Body)
+		rec.Body.Close()
+
+		if err != nil {
+
+			err = fail(rec.Path, fmt.Errorf("Failed to read body, %w", err))
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		id, err := properties.Id(body)
+
+		if err != nil {
+
+			err = fail(rec.Path, fmt.Errorf("Failed to derive subject ID, %w", err))
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		items = append(items, &subjectItem{id: id, label: fmt.Sprintf("%d", id), body: body})
+
+		if len(items) < chunk_size {
+			continue
+		}
+
+		err = fanout.Each(ctx, workers, items, recompile)
+
+		if err != nil {
+			return err
+		}
+
+		items = make([]*subjectItem, 0, chunk_size)
+	}
+
+	return fanout.Each(ctx, workers, items, recompile)
+}
+
+// discardSubjectWriter discards any writes buffered by 'subject_writer' so that they are not published. Writers which don't
+// buffer their writes have already persisted them and are simply closed.
+func discardSubjectWriter(ctx context.Context, subject_writer writer.Writer) {
+
+	discarded, err := geo_writers.Discard(ctx, subject_writer)
+
+	if err != nil {
+		slog.Error("Failed to discard subject writer", "error", err)
+		return
+	}
+
+	if discarded {
+		return
+	}
+
+	err = subject_writer.Close(ctx)
+
+	if err != nil {
+		slog.Error("Failed to close subject writer", "error", err)
+	}
+}
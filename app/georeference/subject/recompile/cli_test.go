@@ -0,0 +1,136 @@
+package recompile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func setupRecompileOptions(t *testing.T) (*RunOptions, *georeference.RecompileGeorefencesForSubjectOptions, reader.Reader) {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../../../../fixtures")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	tmp_dir := t.TempDir()
+
+	err = os.CopyFS(tmp_dir, os.DirFS(filepath.Join(path_fixtures, "sfomuseum-data-collection")))
+
+	if err != nil {
+		t.Fatalf("Failed to copy fixtures, %v", err)
+	}
+
+	depiction_uri := fmt.Sprintf("repo://%s/sfomuseum-data-media-collection", path_fixtures)
+	subject_uri := fmt.Sprintf("repo://%s", tmp_dir)
+
+	depiction_reader, err := reader.NewReader(ctx, depiction_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create depiction reader, %v", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, subject_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create subject reader, %v", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s/whosonfirst-data-admin", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	sfomuseum_reader, err := reader.NewMultiReaderFromURIs(ctx, depiction_uri, subject_uri, fmt.Sprintf("repo://%s/sfomuseum-data-architecture", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create sfomuseum reader, %v", err)
+	}
+
+	opts := &RunOptions{
+		Mode:             "cli",
+		SubjectWriterURI: subject_uri,
+		Workers:          2,
+		CheckpointPath:   filepath.Join(t.TempDir(), "checkpoint.txt"),
+	}
+
+	recompile_opts := &georeference.RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          depiction_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		DefaultGeometryFeatureId: 1159396131,
+	}
+
+	return opts, recompile_opts, subject_reader
+}
+
+func TestRecompileSubjects(t *testing.T) {
+
+	ctx := context.Background()
+	opts, recompile_opts, subject_reader := setupRecompileOptions(t)
+
+	// 1234 does not exist
+
+	opts.SubjectIds = []int64{1511907389, 1234, 1897902471, 1511948573}
+	opts.ContinueOnError = true
+
+	summary, err := recompileSubjects(ctx, opts, recompile_opts, subject_reader, new(bytes.Buffer))
+
+	if err == nil {
+		t.Fatalf("Expected run with failures to return an error")
+	}
+
+	if summary.Failed != 1 || summary.Changed+summary.Unchanged != 3 || summary.Skipped != 0 {
+		t.Fatalf("Unexpected summary, %v", summary)
+	}
+
+	// Resume from checkpoint
+
+	summary, err = recompileSubjects(ctx, opts, recompile_opts, subject_reader, new(bytes.Buffer))
+
+	if err == nil {
+		t.Fatalf("Expected resumed run with failures to return an error")
+	}
+
+	if summary.Failed != 1 || summary.Changed+summary.Unchanged != 0 || summary.Skipped != 3 {
+		t.Fatalf("Unexpected summary for resumed run, %v", summary)
+	}
+
+	// Stop at the first error
+
+	opts.ContinueOnError = false
+	opts.CheckpointPath = ""
+	opts.Workers = 1
+	opts.SubjectIds = []int64{1234, 1511907389, 1897902471}
+
+	summary, err = recompileSubjects(ctx, opts, recompile_opts, subject_reader, new(bytes.Buffer))
+
+	if err == nil {
+		t.Fatalf("Expected run to fail")
+	}
+
+	if summary.Failed != 1 || summary.Changed+summary.Unchanged != 0 {
+		t.Fatalf("Expected run to stop after first failure, %v", summary)
+	}
+
+	// Checkpoints and dry runs don't mix
+
+	opts.DryRun = true
+	opts.CheckpointPath = filepath.Join(t.TempDir(), "checkpoint.txt")
+
+	_, err = recompileSubjects(ctx, opts, recompile_opts, subject_reader, new(bytes.Buffer))
+
+	if err == nil {
+		t.Fatalf("Expected dry run with checkpoint to fail")
+	}
+}
@@ -8,13 +8,18 @@ import (
 
 	"github.com/sfomuseum/go-flags/flagset"
 	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
 )
 
+var mode string
+var server_uri string
+
 var verbose bool
 
 var depiction_reader_uri string
 var whosonfirst_reader_uri string
 var sfomuseum_reader_uri string
+var reader_cache_uri string
 
 var subject_reader_uri string
 var subject_writer_uri string
@@ -26,9 +31,19 @@ var default_geometry_feature_id int64
 
 var iterator_uri string
 
+var membership_uri string
+
+var dry_run bool
+
+var workers int
+var checkpoint_path string
+var continue_on_error bool
+
 func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("reference")
+	fs.StringVar(&mode, "mode", "cli", "Valid options are: cli, lambda, server.")
+	fs.StringVar(&server_uri, "server-uri", "http://localhost:8080", "The address to listen for requests on when -mode is \"server\".")
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 
 	// Assumed to be something in sfomuseum-data-media-collection
@@ -36,6 +51,7 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-reader URI.")
 	fs.StringVar(&whosonfirst_reader_uri, "whosonfirst-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
 	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&reader_cache_uri, "reader-cache-uri", "", "An optional cache URI used to avoid reading the same Who's On First and SFO Museum records more than once. Valid options are: lru://?size={SIZE} (an in-memory cache), disk://{PATH} (an on-disk cache which is never expired).")
 
 	fs.StringVar(&subject_reader_uri, "subject-reader-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-reader URI.")
 	fs.StringVar(&subject_writer_uri, "subject-writer-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-writer URI.")
@@ -47,6 +63,14 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs.StringVar(&iterator_uri, "iterator-uri", "repo://?include=properties.georef:depicted=.*", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to derive records whose georeference data should be recompiled.")
 
+	fs.StringVar(&membership_uri, "membership-uri", georeference.DEFAULT_MEMBERSHIP_URI, "A URI used to derive the list of depictions for each subject. Valid options are: property://?path={PROPERTY} (read depiction IDs from one or more subject properties), depictions:// (read depiction IDs from the georef:depictions and geotag:depictions properties; since georef:depictions is written by recompiling this is not suitable for recompiling subjects from scratch), parent://?source={PATH}[&iterator-uri={URI}] (find depictions whose wof:parent_id property is equal to the subject's ID by iterating one or more sources).")
+
+	fs.IntVar(&workers, "workers", 4, "The maximum number of subjects to recompile concurrently when -mode is \"cli\".")
+	fs.StringVar(&checkpoint_path, "checkpoint", "", "The path to a file used to record the IDs of subjects which have been recompiled, one per line, when -mode is \"cli\". Subjects already listed in this file are skipped so that an interrupted run can be resumed. If -subject-writer-uri buffers its writes until it is closed (for example githubapi-pr://) subjects are only recorded once the writer has been closed successfully. This flag can not be used with -dry-run.")
+	fs.BoolVar(&continue_on_error, "continue-on-error", false, "Log subjects which fail to be recompiled and keep going, rather than stopping at the first error, when -mode is \"cli\".")
+
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
+
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "...\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options]\n", os.Args[0])
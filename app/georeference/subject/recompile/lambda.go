@@ -0,0 +1,20 @@
+package recompile
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func runLambda(ctx context.Context, opts *RunOptions, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_reader reader.Reader) error {
+
+	handler := func(ctx context.Context, req *Request) (json.RawMessage, error) {
+		return processRequest(ctx, opts, recompile_opts, subject_reader, req)
+	}
+
+	lambda.Start(handler)
+	return nil
+}
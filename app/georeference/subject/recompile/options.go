@@ -12,17 +12,25 @@ import (
 // depiction: an image of a collection object, for example
 
 type RunOptions struct {
+	Mode                     string
+	ServerURI                string
 	Verbose                  bool
 	SubjectReaderURI         string
 	SubjectWriterURI         string
 	DepictionReaderURI       string
 	WhosOnFirstReaderURI     string
 	SFOMuseumReaderURI       string
+	ReaderCacheURI           string
 	GitHubAccessTokenURI     string
 	SubjectIds               []int64
 	IteratorURI              string
 	IteratorSources          []string
+	MembershipURI            string
 	DefaultGeometryFeatureId int64
+	DryRun                   bool
+	Workers                  int
+	CheckpointPath           string
+	ContinueOnError          bool
 }
 
 func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
@@ -38,17 +46,25 @@ func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions,
 	iterator_sources := fs.Args()
 
 	opts := &RunOptions{
+		Mode:                     mode,
+		ServerURI:                server_uri,
 		Verbose:                  verbose,
 		SubjectReaderURI:         subject_reader_uri,
 		SubjectWriterURI:         subject_writer_uri,
 		DepictionReaderURI:       depiction_reader_uri,
 		WhosOnFirstReaderURI:     whosonfirst_reader_uri,
 		SFOMuseumReaderURI:       sfomuseum_reader_uri,
+		ReaderCacheURI:           reader_cache_uri,
 		GitHubAccessTokenURI:     access_token_uri,
 		SubjectIds:               subject_ids,
 		DefaultGeometryFeatureId: default_geometry_feature_id,
 		IteratorURI:              iterator_uri,
 		IteratorSources:          iterator_sources,
+		MembershipURI:            membership_uri,
+		DryRun:                   dry_run,
+		Workers:                  workers,
+		CheckpointPath:           checkpoint_path,
+		ContinueOnError:          continue_on_error,
 	}
 
 	return opts, nil
@@ -21,16 +21,11 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log/slog"
 
 	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
 	"github.com/whosonfirst/go-reader/v2"
-	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
-	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	gh_writer "github.com/whosonfirst/go-writer-github/v3"
-	"github.com/whosonfirst/go-writer/v3"
 )
 
 // Run executes the "geoference-recompile-subject" application with a default `flag.FlagSet` instance.
@@ -85,95 +80,40 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 		return fmt.Errorf("Failed to create architecture reader, %w", err)
 	}
 
-	subject_writer, err := writer.NewWriter(ctx, opts.SubjectWriterURI)
+	var subject_reader reader.Reader
 
-	if err != nil {
-		return fmt.Errorf("Failed to create subject writer, %w", err)
-	}
-
-	recompile_opts := &georeference.RecompileGeorefencesForSubjectOptions{
-		DepictionReader:          depiction_reader,
-		SFOMuseumReader:          sfomuseum_reader,
-		WhosOnFirstReader:        whosonfirst_reader,
-		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
-	}
-
-	if len(opts.SubjectIds) > 0 {
-
-		slog.Debug("Recompile georeference data for specific record IDs", "count", len(opts.SubjectIds))
+	if opts.Mode != "cli" || len(opts.SubjectIds) > 0 || opts.DryRun {
 
-		subject_reader, err := reader.NewReader(ctx, opts.SubjectReaderURI)
+		subject_reader, err = reader.NewReader(ctx, opts.SubjectReaderURI)
 
 		if err != nil {
 			return fmt.Errorf("Failed to create subject reader, %w", err)
 		}
-
-		for _, id := range opts.SubjectIds {
-
-			body, err := wof_reader.LoadBytes(ctx, subject_reader, id)
-
-			if err != nil {
-				return fmt.Errorf("Failed to read body for %d, %w", id, err)
-			}
-
-			has_changed, new_body, err := georeference.RecompileGeorefencesForSubject(ctx, recompile_opts, body)
-
-			if err != nil {
-				return fmt.Errorf("Failed to recompile georeferences for %d, %w", id, err)
-			}
-
-			if !has_changed {
-				continue
-			}
-
-			_, err = wof_writer.WriteBytes(ctx, subject_writer, new_body)
-
-			if err != nil {
-				return fmt.Errorf("Failed to write changes for %d, %w", id, err)
-			}
-		}
 	}
 
-	if len(opts.IteratorSources) > 0 {
-
-		slog.Debug("Recompile georeference data from iterator", "source", len(opts.IteratorURI))
+	membership, err := georeference.NewSubjectMembership(ctx, opts.MembershipURI)
 
-		iter, err := iterate.NewIterator(ctx, opts.IteratorURI)
-
-		if err != nil {
-			return fmt.Errorf("Failed to create new iterator, %w", err)
-		}
-
-		for rec, err := range iter.Iterate(ctx, opts.IteratorSources...) {
-
-			if err != nil {
-				return fmt.Errorf("Iterator signaled an error, %w", err)
-			}
-
-			body, err := io.ReadAll(rec.Body)
-			rec.Body.Close()
-
-			if err != nil {
-				return fmt.Errorf("Failed to close reader for %s, %w", rec.Path, err)
-			}
-
-			has_changed, new_body, err := georeference.RecompileGeorefencesForSubject(ctx, recompile_opts, body)
-
-			if err != nil {
-				return fmt.Errorf("Failed to recompile georeferences for %s, %w", rec.Path, err)
-			}
-
-			if !has_changed {
-				continue
-			}
-
-			_, err = wof_writer.WriteBytes(ctx, subject_writer, new_body)
+	if err != nil {
+		return fmt.Errorf("Failed to create subject membership, %w", err)
+	}
 
-			if err != nil {
-				return fmt.Errorf("Failed to write changes for %s, %w", rec.Path, err)
-			}
-		}
+	recompile_opts := &georeference.RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          depiction_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		ReaderCacheURI:           opts.ReaderCacheURI,
+		WhosOnFirstReader:        whosonfirst_reader,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		Membership:               membership,
 	}
 
-	return nil
+	switch opts.Mode {
+	case "cli":
+		return runCommandLine(ctx, opts, recompile_opts, subject_reader)
+	case "lambda":
+		return runLambda(ctx, opts, recompile_opts, subject_reader)
+	case "server":
+		return runServer(ctx, opts, recompile_opts, subject_reader)
+	default:
+		return fmt.Errorf("Invalid or unsupported mode")
+	}
 }
@@ -0,0 +1,131 @@
+package recompile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/sfomuseum/go-sfomuseum-geo/app/georeference/request"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/whosonfirst/go-reader/v2"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// Request defines the JSON-encoded payload used to recompile georeferences for one or more subjects in "lambda" and "server" mode.
+type Request struct {
+	// Subjects is the list of subject (object) IDs to recompile georeference data for.
+	Subjects []int64 `json:"subject_ids"`
+}
+
+func processRequest(ctx context.Context, opts *RunOptions, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_reader reader.Reader, req *Request) ([]byte, error) {
+
+	if req == nil || len(req.Subjects) == 0 {
+		return nil, &request.InvalidRequestError{Reason: "Request is missing subject IDs"}
+	}
+
+	subject_writer, update_plan, err := newSubjectWriter(ctx, opts, subject_reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// abort discards any writes buffered by 'subject_writer' and then returns 'err'. Buffered writers (for example
+	// `githubapi-pr://`) are deliberately not closed since that would publish a partial update.
+	abort := func(err error) ([]byte, error) {
+		discardSubjectWriter(ctx, subject_writer)
+		return nil, err
+	}
+
+	fc := geojson.NewFeatureCollection()
+
+	for _, id := range req.Subjects {
+
+		body, err := wof_reader.LoadBytes(ctx, subject_reader, id)
+
+		if err != nil {
+			return abort(fmt.Errorf("Failed to read body for %d, %w", id, err))
+		}
+
+		has_changed, new_body, err := recompileSubject(ctx, recompile_opts, subject_writer, body)
+
+		if err != nil {
+			return abort(fmt.Errorf("Failed to recompile georeferences for %d, %w", id, err))
+		}
+
+		if !has_changed {
+			continue
+		}
+
+		f, err := geojson.UnmarshalFeature(new_body)
+
+		if err != nil {
+			return abort(fmt.Errorf("Failed to unmarshal feature for %d, %w", id, err))
+		}
+
+		fc.Append(f)
+	}
+
+	err = subject_writer.Close(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to close subject writer, %w", err)
+	}
+
+	if update_plan != nil {
+		return json.Marshal(update_plan)
+	}
+
+	return fc.MarshalJSON()
+}
+
+// newSubjectWriter returns a new `writer.Writer` instance for subject records. If 'opts.DryRun' is true this
+// will be a `plan.PlanWriter` instance and the `plan.Plan` it records changes to is returned as well.
+func newSubjectWriter(ctx context.Context, opts *RunOptions, subject_reader reader.Reader) (writer.Writer, *plan.Plan, error) {
+
+	if opts.DryRun {
+
+		update_plan := plan.NewPlan()
+		subject_writer, err := plan.NewPlanWriter(ctx, update_plan, plan.SubjectRole, subject_reader)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to create subject writer, %w", err)
+		}
+
+		return subject_writer, update_plan, nil
+	}
+
+	subject_writer, err := geo_writers.NewWriter(ctx, opts.SubjectWriterURI)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to create subject writer, %w", err)
+	}
+
+	return subject_writer, nil, nil
+}
+
+// recompileSubject recompiles the georeference data for 'body' and writes the updated record to 'subject_writer' if it has changed.
+func recompileSubject(ctx context.Context, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_writer writer.Writer, body []byte) (bool, []byte, error) {
+
+	has_changed, new_body, err := georeference.RecompileGeorefencesForSubject(ctx, recompile_opts, body)
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	if !has_changed {
+		return false, nil, nil
+	}
+
+	_, err = wof_writer.WriteBytes(ctx, subject_writer, new_body)
+
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to write changes, %w", err)
+	}
+
+	return true, new_body, nil
+}
@@ -0,0 +1,19 @@
+package recompile
+
+import (
+	"context"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/georeference/request"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func runServer(ctx context.Context, opts *RunOptions, recompile_opts *georeference.RecompileGeorefencesForSubjectOptions, subject_reader reader.Reader) error {
+
+	process := func(ctx context.Context, req *Request) ([]byte, error) {
+		return processRequest(ctx, opts, recompile_opts, subject_reader, req)
+	}
+
+	handler := request.Handler(process, opts.DryRun)
+	return request.Serve(ctx, opts.ServerURI, handler)
+}
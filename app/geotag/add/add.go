@@ -69,6 +69,7 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 		WhosOnFirstReader:  whosonfirst_reader,
 		DepictionWriterURI: depiction_writer_uri, // to be remove post writer/v3 (Clone) release
 		SubjectWriterURI:   subject_writer_uri,   // to be remove post writer/v3 (Clone) release
+		DryRun:             dry_run,
 	}
 
 	switch mode {
@@ -76,6 +77,8 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 		return runCommandLine(ctx, opts)
 	case "lambda":
 		return runLambda(ctx, opts)
+	case "batch":
+		return runBatch(ctx, opts)
 	default:
 		return fmt.Errorf("Invalid or unsupported mode")
 	}
@@ -0,0 +1,71 @@
+package add
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/batch"
+	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+func runBatch(ctx context.Context, opts *geotag.AddGeotagDepictionOptions) error {
+
+	km := batch.NewKeyedMutex()
+
+	process := func(ctx context.Context, line []byte) (int64, *geo_writers.UpdateResult, error) {
+
+		var update *geotag.Depiction
+
+		err := json.Unmarshal(line, &update)
+
+		if err != nil {
+			return 0, nil, fmt.Errorf("Failed to decode line, %w", err)
+		}
+
+		if update == nil || update.DepictionId == 0 {
+			return 0, nil, fmt.Errorf("Line is missing depiction ID")
+		}
+
+		if update.Feature == nil {
+			return update.DepictionId, nil, fmt.Errorf("Line is missing geotag feature")
+		}
+
+		unlock, err := batch.LockSubject(ctx, km, opts.DepictionReader, update.DepictionId)
+
+		if err != nil {
+			return update.DepictionId, nil, err
+		}
+
+		defer unlock()
+
+		r, err := geotag.AddGeotagDepiction(ctx, opts, update)
+
+		if err != nil {
+			return update.DepictionId, nil, fmt.Errorf("Failed to geotag depiction %d, %w", update.DepictionId, err)
+		}
+
+		return update.DepictionId, r, nil
+	}
+
+	batch_opts := &batch.RunOptions{
+		Workers: batch_workers,
+		Process: process,
+	}
+
+	summary, err := batch.RunWithPaths(ctx, batch_opts, batch_input, batch_results)
+
+	if err != nil {
+		return fmt.Errorf("Failed to process batch, %w", err)
+	}
+
+	slog.Info("Batch complete", "total", summary.Total, "succeeded", summary.Succeeded, "failed", summary.Failed)
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d lines failed", summary.Failed, summary.Total)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 
 	geojson "github.com/sfomuseum/go-geojson-geotag/v2"
@@ -28,11 +29,24 @@ func runCommandLine(ctx context.Context, opts *geotag.AddGeotagDepictionOptions)
 			Feature:     f,
 		}
 
-		_, err := geotag.AddGeotagDepiction(ctx, opts, update)
+		r, err := geotag.AddGeotagDepiction(ctx, opts, update)
 
 		if err != nil {
 			return fmt.Errorf("Failed to geotag depiction %d, %v", depiction_id, err)
 		}
+
+		r.Log(slog.Default())
+
+		if opts.DryRun {
+
+			rsp, err := r.Response()
+
+			if err != nil {
+				return fmt.Errorf("Failed to derive response for depiction %d, %v", depiction_id, err)
+			}
+
+			fmt.Println(string(rsp))
+		}
 	}
 
 	return nil
@@ -12,6 +12,10 @@ import (
 
 var mode string
 
+var batch_input string
+var batch_results string
+var batch_workers int
+
 var depiction_reader_uri string
 var depiction_writer_uri string
 
@@ -25,12 +29,17 @@ var access_token_uri string
 var depictions multi.MultiInt64
 
 var verbose bool
+var dry_run bool
 
 func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("geotag")
 
-	fs.StringVar(&mode, "mode", "cli", "Valid options are: cli, lambda.")
+	fs.StringVar(&mode, "mode", "cli", "Valid options are: batch, cli, lambda.")
+
+	fs.StringVar(&batch_input, "batch-input", "-", "The path to a JSONL file, where each line is a JSON-encoded geotag.Depiction object, to process when -mode is \"batch\". If \"-\" then input is read from STDIN.")
+	fs.StringVar(&batch_results, "batch-results", "-", "The path to write the JSONL-encoded result for each line of input to when -mode is \"batch\". If \"-\" then results are written to STDOUT.")
+	fs.IntVar(&batch_workers, "batch-workers", 4, "The maximum number of lines to process concurrently when -mode is \"batch\".")
 
 	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "", "A valid whosonfirst/go-reader URI.")
 	fs.StringVar(&depiction_writer_uri, "depiction-writer-uri", "", "A valid whosonfirst/go-writer URI.")
@@ -45,6 +54,7 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 	fs.Var(&depictions, "depiction-id", "One or more valid Who's On First IDs for the records being depicted.")
 
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "update-depiction is a command-tool for applying geotagging updates to one or more depictions.\n")
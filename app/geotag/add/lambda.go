@@ -3,6 +3,7 @@ package add
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
@@ -12,12 +13,13 @@ func runLambda(ctx context.Context, opts *geotag.AddGeotagDepictionOptions) erro
 
 	handler := func(ctx context.Context, update *geotag.Depiction) error {
 
-		_, err := geotag.AddGeotagDepiction(ctx, opts, update)
+		r, err := geotag.AddGeotagDepiction(ctx, opts, update)
 
 		if err != nil {
 			return fmt.Errorf("Failed to update depiction %d, %v", update.DepictionId, err)
 		}
 
+		r.Log(slog.Default())
 		return nil
 	}
 
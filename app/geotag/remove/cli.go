@@ -3,6 +3,7 @@ package remove
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
 )
@@ -15,12 +16,20 @@ func runCommandLine(ctx context.Context, opts *geotag.RemoveGeotagDepictionOptio
 			DepictionId: depiction_id,
 		}
 
-		rsp, err := geotag.RemoveGeotagDepiction(ctx, opts, update)
+		r, err := geotag.RemoveGeotagDepiction(ctx, opts, update)
 
 		if err != nil {
 			return fmt.Errorf("Failed to remove geotag depiction %d, %v", depiction_id, err)
 		}
 
+		r.Log(slog.Default())
+
+		rsp, err := r.Response()
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive response for depiction %d, %v", depiction_id, err)
+		}
+
 		fmt.Println(string(rsp))
 	}
 
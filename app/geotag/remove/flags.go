@@ -26,6 +26,7 @@ var access_token_uri string
 
 var depictions multi.MultiInt64
 var verbose bool
+var dry_run bool
 
 func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 
@@ -48,6 +49,7 @@ func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
 	fs.Int64Var(&default_geometry_id, "default-geometry-id", 1, "A valid Who's On First (or equivalent) ID whose geometry will be used as default geometry for records, if necessary")
 
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "update-depiction is a command-tool for applying geotagging updates to one or more depictions.\n")
@@ -3,6 +3,7 @@ package remove
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
@@ -12,12 +13,13 @@ func runLambda(ctx context.Context, opts *geotag.RemoveGeotagDepictionOptions) e
 
 	handler := func(ctx context.Context, update *geotag.Depiction) error {
 
-		_, err := geotag.RemoveGeotagDepiction(ctx, opts, update)
+		r, err := geotag.RemoveGeotagDepiction(ctx, opts, update)
 
 		if err != nil {
 			return fmt.Errorf("Failed to remove depiction %d, %v", update.DepictionId, err)
 		}
 
+		r.Log(slog.Default())
 		return nil
 	}
 
@@ -86,6 +86,7 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 		WhosOnFirstReader:  whosonfirst_reader,
 		DefaultGeometry:    default_geom,
 		Author:             "",
+		DryRun:             dry_run,
 	}
 
 	switch mode {
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+var verbose bool
+
+var iterator_uri string
+var format string
+
+var check_subject_geometry bool
+
+var depiction_reader_uri string
+var whosonfirst_reader_uri string
+var sfomuseum_reader_uri string
+
+var membership_uri string
+var default_geometry_feature_id int64
+
+func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("lint")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	fs.StringVar(&iterator_uri, "iterator-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to iterate depiction and subject records (for example sfomuseum-data-media-collection and sfomuseum-data-collection) and their alternate geometry files.")
+	fs.StringVar(&format, "format", "text", "The output format for the lint report. Valid options are: text (a human-readable report with violations grouped by rule), json (a JSON-encoded report).")
+
+	fs.BoolVar(&check_subject_geometry, "check-subject-geometry", true, "Recompile the georeferences for each subject and report subjects whose geometry does not match the recompiled geometry.")
+
+	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-reader URI used to load depictions when -check-subject-geometry is true.")
+	fs.StringVar(&whosonfirst_reader_uri, "whosonfirst-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI used when -check-subject-geometry is true.")
+	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI used when -check-subject-geometry is true.")
+
+	fs.StringVar(&membership_uri, "membership-uri", georeference.DEFAULT_MEMBERSHIP_URI, "A URI used to derive the list of depictions for each subject when -check-subject-geometry is true. See the -membership-uri flag of the georef-recompile-subject tool for details.")
+	fs.Int64Var(&default_geometry_feature_id, "default-geometry-feature-id", 1729828959, "The WOF ID for the Feature whose centroid will be used as a default absent any references.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Report inconsistencies in the georeference and geotag data of depiction and subject records.\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] iterator-source(N) iterator-source(N)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
@@ -0,0 +1,129 @@
+package lint
+
+/*
+
+> ./bin/geo-lint \
+	-whosonfirst-reader-uri repo:///usr/local/data/whosonfirst-data-admin \
+	/usr/local/data/sfomuseum-data-media-collection \
+	/usr/local/data/sfomuseum-data-collection
+
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	geo_lint "github.com/sfomuseum/go-sfomuseum-geo/lint"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+const FORMAT_TEXT string = "text"
+const FORMAT_JSON string = "json"
+
+// Run executes the "lint" application with a default `flag.FlagSet` instance.
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet(ctx)
+	return RunWithFlagSet(ctx, fs)
+}
+
+// RunWithFlagSet executes the "lint" application with a `flag.FlagSet` instance defined by 'fs'.
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := RunOptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts, os.Stdout)
+}
+
+// RunWithOptions executes the "lint" application with 'opts', writing the lint report to 'wr'. An error is returned
+// if any violations are found.
+func RunWithOptions(ctx context.Context, opts *RunOptions, wr io.Writer) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	switch opts.Format {
+	case FORMAT_TEXT, FORMAT_JSON:
+		// pass
+	default:
+		return fmt.Errorf("Invalid or unsupported format, %s", opts.Format)
+	}
+
+	if len(opts.IteratorSources) == 0 {
+		return fmt.Errorf("No iterator sources to lint")
+	}
+
+	linter_opts := &geo_lint.LinterOptions{}
+
+	if opts.CheckSubjectGeometry {
+
+		depiction_reader, err := reader.NewReader(ctx, opts.DepictionReaderURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create depiction reader, %w", err)
+		}
+
+		whosonfirst_reader, err := reader.NewReader(ctx, opts.WhosOnFirstReaderURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create whosonfirst reader, %w", err)
+		}
+
+		sfomuseum_reader, err := reader.NewReader(ctx, opts.SFOMuseumReaderURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create sfomuseum reader, %w", err)
+		}
+
+		membership, err := georeference.NewSubjectMembership(ctx, opts.MembershipURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create subject membership, %w", err)
+		}
+
+		linter_opts.RecompileOptions = &georeference.RecompileGeorefencesForSubjectOptions{
+			DepictionReader:          depiction_reader,
+			SFOMuseumReader:          sfomuseum_reader,
+			WhosOnFirstReader:        whosonfirst_reader,
+			DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+			Membership:               membership,
+		}
+	}
+
+	linter := geo_lint.NewLinter(linter_opts)
+
+	err := linter.AddFromIterator(ctx, opts.IteratorURI, opts.IteratorSources...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add records to linter, %w", err)
+	}
+
+	rpt := linter.Report(ctx)
+
+	switch opts.Format {
+	case FORMAT_JSON:
+		err = rpt.WriteJSON(wr)
+	default:
+		err = rpt.WriteText(wr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(rpt.Violations) > 0 {
+		return fmt.Errorf("Found %d violations", len(rpt.Violations))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package lint
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	Verbose                  bool
+	IteratorURI              string
+	IteratorSources          []string
+	Format                   string
+	CheckSubjectGeometry     bool
+	DepictionReaderURI       string
+	WhosOnFirstReaderURI     string
+	SFOMuseumReaderURI       string
+	MembershipURI            string
+	DefaultGeometryFeatureId int64
+}
+
+func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	err := flagset.SetFlagsFromEnvVars(fs, "SFOMUSEUM")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set flags from environment variables, %w", err)
+	}
+
+	opts := &RunOptions{
+		Verbose:                  verbose,
+		IteratorURI:              iterator_uri,
+		IteratorSources:          fs.Args(),
+		Format:                   format,
+		CheckSubjectGeometry:     check_subject_geometry,
+		DepictionReaderURI:       depiction_reader_uri,
+		WhosOnFirstReaderURI:     whosonfirst_reader_uri,
+		SFOMuseumReaderURI:       sfomuseum_reader_uri,
+		MembershipURI:            membership_uri,
+		DefaultGeometryFeatureId: default_geometry_feature_id,
+	}
+
+	return opts, nil
+}
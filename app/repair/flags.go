@@ -0,0 +1,93 @@
+package repair
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+)
+
+var verbose bool
+
+var depiction_reader_uri string
+var depiction_writer_uri string
+
+var subject_reader_uri string
+var subject_writer_uri string
+
+var whosonfirst_reader_uri string
+var sfomuseum_reader_uri string
+var reader_cache_uri string
+
+var access_token_uri string
+
+var depictions multi.MultiInt64
+var subjects multi.MultiInt64
+
+var iterator_uri string
+
+var membership_uri string
+var default_geometry_feature_id int64
+var author string
+
+var commit_message_template string
+var pull_request_title_template string
+var pull_request_description_template string
+var branch_template string
+var reference_validation string
+
+var dry_run bool
+
+func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("repair")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	// Assumed to be something in sfomuseum-data-media-collection
+
+	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&depiction_writer_uri, "depiction-writer-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-writer URI.")
+
+	// Assumed to be something in sfomuseum-data-collection
+
+	fs.StringVar(&subject_reader_uri, "subject-reader-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&subject_writer_uri, "subject-writer-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-writer URI.")
+
+	fs.StringVar(&whosonfirst_reader_uri, "whosonfirst-reader-uri", "https://data.whosonfirst.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&reader_cache_uri, "reader-cache-uri", "", "An optional cache URI used to avoid reading the same Who's On First and SFO Museum records more than once. Valid options are: lru://?size={SIZE} (an in-memory cache), disk://{PATH} (an on-disk cache which is never expired).")
+
+	fs.StringVar(&access_token_uri, "access-token", "", "A valid gocloud.dev/runtimevar URI")
+
+	fs.Var(&depictions, "depiction-id", "One or more depiction IDs to repair.")
+	fs.Var(&subjects, "subject-id", "One or more subject IDs to recompile.")
+
+	fs.StringVar(&iterator_uri, "iterator-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to lint the sources passed as arguments (see the geo-lint tool) in order to find the depictions and subjects to repair.")
+
+	fs.StringVar(&membership_uri, "membership-uri", georeference.DEFAULT_MEMBERSHIP_URI, "A URI used to derive the list of depictions for each subject. See the -membership-uri flag of the georef-recompile-subject tool for details.")
+	fs.Int64Var(&default_geometry_feature_id, "default-geometry-feature-id", 1729828959, "The WOF ID for the Feature whose centroid will be used as a default absent any references.")
+	fs.StringVar(&reference_validation, "reference-validation", "warn", "How to handle references to deprecated, superseded or not current Who's On First records when alternate geometry files are regenerated. Valid options are: follow (replace superseded records with the record they were superseded by), warn (record and store them as-is), refuse (fail the update).")
+	fs.StringVar(&author, "author", "", "The name of the person (or process) repairing the records. This is used as the commit author for githubapi:// writers.")
+
+	fs.StringVar(&commit_message_template, "commit-message-template", github.DEFAULT_COMMIT_MESSAGE_TEMPLATE, "A text/template template used to derive commit messages for GitHub writers. Templates are passed a github.TemplateContext struct with Author, Action, WhosOnFirstId, SubjectId, Labels, Places, Timestamp and Path fields.")
+	fs.StringVar(&pull_request_title_template, "pull-request-title-template", github.DEFAULT_PULL_REQUEST_TITLE_TEMPLATE, "A text/template template used to derive pull request titles for githubapi-pr:// writers.")
+	fs.StringVar(&pull_request_description_template, "pull-request-description-template", github.DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE, "A text/template template used to derive pull request descriptions for githubapi-pr:// writers.")
+	fs.StringVar(&branch_template, "branch-template", github.DEFAULT_BRANCH_TEMPLATE, "A text/template template used to derive branch names for githubapi-pr:// writers.")
+
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead emit a JSON-encoded plan of the records that would be written, and how they would change, to STDOUT.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Repair inconsistencies between depictions, their alternate geometry files and their subjects.\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] [iterator-source(N) iterator-source(N)]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
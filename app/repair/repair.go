@@ -0,0 +1,198 @@
+package repair
+
+/*
+
+> ./bin/geo-repair \
+	-whosonfirst-reader-uri repo:///usr/local/data/whosonfirst-data-admin \
+	-dry-run \
+	/usr/local/data/sfomuseum-data-media-collection \
+	/usr/local/data/sfomuseum-data-collection
+
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	geo_lint "github.com/sfomuseum/go-sfomuseum-geo/lint"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_repair "github.com/sfomuseum/go-sfomuseum-geo/repair"
+	"github.com/whosonfirst/go-reader/v2"
+	gh_writer "github.com/whosonfirst/go-writer-github/v3"
+)
+
+// Run executes the "repair" application with a default `flag.FlagSet` instance.
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet(ctx)
+	return RunWithFlagSet(ctx, fs)
+}
+
+// RunWithFlagSet executes the "repair" application with a `flag.FlagSet` instance defined by 'fs'.
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := RunOptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts, os.Stdout)
+}
+
+// RunWithOptions executes the "repair" application with 'opts'. If 'opts.DryRun' is true the JSON-encoded plan of
+// changes is written to 'wr'.
+func RunWithOptions(ctx context.Context, opts *RunOptions, wr io.Writer) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	var err error
+
+	opts.DepictionWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.DepictionWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for depiction writer URI, %w", err)
+	}
+
+	opts.SubjectWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.SubjectWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for subject writer URI, %w", err)
+	}
+
+	depiction_reader, err := reader.NewReader(ctx, opts.DepictionReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create depiction reader, %w", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, opts.SubjectReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create subject reader, %w", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, opts.WhosOnFirstReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create whosonfirst reader, %w", err)
+	}
+
+	sfomuseum_reader, err := reader.NewReader(ctx, opts.SFOMuseumReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create sfomuseum reader, %w", err)
+	}
+
+	membership, err := georeference.NewSubjectMembership(ctx, opts.MembershipURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create subject membership, %w", err)
+	}
+
+	assign_opts := &georeference.AssignReferencesOptions{
+		DepictionReader:          depiction_reader,
+		SubjectReader:            subject_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		ReaderCacheURI:           opts.ReaderCacheURI,
+		DepictionWriterURI:       opts.DepictionWriterURI,
+		SubjectWriterURI:         opts.SubjectWriterURI,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		SubjectMembership:        membership,
+		Author:                   opts.Author,
+		GitHubTemplates:          opts.GitHubTemplates,
+		ReferenceValidation:      opts.ReferenceValidation,
+		DryRun:                   opts.DryRun,
+	}
+
+	depiction_ids := slices.Clone(opts.Depictions)
+	subject_ids := slices.Clone(opts.Subjects)
+
+	if len(opts.IteratorSources) > 0 {
+
+		linter_opts := &geo_lint.LinterOptions{
+			RecompileOptions: &georeference.RecompileGeorefencesForSubjectOptions{
+				DepictionReader:          depiction_reader,
+				SFOMuseumReader:          sfomuseum_reader,
+				WhosOnFirstReader:        whosonfirst_reader,
+				DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+				Membership:               membership,
+				ReaderCacheURI:           opts.ReaderCacheURI,
+			},
+		}
+
+		linter := geo_lint.NewLinter(linter_opts)
+
+		err = linter.AddFromIterator(ctx, opts.IteratorURI, opts.IteratorSources...)
+
+		if err != nil {
+			return fmt.Errorf("Failed to add records to linter, %w", err)
+		}
+
+		rpt := linter.Report(ctx)
+
+		slog.Debug("Lint complete", "records", rpt.Records, "violations", len(rpt.Violations))
+
+		for _, v := range rpt.Violations {
+
+			switch {
+			case v.Rule == geo_lint.RULE_MISSING_ALT_FILE && v.Role == plan.DepictionRole,
+				v.Rule == geo_lint.RULE_MISSING_SUBJECT_REFERENCE:
+
+				if !slices.Contains(depiction_ids, v.Id) {
+					depiction_ids = append(depiction_ids, v.Id)
+				}
+
+			case v.Rule == geo_lint.RULE_SUBJECT_GEOMETRY_MISMATCH:
+
+				if !slices.Contains(subject_ids, v.Id) {
+					subject_ids = append(subject_ids, v.Id)
+				}
+
+			default:
+				slog.Warn("Violation can not be repaired automatically", "rule", v.Rule, "id", v.Id, "path", v.Path, "message", v.Message)
+			}
+		}
+	}
+
+	if len(depiction_ids) == 0 && len(subject_ids) == 0 {
+		slog.Info("Nothing to repair")
+		return nil
+	}
+
+	slog.Debug("Repair records", "depictions", len(depiction_ids), "subjects", len(subject_ids))
+
+	rsp, err := geo_repair.Repair(ctx, assign_opts, depiction_ids, subject_ids)
+
+	if err != nil {
+		return fmt.Errorf("Failed to repair records, %w", err)
+	}
+
+	rsp.Log(slog.Default())
+
+	if opts.DryRun {
+
+		body, err := rsp.Response()
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive plan, %w", err)
+		}
+
+		_, err = fmt.Fprintln(wr, string(body))
+
+		if err != nil {
+			return fmt.Errorf("Failed to write plan, %w", err)
+		}
+	}
+
+	return nil
+}
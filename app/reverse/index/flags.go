@@ -0,0 +1,43 @@
+package index
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
+)
+
+var verbose bool
+
+var index_path string
+var iterator_uri string
+var rebuild bool
+
+var place_ids multi.MultiInt64
+var format string
+
+func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("reverse")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	fs.StringVar(&index_path, "index", "", "The path to a JSON-encoded reverse index file. If the file does not exist, or -rebuild is true, the index will be built from the sources passed as arguments and then written to this path. If empty the index will be built in memory and not persisted.")
+	fs.StringVar(&iterator_uri, "iterator-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v3.Iterator URI used to iterate depiction and subject records (for example sfomuseum-data-media-collection and sfomuseum-data-collection) when building the index.")
+	fs.BoolVar(&rebuild, "rebuild", false, "Rebuild the index from the sources passed as arguments even if -index already exists.")
+
+	fs.Var(&place_ids, "place-id", "One or more Who's On First place IDs to find depiction and subject records that reference them.")
+	fs.StringVar(&format, "format", "ids", "The output format for -place-id lookups. Valid options are: ids (a list of affected subject IDs, one per line), request (a JSON-encoded {\"subject_ids\": [...]} request for the georef-recompile-subject tool in lambda or server mode), json (a JSON-encoded list of all the depiction and subject records, and the properties by which they reference each place).")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Build, or query, a reverse index of the Who's On First places referenced by depiction and subject records.\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] [iterator-source(N) iterator-source(N)]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
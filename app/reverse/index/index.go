@@ -0,0 +1,152 @@
+package index
+
+/*
+
+> ./bin/sfomuseum-geo-reverse-index \
+	-index /usr/local/data/reverse.json \
+	/usr/local/data/sfomuseum-data-media-collection \
+	/usr/local/data/sfomuseum-data-collection
+
+> ./bin/sfomuseum-geo-reverse-index \
+	-index /usr/local/data/reverse.json \
+	-place-id 102025263
+1511907389
+1897902471
+
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/reverse"
+)
+
+const FORMAT_IDS string = "ids"
+const FORMAT_REQUEST string = "request"
+const FORMAT_JSON string = "json"
+
+// Run executes the "reverse index" application with a default `flag.FlagSet` instance.
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet(ctx)
+	return RunWithFlagSet(ctx, fs)
+}
+
+// RunWithFlagSet executes the "reverse index" application with a `flag.FlagSet` instance defined by 'fs'.
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := RunOptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts, os.Stdout)
+}
+
+// RunWithOptions executes the "reverse index" application with 'opts', writing the results of any place ID lookups to 'wr'.
+func RunWithOptions(ctx context.Context, opts *RunOptions, wr io.Writer) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	switch opts.Format {
+	case FORMAT_IDS, FORMAT_REQUEST, FORMAT_JSON:
+		// pass
+	default:
+		return fmt.Errorf("Invalid or unsupported format")
+	}
+
+	idx, err := loadIndex(ctx, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if len(opts.PlaceIds) == 0 {
+		return nil
+	}
+
+	switch opts.Format {
+	case FORMAT_JSON:
+
+		enc := json.NewEncoder(wr)
+		err = enc.Encode(idx.Lookup(opts.PlaceIds...))
+
+	case FORMAT_REQUEST:
+
+		req := map[string][]int64{
+			"subject_ids": idx.Subjects(opts.PlaceIds...),
+		}
+
+		enc := json.NewEncoder(wr)
+		err = enc.Encode(req)
+
+	default:
+
+		for _, id := range idx.Subjects(opts.PlaceIds...) {
+
+			_, err = fmt.Fprintln(wr, id)
+
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to write results, %w", err)
+	}
+
+	return nil
+}
+
+// loadIndex reads the index stored at 'opts.IndexPath' or builds (and then stores) a new index from 'opts.IteratorSources'.
+func loadIndex(ctx context.Context, opts *RunOptions) (*reverse.Index, error) {
+
+	if opts.IndexPath != "" && !opts.Rebuild {
+
+		_, err := os.Stat(opts.IndexPath)
+
+		if err == nil {
+			slog.Debug("Read reverse index", "path", opts.IndexPath)
+			return reverse.ReadIndexFromPath(opts.IndexPath)
+		}
+
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Failed to stat %s, %w", opts.IndexPath, err)
+		}
+	}
+
+	if len(opts.IteratorSources) == 0 {
+		return nil, fmt.Errorf("One or more iterator sources are required to build the index")
+	}
+
+	slog.Debug("Build reverse index", "sources", len(opts.IteratorSources))
+
+	idx, err := reverse.NewIndexFromIterator(ctx, opts.IteratorURI, opts.IteratorSources...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build index, %w", err)
+	}
+
+	if opts.IndexPath != "" {
+
+		err = idx.WriteToPath(opts.IndexPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to write index, %w", err)
+		}
+
+		slog.Debug("Wrote reverse index", "path", opts.IndexPath, "records", idx.Count())
+	}
+
+	return idx, nil
+}
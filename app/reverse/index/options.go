@@ -0,0 +1,42 @@
+package index
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	Verbose         bool
+	IndexPath       string
+	IteratorURI     string
+	IteratorSources []string
+	Rebuild         bool
+	PlaceIds        []int64
+	Format          string
+}
+
+func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	err := flagset.SetFlagsFromEnvVars(fs, "SFOMUSEUM")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set flags from environment variables, %w", err)
+	}
+
+	opts := &RunOptions{
+		Verbose:         verbose,
+		IndexPath:       index_path,
+		IteratorURI:     iterator_uri,
+		IteratorSources: fs.Args(),
+		Rebuild:         rebuild,
+		PlaceIds:        place_ids,
+		Format:          format,
+	}
+
+	return opts, nil
+}
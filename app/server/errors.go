@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Error defines a structured error returned by the API.
+type Error struct {
+	// The HTTP status code for the error.
+	Code int `json:"code"`
+	// A human-readable description of the error.
+	Message string `json:"message"`
+}
+
+// ErrorResponse defines the JSON-encoded body returned by the API when an error occurs.
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+func writeError(rsp http.ResponseWriter, code int, message string) {
+
+	e := &ErrorResponse{
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	}
+
+	rsp.Header().Set("Content-Type", "application/json")
+	rsp.WriteHeader(code)
+
+	enc := json.NewEncoder(rsp)
+	err := enc.Encode(e)
+
+	if err != nil {
+		slog.Error("Failed to encode error response", "error", err)
+	}
+}
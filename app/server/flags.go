@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+var server_uri string
+var verbose bool
+
+var depiction_reader_uri string
+var depiction_writer_uri string
+
+var subject_reader_uri string
+var subject_writer_uri string
+
+var whosonfirst_reader_uri string
+var sfomuseum_reader_uri string
+
+var access_token_uri string
+
+var default_geometry_feature_id int64
+
+var reference_validation string
+
+var author string
+
+var dry_run bool
+
+func DefaultFlagSet(ctx context.Context) *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("server")
+
+	fs.StringVar(&server_uri, "server-uri", "http://localhost:8080", "The address to listen for requests on.")
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	// Assumed to be something in sfomuseum-data-media-collection
+
+	fs.StringVar(&depiction_reader_uri, "depiction-reader-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&depiction_writer_uri, "depiction-writer-uri", "repo:///usr/local/data/sfomuseum-data-media-collection", "A valid whosonfirst/go-writer URI.")
+
+	// Assumed to be something in sfomuseum-data-collection
+
+	fs.StringVar(&subject_reader_uri, "subject-reader-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&subject_writer_uri, "subject-writer-uri", "repo:///usr/local/data/sfomuseum-data-collection", "A valid whosonfirst/go-writer URI.")
+
+	fs.StringVar(&whosonfirst_reader_uri, "whosonfirst-reader-uri", "https://data.whosonfirst.org/geojson/", "A valid whosonfirst/go-reader URI.")
+	fs.StringVar(&sfomuseum_reader_uri, "sfomuseum-reader-uri", "https://static.sfomuseum.org/geojson/", "A valid whosonfirst/go-reader URI.")
+
+	fs.StringVar(&access_token_uri, "access-token", "", "A valid gocloud.dev/runtimevar URI")
+
+	fs.Int64Var(&default_geometry_feature_id, "default-geometry-feature-id", 1729828959, "The WOF ID for the Feature whose centroid will be used as a default absent any references or geotags.")
+
+	fs.StringVar(&reference_validation, "reference-validation", "warn", "How to handle references to deprecated, superseded or not current Who's On First records. Valid options are: follow (replace superseded records with the record they were superseded by), warn (record and store them as-is), refuse (fail the update).")
+
+	fs.StringVar(&author, "author", "", "The name of the person (or process) performing updates. This is used as the commit author for githubapi:// writers.")
+
+	fs.BoolVar(&dry_run, "dry-run", false, "Do not write any changes. Instead return a JSON-encoded plan of the records that would be written, and how they would change.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "sfomuseum-geo-server is an HTTP server exposing geotagging and georeferencing operations as a JSON API.\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
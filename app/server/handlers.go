@@ -0,0 +1,472 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/whosonfirst/go-reader/v2"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// HandlerOptions defines configuration options for the HTTP handlers exposed by the "sfomuseum-geo-server" application.
+type HandlerOptions struct {
+	// A valid whosonfirst/go-reader.Reader instance for reading depiction features.
+	DepictionReader reader.Reader
+	// A valid whosonfirst/go-reader.Reader instance for reading subject features.
+	SubjectReader reader.Reader
+	// A valid whosonfirst/go-reader.Reader instance for reading Who's On First features.
+	WhosOnFirstReader reader.Reader
+	// A valid whosonfirst/go-reader.Reader instance for reading "sfomuseum" features (for example the aviation collection).
+	SFOMuseumReader reader.Reader
+	// A valid whosonfirst/go-writer.Writer URI for writing depiction features.
+	DepictionWriterURI string
+	// A valid whosonfirst/go-writer.Writer URI for writing subject features.
+	SubjectWriterURI string
+	// DefaultGeometryFeatureId is the Who's On First ID to use for deriving a default geometry when none are defined by georeferences (or geotags.)
+	DefaultGeometryFeatureId int64
+	// ReferenceValidation defines how references to deprecated, superseded or not current Who's On First records are handled by `PUT /georef/{id}` requests.
+	ReferenceValidation georeference.ValidationMode
+	// Author is the name of the person (or process) to associate with commit messages if using a `githubapi://` writer.
+	Author string
+	// DryRun is a boolean flag signaling that no data should be written. Instead a JSON-encoded `plan.Plan` is returned.
+	DryRun bool
+}
+
+// AssignReferencesRequest defines the JSON-encoded body for `PUT /georef/{id}` requests.
+type AssignReferencesRequest struct {
+	// References is the list of `georeference.Reference` instances to assign to the depiction, replacing any existing references.
+	References []*georeference.Reference `json:"references"`
+}
+
+// NewServeMux returns a new `http.ServeMux` instance with the following routes:
+//
+//	POST /geotag                  Add geotagging information to a depiction (`geotag.AddGeotagDepiction`).
+//	DELETE /geotag/{id}           Remove geotagging information from a depiction (`geotag.RemoveGeotagDepiction`).
+//	PUT /georef/{id}              Assign georeferences to a depiction (`georeference.AssignReferences`).
+//	POST /subjects/{id}/recompile Recompile georeferences for a subject (`georeference.RecompileSubject`).
+func NewServeMux(ctx context.Context, opts *HandlerOptions) (*http.ServeMux, error) {
+
+	default_body, err := wof_reader.LoadBytes(ctx, opts.SFOMuseumReader, opts.DefaultGeometryFeatureId)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load feature for default geometry ID, %w", err)
+	}
+
+	default_f, err := geojson.UnmarshalFeature(default_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal feature for default geometry, %w", err)
+	}
+
+	default_geom := geojson.NewGeometry(default_f.Geometry)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /geotag", addGeotagHandler(opts))
+	mux.Handle("DELETE /geotag/{id}", removeGeotagHandler(opts, default_geom))
+	mux.Handle("PUT /georef/{id}", assignReferencesHandler(opts))
+	mux.Handle("POST /subjects/{id}/recompile", recompileSubjectHandler(opts))
+
+	return mux, nil
+}
+
+func addGeotagHandler(opts *HandlerOptions) http.Handler {
+
+	geotag_opts := &geotag.AddGeotagDepictionOptions{
+		DepictionReader:    opts.DepictionReader,
+		SubjectReader:      opts.SubjectReader,
+		WhosOnFirstReader:  opts.WhosOnFirstReader,
+		DepictionWriterURI: opts.DepictionWriterURI,
+		SubjectWriterURI:   opts.SubjectWriterURI,
+		Author:             opts.Author,
+		DryRun:             opts.DryRun,
+	}
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		ctx := req.Context()
+
+		var update *geotag.Depiction
+
+		dec := json.NewDecoder(req.Body)
+		err := dec.Decode(&update)
+
+		if err != nil {
+			writeError(rsp, http.StatusBadRequest, fmt.Sprintf("Failed to decode request, %v", err))
+			return
+		}
+
+		if update == nil {
+			writeError(rsp, http.StatusBadRequest, "Request body is empty")
+			return
+		}
+
+		if update.Feature == nil {
+			writeError(rsp, http.StatusUnprocessableEntity, "Request is missing geotag feature")
+			return
+		}
+
+		if !ensureRecord(ctx, rsp, opts.DepictionReader, update.DepictionId, "depiction") {
+			return
+		}
+
+		r, err := geotag.AddGeotagDepiction(ctx, geotag_opts, update)
+
+		if err != nil {
+			slog.Error("Failed to add geotag", "depiction id", update.DepictionId, "error", err)
+			writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to geotag depiction %d", update.DepictionId))
+			return
+		}
+
+		writeResult(rsp, opts, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func removeGeotagHandler(opts *HandlerOptions, default_geom *geojson.Geometry) http.Handler {
+
+	geotag_opts := &geotag.RemoveGeotagDepictionOptions{
+		DepictionReader:    opts.DepictionReader,
+		DepictionWriterURI: opts.DepictionWriterURI,
+		SubjectReader:      opts.SubjectReader,
+		SubjectWriterURI:   opts.SubjectWriterURI,
+		WhosOnFirstReader:  opts.WhosOnFirstReader,
+		DefaultGeometry:    default_geom,
+		Author:             opts.Author,
+		DryRun:             opts.DryRun,
+	}
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		ctx := req.Context()
+
+		depiction_id, ok := pathId(rsp, req)
+
+		if !ok {
+			return
+		}
+
+		if !ensureRecord(ctx, rsp, opts.DepictionReader, depiction_id, "depiction") {
+			return
+		}
+
+		update := &geotag.Depiction{
+			DepictionId: depiction_id,
+		}
+
+		r, err := geotag.RemoveGeotagDepiction(ctx, geotag_opts, update)
+
+		if err != nil {
+			slog.Error("Failed to remove geotag", "depiction id", depiction_id, "error", err)
+			writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to remove geotag from depiction %d", depiction_id))
+			return
+		}
+
+		writeResult(rsp, opts, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func assignReferencesHandler(opts *HandlerOptions) http.Handler {
+
+	assign_opts := assignReferencesOptions(opts)
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		ctx := req.Context()
+
+		depiction_id, ok := pathId(rsp, req)
+
+		if !ok {
+			return
+		}
+
+		var assign_req *AssignReferencesRequest
+
+		dec := json.NewDecoder(req.Body)
+		err := dec.Decode(&assign_req)
+
+		if err != nil || assign_req == nil {
+			writeError(rsp, http.StatusBadRequest, "Failed to decode request")
+			return
+		}
+
+		if !ensureRecord(ctx, rsp, opts.DepictionReader, depiction_id, "depiction") {
+			return
+		}
+
+		err = checkReferences(assign_req.References...)
+
+		if err != nil {
+			writeError(rsp, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		_, _, _, err = georeference.ValidateReferences(ctx, opts.WhosOnFirstReader, opts.ReferenceValidation, assign_req.References...)
+
+		if err != nil {
+
+			var validation_err *georeference.ValidationError
+
+			if errors.As(err, &validation_err) || isNotFound(err) {
+				writeError(rsp, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+
+			slog.Error("Failed to validate references", "depiction id", depiction_id, "error", err)
+			writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to validate references for depiction %d", depiction_id))
+			return
+		}
+
+		r, err := georeference.AssignReferences(ctx, assign_opts, depiction_id, assign_req.References...)
+
+		if err != nil {
+			slog.Error("Failed to assign references", "depiction id", depiction_id, "error", err)
+			writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to assign references to depiction %d", depiction_id))
+			return
+		}
+
+		writeResult(rsp, opts, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func recompileSubjectHandler(opts *HandlerOptions) http.Handler {
+
+	assign_opts := assignReferencesOptions(opts)
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		ctx := req.Context()
+
+		subject_id, ok := pathId(rsp, req)
+
+		if !ok {
+			return
+		}
+
+		if !ensureRecord(ctx, rsp, opts.SubjectReader, subject_id, "subject") {
+			return
+		}
+
+		r, err := georeference.RecompileSubject(ctx, assign_opts, subject_id)
+
+		if err != nil {
+			slog.Error("Failed to recompile subject", "subject id", subject_id, "error", err)
+			writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to recompile georeferences for subject %d", subject_id))
+			return
+		}
+
+		writeResult(rsp, opts, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// assignReferencesOptions returns the `georeference.AssignReferencesOptions` used to assign references to, and recompile
+// the georeferences for, records.
+func assignReferencesOptions(opts *HandlerOptions) *georeference.AssignReferencesOptions {
+
+	assign_opts := &georeference.AssignReferencesOptions{
+		DepictionReader:          opts.DepictionReader,
+		SubjectReader:            opts.SubjectReader,
+		WhosOnFirstReader:        opts.WhosOnFirstReader,
+		SFOMuseumReader:          opts.SFOMuseumReader,
+		DepictionWriterURI:       opts.DepictionWriterURI,
+		SubjectWriterURI:         opts.SubjectWriterURI,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		ReferenceValidation:      opts.ReferenceValidation,
+		Author:                   opts.Author,
+		DryRun:                   opts.DryRun,
+	}
+
+	return assign_opts
+}
+
+// checkReferences ensures that each element in 'refs' has a unique label and one or more IDs. Whether those IDs can be read,
+// and are current, is determined by `georeference.ValidateReferences`.
+func checkReferences(refs ...*georeference.Reference) error {
+
+	labels := make([]string, 0)
+
+	for idx, ref := range refs {
+
+		if ref == nil {
+			return fmt.Errorf("Reference at offset %d is empty", idx)
+		}
+
+		if ref.Label == "" {
+			return fmt.Errorf("Reference at offset %d is missing a label", idx)
+		}
+
+		if slices.Contains(labels, ref.Label) {
+			return fmt.Errorf("Multiple references with duplicate label, '%s'", ref.Label)
+		}
+
+		labels = append(labels, ref.Label)
+
+		if len(ref.Ids) == 0 {
+			return fmt.Errorf("Reference '%s' is missing IDs", ref.Label)
+		}
+	}
+
+	return nil
+}
+
+// ensureRecord writes a 404 error to 'rsp' and returns false if the record for 'id' can not be found in 'r'.
+func ensureRecord(ctx context.Context, rsp http.ResponseWriter, r reader.Reader, id int64, role string) bool {
+
+	exists, err := recordExists(ctx, r, id)
+
+	if err != nil {
+		slog.Error("Failed to determine whether record exists", "id", id, "role", role, "error", err)
+		writeError(rsp, http.StatusInternalServerError, fmt.Sprintf("Failed to determine whether %s %d exists", role, id))
+		return false
+	}
+
+	if !exists {
+		writeError(rsp, http.StatusNotFound, fmt.Sprintf("Unknown %s %d", role, id))
+		return false
+	}
+
+	return true
+}
+
+func recordExists(ctx context.Context, r reader.Reader, id int64) (bool, error) {
+
+	rel_path, err := uri.Id2RelPath(id)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to derive path for %d, %w", id, err)
+	}
+
+	exists, err := r.Exists(ctx, rel_path)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to determine whether %s exists, %w", rel_path, err)
+	}
+
+	if exists {
+		return true, nil
+	}
+
+	// The whosonfirst/go-reader.MultiReader implementation of Exists only returns true if
+	// a record exists in all of its readers (and the HTTPReader implementation returns false
+	// for any non-200 response) so fall back to reading the record directly. Only errors which
+	// mean the record does not exist are reported as such.
+
+	fh, err := r.Read(ctx, rel_path)
+
+	if err != nil {
+
+		if isNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Failed to read %s, %w", rel_path, err)
+	}
+
+	fh.Close()
+	return true, nil
+}
+
+// isNotFound returns a boolean value indicating whether 'err', returned while reading a record, means that the record does
+// not exist. The readers in whosonfirst/go-reader do not wrap a common "not found" error so, in addition to `fs.ErrNotExist`
+// and `sql.ErrNoRows`, this matches the messages returned by the FileReader, HTTPReader and MultiReader implementations. Errors
+// wrapping more than one error (for example those returned by a MultiReader) only mean the record does not exist if that is
+// true for all of them.
+func isNotFound(err error) bool {
+
+	var multi_err interface{ WrappedErrors() []error }
+
+	if errors.As(err, &multi_err) {
+
+		wrapped := multi_err.WrappedErrors()
+
+		if len(wrapped) == 0 {
+			return false
+		}
+
+		for _, e := range wrapped {
+
+			if !isNotFound(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+
+	msg := err.Error()
+
+	for _, str := range []string{"no such file or directory", "Unexpected status code: 404", "Unable to read URI"} {
+
+		if strings.Contains(msg, str) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathId parses the "{id}" path value in 'req' writing a 400 error to 'rsp' and returning false if it is invalid.
+func pathId(rsp http.ResponseWriter, req *http.Request) (int64, bool) {
+
+	str_id := req.PathValue("id")
+
+	id, err := strconv.ParseInt(str_id, 10, 64)
+
+	if err != nil || id <= 0 {
+		writeError(rsp, http.StatusBadRequest, fmt.Sprintf("Invalid ID '%s'", str_id))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// writeResult logs each of the records in 'r' and then writes its JSON-encoded response to 'rsp'.
+func writeResult(rsp http.ResponseWriter, opts *HandlerOptions, r *geo_writers.UpdateResult) {
+
+	r.Log(slog.Default())
+
+	body, err := r.Response()
+
+	if err != nil {
+		slog.Error("Failed to derive response", "error", err)
+		writeError(rsp, http.StatusInternalServerError, "Failed to derive response")
+		return
+	}
+
+	writeResponse(rsp, opts, body)
+}
+
+func writeResponse(rsp http.ResponseWriter, opts *HandlerOptions, body []byte) {
+
+	if opts.DryRun {
+		rsp.Header().Set("Content-Type", "application/json")
+	} else {
+		rsp.Header().Set("Content-Type", "application/geo+json")
+	}
+
+	rsp.Write(body)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/internal/testfixtures"
+	"github.com/tidwall/gjson"
+)
+
+func setupServeMux(t *testing.T) *http.ServeMux {
+
+	ctx := context.Background()
+
+	root := testfixtures.Root(t)
+	repos := testfixtures.NewRepos(t)
+
+	opts := &HandlerOptions{
+		DepictionReader:          repos.DepictionReader,
+		SubjectReader:            repos.SubjectReader,
+		WhosOnFirstReader:        testfixtures.NewReader(t, testfixtures.RepoURI(root, testfixtures.WHOSONFIRST), testfixtures.RepoURI(root, testfixtures.ARCHITECTURE)),
+		SFOMuseumReader:          repos.SFOMuseumReader,
+		DepictionWriterURI:       repos.DepictionURI,
+		SubjectWriterURI:         repos.SubjectURI,
+		DefaultGeometryFeatureId: testfixtures.DEFAULT_GEOMETRY_FEATURE_ID,
+	}
+
+	mux, err := NewServeMux(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create serve mux, %v", err)
+	}
+
+	return mux
+}
+
+func TestServeMux(t *testing.T) {
+
+	mux := setupServeMux(t)
+
+	tests := []struct {
+		Method string
+		Path   string
+		Body   string
+		Status int
+	}{
+		{http.MethodPut, "/georef/1897903961", `{"references":[{"label":"sfomuseum:depicts","ids":[102025263]}]}`, http.StatusOK},
+		{http.MethodPut, "/georef/1", `{"references":[{"label":"sfomuseum:depicts","ids":[102025263]}]}`, http.StatusNotFound},
+		{http.MethodPut, "/georef/1897903961", `{"references":[{"label":"sfomuseum:depicts","ids":[1]}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/georef/1897903961", `{"references":[{"label":"","ids":[102025263]}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/georef/1897903961", `{`, http.StatusBadRequest},
+		{http.MethodPut, "/georef/bogus", `{"references":[]}`, http.StatusBadRequest},
+		{http.MethodDelete, "/geotag/1", ``, http.StatusNotFound},
+		{http.MethodPost, "/geotag", `{"depiction_id":1897903961}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/geotag", `null`, http.StatusBadRequest},
+		{http.MethodPost, "/subjects/1/recompile", ``, http.StatusNotFound},
+		{http.MethodPost, "/subjects/1511907389/recompile", ``, http.StatusOK},
+	}
+
+	for _, test := range tests {
+
+		req := httptest.NewRequest(test.Method, test.Path, bytes.NewBufferString(test.Body))
+		rsp := httptest.NewRecorder()
+
+		mux.ServeHTTP(rsp, req)
+
+		if rsp.Code != test.Status {
+			t.Fatalf("Expected status %d for %s %s, got %d (%s)", test.Status, test.Method, test.Path, rsp.Code, rsp.Body.String())
+		}
+
+		body := rsp.Body.Bytes()
+
+		if test.Status == http.StatusOK {
+
+			if gjson.GetBytes(body, "type").String() != "FeatureCollection" {
+				t.Fatalf("Expected FeatureCollection for %s %s, %s", test.Method, test.Path, string(body))
+			}
+
+			continue
+		}
+
+		if gjson.GetBytes(body, "error.code").Int() != int64(test.Status) || gjson.GetBytes(body, "error.message").String() == "" {
+			t.Fatalf("Unexpected error response for %s %s, %s", test.Method, test.Path, string(body))
+		}
+	}
+}
+
+// brokenReader is a `reader.Reader` implementation whose methods always fail.
+type brokenReader struct{}
+
+func (r *brokenReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	return nil, errors.New("Broken reader")
+}
+
+func (r *brokenReader) Exists(ctx context.Context, path string) (bool, error) {
+	return false, errors.New("Broken reader")
+}
+
+func (r *brokenReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
+
+func TestEnsureRecordError(t *testing.T) {
+
+	ctx := context.Background()
+	rsp := httptest.NewRecorder()
+
+	if ensureRecord(ctx, rsp, &brokenReader{}, 1897903961, "depiction") {
+		t.Fatalf("Expected ensureRecord to fail")
+	}
+
+	if rsp.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, rsp.Code)
+	}
+}
+
+// missingReader is a `reader.Reader` implementation whose Exists method always returns false and whose Read method
+// always returns 'err'.
+type missingReader struct {
+	err error
+}
+
+func (r *missingReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	return nil, fmt.Errorf("Failed to read %s, %w", path, r.err)
+}
+
+func (r *missingReader) Exists(ctx context.Context, path string) (bool, error) {
+	return false, nil
+}
+
+func (r *missingReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
+
+func TestRecordExists(t *testing.T) {
+
+	ctx := context.Background()
+
+	exists, err := recordExists(ctx, &missingReader{err: fs.ErrNotExist}, 1897903961)
+
+	if err != nil {
+		t.Fatalf("Expected missing record to not return an error, %v", err)
+	}
+
+	if exists {
+		t.Fatalf("Expected missing record to not exist")
+	}
+
+	_, err = recordExists(ctx, &missingReader{err: fs.ErrPermission}, 1897903961)
+
+	if err == nil {
+		t.Fatalf("Expected permission error to be returned")
+	}
+
+	rsp := httptest.NewRecorder()
+
+	if ensureRecord(ctx, rsp, &missingReader{err: fs.ErrPermission}, 1897903961, "depiction") {
+		t.Fatalf("Expected ensureRecord to fail")
+	}
+
+	if rsp.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, rsp.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+)
+
+// subject: a collection object, for example
+// depiction: an image of a collection object, for example
+
+type RunOptions struct {
+	ServerURI                string
+	Verbose                  bool
+	SubjectReaderURI         string
+	SubjectWriterURI         string
+	DepictionReaderURI       string
+	DepictionWriterURI       string
+	WhosOnFirstReaderURI     string
+	SFOMuseumReaderURI       string
+	GitHubAccessTokenURI     string
+	DefaultGeometryFeatureId int64
+	ReferenceValidation      georeference.ValidationMode
+	Author                   string
+	DryRun                   bool
+}
+
+func RunOptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	err := flagset.SetFlagsFromEnvVars(fs, "SFOMUSEUM")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to set flags from environment variables, %w", err)
+	}
+
+	validation_mode, err := georeference.ParseValidationMode(reference_validation)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid -reference-validation flag, %w", err)
+	}
+
+	opts := &RunOptions{
+		ServerURI:                server_uri,
+		Verbose:                  verbose,
+		SubjectReaderURI:         subject_reader_uri,
+		SubjectWriterURI:         subject_writer_uri,
+		DepictionReaderURI:       depiction_reader_uri,
+		DepictionWriterURI:       depiction_writer_uri,
+		WhosOnFirstReaderURI:     whosonfirst_reader_uri,
+		SFOMuseumReaderURI:       sfomuseum_reader_uri,
+		GitHubAccessTokenURI:     access_token_uri,
+		DefaultGeometryFeatureId: default_geometry_feature_id,
+		ReferenceValidation:      validation_mode,
+		Author:                   author,
+		DryRun:                   dry_run,
+	}
+
+	return opts, nil
+}
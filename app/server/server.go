@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/whosonfirst/go-reader/v2"
+	gh_writer "github.com/whosonfirst/go-writer-github/v3"
+)
+
+// Run executes the "sfomuseum-geo-server" application with a default `flag.FlagSet` instance.
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet(ctx)
+	return RunWithFlagSet(ctx, fs)
+}
+
+// RunWithFlagSet executes the "sfomuseum-geo-server" application with a `flag.FlagSet` instance defined by 'fs'.
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := RunOptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts)
+}
+
+// RunWithOptions executes the "sfomuseum-geo-server" application with 'opts'.
+func RunWithOptions(ctx context.Context, opts *RunOptions) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	var err error
+
+	opts.DepictionWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.DepictionWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for depiction writer URI, %w", err)
+	}
+
+	opts.SubjectWriterURI, err = gh_writer.EnsureGitHubAccessToken(ctx, opts.SubjectWriterURI, opts.GitHubAccessTokenURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to ensure access token for subject writer URI, %w", err)
+	}
+
+	depiction_reader, err := reader.NewReader(ctx, opts.DepictionReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create depiction reader, %w", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, opts.SubjectReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create subject reader, %w", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, opts.WhosOnFirstReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create whosonfirst reader, %w", err)
+	}
+
+	sfomuseum_reader, err := reader.NewReader(ctx, opts.SFOMuseumReaderURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create sfomuseum reader, %w", err)
+	}
+
+	handler_opts := &HandlerOptions{
+		DepictionReader:          depiction_reader,
+		SubjectReader:            subject_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		DepictionWriterURI:       opts.DepictionWriterURI,
+		SubjectWriterURI:         opts.SubjectWriterURI,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		ReferenceValidation:      opts.ReferenceValidation,
+		Author:                   opts.Author,
+		DryRun:                   opts.DryRun,
+	}
+
+	mux, err := NewServeMux(ctx, handler_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create serve mux, %w", err)
+	}
+
+	u, err := url.Parse(opts.ServerURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to parse server URI, %w", err)
+	}
+
+	s := &http.Server{
+		Addr:    u.Host,
+		Handler: mux,
+	}
+
+	slog.Info("Listening for requests", "address", opts.ServerURI)
+	return s.ListenAndServe()
+}
@@ -0,0 +1,189 @@
+// Package batch provides methods for processing JSONL-encoded update requests with bounded concurrency
+// and recording the outcome of each line in a JSONL-encoded result log.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// MAX_LINE_SIZE is the maximum size, in bytes, of a single line of JSONL input.
+const MAX_LINE_SIZE int = 10 * 1024 * 1024
+
+// ProcessFunc is a function for processing a single line of JSONL input. It returns the ID of the depiction
+// that was updated and the `writers.UpdateResult` returned by the underlying update method.
+type ProcessFunc func(ctx context.Context, line []byte) (int64, *geo_writers.UpdateResult, error)
+
+// RunOptions defines configuration options for the `Run` method.
+type RunOptions struct {
+	// Workers is the maximum number of lines to process concurrently. If less than 1 then lines are processed one at a time.
+	Workers int
+	// Process is the `ProcessFunc` used to process each line.
+	Process ProcessFunc
+}
+
+// Result defines the outcome of processing a single line of JSONL input.
+type Result struct {
+	// The (1-based) line number of the input.
+	Line int `json:"line"`
+	// The ID of the depiction being updated, if known.
+	DepictionId int64 `json:"depiction_id,omitempty"`
+	// A boolean flag indicating whether the line was processed successfully.
+	Success bool `json:"success"`
+	// The error message, if the line was not processed successfully.
+	Error string `json:"error,omitempty"`
+	// The list of records which were changed (or, for dry runs, would be changed) by the line.
+	Changed []*ChangedRecord `json:"changed"`
+}
+
+// inputLine defines a single (non-empty) line of JSONL input.
+type inputLine struct {
+	// The (1-based) line number of the input.
+	number int
+	// The body of the line.
+	body []byte
+}
+
+// Summary defines the aggregate outcome of processing all the lines in a JSONL input.
+type Summary struct {
+	// The total number of lines processed.
+	Total int `json:"total"`
+	// The number of lines processed successfully.
+	Succeeded int `json:"succeeded"`
+	// The number of lines which failed.
+	Failed int `json:"failed"`
+}
+
+// Run processes each line in 'r' using 'opts.Process' with at most 'opts.Workers' lines processed concurrently, writing
+// a JSONL-encoded `Result` for each line to 'wr' as it completes. An error processing any given line is recorded in its
+// result and does not stop the remaining lines from being processed. Empty lines are skipped.
+func Run(ctx context.Context, opts *RunOptions, r io.Reader, wr io.Writer) (*Summary, error) {
+
+	if opts.Process == nil {
+		return nil, fmt.Errorf("Missing process function")
+	}
+
+	workers := opts.Workers
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	logger := slog.Default()
+	logger = logger.With("action", "batch")
+
+	summary := new(Summary)
+
+	mu := new(sync.Mutex)
+
+	enc := json.NewEncoder(wr)
+
+	record := func(rsp *Result) {
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		summary.Total += 1
+
+		if rsp.Success {
+			summary.Succeeded += 1
+		} else {
+			summary.Failed += 1
+		}
+
+		err := enc.Encode(rsp)
+
+		if err != nil {
+			logger.Error("Failed to write result", "line", rsp.Line, "error", err)
+		}
+	}
+
+	// process never returns an error for a line that fails; the failure is recorded in its result instead
+	process := func(ctx context.Context, l *inputLine) error {
+
+		logger.Debug("Process line", "line", l.number)
+
+		rsp := &Result{
+			Line:    l.number,
+			Changed: make([]*ChangedRecord, 0),
+		}
+
+		depiction_id, update_result, err := opts.Process(ctx, l.body)
+		rsp.DepictionId = depiction_id
+
+		if err != nil {
+			logger.Warn("Failed to process line", "line", l.number, "error", err)
+			rsp.Error = err.Error()
+			record(rsp)
+			return nil
+		}
+
+		rsp.Success = true
+		rsp.Changed = ChangedRecords(update_result)
+
+		record(rsp)
+		return nil
+	}
+
+	// Lines are processed in chunks so that the entire input is not held in memory at once
+
+	chunk_size := workers * 10
+	lines := make([]*inputLine, 0, chunk_size)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_LINE_SIZE)
+
+	line_no := 0
+
+	for scanner.Scan() {
+
+		line_no += 1
+
+		line := scanner.Bytes()
+
+		if len(line) == 0 {
+			continue
+		}
+
+		// The scanner will reuse its buffer so copy the line before handing it off
+
+		body := make([]byte, len(line))
+		copy(body, line)
+
+		lines = append(lines, &inputLine{number: line_no, body: body})
+
+		if len(lines) < chunk_size {
+			continue
+		}
+
+		err := fanout.Each(ctx, workers, lines, process)
+
+		if err != nil {
+			return summary, err
+		}
+
+		lines = make([]*inputLine, 0, chunk_size)
+	}
+
+	err := fanout.Each(ctx, workers, lines, process)
+
+	if err != nil {
+		return summary, err
+	}
+
+	err = scanner.Err()
+
+	if err != nil {
+		return summary, fmt.Errorf("Failed to read input, %w", err)
+	}
+
+	return summary, nil
+}
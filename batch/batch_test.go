@@ -0,0 +1,186 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/tidwall/gjson"
+)
+
+func TestRun(t *testing.T) {
+
+	ctx := context.Background()
+
+	input := strings.Join([]string{
+		`{"depiction_id":1}`,
+		`{"depiction_id":2}`,
+		`this is not JSON`,
+		``,
+		`{"depiction_id":3}`,
+		`{"depiction_id":4}`,
+		`{"depiction_id":5}`,
+	}, "\n")
+
+	var active int64
+	var max_active int64
+
+	process := func(ctx context.Context, line []byte) (int64, *geo_writers.UpdateResult, error) {
+
+		n := atomic.AddInt64(&active, 1)
+		defer atomic.AddInt64(&active, -1)
+
+		for {
+			m := atomic.LoadInt64(&max_active)
+
+			if n <= m || atomic.CompareAndSwapInt64(&max_active, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		if !gjson.ValidBytes(line) {
+			return 0, nil, fmt.Errorf("Invalid JSON")
+		}
+
+		id := gjson.GetBytes(line, "depiction_id").Int()
+
+		r := geo_writers.NewUpdateResult()
+		r.Add(&geo_writers.UpdateRecord{Id: id, Role: plan.DepictionRole, Changed: true, URI: fmt.Sprintf("%d.geojson", id)})
+		r.Add(&geo_writers.UpdateRecord{Id: id + 100, Role: plan.SubjectRole, Changed: false, URI: fmt.Sprintf("%d.geojson", id+100)})
+
+		return id, r, nil
+	}
+
+	opts := &RunOptions{
+		Workers: 2,
+		Process: process,
+	}
+
+	var buf bytes.Buffer
+
+	summary, err := Run(ctx, opts, strings.NewReader(input), &buf)
+
+	if err != nil {
+		t.Fatalf("Failed to run batch, %v", err)
+	}
+
+	if summary.Total != 6 || summary.Succeeded != 5 || summary.Failed != 1 {
+		t.Fatalf("Unexpected summary, %v", summary)
+	}
+
+	if max_active > 2 {
+		t.Fatalf("Expected at most 2 concurrent workers, got %d", max_active)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+
+	count := 0
+
+	for scanner.Scan() {
+
+		var rsp *Result
+
+		err := json.Unmarshal(scanner.Bytes(), &rsp)
+
+		if err != nil {
+			t.Fatalf("Failed to unmarshal result, %v", err)
+		}
+
+		count += 1
+
+		switch rsp.Line {
+		case 3:
+
+			if rsp.Success || rsp.Error == "" {
+				t.Fatalf("Expected line 3 to fail, %v", rsp)
+			}
+
+		default:
+
+			if !rsp.Success {
+				t.Fatalf("Expected line %d to succeed, %s", rsp.Line, rsp.Error)
+			}
+
+			if len(rsp.Changed) != 1 || rsp.Changed[0].Id != rsp.DepictionId {
+				t.Fatalf("Unexpected changed records for line %d", rsp.Line)
+			}
+		}
+	}
+
+	if count != 6 {
+		t.Fatalf("Expected 6 results, got %d", count)
+	}
+}
+
+func TestChangedRecords(t *testing.T) {
+
+	plan_body := []byte(`{"changes":[{"id":1,"role":"depiction","uri":"a","action":"update","properties":[],"geometry_changed":false},{"id":1,"role":"alt","alt_label":"georef_x","uri":"b","action":"create","properties":[],"geometry_changed":true}]}`)
+
+	p := plan.NewPlan()
+
+	err := json.Unmarshal(plan_body, p)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal plan, %v", err)
+	}
+
+	r := geo_writers.NewUpdateResult()
+	r.Plan = p
+
+	changed := ChangedRecords(r)
+
+	if len(changed) != 1 || changed[0].AltLabel != "georef_x" {
+		t.Fatalf("Unexpected changed records for plan, %v", changed)
+	}
+
+	r = geo_writers.NewUpdateResult()
+	r.Add(&geo_writers.UpdateRecord{Id: 1, Role: plan.DepictionRole, Changed: false, URI: "a"})
+	r.Add(&geo_writers.UpdateRecord{Id: 2, Role: plan.AltRole, AltLabel: "georef_x", Changed: true, URI: "b"})
+
+	changed = ChangedRecords(r)
+
+	if len(changed) != 1 || changed[0].Id != 2 || changed[0].AltLabel != "georef_x" {
+		t.Fatalf("Unexpected changed records, %v", changed)
+	}
+}
+
+func TestKeyedMutex(t *testing.T) {
+
+	km := NewKeyedMutex()
+
+	var active int64
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 10; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			unlock := km.Lock("subject")
+			defer unlock()
+
+			if atomic.AddInt64(&active, 1) > 1 {
+				t.Errorf("Expected lock to be held by a single goroutine")
+			}
+
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&active, -1)
+		}()
+	}
+
+	wg.Wait()
+}
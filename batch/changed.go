@@ -0,0 +1,53 @@
+package batch
+
+import (
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// ChangedRecord defines a record which was changed by processing a line of input.
+type ChangedRecord struct {
+	// The Who's On First ID of the record.
+	Id int64 `json:"id"`
+	// The alternate geometry label of the record, if it is an alternate geometry.
+	AltLabel string `json:"alt_label,omitempty"`
+}
+
+// ChangedRecords returns the list of `ChangedRecord` instances derived from 'r'. If 'r' describes a dry run only the
+// changes in its `plan.Plan` which create or modify a record are included. Otherwise the records returned by its `Changed`
+// method are included.
+func ChangedRecords(r *geo_writers.UpdateResult) []*ChangedRecord {
+
+	changed := make([]*ChangedRecord, 0)
+
+	if r == nil {
+		return changed
+	}
+
+	if r.DryRun() {
+
+		for _, c := range r.Plan.Changes {
+
+			if c.Action == plan.UpdateAction && !c.Changed() {
+				continue
+			}
+
+			changed = append(changed, &ChangedRecord{
+				Id:       c.Id,
+				AltLabel: c.AltLabel,
+			})
+		}
+
+		return changed
+	}
+
+	for _, rec := range r.Changed() {
+
+		changed = append(changed, &ChangedRecord{
+			Id:       rec.Id,
+			AltLabel: rec.AltLabel,
+		})
+	}
+
+	return changed
+}
@@ -0,0 +1,139 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Checkpoint records the IDs of records which have been processed, one per line, in a plain-text file so that
+// an interrupted run can be resumed without reprocessing those records.
+type Checkpoint struct {
+	path string
+	fh   *os.File
+	done map[int64]bool
+	mu   *sync.Mutex
+}
+
+// NewCheckpoint returns a new `Checkpoint` instance for the file at 'path', reading any IDs already recorded in that
+// file. The file is created if it does not exist. A trailing partial line (for example, from a run that was killed
+// in the middle of writing an ID) is ignored.
+func NewCheckpoint(path string) (*Checkpoint, error) {
+
+	done := make(map[int64]bool)
+
+	body, err := os.ReadFile(path)
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Failed to read checkpoint file %s, %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	line_no := 0
+
+	for scanner.Scan() {
+
+		line_no += 1
+
+		line := bytes.TrimSpace(scanner.Bytes())
+
+		if len(line) == 0 {
+			continue
+		}
+
+		id, err := strconv.ParseInt(string(line), 10, 64)
+
+		if err != nil {
+			slog.Warn("Invalid checkpoint ID, skipping", "path", path, "line", line_no, "error", err)
+			continue
+		}
+
+		done[id] = true
+	}
+
+	err = scanner.Err()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse checkpoint file %s, %w", path, err)
+	}
+
+	fh, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open checkpoint file %s for writing, %w", path, err)
+	}
+
+	// Make sure new IDs don't get appended to a partial line
+
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+
+		_, err := fh.Write([]byte("\n"))
+
+		if err != nil {
+			fh.Close()
+			return nil, fmt.Errorf("Failed to write checkpoint file %s, %w", path, err)
+		}
+	}
+
+	c := &Checkpoint{
+		path: path,
+		fh:   fh,
+		done: done,
+		mu:   new(sync.Mutex),
+	}
+
+	return c, nil
+}
+
+// Done returns a boolean value indicating whether 'id' has already been recorded as processed.
+func (c *Checkpoint) Done(id int64) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.done[id]
+}
+
+// Count returns the number of IDs which have been recorded as processed.
+func (c *Checkpoint) Count() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.done)
+}
+
+// Record records 'id' as processed, syncing the checkpoint file to disk before returning.
+func (c *Checkpoint) Record(id int64) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done[id] {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(c.fh, "%d\n", id)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write checkpoint for %d, %w", id, err)
+	}
+
+	err = c.fh.Sync()
+
+	if err != nil {
+		return fmt.Errorf("Failed to sync checkpoint file %s, %w", c.path, err)
+	}
+
+	c.done[id] = true
+	return nil
+}
+
+// Close closes the underlying checkpoint file.
+func (c *Checkpoint) Close() error {
+	return c.fh.Close()
+}
@@ -0,0 +1,80 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "checkpoint.txt")
+
+	c, err := NewCheckpoint(path)
+
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint, %v", err)
+	}
+
+	for _, id := range []int64{1, 2, 2, 3} {
+
+		err := c.Record(id)
+
+		if err != nil {
+			t.Fatalf("Failed to record %d, %v", id, err)
+		}
+	}
+
+	if c.Count() != 3 {
+		t.Fatalf("Expected 3 IDs, got %d", c.Count())
+	}
+
+	err = c.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close checkpoint, %v", err)
+	}
+
+	// Simulate a run which was interrupted while writing an ID
+
+	fh, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to open checkpoint, %v", err)
+	}
+
+	_, err = fh.Write([]byte("4x"))
+	fh.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to write partial line, %v", err)
+	}
+
+	c, err = NewCheckpoint(path)
+
+	if err != nil {
+		t.Fatalf("Failed to reopen checkpoint, %v", err)
+	}
+
+	defer c.Close()
+
+	if c.Count() != 3 || !c.Done(1) || !c.Done(3) || c.Done(4) {
+		t.Fatalf("Unexpected checkpoint state after resume")
+	}
+
+	err = c.Record(5)
+
+	if err != nil {
+		t.Fatalf("Failed to record 5, %v", err)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint, %v", err)
+	}
+
+	if string(body) != "1\n2\n3\n4x\n5\n" {
+		t.Fatalf("Unexpected checkpoint file, %q", string(body))
+	}
+}
@@ -0,0 +1,41 @@
+package batch
+
+import (
+	"sync"
+)
+
+// KeyedMutex provides a mutual exclusion lock for each of an arbitrary set of string keys. It is used to ensure
+// that lines which update the same record (for example depictions which share a subject) are not processed concurrently.
+type KeyedMutex struct {
+	mu    *sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewKeyedMutex returns a new `KeyedMutex` instance.
+func NewKeyedMutex() *KeyedMutex {
+
+	km := &KeyedMutex{
+		mu:    new(sync.Mutex),
+		locks: make(map[string]*sync.Mutex),
+	}
+
+	return km
+}
+
+// Lock acquires the lock for 'key' and returns a function which releases it.
+func (km *KeyedMutex) Lock(key string) func() {
+
+	km.mu.Lock()
+
+	l, exists := km.locks[key]
+
+	if !exists {
+		l = new(sync.Mutex)
+		km.locks[key] = l
+	}
+
+	km.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
@@ -0,0 +1,51 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// STDIO is the path used to signal that input should be read from STDIN or results written to STDOUT.
+const STDIO string = "-"
+
+// RunWithPaths processes each line in the file at 'input_path' writing results to the file at 'results_path'. If
+// either path is "-" then input will be read from STDIN or results written to STDOUT, respectively.
+func RunWithPaths(ctx context.Context, opts *RunOptions, input_path string, results_path string) (*Summary, error) {
+
+	var r io.Reader
+	var wr io.Writer
+
+	switch input_path {
+	case STDIO:
+		r = os.Stdin
+	default:
+
+		fh, err := os.Open(input_path)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s for reading, %w", input_path, err)
+		}
+
+		defer fh.Close()
+		r = fh
+	}
+
+	switch results_path {
+	case STDIO:
+		wr = os.Stdout
+	default:
+
+		fh, err := os.Create(results_path)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s for writing, %w", results_path, err)
+		}
+
+		defer fh.Close()
+		wr = fh
+	}
+
+	return Run(ctx, opts, r, wr)
+}
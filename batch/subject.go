@@ -0,0 +1,30 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+// LockSubject acquires the lock in 'km' for the subject (parent) of the depiction 'depiction_id', read from 'r', and
+// returns a function which releases it. This ensures that depictions which share a subject are not updated concurrently.
+func LockSubject(ctx context.Context, km *KeyedMutex, r reader.Reader, depiction_id int64) (func(), error) {
+
+	body, err := wof_reader.LoadBytes(ctx, r, depiction_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load depiction %d, %w", depiction_id, err)
+	}
+
+	subject_id, err := properties.ParentId(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive subject (parent) ID for depiction %d, %w", depiction_id, err)
+	}
+
+	unlock := km.Lock(fmt.Sprintf("subject:%d", subject_id))
+	return unlock, nil
+}
@@ -0,0 +1,76 @@
+// Package cache provides caches, and a caching whosonfirst/go-reader/v2.Reader implementation, used to avoid
+// reading the same Who's On First (and SFO Museum) records over and over again.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// DEFAULT_LRU_SIZE is the default maximum number of items stored by the "lru://" `Cache` scheme.
+const DEFAULT_LRU_SIZE int = 1024
+
+// ErrCacheMiss is returned by `Cache.Get` if a key is not present in the cache.
+var ErrCacheMiss = errors.New("Cache miss")
+
+// Cache defines an interface for storing and retrieving the bodies of records.
+type Cache interface {
+	// Get returns the body stored for 'key' or `ErrCacheMiss` if it is not present.
+	Get(context.Context, string) ([]byte, error)
+	// Set stores the body for 'key'.
+	Set(context.Context, string, []byte) error
+}
+
+// NewCache returns a new `Cache` instance derived from 'uri'. Valid schemes are:
+//
+//	lru://?size={SIZE}  An in-memory least-recently-used cache storing at most 'size' (default is `DEFAULT_LRU_SIZE`) items.
+//	disk://{PATH}       An on-disk cache storing each item as a file in the directory defined by 'path'. Items are never expired.
+func NewCache(ctx context.Context, uri string) (Cache, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse cache URI, %w", err)
+	}
+
+	q := u.Query()
+
+	switch u.Scheme {
+	case "lru":
+
+		size := DEFAULT_LRU_SIZE
+
+		if q.Has("size") {
+
+			v, err := strconv.Atoi(q.Get("size"))
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid ?size= parameter, %w", err)
+			}
+
+			size = v
+		}
+
+		return NewLRUCache(size)
+
+	case "disk":
+
+		path := u.Path
+
+		if u.Host != "" {
+			path = u.Host + path
+		}
+
+		if path == "" {
+			return nil, fmt.Errorf("Missing path for disk cache")
+		}
+
+		return NewDiskCache(path)
+
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported cache scheme '%s'", u.Scheme)
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewCache(t *testing.T) {
+
+	ctx := context.Background()
+
+	valid := []string{
+		"lru://",
+		"lru://?size=10",
+		fmt.Sprintf("disk://%s", t.TempDir()),
+	}
+
+	for _, uri := range valid {
+
+		_, err := NewCache(ctx, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create cache for %s, %v", uri, err)
+		}
+	}
+
+	invalid := []string{
+		"lru://?size=0",
+		"lru://?size=bob",
+		"disk://",
+		"memcache://",
+	}
+
+	for _, uri := range invalid {
+
+		_, err := NewCache(ctx, uri)
+
+		if err == nil {
+			t.Fatalf("Expected %s to fail", uri)
+		}
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+
+	ctx := context.Background()
+
+	c, err := NewLRUCache(2)
+
+	if err != nil {
+		t.Fatalf("Failed to create cache, %v", err)
+	}
+
+	c.Set(ctx, "a", []byte("a"))
+	c.Set(ctx, "b", []byte("b"))
+
+	// Make "a" the most recently used item so that "b" is discarded
+
+	_, err = c.Get(ctx, "a")
+
+	if err != nil {
+		t.Fatalf("Failed to get a, %v", err)
+	}
+
+	c.Set(ctx, "c", []byte("c"))
+
+	if c.Len() != 2 {
+		t.Fatalf("Expected 2 items, got %d", c.Len())
+	}
+
+	_, err = c.Get(ctx, "b")
+
+	if !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected b to be discarded, %v", err)
+	}
+
+	for _, k := range []string{"a", "c"} {
+
+		body, err := c.Get(ctx, k)
+
+		if err != nil {
+			t.Fatalf("Failed to get %s, %v", k, err)
+		}
+
+		if string(body) != k {
+			t.Fatalf("Unexpected body for %s, %s", k, string(body))
+		}
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	c, err := NewDiskCache(root)
+
+	if err != nil {
+		t.Fatalf("Failed to create cache, %v", err)
+	}
+
+	_, err = c.Get(ctx, "a")
+
+	if !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected cache miss, %v", err)
+	}
+
+	err = c.Set(ctx, "a", []byte("a"))
+
+	if err != nil {
+		t.Fatalf("Failed to set a, %v", err)
+	}
+
+	// Items persist across instances
+
+	c, err = NewDiskCache(root)
+
+	if err != nil {
+		t.Fatalf("Failed to create cache, %v", err)
+	}
+
+	body, err := c.Get(ctx, "a")
+
+	if err != nil {
+		t.Fatalf("Failed to get a, %v", err)
+	}
+
+	if string(body) != "a" {
+		t.Fatalf("Unexpected body, %s", string(body))
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DiskCache implements the `Cache` interface storing each item as a file in a directory. Items are never expired so
+// the directory should be removed when the records it contains are known to have changed.
+type DiskCache struct {
+	root string
+}
+
+// NewDiskCache returns a new `DiskCache` instance storing items in 'root', which is created if it does not exist.
+func NewDiskCache(root string) (*DiskCache, error) {
+
+	abs_root, err := filepath.Abs(root)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive absolute path for %s, %w", root, err)
+	}
+
+	err = os.MkdirAll(abs_root, 0755)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create %s, %w", abs_root, err)
+	}
+
+	c := &DiskCache{
+		root: abs_root,
+	}
+
+	return c, nil
+}
+
+// Get returns the body stored for 'key' or `ErrCacheMiss` if it is not present.
+func (c *DiskCache) Get(ctx context.Context, key string) ([]byte, error) {
+
+	body, err := os.ReadFile(c.path(key))
+
+	if err != nil {
+
+		if os.IsNotExist(err) {
+			return nil, ErrCacheMiss
+		}
+
+		return nil, fmt.Errorf("Failed to read cache item, %w", err)
+	}
+
+	return body, nil
+}
+
+// Set stores the body for 'key'. The body is written to a temporary file which is then renamed so that concurrent
+// readers never see a partially written item.
+func (c *DiskCache) Set(ctx context.Context, key string, body []byte) error {
+
+	path := c.path(key)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create cache directory, %w", err)
+	}
+
+	fh, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	_, err = fh.Write(body)
+
+	if err != nil {
+		fh.Close()
+		os.Remove(fh.Name())
+		return fmt.Errorf("Failed to write cache item, %w", err)
+	}
+
+	err = fh.Close()
+
+	if err != nil {
+		os.Remove(fh.Name())
+		return fmt.Errorf("Failed to close cache item, %w", err)
+	}
+
+	err = os.Rename(fh.Name(), path)
+
+	if err != nil {
+		os.Remove(fh.Name())
+		return fmt.Errorf("Failed to rename cache item, %w", err)
+	}
+
+	return nil
+}
+
+// path returns the path for 'key', which is the hex-encoded SHA-256 hash of 'key' nested two levels deep so that
+// no single directory contains too many files.
+func (c *DiskCache) path(key string) string {
+
+	sum := sha256.Sum256([]byte(key))
+	fname := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.root, fname[0:2], fname[2:4], fname)
+}
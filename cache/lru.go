@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// LRUCache implements the `Cache` interface storing at most a fixed number of items in memory, discarding the least
+// recently used item when that number is exceeded.
+type LRUCache struct {
+	size  int
+	items map[string]*list.Element
+	order *list.List
+	mu    *sync.Mutex
+}
+
+type lruItem struct {
+	key  string
+	body []byte
+}
+
+// NewLRUCache returns a new `LRUCache` instance storing at most 'size' items.
+func NewLRUCache(size int) (*LRUCache, error) {
+
+	if size < 1 {
+		return nil, fmt.Errorf("Invalid size, must be greater than zero")
+	}
+
+	c := &LRUCache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		mu:    new(sync.Mutex),
+	}
+
+	return c, nil
+}
+
+// Get returns the body stored for 'key' or `ErrCacheMiss` if it is not present.
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	c.order.MoveToFront(el)
+	return slices.Clone(el.Value.(*lruItem).body), nil
+}
+
+// Set stores the body for 'key', discarding the least recently used item if the cache is full.
+func (c *LRUCache) Set(ctx context.Context, key string, body []byte) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	body = slices.Clone(body)
+
+	el, ok := c.items[key]
+
+	if ok {
+		el.Value.(*lruItem).body = body
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem{key: key, body: body})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of items in the cache.
+func (c *LRUCache) Len() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
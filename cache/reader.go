@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/whosonfirst/go-ioutil"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+// CachingReader implements the whosonfirst/go-reader/v2.Reader interface reading records from a `Cache` instance
+// before falling back to another reader and caching its results. Hits and misses are counted and logged at the debug level.
+type CachingReader struct {
+	reader reader.Reader
+	cache  Cache
+	name   string
+	hits   *atomic.Int64
+	misses *atomic.Int64
+}
+
+// NewCachingReader returns a new `CachingReader` instance for reading records from 'r' using 'c'. Keys are prefixed
+// by 'name' so that more than one reader can share the same cache. 'name' is also included in log messages.
+func NewCachingReader(r reader.Reader, c Cache, name string) *CachingReader {
+
+	cr := &CachingReader{
+		reader: r,
+		cache:  c,
+		name:   name,
+		hits:   new(atomic.Int64),
+		misses: new(atomic.Int64),
+	}
+
+	return cr
+}
+
+// Read returns an `io.ReadSeekCloser` for 'path', reading it from the cache if present or from the underlying reader otherwise.
+func (r *CachingReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+
+	logger := slog.Default()
+	logger = logger.With("reader", r.name)
+	logger = logger.With("path", path)
+
+	key := r.key(path)
+
+	body, err := r.cache.Get(ctx, key)
+
+	if err == nil {
+		r.hits.Add(1)
+		logger.Debug("Cache hit")
+		return ioutil.NewReadSeekCloser(bytes.NewReader(body))
+	}
+
+	if !errors.Is(err, ErrCacheMiss) {
+		logger.Warn("Failed to read from cache", "error", err)
+	}
+
+	r.misses.Add(1)
+	logger.Debug("Cache miss")
+
+	fh, err := r.reader.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fh.Close()
+
+	body, err = io.ReadAll(fh)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	err = r.cache.Set(ctx, key, body)
+
+	if err != nil {
+		logger.Warn("Failed to write to cache", "error", err)
+	}
+
+	return ioutil.NewReadSeekCloser(bytes.NewReader(body))
+}
+
+// Exists returns a boolean value indicating whether 'path' is present in the cache or exists in the underlying reader.
+func (r *CachingReader) Exists(ctx context.Context, path string) (bool, error) {
+
+	_, err := r.cache.Get(ctx, r.key(path))
+
+	if err == nil {
+		return true, nil
+	}
+
+	return r.reader.Exists(ctx, path)
+}
+
+// ReaderURI returns the URI for 'path' as defined by the underlying reader.
+func (r *CachingReader) ReaderURI(ctx context.Context, path string) string {
+	return r.reader.ReaderURI(ctx, path)
+}
+
+// Hits returns the number of reads served from the cache.
+func (r *CachingReader) Hits() int64 {
+	return r.hits.Load()
+}
+
+// Misses returns the number of reads served by the underlying reader.
+func (r *CachingReader) Misses() int64 {
+	return r.misses.Load()
+}
+
+// LogStats logs the number of cache hits and misses for 'r' at the debug level.
+func (r *CachingReader) LogStats(ctx context.Context) {
+	slog.Debug("Reader cache statistics", "reader", r.name, "hits", r.Hits(), "misses", r.Misses())
+}
+
+func (r *CachingReader) key(path string) string {
+	return fmt.Sprintf("%s#%s", r.name, path)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/whosonfirst/go-reader/v2"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+func TestCachingReader(t *testing.T) {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../fixtures/whosonfirst-data-admin")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	r, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	c, err := NewLRUCache(10)
+
+	if err != nil {
+		t.Fatalf("Failed to create cache, %v", err)
+	}
+
+	cr := NewCachingReader(r, c, "whosonfirst")
+
+	var expected []byte
+
+	for i := 0; i < 3; i++ {
+
+		body, err := wof_reader.LoadBytes(ctx, cr, 102025263)
+
+		if err != nil {
+			t.Fatalf("Failed to load record, %v", err)
+		}
+
+		if i == 0 {
+			expected = body
+		} else if string(body) != string(expected) {
+			t.Fatalf("Unexpected body for cached read")
+		}
+	}
+
+	if cr.Hits() != 2 || cr.Misses() != 1 {
+		t.Fatalf("Unexpected hits (%d) and misses (%d)", cr.Hits(), cr.Misses())
+	}
+
+	_, err = cr.Read(ctx, "missing.geojson")
+
+	if err == nil {
+		t.Fatalf("Expected read for missing record to fail")
+	}
+
+	// Readers sharing a cache do not share keys
+
+	other := NewCachingReader(r, c, "sfomuseum")
+
+	fh, err := other.Read(ctx, "102/025/263/102025263.geojson")
+
+	if err != nil {
+		t.Fatalf("Failed to read record, %v", err)
+	}
+
+	defer fh.Close()
+
+	_, err = io.ReadAll(fh)
+
+	if err != nil {
+		t.Fatalf("Failed to read body, %v", err)
+	}
+
+	if other.Hits() != 0 || other.Misses() != 1 {
+		t.Fatalf("Unexpected hits (%d) and misses (%d) for other reader", other.Hits(), other.Misses())
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/lint"
+)
+
+func main() {
+
+	ctx := context.Background()
+
+	err := lint.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to run lint, %v", err)
+	}
+}
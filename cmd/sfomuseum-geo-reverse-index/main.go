@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/app/reverse/index"
+)
+
+func main() {
+
+	ctx := context.Background()
+
+	err := index.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to run reverse index, %v", err)
+	}
+}
@@ -4,8 +4,14 @@ const RESERVED_GEOTAG_DEPICTIONS string = "geotag:depicts"
 
 const RESERVED_GEOTAG_SUBJECT string = "geotag:subject"
 
+const RESERVED_GEOTAG_SUBJECT_DEPICTIONS string = "geotag:depictions"
+
 const RESERVED_GEOTAG_BELONGSTO string = "geotag:whosonfirst_belongsto"
 
+const RESERVED_GEOTAG_CAMERA string = "geotag:whosonfirst_camera"
+
+const RESERVED_GEOTAG_TARGET string = "geotag:whosonfirst_target"
+
 const RESERVED_GEOTAG_LASTMODIFIED string = "geotag:lastmodified"
 
 const RESERVED_GEOREFERENCE_BELONGSTO string = "georef:whosonfirst_belongsto"
@@ -18,4 +24,18 @@ const RESERVED_GEOREFERENCE_LABEL string = "georef:label"
 
 const RESERVED_GEOREFERENCE_LASTMODIFIED string = "georef:lastmodified"
 
+const RESERVED_GEOREFERENCE_AUTHOR string = "georef:author"
+
+const RESERVED_GEOREFERENCE_CREATED string = "georef:created"
+
+const RESERVED_GEOREFERENCE_CITATION string = "georef:citation"
+
+const RESERVED_GEOREFERENCE_NOTE string = "georef:note"
+
+const RESERVED_GEOREFERENCE_CONFIDENCE string = "georef:confidence"
+
+const RESERVED_GEOREFERENCE_PROVENANCE string = "georef:provenance"
+
+const RESERVED_GEOREFERENCE_DEPICTION string = "georef:depiction"
+
 const RESERVED_WOF_DEPICTS string = "wof:depicts"
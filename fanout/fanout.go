@@ -0,0 +1,125 @@
+// Package fanout provides helper methods for processing a list of items concurrently, with an upper limit on the number of
+// items processed at once, stopping at the first error.
+package fanout
+
+import (
+	"context"
+	"sync"
+)
+
+// DEFAULT_LIMIT is the default maximum number of items processed at once.
+const DEFAULT_LIMIT int = 10
+
+type limitKey struct{}
+
+// WithLimit returns a copy of 'ctx' which defines the maximum number of items to process at once for any calls to `Each`
+// or `Map` which are passed a limit of zero (or less).
+func WithLimit(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, limitKey{}, limit)
+}
+
+// LimitFromContext returns the maximum number of items to process at once defined by `WithLimit` or `DEFAULT_LIMIT`
+// if it is undefined or less than one.
+func LimitFromContext(ctx context.Context) int {
+
+	limit, ok := ctx.Value(limitKey{}).(int)
+
+	if !ok || limit < 1 {
+		return DEFAULT_LIMIT
+	}
+
+	return limit
+}
+
+// Each calls 'fn' for each element in 'items' with at most 'limit' calls running at once. If 'limit' is zero (or less)
+// the value returned by `LimitFromContext` is used. When a call to 'fn' returns an error the context passed to all the
+// other calls is cancelled and no further calls are started. Each always waits for all the calls it has started to complete
+// before returning the first error encountered or, if 'ctx' was cancelled, its error.
+func Each[T any](ctx context.Context, limit int, items []T, fn func(context.Context, T) error) error {
+
+	if limit < 1 {
+		limit = LimitFromContext(ctx)
+	}
+
+	fanout_ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	throttle := make(chan bool, limit)
+	wg := new(sync.WaitGroup)
+
+	var first_err error
+	err_once := new(sync.Once)
+
+items_loop:
+	for _, item := range items {
+
+		select {
+		case <-fanout_ctx.Done():
+			break items_loop
+		case throttle <- true:
+
+			wg.Add(1)
+
+			go func(item T) {
+
+				defer func() {
+					<-throttle
+					wg.Done()
+				}()
+
+				if fanout_ctx.Err() != nil {
+					return
+				}
+
+				err := fn(fanout_ctx, item)
+
+				if err != nil {
+
+					err_once.Do(func() {
+						first_err = err
+					})
+
+					cancel()
+				}
+			}(item)
+		}
+	}
+
+	wg.Wait()
+
+	if first_err != nil {
+		return first_err
+	}
+
+	return ctx.Err()
+}
+
+// Map calls 'fn' for each element in 'items', using `Each`, and returns the results in the same order as 'items'.
+func Map[T any, R any](ctx context.Context, limit int, items []T, fn func(context.Context, T) (R, error)) ([]R, error) {
+
+	results := make([]R, len(items))
+
+	indices := make([]int, len(items))
+
+	for idx := range items {
+		indices[idx] = idx
+	}
+
+	err := Each(ctx, limit, indices, func(ctx context.Context, idx int) error {
+
+		r, err := fn(ctx, items[idx])
+
+		if err != nil {
+			return err
+		}
+
+		results[idx] = r
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
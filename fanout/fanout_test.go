@@ -0,0 +1,164 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForGoroutines waits up to one second for the number of running goroutines to drop to 'expected' or less
+// and returns the number of goroutines still running.
+func waitForGoroutines(expected int) int {
+
+	count := runtime.NumGoroutine()
+
+	for i := 0; i < 100 && count > expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+		count = runtime.NumGoroutine()
+	}
+
+	return count
+}
+
+func TestMap(t *testing.T) {
+
+	ctx := context.Background()
+
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	results, err := Map(ctx, 3, items, func(ctx context.Context, i int) (int, error) {
+		return i * 2, nil
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to map items, %v", err)
+	}
+
+	if !slices.Equal(results, []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20}) {
+		t.Fatalf("Unexpected results, %v", results)
+	}
+}
+
+func TestEachLimit(t *testing.T) {
+
+	ctx := context.Background()
+
+	items := make([]int, 50)
+
+	running := new(atomic.Int64)
+	max_running := new(atomic.Int64)
+
+	err := Each(ctx, 4, items, func(ctx context.Context, i int) error {
+
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			m := max_running.Load()
+
+			if n <= m || max_running.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to process items, %v", err)
+	}
+
+	if max_running.Load() > 4 {
+		t.Fatalf("Expected at most 4 items to be processed at once, got %d", max_running.Load())
+	}
+
+	ctx = WithLimit(ctx, 2)
+
+	if LimitFromContext(ctx) != 2 {
+		t.Fatalf("Unexpected limit from context, %d", LimitFromContext(ctx))
+	}
+
+	if LimitFromContext(context.Background()) != DEFAULT_LIMIT {
+		t.Fatalf("Expected default limit")
+	}
+}
+
+func TestEachError(t *testing.T) {
+
+	ctx := context.Background()
+
+	baseline := runtime.NumGoroutine()
+
+	items := make([]int, 100)
+
+	for idx := range items {
+		items[idx] = idx
+	}
+
+	expected := errors.New("Failed")
+	started := new(atomic.Int64)
+
+	err := Each(ctx, 10, items, func(ctx context.Context, i int) error {
+
+		started.Add(1)
+
+		if i == 5 {
+			return expected
+		}
+
+		// Block until the failure cancels the context
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+			return nil
+		}
+	})
+
+	if !errors.Is(err, expected) {
+		t.Fatalf("Expected first error to be returned, got %v", err)
+	}
+
+	if started.Load() == int64(len(items)) {
+		t.Fatalf("Expected remaining items not to be processed after failure")
+	}
+
+	count := waitForGoroutines(baseline)
+
+	if count > baseline {
+		t.Fatalf("Expected %d goroutines after failure, got %d", baseline, count)
+	}
+}
+
+func TestEachCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	baseline := runtime.NumGoroutine()
+
+	items := make([]int, 20)
+
+	err := Each(ctx, 2, items, func(ctx context.Context, i int) error {
+
+		cancel()
+
+		<-ctx.Done()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context cancelled error, got %v", err)
+	}
+
+	count := waitForGoroutines(baseline)
+
+	if count > baseline {
+		t.Fatalf("Expected %d goroutines after cancellation, got %d", baseline, count)
+	}
+}
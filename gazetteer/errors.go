@@ -0,0 +1,37 @@
+package gazetteer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotFoundError is the error returned when a place name can not be resolved.
+type NotFoundError struct {
+	// The name that could not be resolved.
+	Name string
+}
+
+// Error returns a string representation of 'e'.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No places found matching '%s'", e.Name)
+}
+
+// AmbiguousNameError is the error returned when a place name resolves to more than one place.
+type AmbiguousNameError struct {
+	// The name that could not be resolved.
+	Name string
+	// The list of places matching 'Name'.
+	Candidates []*Place
+}
+
+// Error returns a string representation of 'e' including the list of candidate places.
+func (e *AmbiguousNameError) Error() string {
+
+	candidates := make([]string, len(e.Candidates))
+
+	for idx, p := range e.Candidates {
+		candidates[idx] = p.String()
+	}
+
+	return fmt.Sprintf("Name '%s' is ambiguous, candidates are: %s", e.Name, strings.Join(candidates, "; "))
+}
@@ -0,0 +1,328 @@
+package gazetteer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
+)
+
+// NAME_PREFIX is the prefix for Who's On First name properties (for example `name:eng_x_preferred`) and for
+// reference values which should be resolved as place names rather than Who's On First IDs (for example `name:Bangkok`).
+const NAME_PREFIX string = "name:"
+
+// Index is an in-memory index of Who's On First place names, placetypes and hierarchies used to resolve place
+// names to Who's On First IDs.
+type Index struct {
+	places map[int64]*Place
+	names  map[string][]int64
+	mu     *sync.RWMutex
+}
+
+// NewIndex returns a new, empty, `Index` instance.
+func NewIndex() *Index {
+
+	idx := &Index{
+		places: make(map[int64]*Place),
+		names:  make(map[string][]int64),
+		mu:     new(sync.RWMutex),
+	}
+
+	return idx
+}
+
+// NewIndexFromIterator returns a new `Index` instance populated with all the records emitted by a
+// `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived from 'iterator_uri' for 'sources'.
+func NewIndexFromIterator(ctx context.Context, iterator_uri string, sources ...string) (*Index, error) {
+
+	idx := NewIndex()
+
+	err := idx.IndexIterator(ctx, iterator_uri, sources...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// IndexIterator adds all the records emitted by a `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived
+// from 'iterator_uri' for 'sources' to 'idx'. Alternate geometry files are skipped.
+func (idx *Index) IndexIterator(ctx context.Context, iterator_uri string, sources ...string) error {
+
+	iter, err := iterate.NewIterator(ctx, iterator_uri)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create new iterator, %w", err)
+	}
+
+	defer iter.Close()
+
+	for rec, err := range iter.Iterate(ctx, sources...) {
+
+		if err != nil {
+			return fmt.Errorf("Iterator signaled an error, %w", err)
+		}
+
+		body, err := io.ReadAll(rec.Body)
+		rec.Body.Close()
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", rec.Path, err)
+		}
+
+		if strings.Contains(rec.Path, "-alt-") {
+			continue
+		}
+
+		err = idx.Add(ctx, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to index %s, %w", rec.Path, err)
+		}
+	}
+
+	slog.Debug("Indexed gazetteer", "places", idx.Count())
+	return nil
+}
+
+// Add adds the Who's On First record in 'body' to 'idx'. The record is indexed by its `wof:name` property and
+// the values of any `name:{LANG}_x_{QUALIFIER}` properties. Records without a `wof:name` property are logged
+// and skipped.
+func (idx *Index) Add(ctx context.Context, body []byte) error {
+
+	id_rsp := gjson.GetBytes(body, "properties.wof:id")
+
+	if !id_rsp.Exists() {
+		return fmt.Errorf("Record is missing wof:id property")
+	}
+
+	id := id_rsp.Int()
+
+	name := gjson.GetBytes(body, "properties.wof:name").String()
+
+	if name == "" {
+		slog.Warn("Record is missing wof:name property, skipping", "id", id)
+		return nil
+	}
+
+	is_current := true
+
+	current_rsp := gjson.GetBytes(body, "properties.mz:is_current")
+
+	if current_rsp.Exists() && current_rsp.Int() == 0 {
+		is_current = false
+	}
+
+	deprecated_rsp := gjson.GetBytes(body, "properties.edtf:deprecated")
+
+	if deprecated_rsp.Exists() && deprecated_rsp.String() != "" {
+		is_current = false
+	}
+
+	if len(gjson.GetBytes(body, "properties.wof:superseded_by").Array()) > 0 {
+		is_current = false
+	}
+
+	ancestors := make([]int64, 0)
+
+	for _, h := range gjson.GetBytes(body, "properties.wof:hierarchy").Array() {
+
+		for _, v := range h.Map() {
+
+			ancestor_id := v.Int()
+
+			if ancestor_id > 0 && ancestor_id != id && !slices.Contains(ancestors, ancestor_id) {
+				ancestors = append(ancestors, ancestor_id)
+			}
+		}
+	}
+
+	slices.Sort(ancestors)
+
+	p := &Place{
+		Id:        id,
+		Name:      name,
+		Placetype: gjson.GetBytes(body, "properties.wof:placetype").String(),
+		Country:   gjson.GetBytes(body, "properties.wof:country").String(),
+		Ancestors: ancestors,
+		IsCurrent: is_current,
+	}
+
+	names := []string{
+		name,
+	}
+
+	gjson.GetBytes(body, "properties").ForEach(func(k gjson.Result, v gjson.Result) bool {
+
+		if !strings.HasPrefix(k.String(), NAME_PREFIX) {
+			return true
+		}
+
+		for _, n := range v.Array() {
+
+			if n.Type == gjson.String && n.String() != "" {
+				names = append(names, n.String())
+			}
+		}
+
+		return true
+	})
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.places[id] = p
+
+	for _, n := range names {
+
+		key := normalizeName(n)
+		ids := idx.names[key]
+
+		if !slices.Contains(ids, id) {
+			idx.names[key] = append(ids, id)
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of places in 'idx'.
+func (idx *Index) Count() int {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.places)
+}
+
+// Place returns the `Place` for 'id' or nil if it is not present in 'idx'.
+func (idx *Index) Place(id int64) *Place {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.places[id]
+}
+
+// Lookup returns all the places in 'idx' matching 'query', sorted by ID. 'query' takes the form of a place name
+// optionally followed by one or more comma-separated qualifiers, for example "Sydney, AU" or "Sydney, New South Wales".
+// Each qualifier must match a place's country code, placetype or the name of one of its (indexed) ancestors. If a query
+// matches both current and non-current places only the current places are returned.
+func (idx *Index) Lookup(query string) []*Place {
+
+	parts := strings.Split(query, ",")
+
+	name := normalizeName(parts[0])
+	qualifiers := make([]string, 0)
+
+	for _, q := range parts[1:] {
+
+		q = normalizeName(q)
+
+		if q != "" {
+			qualifiers = append(qualifiers, q)
+		}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	candidates := make([]*Place, 0)
+
+	for _, id := range idx.names[name] {
+
+		p := idx.places[id]
+
+		if idx.matchesQualifiers(p, qualifiers) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	current := make([]*Place, 0)
+
+	for _, p := range candidates {
+
+		if p.IsCurrent {
+			current = append(current, p)
+		}
+	}
+
+	if len(current) > 0 {
+		candidates = current
+	}
+
+	slices.SortFunc(candidates, func(a *Place, b *Place) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return candidates
+}
+
+// Resolve returns the single `Place` matching 'query' (see `Lookup` for details). If no places match a `NotFoundError`
+// is returned. If more than one place matches an `AmbiguousNameError`, listing the candidates, is returned.
+func (idx *Index) Resolve(ctx context.Context, query string) (*Place, error) {
+
+	candidates := idx.Lookup(query)
+
+	switch len(candidates) {
+	case 0:
+		return nil, &NotFoundError{Name: query}
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, &AmbiguousNameError{Name: query, Candidates: candidates}
+	}
+}
+
+// ResolveName returns the Who's On First ID of the single place matching 'query'. It is a thin wrapper around
+// the `Resolve` method.
+func (idx *Index) ResolveName(ctx context.Context, query string) (int64, error) {
+
+	p, err := idx.Resolve(ctx, query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return p.Id, nil
+}
+
+func (idx *Index) matchesQualifiers(p *Place, qualifiers []string) bool {
+
+	for _, q := range qualifiers {
+
+		if normalizeName(p.Country) == q || normalizeName(p.Placetype) == q {
+			continue
+		}
+
+		matches_ancestor := false
+
+		for _, ancestor_id := range p.Ancestors {
+
+			ancestor, ok := idx.places[ancestor_id]
+
+			if ok && normalizeName(ancestor.Name) == q {
+				matches_ancestor = true
+				break
+			}
+		}
+
+		if !matches_ancestor {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,150 @@
+package gazetteer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func newTestIndex(t *testing.T) *Index {
+
+	ctx := context.Background()
+
+	path_admin, err := filepath.Abs("../fixtures/whosonfirst-data-admin")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	idx, err := NewIndexFromIterator(ctx, "repo://", path_admin)
+
+	if err != nil {
+		t.Fatalf("Failed to create index, %v", err)
+	}
+
+	records := []string{
+		// Sydney, Nova Scotia
+		`{"properties":{"wof:id":101736545,"wof:name":"Sydney","wof:placetype":"locality","wof:country":"CA","wof:hierarchy":[{"country_id":85633041,"region_id":85682123,"locality_id":101736545}]}}`,
+		// New South Wales
+		`{"properties":{"wof:id":85681545,"wof:name":"New South Wales","wof:placetype":"region","wof:country":"AU","wof:hierarchy":[{"country_id":85632793,"region_id":85681545}]}}`,
+		// A deprecated Noumea
+		`{"properties":{"wof:id":1234,"wof:name":"Noumea","wof:placetype":"locality","wof:country":"NC","edtf:deprecated":"2020-01-01"}}`,
+		// Alternate names
+		`{"properties":{"wof:id":5678,"wof:name":"San Francisco International Airport","wof:placetype":"campus","wof:country":"US","name:eng_x_variant":["SFO"]}}`,
+	}
+
+	for _, body := range records {
+
+		err := idx.Add(ctx, []byte(body))
+
+		if err != nil {
+			t.Fatalf("Failed to add record, %v", err)
+		}
+	}
+
+	return idx
+}
+
+func TestIndexResolve(t *testing.T) {
+
+	ctx := context.Background()
+	idx := newTestIndex(t)
+
+	tests := map[string]int64{
+		"Bangkok":                 102025263,
+		"bangkok":                 102025263,
+		" Noumea ":                890413117,
+		"Sydney, AU":              101932003,
+		"Sydney, New South Wales": 101932003,
+		"Sydney, CA":              101736545,
+		"Sydney, locality, AU":    101932003,
+		"SFO":                     5678,
+	}
+
+	for query, expected := range tests {
+
+		p, err := idx.Resolve(ctx, query)
+
+		if err != nil {
+			t.Fatalf("Failed to resolve '%s', %v", query, err)
+		}
+
+		if p.Id != expected {
+			t.Fatalf("Expected '%s' to resolve to %d, got %d", query, expected, p.Id)
+		}
+	}
+}
+
+func TestIndexResolveAmbiguous(t *testing.T) {
+
+	ctx := context.Background()
+	idx := newTestIndex(t)
+
+	_, err := idx.Resolve(ctx, "Sydney")
+
+	var ambiguous_err *AmbiguousNameError
+
+	if !errors.As(err, &ambiguous_err) {
+		t.Fatalf("Expected ambiguous name error, got %v", err)
+	}
+
+	if len(ambiguous_err.Candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %d", len(ambiguous_err.Candidates))
+	}
+
+	if ambiguous_err.Candidates[0].Id != 101736545 || ambiguous_err.Candidates[1].Id != 101932003 {
+		t.Fatalf("Unexpected candidates, %v", ambiguous_err.Error())
+	}
+
+	_, err = idx.Resolve(ctx, "Sydney, NZ")
+
+	var notfound_err *NotFoundError
+
+	if !errors.As(err, &notfound_err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+}
+
+func TestIndexAdd(t *testing.T) {
+
+	ctx := context.Background()
+	idx := NewIndex()
+
+	err := idx.Add(ctx, []byte(`{"properties":{"wof:name":"Nowhere"}}`))
+
+	if err == nil {
+		t.Fatalf("Expected record without wof:id to fail")
+	}
+
+	err = idx.Add(ctx, []byte(`{"properties":{"wof:id":1}}`))
+
+	if err != nil {
+		t.Fatalf("Expected record without wof:name to be skipped, %v", err)
+	}
+
+	if idx.Place(1) != nil {
+		t.Fatalf("Expected record without wof:name not to be indexed")
+	}
+
+	err = idx.Add(ctx, []byte(`{"properties":{"wof:id":102025263,"wof:name":"Bangkok","wof:placetype":"locality","wof:country":"TH","wof:hierarchy":[{"country_id":85632293,"locality_id":102025263}]}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to add record, %v", err)
+	}
+
+	p := idx.Place(102025263)
+
+	if p == nil {
+		t.Fatalf("Failed to retrieve place")
+	}
+
+	if fmt.Sprintf("%v", p.Ancestors) != "[85632293]" {
+		t.Fatalf("Unexpected ancestors, %v", p.Ancestors)
+	}
+
+	if p.String() != "102025263 Bangkok (locality, TH)" {
+		t.Fatalf("Unexpected string, %s", p.String())
+	}
+}
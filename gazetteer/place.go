@@ -0,0 +1,40 @@
+package gazetteer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Place is a struct containing the details of a Who's On First record used to resolve place names.
+type Place struct {
+	// The Who's On First ID of the place.
+	Id int64 `json:"wof:id"`
+	// The (default) name of the place.
+	Name string `json:"wof:name"`
+	// The placetype of the place.
+	Placetype string `json:"wof:placetype"`
+	// The (ISO) country code of the place.
+	Country string `json:"wof:country,omitempty"`
+	// The unique list of ancestor IDs, derived from the place's `wof:hierarchy` property, excluding the place itself.
+	Ancestors []int64 `json:"ancestors,omitempty"`
+	// IsCurrent is a boolean value indicating whether the place is current (not deprecated, superseded or explicitly not current).
+	IsCurrent bool `json:"is_current"`
+}
+
+// String returns a human-readable representation of 'p' suitable for listing candidates.
+func (p *Place) String() string {
+
+	details := []string{
+		p.Placetype,
+	}
+
+	if p.Country != "" {
+		details = append(details, p.Country)
+	}
+
+	if !p.IsCurrent {
+		details = append(details, "not current")
+	}
+
+	return fmt.Sprintf("%d %s (%s)", p.Id, p.Name, strings.Join(details, ", "))
+}
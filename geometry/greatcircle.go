@@ -0,0 +1,184 @@
+package geometry
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// GREAT_CIRCLE_STEP is the maximum distance, in degrees, between interpolated points on a great circle arc.
+const GREAT_CIRCLE_STEP float64 = 1.0
+
+// DeriveGreatCircleRoute returns a geometry tracing the great circle arcs between each successive pair of 'points'.
+// If the route crosses the antimeridian it will be split in to multiple lines and an `orb.MultiLineString` is returned,
+// otherwise an `orb.LineString`. Successive duplicate points are ignored and at least two distinct points are required.
+func DeriveGreatCircleRoute(points ...orb.Point) (orb.Geometry, error) {
+
+	stops := make([]orb.Point, 0)
+
+	for _, pt := range points {
+
+		if len(stops) > 0 && stops[len(stops)-1].Equal(pt) {
+			continue
+		}
+
+		stops = append(stops, pt)
+	}
+
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("Route requires at least two distinct points")
+	}
+
+	route := make(orb.LineString, 0)
+
+	for i := 1; i < len(stops); i++ {
+
+		arc, err := GreatCircle(stops[i-1], stops[i])
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive great circle for leg %d, %w", i, err)
+		}
+
+		if len(route) > 0 {
+			arc = arc[1:]
+		}
+
+		route = append(route, arc...)
+	}
+
+	lines := SplitAntimeridian(route)
+
+	if len(lines) == 1 {
+		return lines[0], nil
+	}
+
+	return lines, nil
+}
+
+// GreatCircle returns an `orb.LineString` interpolating the great circle arc between 'from' and 'to' with
+// points no more than `GREAT_CIRCLE_STEP` degrees apart. Longitudes are normalized to the range -180 to 180.
+func GreatCircle(from orb.Point, to orb.Point) (orb.LineString, error) {
+
+	lat1 := radians(from.Lat())
+	lon1 := radians(from.Lon())
+	lat2 := radians(to.Lat())
+	lon2 := radians(to.Lon())
+
+	a := math.Pow(math.Sin((lat2-lat1)/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((lon2-lon1)/2), 2)
+	d := 2 * math.Asin(math.Sqrt(math.Min(1.0, a)))
+
+	if d == 0 {
+		return orb.LineString{from, to}, nil
+	}
+
+	sin_d := math.Sin(d)
+
+	if math.Abs(sin_d) < 1e-12 {
+		return nil, fmt.Errorf("Great circle between antipodal points %v and %v is undefined", from, to)
+	}
+
+	count := int(math.Ceil(degrees(d) / GREAT_CIRCLE_STEP))
+
+	if count < 1 {
+		count = 1
+	}
+
+	line := make(orb.LineString, count+1)
+
+	line[0] = orb.Point{normalizeLongitude(from.Lon()), from.Lat()}
+	line[count] = orb.Point{normalizeLongitude(to.Lon()), to.Lat()}
+
+	for i := 1; i < count; i++ {
+
+		f := float64(i) / float64(count)
+
+		a := math.Sin((1-f)*d) / sin_d
+		b := math.Sin(f*d) / sin_d
+
+		x := a*math.Cos(lat1)*math.Cos(lon1) + b*math.Cos(lat2)*math.Cos(lon2)
+		y := a*math.Cos(lat1)*math.Sin(lon1) + b*math.Cos(lat2)*math.Sin(lon2)
+		z := a*math.Sin(lat1) + b*math.Sin(lat2)
+
+		lat := math.Atan2(z, math.Sqrt(x*x+y*y))
+		lon := math.Atan2(y, x)
+
+		line[i] = orb.Point{degrees(lon), degrees(lat)}
+	}
+
+	return line, nil
+}
+
+// SplitAntimeridian splits 'ls' in to one or more lines wherever a segment crosses the antimeridian (that is
+// the longitude difference between two successive points is greater than 180 degrees). The latitude at which
+// each segment crosses is interpolated and added to the end and start of the lines on either side of the split.
+func SplitAntimeridian(ls orb.LineString) orb.MultiLineString {
+
+	lines := make(orb.MultiLineString, 0)
+
+	if len(ls) == 0 {
+		return lines
+	}
+
+	current := orb.LineString{ls[0]}
+
+	for i := 1; i < len(ls); i++ {
+
+		prev := ls[i-1]
+		pt := ls[i]
+
+		delta := pt.Lon() - prev.Lon()
+
+		if math.Abs(delta) <= 180.0 {
+			current = append(current, pt)
+			continue
+		}
+
+		// Which side of the antimeridian are we leaving from?
+
+		edge := 180.0
+		unwrapped := pt.Lon() + 360.0
+
+		if delta > 0 {
+			edge = -180.0
+			unwrapped = pt.Lon() - 360.0
+		}
+
+		f := (edge - prev.Lon()) / (unwrapped - prev.Lon())
+		lat := prev.Lat() + f*(pt.Lat()-prev.Lat())
+
+		crossing := orb.Point{edge, lat}
+
+		if !current[len(current)-1].Equal(crossing) {
+			current = append(current, crossing)
+		}
+
+		lines = append(lines, current)
+
+		current = orb.LineString{orb.Point{-edge, lat}, pt}
+	}
+
+	lines = append(lines, current)
+	return lines
+}
+
+func normalizeLongitude(lon float64) float64 {
+
+	for lon > 180.0 {
+		lon -= 360.0
+	}
+
+	for lon < -180.0 {
+		lon += 360.0
+	}
+
+	return lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180.0
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180.0 / math.Pi
+}
@@ -0,0 +1,136 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestGreatCircle(t *testing.T) {
+
+	from := orb.Point{0, 0}
+	to := orb.Point{90, 0}
+
+	line, err := GreatCircle(from, to)
+
+	if err != nil {
+		t.Fatalf("Failed to derive great circle, %v", err)
+	}
+
+	if len(line) != 91 {
+		t.Fatalf("Expected 91 points, got %d", len(line))
+	}
+
+	if !line[0].Equal(from) || !line[len(line)-1].Equal(to) {
+		t.Fatalf("Unexpected end points, %v %v", line[0], line[len(line)-1])
+	}
+
+	// Along the equator the midpoint should be on the equator
+
+	mid := line[45]
+
+	if math.Abs(mid.Lat()) > 1e-9 || math.Abs(mid.Lon()-45.0) > 1e-9 {
+		t.Fatalf("Unexpected mid point, %v", mid)
+	}
+
+	_, err = GreatCircle(orb.Point{0, 0}, orb.Point{180, 0})
+
+	if err == nil {
+		t.Fatalf("Expected antipodal points to fail")
+	}
+}
+
+func TestDeriveGreatCircleRoute(t *testing.T) {
+
+	// SFO to Sydney crosses the antimeridian
+
+	sfo := orb.Point{-122.384, 37.6188}
+	syd := orb.Point{151.177, -33.9461}
+
+	geom, err := DeriveGreatCircleRoute(sfo, syd)
+
+	if err != nil {
+		t.Fatalf("Failed to derive route, %v", err)
+	}
+
+	mls, ok := geom.(orb.MultiLineString)
+
+	if !ok {
+		t.Fatalf("Expected MultiLineString, got %s", geom.GeoJSONType())
+	}
+
+	if len(mls) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(mls))
+	}
+
+	first := mls[0]
+	second := mls[1]
+
+	if !first[0].Equal(sfo) || !second[len(second)-1].Equal(syd) {
+		t.Fatalf("Unexpected route end points")
+	}
+
+	if first[len(first)-1].Lon() != -180.0 || second[0].Lon() != 180.0 {
+		t.Fatalf("Expected route to be split at the antimeridian, %v %v", first[len(first)-1], second[0])
+	}
+
+	if first[len(first)-1].Lat() != second[0].Lat() {
+		t.Fatalf("Expected split points to share latitude")
+	}
+
+	for _, ls := range mls {
+
+		for i := 1; i < len(ls); i++ {
+
+			if math.Abs(ls[i].Lon()-ls[i-1].Lon()) > 180.0 {
+				t.Fatalf("Line contains a segment which wraps the antimeridian, %v %v", ls[i-1], ls[i])
+			}
+		}
+	}
+
+	// Sydney to Noumea does not
+
+	noumea := orb.Point{166.45, -22.27}
+
+	geom, err = DeriveGreatCircleRoute(syd, noumea, noumea)
+
+	if err != nil {
+		t.Fatalf("Failed to derive route, %v", err)
+	}
+
+	if geom.GeoJSONType() != "LineString" {
+		t.Fatalf("Expected LineString, got %s", geom.GeoJSONType())
+	}
+
+	_, err = DeriveGreatCircleRoute(syd, syd)
+
+	if err == nil {
+		t.Fatalf("Expected route with a single distinct point to fail")
+	}
+}
+
+func TestSplitAntimeridian(t *testing.T) {
+
+	ls := orb.LineString{
+		orb.Point{170, 10},
+		orb.Point{-170, 20},
+		orb.Point{-160, 20},
+	}
+
+	lines := SplitAntimeridian(ls)
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	expected := orb.Point{180, 15}
+
+	if !lines[0][len(lines[0])-1].Equal(expected) {
+		t.Fatalf("Unexpected crossing point, %v", lines[0][len(lines[0])-1])
+	}
+
+	if !lines[1][0].Equal(orb.Point{-180, 15}) {
+		t.Fatalf("Unexpected start point, %v", lines[1][0])
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/planar"
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
 	"github.com/whosonfirst/go-whosonfirst-feature/geometry"
@@ -21,91 +22,66 @@ import (
 //
 // If a feature associated with an ID has either "lbl:" or "geotag:" latitude and longitude properties those
 // will be used in place of a centroid derived from the features geometry.
+//
+// Features are read concurrently, with at most `fanout.LimitFromContext(ctx)` features being read at once.
 func DeriveMultiPointFromIds(ctx context.Context, r reader.Reader, ids ...int64) (orb.Geometry, error) {
 
-	done_ch := make(chan bool)
-	err_ch := make(chan error)
-	geom_ch := make(chan orb.Geometry)
-
-	for _, id := range ids {
-
-		go func(id int64) {
+	geoms, err := fanout.Map(ctx, 0, ids, func(ctx context.Context, id int64) (orb.Geometry, error) {
 
-			logger := slog.Default()
-			logger = logger.With("id", id)
+		logger := slog.Default()
+		logger = logger.With("id", id)
 
-			logger.Debug("Derive geometry")
+		logger.Debug("Derive geometry")
 
-			defer func() {
-				done_ch <- true
-			}()
+		body, err := wof_reader.LoadBytes(ctx, r, id)
 
-			body, err := wof_reader.LoadBytes(ctx, r, id)
-
-			if err != nil {
-				logger.Error("Failed to read data", "error", err)
-				err_ch <- fmt.Errorf("Failed to read %d, %w", id, err)
-				return
-			}
+		if err != nil {
+			logger.Error("Failed to read data", "error", err)
+			return nil, fmt.Errorf("Failed to read %d, %w", id, err)
+		}
 
-			prefixes := []string{
-				"geotag",
-				"lbl",
-			}
+		prefixes := []string{
+			"geotag",
+			"lbl",
+		}
 
-			logger.Debug("Derive centroid from properties")
+		logger.Debug("Derive centroid from properties")
 
-			for _, prefix := range prefixes {
+		for _, prefix := range prefixes {
 
-				logger.Debug("Check properties", "prefix", prefix)
+			logger.Debug("Check properties", "prefix", prefix)
 
-				lat_path := fmt.Sprintf("properties.%s:latitude", prefix)
-				lon_path := fmt.Sprintf("properties.%s:longitude", prefix)
+			lat_path := fmt.Sprintf("properties.%s:latitude", prefix)
+			lon_path := fmt.Sprintf("properties.%s:longitude", prefix)
 
-				lat_rsp := gjson.GetBytes(body, lat_path)
-				lon_rsp := gjson.GetBytes(body, lon_path)
+			lat_rsp := gjson.GetBytes(body, lat_path)
+			lon_rsp := gjson.GetBytes(body, lon_path)
 
-				if lat_rsp.Exists() && lon_rsp.Exists() {
+			if lat_rsp.Exists() && lon_rsp.Exists() {
 
-					lat := lat_rsp.Float()
-					lon := lon_rsp.Float()
+				lat := lat_rsp.Float()
+				lon := lon_rsp.Float()
 
-					logger.Debug("Return centroid", "prefix", prefix)
-					pt := orb.Point{lon, lat}
-					geom_ch <- pt
-					return
-				}
+				logger.Debug("Return centroid", "prefix", prefix)
+				pt := orb.Point{lon, lat}
+				return pt, nil
 			}
+		}
 
-			logger.Debug("Derive centroid from geometry")
-
-			geom, err := geometry.Geometry(body)
+		logger.Debug("Derive centroid from geometry")
 
-			if err != nil {
-				logger.Error("Failed to derive geometry", "error", err)
-				err_ch <- fmt.Errorf("Failed to derive geometry for %d, %w", id, err)
-				return
-			}
+		geom, err := geometry.Geometry(body)
 
-			orb_geom := geom.Geometry()
-			geom_ch <- orb_geom
-			return
-		}(id)
+		if err != nil {
+			logger.Error("Failed to derive geometry", "error", err)
+			return nil, fmt.Errorf("Failed to derive geometry for %d, %w", id, err)
+		}
 
-	}
+		return geom.Geometry(), nil
+	})
 
-	remaining := len(ids)
-	geoms := make([]orb.Geometry, 0)
-
-	for remaining > 0 {
-		select {
-		case <-done_ch:
-			remaining -= 1
-		case err := <-err_ch:
-			return nil, fmt.Errorf("Failed to derive geometry for subject, %w", err)
-		case geom := <-geom_ch:
-			geoms = append(geoms, geom)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive geometry for subject, %w", err)
 	}
 
 	return DeriveMultiPointFromGeoms(ctx, geoms...)
@@ -118,48 +94,33 @@ func DeriveMultiPointFromIds(ctx context.Context, r reader.Reader, ids ...int64)
 // final geometry (rather than deriving a centroid).
 func DeriveMultiPointFromGeoms(ctx context.Context, geoms ...orb.Geometry) (orb.Geometry, error) {
 
-	done_ch := make(chan bool)
-	err_ch := make(chan error)
-	centroid_ch := make(chan orb.Point)
-
 	logger := slog.Default()
 
-	for _, geom := range geoms {
+	centroids, err := fanout.Map(ctx, 0, geoms, func(ctx context.Context, orb_geom orb.Geometry) ([]orb.Point, error) {
 
-		go func(orb_geom orb.Geometry) {
+		switch orb_geom.GeoJSONType() {
+		case "MultiPoint":
 
-			defer func() {
-				done_ch <- true
-			}()
+			logger.Debug("Return centroids from multipoint")
+			return orb_geom.(orb.MultiPoint), nil
 
-			switch orb_geom.GeoJSONType() {
-			case "MultiPoint":
+		default:
 
-				logger.Debug("Return centroids from multipoint")
-				for _, pt := range orb_geom.(orb.MultiPoint) {
-					centroid_ch <- pt
-				}
-
-			default:
-
-				logger.Debug("Return planar centroid")
-				pt, _ := planar.CentroidArea(orb_geom)
-				centroid_ch <- pt
-			}
+			logger.Debug("Return planar centroid")
+			pt, _ := planar.CentroidArea(orb_geom)
+			return []orb.Point{pt}, nil
+		}
+	})
 
-		}(geom)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive geometry for subject, %w", err)
 	}
 
-	remaining := len(geoms)
 	points := make([]orb.Point, 0)
 
-	for remaining > 0 {
-		select {
-		case <-done_ch:
-			remaining -= 1
-		case err := <-err_ch:
-			return nil, fmt.Errorf("Failed to derive geometry for subject, %w", err)
-		case pt := <-centroid_ch:
+	for _, pts := range centroids {
+
+		for _, pt := range pts {
 			logger.Debug("Add point if not exist", "point", pt)
 			points = AddPointIfNotExist(points, pt)
 		}
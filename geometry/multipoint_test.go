@@ -0,0 +1,70 @@
+package geometry
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func TestDeriveMultiPointFromIds(t *testing.T) {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../fixtures/whosonfirst-data-admin")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	r, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	ctx = fanout.WithLimit(ctx, 1)
+
+	geom, err := DeriveMultiPointFromIds(ctx, r, 102025263, 101932003)
+
+	if err != nil {
+		t.Fatalf("Failed to derive multipoint, %v", err)
+	}
+
+	if len(geom.(orb.MultiPoint)) != 2 {
+		t.Fatalf("Expected 2 points, got %v", geom)
+	}
+
+	// Ensure that no goroutines are left behind when a record can not be read
+
+	baseline := runtime.NumGoroutine()
+
+	ids := []int64{102025263, 101932003, 890413117}
+
+	for i := 0; i < 20; i++ {
+		ids = append(ids, int64(i+1))
+	}
+
+	_, err = DeriveMultiPointFromIds(context.Background(), r, ids...)
+
+	if err == nil {
+		t.Fatalf("Expected missing records to fail")
+	}
+
+	count := runtime.NumGoroutine()
+
+	for i := 0; i < 100 && count > baseline; i++ {
+		time.Sleep(10 * time.Millisecond)
+		count = runtime.NumGoroutine()
+	}
+
+	if count > baseline {
+		t.Fatalf("Expected %d goroutines after failure, got %d", baseline, count)
+	}
+}
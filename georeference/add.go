@@ -2,8 +2,219 @@ package georeference
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-uri"
 )
 
-func AddReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, refs ...*Reference) ([]byte, error) {
-	return AssignReferences(ctx, opts, depiction_id, refs...)
+// AddReferences merges 'refs' with any existing georeferences (stored in the `georef:depicted` property) for 'depiction_id'
+// and then assigns the combined set of references using `AssignReferences`. References whose label already exists for the
+// depiction will have their Who's On First IDs appended to the existing list of IDs for that label.
+func AddReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, refs ...*Reference) (*geo_writers.UpdateResult, error) {
+
+	existing_refs, err := ExistingReferences(ctx, opts, depiction_id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	merged_refs := MergeReferences(existing_refs, refs...)
+	return AssignReferences(ctx, opts, depiction_id, merged_refs...)
+}
+
+// ExistingReferences returns the list of `Reference` instances derived from the `georef:depicted` property of 'depiction_id'.
+// The alternate geometry label for each reference is recovered from the depiction's `src:geom_alt` property, or for custom
+// alt labels from the alt files themselves, so that existing alt files are updated, rather than replaced, when the references
+// are reassigned.
+func ExistingReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64) ([]*Reference, error) {
+
+	depiction_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load depiction %d, %w", depiction_id, err)
+	}
+
+	depicted, err := LoadGeoreferenceDepicted(depiction_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load existing georeferences for depiction %d, %w", depiction_id, err)
+	}
+
+	alt_labels := make([]string, 0)
+
+	for _, r := range gjson.GetBytes(depiction_body, "properties.src:geom_alt").Array() {
+		alt_labels = append(alt_labels, r.String())
+	}
+
+	refs := make([]*Reference, len(depicted))
+
+	// Alt labels which have already been assigned to a reference
+	claimed := make([]string, 0)
+
+	for idx, d := range depicted {
+
+		alt_label := AltLabelFromExisting(d.Label, alt_labels)
+
+		if alt_label != "" {
+			claimed = append(claimed, alt_label)
+		}
+
+		refs[idx] = &Reference{
+			Ids:        d.Depicts,
+			Label:      d.Label,
+			AltLabel:   alt_label,
+			Provenance: d.Provenance(),
+		}
+	}
+
+	// References with a custom alt label can not be matched by name so look for the (georef_) alt file
+	// which contains the reference's label as a property.
+
+	for _, r := range refs {
+
+		if r.AltLabel != "" {
+			continue
+		}
+
+		for _, alt_label := range alt_labels {
+
+			if !strings.HasPrefix(alt_label, GEOREF_ALT_PREFIX) || slices.Contains(claimed, alt_label) {
+				continue
+			}
+
+			ok, err := altFileHasLabel(ctx, opts.DepictionReader, depiction_id, alt_label, r.Label)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				r.AltLabel = alt_label
+				claimed = append(claimed, alt_label)
+				break
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// altFileHasLabel returns a boolean value indicating whether the (non-deprecated) alt file labeled 'alt_label' for 'depiction_id'
+// has a property named 'label'. Alt files which do not exist are ignored.
+func altFileHasLabel(ctx context.Context, r reader.Reader, depiction_id int64, alt_label string, label string) (bool, error) {
+
+	alt_uri_args := &uri.URIArgs{
+		IsAlternate: true,
+		AltGeom: &uri.AltGeom{
+			Source: alt_label,
+		},
+	}
+
+	alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
+	}
+
+	exists, err := r.Exists(ctx, alt_uri)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to determine whether %s exists, %w", alt_uri, err)
+	}
+
+	if !exists {
+		return false, nil
+	}
+
+	alt_r, err := r.Read(ctx, alt_uri)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to read %s, %w", alt_uri, err)
+	}
+
+	defer alt_r.Close()
+
+	alt_body, err := io.ReadAll(alt_r)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to read body for %s, %w", alt_uri, err)
+	}
+
+	if properties.Deprecated(alt_body) != "" {
+		return false, nil
+	}
+
+	label_path := fmt.Sprintf("properties.%s", gjson.Escape(label))
+	return gjson.GetBytes(alt_body, label_path).Exists(), nil
+}
+
+// MergeReferences returns a new list of `Reference` instances combining 'existing' and 'refs'. If a reference in 'refs'
+// has the same label as one in 'existing' its IDs are appended (if not already present) to the existing reference.
+// If a reference in 'refs' adds new IDs to, or has its own provenance for, an existing label then its provenance
+// replaces the existing provenance. Otherwise the existing provenance is preserved.
+func MergeReferences(existing []*Reference, refs ...*Reference) []*Reference {
+
+	merged := make([]*Reference, 0)
+	lookup := make(map[string]*Reference)
+
+	for _, r := range existing {
+
+		m := &Reference{
+			Ids:        slices.Clone(r.Ids),
+			Label:      r.Label,
+			AltLabel:   r.AltLabel,
+			Provenance: r.Provenance,
+		}
+
+		lookup[r.Label] = m
+		merged = append(merged, m)
+	}
+
+	for _, r := range refs {
+
+		m, exists := lookup[r.Label]
+
+		if !exists {
+
+			m = &Reference{
+				Ids:      make([]int64, 0),
+				Label:    r.Label,
+				AltLabel: r.AltLabel,
+			}
+
+			lookup[r.Label] = m
+			merged = append(merged, m)
+		}
+
+		if r.AltLabel != "" {
+			m.AltLabel = r.AltLabel
+		}
+
+		has_new_ids := false
+
+		for _, id := range r.Ids {
+
+			if !slices.Contains(m.Ids, id) {
+				m.Ids = append(m.Ids, id)
+				has_new_ids = true
+			}
+		}
+
+		if has_new_ids || (r.Provenance != nil && !r.Provenance.IsZero()) {
+			m.Provenance = r.Provenance
+		}
+
+		slog.Debug("Merge reference", "label", m.Label, "ids", m.Ids)
+	}
+
+	return merged
 }
@@ -0,0 +1,140 @@
+package georeference
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+func TestMergeReferences(t *testing.T) {
+
+	existing := []*Reference{
+		&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}},
+	}
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003, 102025263}},
+		&Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}},
+	}
+
+	merged := MergeReferences(existing, refs...)
+
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 merged references, got %d", len(merged))
+	}
+
+	if !slices.Equal(merged[0].Ids, []int64{101932003, 102025263}) {
+		t.Fatalf("Unexpected IDs for merged reference, %v", merged[0].Ids)
+	}
+
+	if len(existing[0].Ids) != 1 {
+		t.Fatalf("Existing reference was modified")
+	}
+}
+
+func TestAddReferences(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1527829811)
+
+	_, err := AddReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}})
+
+	if err != nil {
+		t.Fatalf("Failed to add first reference, %v", err)
+	}
+
+	_, err = AddReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}})
+
+	if err != nil {
+		t.Fatalf("Failed to add second reference, %v", err)
+	}
+
+	body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	depicted, err := LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		t.Fatalf("Failed to load depicted, %v", err)
+	}
+
+	if len(depicted) != 2 {
+		t.Fatalf("Expected 2 georeferences after merge, got %d", len(depicted))
+	}
+
+	refs, err := ExistingReferences(ctx, opts, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load existing references, %v", err)
+	}
+
+	for _, r := range refs {
+
+		expected := DeriveAltLabelFromReference(&Reference{Label: r.Label})
+
+		if r.AltLabel != expected {
+			t.Fatalf("Expected alt label '%s' for '%s', got '%s'", expected, r.Label, r.AltLabel)
+		}
+	}
+}
+
+func TestAddReferencesCustomAltLabel(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1527829811)
+
+	_, err := AssignReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:depicts", AltLabel: "georef_custom", Ids: []int64{102025263}})
+
+	if err != nil {
+		t.Fatalf("Failed to assign reference, %v", err)
+	}
+
+	refs, err := ExistingReferences(ctx, opts, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load existing references, %v", err)
+	}
+
+	if len(refs) != 1 || refs[0].AltLabel != "georef_custom" {
+		t.Fatalf("Expected custom alt label to be recovered, %v", refs)
+	}
+
+	r, err := AddReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}})
+
+	if err != nil {
+		t.Fatalf("Failed to add reference, %v", err)
+	}
+
+	for _, rec := range r.Records {
+
+		if rec.AltLabel == "georef_custom" && properties.Deprecated(rec.Body) != "" {
+			t.Fatalf("Custom alt file was deprecated")
+		}
+	}
+
+	body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	alt_geoms, err := properties.AltGeometries(body)
+
+	if err != nil {
+		t.Fatalf("Failed to derive alt geometries, %v", err)
+	}
+
+	if !slices.Contains(alt_geoms, "georef_custom") || slices.Contains(alt_geoms, "georef_sfomuseum_depicts") {
+		t.Fatalf("Unexpected alt geometries, %v", alt_geoms)
+	}
+}
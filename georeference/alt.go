@@ -41,3 +41,24 @@ func DeriveAltLabel(raw string) string {
 
 	return string(v)
 }
+
+// AltLabelFromExisting returns the label in 'alt_labels' (typically the values of a depiction's `src:geom_alt` property)
+// that was derived, using `DeriveAltLabelFromReference`, for the georeference labeled 'label'. If there is no match an empty
+// string is returned.
+func AltLabelFromExisting(label string, alt_labels []string) string {
+
+	derived := DeriveAltLabel(label)
+
+	for _, alt_label := range alt_labels {
+
+		if !strings.HasPrefix(alt_label, GEOREF_ALT_PREFIX) {
+			continue
+		}
+
+		if DeriveAltLabel(strings.TrimPrefix(alt_label, GEOREF_ALT_PREFIX)) == derived {
+			return alt_label
+		}
+	}
+
+	return ""
+}
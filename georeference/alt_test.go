@@ -22,3 +22,28 @@ func TestDeriveAltLabel(t *testing.T) {
 		}
 	}
 }
+
+func TestAltLabelFromExisting(t *testing.T) {
+
+	alt_labels := []string{
+		"georef_sfomuseum_depicts",
+		"georef_sfomuseum:flightcover_from",
+		"flightroute",
+	}
+
+	tests := map[string]string{
+		"sfomuseum:depicts":          "georef_sfomuseum_depicts",
+		"sfomuseum:flightcover_from": "georef_sfomuseum:flightcover_from",
+		"sfomuseum:flightcover_to":   "",
+		"flightroute":                "",
+	}
+
+	for label, expected := range tests {
+
+		alt_label := AltLabelFromExisting(label, alt_labels)
+
+		if alt_label != expected {
+			t.Fatalf("Unexpected alt label for '%s'. Expected '%s' but got '%s'", label, expected, alt_label)
+		}
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-sfomuseum-geo"
 	"github.com/sfomuseum/go-sfomuseum-geo/alt"
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
 	// "github.com/sfomuseum/go-sfomuseum-geo/geometry"
 	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
 	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
 	// "github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
@@ -53,12 +55,48 @@ type AssignReferencesOptions struct {
 	SubjectWriterURI string
 	// A valid whosonfirst/go-reader.Reader instance for reading "sfomuseum" features (for example the aviation collection).
 	SFOMuseumReader reader.Reader
+	// ReaderCacheURI is an optional `cache.NewCache` URI (for example "lru://" or "disk:///tmp/wof-cache"). If defined the
+	// `WhosOnFirstReader` and `SFOMuseumReader` readers are placed behind a cache, created for each call to `AssignReferences`
+	// (or `RefreshReferences`), so that records referenced more than once are only read once.
+	ReaderCacheURI string
+	// GitHubAPIURL is an optional base URL for the GitHub API used when `githubapi-pr://` writers are grouped in to a
+	// single coordinated change set. If empty the default GitHub API endpoint is used.
+	GitHubAPIURL string
+	// GitHubTemplates are optional `text/template` templates used to derive the commit messages, pull request titles and
+	// descriptions and branch names for GitHub writers. If nil the default templates are used.
+	GitHubTemplates *github.Templates
+	// SubjectMembership is used to derive the list of depictions for the subject (parent) record when it is recompiled. If nil
+	// the subject's `millsfield:images` property is used.
+	SubjectMembership SubjectMembership
+	// ReferenceValidation defines how references to deprecated, superseded or not current Who's On First records are handled.
+	// If empty `ValidationWarn` is assumed. Any non-current references are recorded in the response under the
+	// `RESERVED_VALIDATIONS_KEY` key.
+	ReferenceValidation ValidationMode
+	// Concurrency is the maximum number of references (or alternate geometry files) to process at once. If zero (or less)
+	// the limit defined by `fanout.WithLimit` for the context, or `fanout.DEFAULT_LIMIT`, is used.
+	Concurrency int
+	// PruneMissingAltFiles is a boolean flag signaling that labels in a depiction's `src:geom_alt` property which have no
+	// corresponding alternate geometry file should be removed. If false the missing file causes an error.
+	PruneMissingAltFiles bool
+	// DryRun is a boolean flag signaling that no data should be written. Instead a JSON-encoded `plan.Plan` describing
+	// the records that would be written, and how they would change, is returned.
+	DryRun bool
 }
 
 // AssignReferences updates records associated with 'depiction_id' (that is the depiction record itself and it's "parent" object record)
 // and 'refs'. An alternate geometry file will be created for each element in 'ref' and a multi-point geometry (derived from 'refs') will
-// be assigned to the depiction and parent (subject) record.
-func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, refs ...*Reference) ([]byte, error) {
+// be assigned to the depiction and parent (subject) record. The `geo_writers.UpdateResult` returned lists each record that was written (or,
+// if 'opts.DryRun' is true, that would be written) along with any reference validations.
+func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, refs ...*Reference) (*geo_writers.UpdateResult, error) {
+
+	r, _, _, err := assignReferences(ctx, opts, depiction_id, false, refs...)
+	return r, err
+}
+
+// assignReferences does the work of `AssignReferences`. If 'defer_subject' is true the subject (parent) record is not updated.
+// Instead the ID of the subject and a `SkipListItem` describing the updated depiction are returned so that the subject can be
+// recompiled, once, after all its depictions have been updated. If 'defer_subject' is false the `SkipListItem` is still returned.
+func assignReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, defer_subject bool, refs ...*Reference) (*geo_writers.UpdateResult, int64, *SkipListItem, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -71,33 +109,36 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		logger.Warn("No references to assign. This will remove all previous references")
 	}
 
-	src_geom := "sfomuseum#georeference"
+	if opts.ReaderCacheURI != "" {
 
-	if opts.SourceGeomSuffix != "" {
-		src_geom = fmt.Sprintf("%s-%s", src_geom, opts.SourceGeomSuffix)
-		logger.Debug("Automatically assign source geom suffix", "suffix", src_geom)
-	}
+		whosonfirst_reader, sfomuseum_reader, log_stats, err := cacheReaders(ctx, opts.ReaderCacheURI, opts.WhosOnFirstReader, opts.SFOMuseumReader)
 
-	logger.Debug("Set up writers")
+		if err != nil {
+			return nil, 0, nil, err
+		}
 
-	github_opts := &github.UpdateWriterURIOptions{
-		Author:        opts.Author,
-		WhosOnFirstId: depiction_id,
-		Action:        github.GeoreferenceAction,
+		defer log_stats()
+
+		cached_opts := *opts
+		cached_opts.WhosOnFirstReader = whosonfirst_reader
+		cached_opts.SFOMuseumReader = sfomuseum_reader
+		cached_opts.ReaderCacheURI = ""
+
+		opts = &cached_opts
 	}
 
-	writers_opts := &geo_writers.CreateWritersOptions{
-		SubjectWriterURI:    opts.SubjectWriterURI,
-		DepictionWriterURI:  opts.DepictionWriterURI,
-		GithubWriterOptions: github_opts,
+	// Ensure that any other functions which process records concurrently (for example geometry.DeriveMultiPointFromIds)
+	// apply the same limit
+
+	if opts.Concurrency > 0 {
+		ctx = fanout.WithLimit(ctx, opts.Concurrency)
 	}
 
-	// See notes in writers/writers.go for why this returns both "Writer" and "MultiWriter" instances (for now)
-	writers, err := geo_writers.CreateWriters(ctx, writers_opts)
+	src_geom := "sfomuseum#georeference"
 
-	if err != nil {
-		logger.Error("Failed to create writers", "error", err)
-		return nil, fmt.Errorf("Failed to create geotag writers, %w", err)
+	if opts.SourceGeomSuffix != "" {
+		src_geom = fmt.Sprintf("%s-%s", src_geom, opts.SourceGeomSuffix)
+		logger.Debug("Automatically assign source geom suffix", "suffix", src_geom)
 	}
 
 	logger.Debug("Load depiction")
@@ -105,7 +146,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 	depiction_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create depiction reader, %w", err)
+		return nil, 0, nil, fmt.Errorf("Failed to create depiction reader, %w", err)
 	}
 
 	logger.Debug("Derive repo for depiction")
@@ -113,7 +154,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 	depiction_repo, err := properties.Repo(depiction_body)
 
 	if err != nil {
-		return nil, fmt.Errorf("Unable to derive wof:repo for depiction %d, %w", depiction_id, err)
+		return nil, 0, nil, fmt.Errorf("Unable to derive wof:repo for depiction %d, %w", depiction_id, err)
 	}
 
 	logger.Debug("Derive parent (subject) for depiction")
@@ -121,13 +162,13 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 	subject_id, err := properties.ParentId(depiction_body)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to derive subject (parent) ID for depiction, %w", err)
+		return nil, 0, nil, fmt.Errorf("Failed to derive subject (parent) ID for depiction, %w", err)
 	}
 
 	logger = logger.With("subject id", subject_id)
 
-	// TBD: use of https://github.com/whosonfirst/go-reader-cachereader for reading depictions
-	// Maybe check for non-nil opts.DepictionCache and update depiction_reader accordingly?
+	// Depictions are not read through the reader cache (see opts.ReaderCacheURI) because the depiction
+	// and its alt files are being updated here.
 
 	var depiction_reader reader.Reader
 
@@ -150,10 +191,6 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 	logger.Debug("Start updating depiction record")
 
-	done_ch := make(chan bool)
-	err_ch := make(chan error)
-	alt_ch := make(chan *alt.WhosOnFirstAltFeature)
-
 	// Map of any given wof:hierarchy dictionary where the value is the dictionary
 	// and the key is the hash of the md5 sum of the JSON-encoded dictionary
 	hierarchies_hash_map := new(sync.Map)
@@ -167,9 +204,11 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 	references_map := new(sync.Map)
 	updates_map := new(sync.Map)
 
+	// Map of the (ordered) points for flight route references keyed by reference label
+	route_points_map := new(sync.Map)
+
 	// START OF create/update alt files for references
 
-	new_alt_features := make([]*alt.WhosOnFirstAltFeature, 0)
 	other_alt_features := make([]*alt.WhosOnFirstAltFeature, 0)
 
 	// Ensure unique reference labels. This is mostly to ensure that any alt files
@@ -182,150 +221,253 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		_, exists := labels_map.LoadOrStore(r.Label, true)
 
 		if exists {
-			return nil, fmt.Errorf("Multiple references with duplicate label, '%s'", r.Label)
+			return nil, 0, nil, fmt.Errorf("Multiple references with duplicate label, '%s'", r.Label)
+		}
+
+		if r.Provenance != nil {
+
+			err := r.Provenance.Validate()
+
+			if err != nil {
+				return nil, 0, nil, fmt.Errorf("Reference '%s' has invalid provenance, %w", r.Label, err)
+			}
 		}
 	}
 
-	// Start iterating references to assign
+	// Ensure that references are current, following or refusing them as necessary
+
+	logger.Debug("Validate references", "mode", opts.ReferenceValidation)
+
+	refs, validations, ref_bodies, err := ValidateReferences(ctx, opts.WhosOnFirstReader, opts.ReferenceValidation, refs...)
+
+	if err != nil {
+		logger.Error("Failed to validate references", "error", err)
+		return nil, 0, nil, fmt.Errorf("Failed to validate references, %w", err)
+	}
+
+	logger.Debug("Set up writers")
+
+	github_opts := &github.UpdateWriterURIOptions{
+		Author:        opts.Author,
+		WhosOnFirstId: depiction_id,
+		Action:        github.GeoreferenceAction,
+		SubjectId:     subject_id,
+		Labels:        referenceLabels(refs),
+		Places:        referencePlaces(refs, ref_bodies),
+		Templates:     opts.GitHubTemplates,
+	}
+
+	var update_plan *plan.Plan
+
+	if opts.DryRun {
+		logger.Debug("Dry run enabled, record changes to plan")
+		update_plan = plan.NewPlan()
+	}
+
+	writers_opts := &geo_writers.CreateWritersOptions{
+		SubjectWriterURI:    opts.SubjectWriterURI,
+		DepictionWriterURI:  opts.DepictionWriterURI,
+		GithubWriterOptions: github_opts,
+		Plan:                update_plan,
+		DepictionReader:     opts.DepictionReader,
+		SubjectReader:       opts.SubjectReader,
+		GitHubAPIURL:        opts.GitHubAPIURL,
+	}
+
+	// See notes in writers/writers.go for why this returns both "Writer" and "MultiWriter" instances (for now)
+	writers, err := geo_writers.CreateWriters(ctx, writers_opts)
+
+	if err != nil {
+		logger.Error("Failed to create writers", "error", err)
+		return nil, 0, nil, fmt.Errorf("Failed to create geotag writers, %w", err)
+	}
+
+	// Resolve the provenance for each reference preserving the provenance of
+	// existing references whose IDs have not changed.
+
+	logger.Debug("Resolve provenance for references")
+
+	existing_depicted, err := LoadGeoreferenceDepicted(depiction_body)
+
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("Failed to load existing georeferences for depiction, %w", err)
+	}
+
+	existing_lookup := make(map[string]*GeoreferenceDepicted)
+
+	for _, d := range existing_depicted {
+		existing_lookup[d.Label] = d
+	}
+
+	now := time.Now()
+	provenance_map := make(map[string]*Provenance)
 
 	for _, r := range refs {
+		provenance_map[r.Label] = resolveProvenance(r, existing_lookup[r.Label], opts.Author, now)
+	}
 
-		go func(ctx context.Context, r *Reference) {
+	// Start iterating references to assign, processing at most opts.Concurrency references at once
 
-			logger.Info("Process reference", "ref", r.Label, "ids", r.Ids, "alt", r.AltLabel)
+	new_alt_features, err := fanout.Map(ctx, opts.Concurrency, refs, func(ctx context.Context, r *Reference) (*alt.WhosOnFirstAltFeature, error) {
 
-			defer func() {
-				done_ch <- true
-			}()
+		logger.Info("Process reference", "ref", r.Label, "ids", r.Ids, "alt", r.AltLabel)
 
-			if len(r.Ids) == 0 {
-				logger.Error("Ref is missing ids")
-				err_ch <- fmt.Errorf("Ref is missing IDs")
-				return
-			}
+		if len(r.Ids) == 0 {
+			logger.Error("Ref is missing ids")
+			return nil, fmt.Errorf("Ref is missing IDs")
+		}
 
-			if r.Label == "" {
-				logger.Error("Ref is missing label")
-				err_ch <- fmt.Errorf("Ref is missing label")
-				return
-			}
+		if r.Label == "" {
+			logger.Error("Ref is missing label")
+			return nil, fmt.Errorf("Ref is missing label")
+		}
 
-			prop_label := r.Label
-			alt_label := DeriveAltLabelFromReference(r)
+		prop_label := r.Label
+		alt_label := DeriveAltLabelFromReference(r)
+
+		// Note we are only assigning the base path for this key (prop_label)
+		// updates_map is "range-ed" below and we build a new new_depicted
+		// dict which is then assigned to properties.{geo.RESERVED_GEOREFERENCE_DEPICTED}
 
-			// Note we are only assigning the base path for this key (prop_label)
-			// updates_map is "range-ed" below and we build a new new_depicted
-			// dict which is then assigned to properties.{geo.RESERVED_GEOREFERENCE_DEPICTED}
+		logger.Debug("Store in updates map", "label", prop_label, "ids", r.Ids)
+		updates_map.Store(prop_label, r.Ids)
 
-			logger.Debug("Store in updates map", "label", prop_label, "ids", r.Ids)
-			updates_map.Store(prop_label, r.Ids)
+		count := len(r.Ids)
+		points := make([]orb.Point, count)
 
-			count := len(r.Ids)
-			points := make([]orb.Point, count)
+		// Remember any given reference (label) can have mutiple WOF IDs
+		// Fetch centroid and hierarchy for each ID in a reference
 
-			// Remember any given reference (label) can have mutiple WOF IDs
-			// Fetch centroid and hierarchy for each ID in a reference
+		for idx, id := range r.Ids {
 
-			for idx, id := range r.Ids {
+			logger := slog.Default()
+			logger = logger.With("depection id", depiction_id)
+			logger = logger.With("ref id", id)
+			logger = logger.With("label", prop_label)
+			logger = logger.With("alt_label", alt_label)
 
-				logger := slog.Default()
-				logger = logger.With("depection id", depiction_id)
-				logger = logger.With("ref id", id)
-				logger = logger.With("label", prop_label)
-				logger = logger.With("alt_label", alt_label)
+			logger.Debug("Process reference")
 
-				logger.Debug("Process reference")
+			// Records will have already been read during validation
 
-				body, err := wof_reader.LoadBytes(ctx, opts.WhosOnFirstReader, id)
+			body, ok := ref_bodies[id]
+
+			if !ok {
+
+				b, err := wof_reader.LoadBytes(ctx, opts.WhosOnFirstReader, id)
 
 				if err != nil {
 					logger.Error("Failed to load record for reference", "error", err)
-					err_ch <- fmt.Errorf("Failed to read record for WOF ID %d, %w", id, err)
-					return
+					return nil, fmt.Errorf("Failed to read record for WOF ID %d, %w", id, err)
 				}
 
-				hiers := properties.Hierarchies(body)
+				body = b
+			}
 
-				for _, h := range hiers {
+			hiers := properties.Hierarchies(body)
 
-					for _, h_id := range h {
-						references_map.Store(h_id, true)
-					}
+			for _, h := range hiers {
 
-					enc_h, err := json.Marshal(h)
+				for _, h_id := range h {
+					references_map.Store(h_id, true)
+				}
 
-					if err != nil {
-						logger.Error("Failed to marshal hierarchy", "error", err)
-						err_ch <- fmt.Errorf("Failed to marshal hierarchy for %d, %w", id, err)
-						return
-					}
+				enc_h, err := json.Marshal(h)
 
-					md5_h := fmt.Sprintf("%x", md5.Sum(enc_h))
-					hierarchies_hash_map.Store(md5_h, h)
+				if err != nil {
+					logger.Error("Failed to marshal hierarchy", "error", err)
+					return nil, fmt.Errorf("Failed to marshal hierarchy for %d, %w", id, err)
+				}
 
-					hier_mu.Lock()
+				md5_h := fmt.Sprintf("%x", md5.Sum(enc_h))
+				hierarchies_hash_map.Store(md5_h, h)
 
-					if !slices.Contains(hier_hashes, md5_h) {
-						hier_hashes = append(hier_hashes, md5_h)
-					}
+				hier_mu.Lock()
 
-					hier_mu.Unlock()
+				if !slices.Contains(hier_hashes, md5_h) {
+					hier_hashes = append(hier_hashes, md5_h)
 				}
 
-				pt, _, err := properties.Centroid(body)
+				hier_mu.Unlock()
+			}
 
-				if err != nil {
-					logger.Error("Failed to derive centroid", "error", err)
-					err_ch <- fmt.Errorf("Failed to derive centroid for %d, %w", id, err)
-					return
-				}
+			pt, _, err := properties.Centroid(body)
 
-				points[idx] = *pt
+			if err != nil {
+				logger.Error("Failed to derive centroid", "error", err)
+				return nil, fmt.Errorf("Failed to derive centroid for %d, %w", id, err)
 			}
 
-			mp := orb.MultiPoint(points)
-			alt_geom := geojson.NewGeometry(mp)
+			points[idx] = *pt
+		}
 
-			alt_props := map[string]any{
-				"wof:id":        depiction_id,
-				"wof:repo":      depiction_repo,
-				"src:alt_label": alt_label,
-				"src:geom":      src_geom,
-			}
+		if IsFlightRouteLabel(prop_label) {
+			route_points_map.Store(prop_label, points)
+		}
 
-			alt_props[prop_label] = r.Ids
+		mp := orb.MultiPoint(points)
+		alt_geom := geojson.NewGeometry(mp)
 
-			alt_feature := &alt.WhosOnFirstAltFeature{
-				Type:       "Feature",
-				Id:         depiction_id,
-				Properties: alt_props,
-				Geometry:   alt_geom,
-			}
+		alt_props := map[string]any{
+			"wof:id":        depiction_id,
+			"wof:repo":      depiction_repo,
+			"src:alt_label": alt_label,
+			"src:geom":      src_geom,
+		}
+
+		alt_props[prop_label] = r.Ids
 
-			logger.Debug("Return new alt feature")
-			alt_ch <- alt_feature
+		for k, v := range provenance_map[prop_label].Properties() {
+			alt_props[k] = v
+		}
 
-		}(ctx, r)
+		alt_feature := &alt.WhosOnFirstAltFeature{
+			Type:       "Feature",
+			Id:         depiction_id,
+			Properties: alt_props,
+			Geometry:   alt_geom,
+		}
+
+		logger.Debug("Return new alt feature")
+		return alt_feature, nil
+	})
+
+	if err != nil {
+		logger.Error("Alt file processing for referent failed", "error", err)
+		return nil, 0, nil, err
 	}
 
-	remaining := len(refs)
+	logger.Debug("Derived new alt features", "count", len(new_alt_features))
+
+	// Derive a great circle route alt file from flight route references, if present
 
-	for remaining > 0 {
+	route_points := make(map[string][]orb.Point)
+	route_ids := make(map[string][]int64)
 
-		select {
-		case <-ctx.Done():
-			break
-		case <-done_ch:
-			remaining -= 1
-		case err := <-err_ch:
-			logger.Error("Alt file processing for referent failed", "error", err)
-			return nil, err
-		case alt_f := <-alt_ch:
-			new_alt_features = append(new_alt_features, alt_f)
-			logger.Debug("Append new alt feature", "count", len(new_alt_features))
+	route_points_map.Range(func(k any, v any) bool {
+		route_points[k.(string)] = v.([]orb.Point)
+		return true
+	})
+
+	for _, r := range refs {
+
+		if IsFlightRouteLabel(r.Label) {
+			route_ids[r.Label] = r.Ids
 		}
 	}
 
+	route_feature, err := DeriveFlightRouteAltFeature(ctx, depiction_id, depiction_repo, src_geom, route_points, route_ids)
+
+	if err != nil {
+		logger.Error("Failed to derive flight route", "error", err)
+		return nil, 0, nil, fmt.Errorf("Failed to derive flight route, %w", err)
+	}
+
+	if route_feature != nil {
+		logger.Debug("Append flight route alt feature")
+		new_alt_features = append(new_alt_features, route_feature)
+	}
+
 	// START OF create/update alt files for references
 
 	logger.Debug("Create/update alt files for references")
@@ -358,23 +500,24 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 	// START OF assign/update georeference:depictions here
 
-	new_depicted := make([]map[string]any, 0)
+	new_depicted := make([]*GeoreferenceDepicted, 0)
 
-	updates_map.Range(func(k any, v any) bool {
+	for _, r := range refs {
 
-		label := k.(string)
-		ids := v.([]int64)
+		v, exists := updates_map.Load(r.Label)
 
-		// TO DO: Make this a properly typed thing
+		if !exists {
+			continue
+		}
 
-		d := map[string]any{
-			geo.RESERVED_GEOREFERENCE_LABEL: label,
-			geo.RESERVED_WOF_DEPICTS:        ids,
+		d := &GeoreferenceDepicted{
+			Label:   r.Label,
+			Depicts: v.([]int64),
 		}
 
+		d.SetProvenance(provenance_map[r.Label])
 		new_depicted = append(new_depicted, d)
-		return true
-	})
+	}
 
 	depiction_updates[fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)] = new_depicted
 
@@ -400,21 +543,21 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 	existing_alt, err := properties.AltGeometries(depiction_body)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to determine existing alt files, %w", err)
+		return nil, 0, nil, fmt.Errorf("Failed to determine existing alt files, %w", err)
 	}
 
 	// Determine whether there are any other alt files (not included in the set of new labels)
 
 	// First create a lookup table for alt files that need to be "removed"
 
-	to_remove := make(map[string]*Reference)
+	to_remove := make(map[string]bool)
 
 	for _, r := range refs {
 
 		// This should never really happen...
 		if len(r.Ids) == 0 {
 			alt_label := DeriveAltLabelFromReference(r)
-			to_remove[alt_label] = r
+			to_remove[alt_label] = true
 		}
 	}
 
@@ -430,36 +573,19 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 		logger.Debug("Compare existing alt file", "label", label, "ok lookup", ok_lookup, "ok remove", ok_remove)
 
-		if len(refs) == 0 && strings.HasPrefix(label, GEOREF_ALT_PREFIX) {
-			logger.Debug("Refs count is 0 and (alt) label is georef, flag alt file for removal", "label", label)
+		// Any georef alt file which is not part of the new set of references is
+		// no longer referenced by the depiction (including the case where there
+		// are no references at all) so flag it for removal.
+
+		if !ok_lookup && strings.HasPrefix(label, GEOREF_ALT_PREFIX) {
+			logger.Debug("(Alt) label is georef and not in the new set of references, flag alt file for removal", "label", label)
+			to_remove[label] = true
 			ok_remove = true
 		}
 
 		if !ok_lookup && !ok_remove {
-			logger.Debug("Append to fetch", "label", label)
-			to_fetch = append(to_fetch, label)
-		}
-	}
-
-	// Fetch any extra alt geometries, if necessary
-
-	if len(to_fetch) > 0 {
-
-		logger.Debug("Fetch additional alt features", "count", len(to_fetch))
-
-		done_ch := make(chan bool)
-		err_ch := make(chan error)
-		alt_ch := make(chan *alt.WhosOnFirstAltFeature)
-
-		for _, label := range to_fetch {
-
-			go func(label string) {
 
-				defer func() {
-					done_ch <- true
-				}()
-
-				logger.Debug("Fetch alt feature", "label", label)
+			if opts.PruneMissingAltFiles {
 
 				alt_uri_geom := &uri.AltGeom{
 					Source: label,
@@ -473,46 +599,76 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 				alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
 
 				if err != nil {
-					err_ch <- fmt.Errorf("Failed to derive rel path for alt file, %w", err)
-					return
+					return nil, 0, nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
 				}
 
-				r, err := depiction_reader.Read(ctx, alt_uri)
+				exists, err := depiction_reader.Exists(ctx, alt_uri)
 
 				if err != nil {
-					err_ch <- fmt.Errorf("Failed to read depiction alt file %s, %w", alt_uri, err)
-					return
+					return nil, 0, nil, fmt.Errorf("Failed to determine whether %s exists, %w", alt_uri, err)
 				}
 
-				defer r.Close()
+				if !exists {
+					logger.Warn("Alt file does not exist, prune label", "label", label)
+					continue
+				}
+			}
 
-				var f *alt.WhosOnFirstAltFeature
+			logger.Debug("Append to fetch", "label", label)
+			to_fetch = append(to_fetch, label)
+		}
+	}
 
-				dec := json.NewDecoder(r)
-				err = dec.Decode(&f)
+	// Fetch any extra alt geometries, if necessary
 
-				if err != nil {
-					err_ch <- fmt.Errorf("Failed to decode depiction alt_file %s, %w", alt_uri, err)
-					return
-				}
+	if len(to_fetch) > 0 {
 
-				alt_ch <- f
+		logger.Debug("Fetch additional alt features", "count", len(to_fetch))
 
-			}(label)
+		fetched, err := fanout.Map(ctx, opts.Concurrency, to_fetch, func(ctx context.Context, label string) (*alt.WhosOnFirstAltFeature, error) {
 
-			remaining := len(to_fetch)
+			logger.Debug("Fetch alt feature", "label", label)
 
-			for remaining > 0 {
-				select {
-				case <-done_ch:
-					remaining -= 1
-				case err := <-err_ch:
-					return nil, err
-				case f := <-alt_ch:
-					other_alt_features = append(other_alt_features, f)
-				}
+			alt_uri_geom := &uri.AltGeom{
+				Source: label,
+			}
+
+			alt_uri_args := &uri.URIArgs{
+				IsAlternate: true,
+				AltGeom:     alt_uri_geom,
+			}
+
+			alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
+			}
+
+			r, err := depiction_reader.Read(ctx, alt_uri)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read depiction alt file %s, %w", alt_uri, err)
 			}
+
+			defer r.Close()
+
+			var f *alt.WhosOnFirstAltFeature
+
+			dec := json.NewDecoder(r)
+			err = dec.Decode(&f)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to decode depiction alt_file %s, %w", alt_uri, err)
+			}
+
+			return f, nil
+		})
+
+		if err != nil {
+			return nil, 0, nil, err
 		}
+
+		other_alt_features = append(other_alt_features, fetched...)
 	}
 
 	// Combine new and other alt features
@@ -558,14 +714,14 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 		if err != nil {
 			logger.Error("Failed to read default geometry record", "id", opts.DefaultGeometryFeatureId, "error", err)
-			return nil, fmt.Errorf("Failed to read default geometry record, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to read default geometry record, %w", err)
 		}
 
 		centroid, _, err := properties.Centroid(body)
 
 		if err != nil {
 			logger.Error("Failed to derive centroid for default geometry record", "id", opts.DefaultGeometryFeatureId, "error", err)
-			return nil, fmt.Errorf("Failed to unmarshal default geometry record, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to unmarshal default geometry record, %w", err)
 		}
 
 		depiction_geom = centroid
@@ -578,7 +734,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 		if err != nil {
 			logger.Error("Failed to marshal hierarchy", "error", err)
-			return nil, fmt.Errorf("Failed to marshal hierarchy for default feature, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to marshal hierarchy for default feature, %w", err)
 		}
 
 		md5_h := fmt.Sprintf("%x", md5.Sum(enc_h))
@@ -586,11 +742,25 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 	} else {
 
-		mp_geom, err := alt.DeriveMultiPointGeometry(ctx, alt_features...)
+		// The flight route is a LineString tracing the other references so
+		// exclude it when deriving the MultiPoint geometry for the depiction
+
+		point_features := make([]*alt.WhosOnFirstAltFeature, 0)
+
+		for _, f := range alt_features {
+
+			if f.Properties["src:alt_label"].(string) == FLIGHT_ROUTE_ALT_LABEL {
+				continue
+			}
+
+			point_features = append(point_features, f)
+		}
+
+		mp_geom, err := alt.DeriveMultiPointGeometry(ctx, point_features...)
 
 		if err != nil {
 			logger.Error("Failed to derive multi point geometry from alt files", "error", err)
-			return nil, fmt.Errorf("Failed to derive multi point geometry, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to derive multi point geometry, %w", err)
 		}
 
 		depiction_geom = mp_geom
@@ -621,7 +791,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
 		}
 
 		logger.Debug("Save alt feature", "uri", alt_uri)
@@ -629,7 +799,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		enc_f, err := alt.FormatAltFeature(f)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to format %s, %w", alt_uri, err)
+			return nil, 0, nil, fmt.Errorf("Failed to format %s, %w", alt_uri, err)
 		}
 
 		r := bytes.NewReader(enc_f)
@@ -640,7 +810,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		_, err = writers.DepictionWriter.Write(ctx, alt_uri, r)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to write new alt feature %s, %w", alt_uri, err)
+			return nil, 0, nil, fmt.Errorf("Failed to write new alt feature %s, %w", alt_uri, err)
 		}
 	}
 
@@ -648,12 +818,12 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 	logger.Debug("Rewrite alt files to \"remove\" (deprecate)", "count", len(to_remove))
 
-	for _, ref := range to_remove {
+	for alt_label, _ := range to_remove {
 
-		logger.Debug("Remove alt file", "label", ref.AltLabel)
+		logger.Debug("Remove alt file", "label", alt_label)
 
 		alt_uri_geom := &uri.AltGeom{
-			Source: ref.AltLabel,
+			Source: alt_label,
 		}
 
 		alt_uri_args := &uri.URIArgs{
@@ -664,57 +834,36 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
 		}
 
-		logger.Debug("Deprecate alt file", "label", alt_uri)
-
-		// In advance of a generic "exists" method/package this will have to do...
-
-		_, err = depiction_reader.Read(ctx, alt_uri)
+		exists, err := depiction_reader.Exists(ctx, alt_uri)
 
 		if err != nil {
-			continue
+			return nil, 0, nil, fmt.Errorf("Failed to determine whether %s exists, %w", alt_uri, err)
 		}
 
-		now := time.Now()
-		deprecated := now.Format("2006-01-02")
-
-		alt_props := map[string]any{
-			"edtf:deprecated": deprecated,
-			"src:alt_label":   ref.AltLabel,
-			"src:geom":        "sfomuseum#georeference",
-			"wof:id":          depiction_id,
-			"wof:repo":        "sfomuseum-data-media-collection",
+		if !exists {
+			logger.Debug("Alt file does not exist, nothing to deprecate", "uri", alt_uri)
+			continue
 		}
 
-		pt := orb.Point{0.0, 0.0}
-		alt_geom := geojson.NewGeometry(pt)
-
-		alt_f := &alt.WhosOnFirstAltFeature{
-			Type:       "Feature",
-			Id:         depiction_id,
-			Properties: alt_props,
-			Geometry:   alt_geom,
-		}
+		logger.Debug("Deprecate alt file", "uri", alt_uri)
 
-		enc_f, err := alt.FormatAltFeature(alt_f)
+		new_alt_body, err := alt.DeprecateAltFeature(ctx, depiction_reader, depiction_id, alt_label)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to format %s, %w", alt_uri, err)
+			return nil, 0, nil, fmt.Errorf("Failed to deprecate alt feature %s, %w", alt_uri, err)
 		}
 
-		r := bytes.NewReader(enc_f)
+		// Note how we're invoking DepictionWriter directly (rather than DepictionMultiWriter)
+		// because this is an alt file.
 
-		// Note how we're invoking depiction_wr directly because sfom_writer doesn't
-		// know how to work with alt files yet.
-
-		_, err = writers.DepictionMultiWriter.Write(ctx, alt_uri, r)
+		_, err = wof_writer.WriteBytes(ctx, writers.DepictionWriter, new_alt_body)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to write deprecate alt feature %s, %w", alt_uri, err)
+			return nil, 0, nil, fmt.Errorf("Failed to write deprecated alt feature %s, %w", alt_uri, err)
 		}
-
 	}
 
 	// END OF resolve alt files
@@ -730,7 +879,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 		v, exists := hierarchies_hash_map.Load(md5_h)
 
 		if !exists {
-			return nil, fmt.Errorf("Failed to load hashed hierarchy (%s) for %d", md5_h, depiction_id)
+			return nil, 0, nil, fmt.Errorf("Failed to load hashed hierarchy (%s) for %d", md5_h, depiction_id)
 		}
 
 		h := v.(map[string]int64)
@@ -745,7 +894,7 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 	if err != nil {
 		logger.Error("Failed to assign depiction properties", "error", err)
-		return nil, fmt.Errorf("Failed to assign depiction properties, %w", err)
+		return nil, 0, nil, fmt.Errorf("Failed to assign depiction properties, %w", err)
 	}
 
 	// Write changes
@@ -765,95 +914,96 @@ func AssignReferences(ctx context.Context, opts *AssignReferencesOptions, depict
 
 		if err != nil {
 			logger.Error("Failed to assign last mod properties for subject record", "error", err)
-			return nil, fmt.Errorf("Failed to assign last mod properties for subject record, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to assign last mod properties for subject record, %w", err)
 		}
 
 		_, err = wof_writer.WriteBytes(ctx, writers.DepictionMultiWriter, new_body)
 
 		if err != nil {
 			logger.Error("Failed to write depiction", "error", err)
-			return nil, fmt.Errorf("Failed to write depiction update, %w", err)
+			return nil, 0, nil, fmt.Errorf("Failed to write depiction update, %w", err)
 		}
 	}
 
 	// END OF update the depiction record
 
 	logger.Debug("Finished updating depiction")
-	logger.Debug("Start updating subject")
 
-	// START OF update the subject (parent) record
+	skip_item := &SkipListItem{
+		Geometry: depiction_geom,
+		Depicted: new_depicted,
+	}
 
-	subject_body, err := wof_reader.LoadBytes(ctx, opts.SubjectReader, subject_id)
+	if defer_subject {
+		logger.Debug("Defer updating subject")
+	} else {
 
-	if err != nil {
-		return nil, fmt.Errorf("Failed to load subject (parent) for depiction, %w", err)
-	}
+		logger.Debug("Start updating subject")
 
-	// START OF denormalize all the georeferenced properties from all the images (depictions) in to the object record
+		// START OF update the subject (parent) record
 
-	logger.Debug("Update subject with depiction geom", "geom", depiction_geom)
+		subject_body, err := wof_reader.LoadBytes(ctx, opts.SubjectReader, subject_id)
 
-	recompile_opts := &RecompileGeorefencesForSubjectOptions{
-		DepictionReader:   depiction_reader,
-		SFOMuseumReader:   opts.SFOMuseumReader,
-		WhosOnFirstReader: opts.WhosOnFirstReader,
-		SkipList: map[int64]*SkipListItem{
-			depiction_id: &SkipListItem{
-				Geometry: depiction_geom,
-				Depicted: new_depicted,
-			},
-		},
-	}
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("Failed to load subject (parent) for depiction, %w", err)
+		}
 
-	subject_has_changed, subject_body, err := RecompileGeorefencesForSubject(ctx, recompile_opts, subject_body)
+		// START OF denormalize all the georeferenced properties from all the images (depictions) in to the object record
 
-	if err != nil {
-		logger.Error("Failed to recompile georeferences for subject", "error", err)
-		return nil, fmt.Errorf("Failed to recompile georeferences for subject, %w", err)
-	}
+		logger.Debug("Update subject with depiction geom", "geom", depiction_geom)
 
-	if subject_has_changed {
+		recompile_opts := &RecompileGeorefencesForSubjectOptions{
+			DepictionReader:   depiction_reader,
+			SFOMuseumReader:   opts.SFOMuseumReader,
+			WhosOnFirstReader: opts.WhosOnFirstReader,
+			SkipList: map[int64]*SkipListItem{
+				depiction_id: skip_item,
+			},
+			Membership:  opts.SubjectMembership,
+			Concurrency: opts.Concurrency,
+		}
 
-		_, err = wof_writer.WriteBytes(ctx, writers.SubjectMultiWriter, subject_body)
+		subject_has_changed, subject_body, err := RecompileGeorefencesForSubject(ctx, recompile_opts, subject_body)
 
 		if err != nil {
-			logger.Error("Failed to write subject record", "error", err)
-			return nil, fmt.Errorf("Failed to write subject update, %w", err)
+			logger.Error("Failed to recompile georeferences for subject", "error", err)
+			return nil, 0, nil, fmt.Errorf("Failed to recompile georeferences for subject, %w", err)
 		}
-	}
-
-	// END OF update the subject (parent) record
 
-	// Close the depiction and subject writers - this is a no-op for many writer but
-	// required for things like the githubapi-tree:// and githubapi-pr:// writers.
+		if subject_has_changed {
 
-	err = writers.DepictionMultiWriter.Close(ctx)
+			_, err = wof_writer.WriteBytes(ctx, writers.SubjectMultiWriter, subject_body)
 
-	if err != nil {
-		logger.Error("Failed to close depiction writer", "error", err)
-		return nil, fmt.Errorf("Failed to close depiction writer, %w", err)
+			if err != nil {
+				logger.Error("Failed to write subject record", "error", err)
+				return nil, 0, nil, fmt.Errorf("Failed to write subject update, %w", err)
+			}
+		}
 	}
 
-	err = writers.SubjectMultiWriter.Close(ctx)
-
-	if err != nil {
-		logger.Error("Failed to close subject writer", "error", err)
-		return nil, fmt.Errorf("Failed to close subject writer, %w", err)
-	}
+	// END OF update the subject (parent) record
 
-	// Now write the subject (object) being depicted
+	// Commit all the staged writes and close the depiction and subject writers. Nothing is
+	// persisted until this point. Closing is a no-op for many writer but required for things
+	// like the githubapi-tree:// and githubapi-pr:// writers.
 
-	fc, err := writers.AsFeatureCollection()
+	err = writers.Commit(ctx)
 
 	if err != nil {
-		return nil, err
+		logger.Error("Failed to commit writes", "error", err)
+		return nil, 0, nil, fmt.Errorf("Failed to commit writes, %w", err)
 	}
 
-	fc_body, err := fc.MarshalJSON()
+	// The result includes every alt file written, including any deprecated alt files, so
+	// that consumers can distinguish retired georeferences from current ones.
+
+	r, err := writers.Result(ctx)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to marshal feature collection, %w", err)
+		return nil, 0, nil, fmt.Errorf("Failed to derive update result, %w", err)
 	}
 
-	return fc_body, nil
+	AppendValidations(r, validations...)
+
+	return r, subject_id, skip_item, nil
 }
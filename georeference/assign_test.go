@@ -1,9 +1,499 @@
 package georeference
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/github/githubtest"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader/v2"
 )
 
+// setupAssignReferencesOptions returns a new `AssignReferencesOptions` instance whose depiction and subject
+// readers and writers point to copies of the fixtures data in a temporary directory.
+func setupAssignReferencesOptions(t *testing.T) *AssignReferencesOptions {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../fixtures")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	tmp_dir := t.TempDir()
+
+	for _, repo := range []string{"sfomuseum-data-media-collection", "sfomuseum-data-collection"} {
+
+		err := os.CopyFS(filepath.Join(tmp_dir, repo), os.DirFS(filepath.Join(path_fixtures, repo)))
+
+		if err != nil {
+			t.Fatalf("Failed to copy %s fixtures, %v", repo, err)
+		}
+	}
+
+	depiction_uri := fmt.Sprintf("repo://%s/sfomuseum-data-media-collection", tmp_dir)
+	subject_uri := fmt.Sprintf("repo://%s/sfomuseum-data-collection", tmp_dir)
+	whosonfirst_uri := fmt.Sprintf("repo://%s/whosonfirst-data-admin", path_fixtures)
+	architecture_uri := fmt.Sprintf("repo://%s/sfomuseum-data-architecture", path_fixtures)
+
+	depiction_reader, err := reader.NewReader(ctx, depiction_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create depiction reader, %v", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, subject_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create subject reader, %v", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, whosonfirst_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	sfomuseum_reader, err := reader.NewMultiReaderFromURIs(ctx, depiction_uri, subject_uri, architecture_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create sfomuseum reader, %v", err)
+	}
+
+	opts := &AssignReferencesOptions{
+		DepictionReader:          depiction_reader,
+		SubjectReader:            subject_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		DefaultGeometryFeatureId: 1159396131,
+		DepictionWriterURI:       depiction_uri,
+		SubjectWriterURI:         subject_uri,
+	}
+
+	return opts
+}
+
 func TestAssignReferences(t *testing.T) {
-	t.Skip()
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1897903961)
+
+	refs := []*Reference{
+		&Reference{
+			Label: "sfomuseum:depicts",
+			Ids:   []int64{102025263},
+		},
+	}
+
+	r, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	if len(r.Records) != 3 || r.Records[0].Role != plan.SubjectRole || r.Records[1].Role != plan.DepictionRole || r.Records[1].Id != depiction_id {
+		t.Fatalf("Expected subject, depiction and alt records, %v", r.Records)
+	}
+
+	if r.Records[2].Role != plan.AltRole || r.Records[2].AltLabel != "georef_sfomuseum_depicts" {
+		t.Fatalf("Expected georef alt record, %v", r.Records[2])
+	}
+
+	if !r.Records[1].Changed {
+		t.Fatalf("Expected depiction record to be changed")
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	depicted_rsp := gjson.GetBytes(body, "features.1.properties.georef:depicted")
+
+	if len(depicted_rsp.Array()) != 1 {
+		t.Fatalf("Expected 1 georef:depicted entry, got %s", depicted_rsp.String())
+	}
+
+	subject_depicted_rsp := gjson.GetBytes(body, "features.0.properties.georef:depicted.sfomuseum:depicts")
+
+	if len(subject_depicted_rsp.Array()) != 1 {
+		t.Fatalf("Expected subject georef:depicted to contain sfomuseum:depicts, got %s", subject_depicted_rsp.String())
+	}
+}
+
+func TestAssignReferencesDryRun(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+	opts.DryRun = true
+
+	depiction_id := int64(1897903961)
+	depiction_path := "189/790/396/1/1897903961.geojson"
+
+	before, err := readTestFile(ctx, opts.DepictionReader, depiction_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	refs := []*Reference{
+		&Reference{
+			Label: "sfomuseum:depicts",
+			Ids:   []int64{102025263},
+		},
+	}
+
+	r, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	var p *plan.Plan
+
+	err = json.Unmarshal(body, &p)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal plan, %v", err)
+	}
+
+	roles := make(map[plan.Role]*plan.Change)
+
+	for _, c := range p.Changes {
+		roles[c.Role] = c
+	}
+
+	for _, r := range []plan.Role{plan.DepictionRole, plan.SubjectRole, plan.AltRole} {
+
+		if _, ok := roles[r]; !ok {
+			t.Fatalf("Expected plan to contain a %s change, %s", r, string(body))
+		}
+	}
+
+	if roles[plan.DepictionRole].Id != depiction_id || !roles[plan.DepictionRole].Changed() {
+		t.Fatalf("Unexpected depiction change, %s", string(body))
+	}
+
+	if roles[plan.AltRole].Action != plan.CreateAction || roles[plan.AltRole].AltLabel != "georef_sfomuseum_depicts" {
+		t.Fatalf("Unexpected alt change, %s", string(body))
+	}
+
+	after, err := readTestFile(ctx, opts.DepictionReader, depiction_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	if !bytes.Equal(before, after) {
+		t.Fatalf("Dry run modified depiction record")
+	}
+
+	exists, err := opts.DepictionReader.Exists(ctx, "189/790/396/1/1897903961-alt-georef_sfomuseum_depicts.geojson")
+
+	if err != nil {
+		t.Fatalf("Failed to determine whether alt file exists, %v", err)
+	}
+
+	if exists {
+		t.Fatalf("Dry run created alt file")
+	}
+}
+
+func TestAssignReferencesGitHubChangeSet(t *testing.T) {
+
+	ctx := context.Background()
+
+	depiction_repo := "sfomuseum-data/sfomuseum-data-media-collection"
+	subject_repo := "sfomuseum-data/sfomuseum-data-collection"
+
+	server := githubtest.NewServer(depiction_repo, subject_repo)
+	defer server.Close()
+
+	opts := setupAssignReferencesOptions(t)
+	opts.Author = "alice"
+	opts.GitHubAPIURL = server.URL
+	opts.DepictionWriterURI = fmt.Sprintf("githubapi-pr://%s?access_token=s33kret&prefix=data", depiction_repo)
+	opts.SubjectWriterURI = fmt.Sprintf("githubapi-pr://%s?access_token=s33kret&prefix=data", subject_repo)
+
+	opts.GitHubTemplates = &github.Templates{
+		PullRequestTitle: `[{{ .Author }}] {{ join .Labels ", " }} for {{ .SubjectId }} ({{ join .Places ", " }})`,
+		Branch:           "georef/{{ .SubjectId }}/{{ .WhosOnFirstId }}",
+	}
+
+	depiction_id := int64(1897903961)
+
+	refs := []*Reference{
+		&Reference{
+			Label: "sfomuseum:depicts",
+			Ids:   []int64{102025263},
+		},
+	}
+
+	_, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	expected := map[string][]string{
+		depiction_repo: []string{
+			"data/189/790/396/1/1897903961.geojson",
+			"data/189/790/396/1/1897903961-alt-georef_sfomuseum_depicts.geojson",
+		},
+		subject_repo: []string{
+			"data/189/790/247/1/1897902471.geojson",
+		},
+	}
+
+	var branch string
+
+	for name, paths := range expected {
+
+		repo := server.Repo(name)
+
+		if len(repo.PullRequests) != 1 {
+			t.Fatalf("Expected 1 pull request in %s, got %d", name, len(repo.PullRequests))
+		}
+
+		pr := repo.PullRequests[0]
+
+		if pr.Title != "[alice] sfomuseum:depicts for 1897902471 (Bangkok)" {
+			t.Fatalf("Unexpected pull request title in %s, %s", name, pr.Title)
+		}
+
+		if pr.Head != "georef/1897902471/1897903961" {
+			t.Fatalf("Unexpected pull request branch in %s, %s", name, pr.Head)
+		}
+
+		if branch == "" {
+			branch = pr.Head
+		} else if pr.Head != branch {
+			t.Fatalf("Expected pull requests to share branch %s, got %s", branch, pr.Head)
+		}
+
+		if !strings.Contains(pr.Body, "Related pull requests") {
+			t.Fatalf("Expected pull request in %s to be cross-linked, %s", name, pr.Body)
+		}
+
+		files, err := server.Files(name, pr.Head)
+
+		if err != nil {
+			t.Fatalf("Failed to retrieve files for %s, %v", name, err)
+		}
+
+		lookup := make(map[string]bool)
+
+		for _, f := range files {
+			lookup[f.Path] = true
+		}
+
+		for _, path := range paths {
+
+			if !lookup[path] {
+				t.Fatalf("Expected %s to be written to %s", path, name)
+			}
+		}
+	}
+}
+
+func readTestFile(ctx context.Context, r reader.Reader, path string) ([]byte, error) {
+
+	fh, err := r.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fh.Close()
+
+	return io.ReadAll(fh)
+}
+
+func TestAssignReferencesReaderCache(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+	opts.DryRun = true
+
+	cache_root := t.TempDir()
+	opts.ReaderCacheURI = fmt.Sprintf("disk://%s", cache_root)
+
+	refs := []*Reference{
+		&Reference{
+			Label: "sfomuseum:depicts",
+			Ids:   []int64{102025263},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+
+		r, err := AssignReferences(ctx, opts, 1897903961, refs...)
+
+		if err != nil {
+			t.Fatalf("Failed to assign references with reader cache, %v", err)
+		}
+
+		body, err := r.Response()
+
+		if err != nil {
+			t.Fatalf("Failed to derive response, %v", err)
+		}
+
+		if len(gjson.GetBytes(body, "changes").Array()) == 0 {
+			t.Fatalf("Expected plan to contain changes, %s", string(body))
+		}
+	}
+
+	count := 0
+
+	err := filepath.WalkDir(cache_root, func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			count += 1
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to walk cache, %v", err)
+	}
+
+	if count == 0 {
+		t.Fatalf("Expected records to be cached")
+	}
+
+	opts.ReaderCacheURI = "bogus://"
+
+	_, err = AssignReferences(ctx, opts, 1897903961, refs...)
+
+	if err == nil {
+		t.Fatalf("Expected invalid reader cache URI to fail")
+	}
+}
+
+func TestAssignReferencesOtherAltFiles(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1897903961)
+	depiction_root := filepath.Join(strings.TrimPrefix(opts.DepictionWriterURI, "repo://"), "data/189/790/396/1")
+	depiction_path := filepath.Join(depiction_root, "1897903961.geojson")
+
+	// More than one existing (non-georef) alt file needs to be fetched. This used to deadlock.
+
+	labels := []string{"geotag-fov", "sfomuseum-example"}
+
+	for _, label := range labels {
+
+		alt_body := fmt.Sprintf(`{"type":"Feature","id":%d,"properties":{"wof:id":%d,"src:alt_label":"%s"},"geometry":{"type":"Point","coordinates":[100.5,13.75]}}`, depiction_id, depiction_id, label)
+		alt_path := filepath.Join(depiction_root, fmt.Sprintf("1897903961-alt-%s.geojson", label))
+
+		err := os.WriteFile(alt_path, []byte(alt_body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write alt file, %v", err)
+		}
+	}
+
+	depiction_body, err := os.ReadFile(depiction_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	depiction_body, err = sjson.SetBytes(depiction_body, "properties.src:geom_alt", labels)
+
+	if err != nil {
+		t.Fatalf("Failed to update depiction, %v", err)
+	}
+
+	err = os.WriteFile(depiction_path, depiction_body, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write depiction, %v", err)
+	}
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+	}
+
+	_, err = AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	depiction_body, err = os.ReadFile(depiction_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	geom_alt := gjson.GetBytes(depiction_body, "properties.src:geom_alt").Array()
+
+	if len(geom_alt) != 3 {
+		t.Fatalf("Expected 3 alt geometries, got %v", geom_alt)
+	}
+}
+
+func TestAssignReferencesFailure(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+	opts.Concurrency = 2
+
+	baseline := runtime.NumGoroutine()
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+		&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}},
+		&Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{102025263}},
+		&Reference{Label: "sfomuseum:example", Ids: []int64{}},
+	}
+
+	_, err := AssignReferences(ctx, opts, 1897903961, refs...)
+
+	if err == nil || !strings.Contains(err.Error(), "Ref is missing IDs") {
+		t.Fatalf("Expected reference without IDs to fail, %v", err)
+	}
+
+	count := runtime.NumGoroutine()
+
+	for i := 0; i < 100 && count > baseline; i++ {
+		time.Sleep(10 * time.Millisecond)
+		count = runtime.NumGoroutine()
+	}
+
+	if count > baseline {
+		t.Fatalf("Expected %d goroutines after failure, got %d", baseline, count)
+	}
 }
@@ -0,0 +1,31 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/cache"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+// cacheReaders wraps 'whosonfirst_reader' and 'sfomuseum_reader' in `cache.CachingReader` instances sharing a new `cache.Cache`
+// instance derived from 'uri'. It also returns a function which logs the cache hits and misses for both readers. Depiction and
+// subject readers are never cached since those records are updated as references are assigned.
+func cacheReaders(ctx context.Context, uri string, whosonfirst_reader reader.Reader, sfomuseum_reader reader.Reader) (reader.Reader, reader.Reader, func(), error) {
+
+	c, err := cache.NewCache(ctx, uri)
+
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to create reader cache, %w", err)
+	}
+
+	cached_whosonfirst := cache.NewCachingReader(whosonfirst_reader, c, "whosonfirst")
+	cached_sfomuseum := cache.NewCachingReader(sfomuseum_reader, c, "sfomuseum")
+
+	log_stats := func() {
+		cached_whosonfirst.LogStats(ctx)
+		cached_sfomuseum.LogStats(ctx)
+	}
+
+	return cached_whosonfirst, cached_sfomuseum, log_stats, nil
+}
@@ -0,0 +1,432 @@
+package georeference
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/tidwall/gjson"
+)
+
+// GeoreferenceDepicted defines a single labeled georeference as stored in the `georef:depicted` property of a depiction.
+type GeoreferenceDepicted struct {
+	// Label is the string label for the class of georeference (for example "sfomuseum:depicts").
+	Label string `json:"georef:label"`
+	// Depicts is the list of Who's On First IDs being referenced for 'Label'.
+	Depicts []int64 `json:"wof:depicts"`
+	// Author is the name of the person (or process) who asserted the georeference.
+	Author string `json:"georef:author,omitempty"`
+	// Created is the Unix timestamp when the georeference was asserted.
+	Created int64 `json:"georef:created,omitempty"`
+	// Citation is an optional reference to the source or evidence for the georeference.
+	Citation string `json:"georef:citation,omitempty"`
+	// Note is an optional free-form note about the georeference.
+	Note string `json:"georef:note,omitempty"`
+	// Confidence is an optional value between 0.0 and 1.0 indicating how confident the author is in the georeference.
+	Confidence *float64 `json:"georef:confidence,omitempty"`
+}
+
+// Provenance returns the `Provenance` for 'd' or nil if no provenance has been recorded.
+func (d *GeoreferenceDepicted) Provenance() *Provenance {
+
+	p := &Provenance{
+		Author:     d.Author,
+		Created:    d.Created,
+		Citation:   d.Citation,
+		Note:       d.Note,
+		Confidence: d.Confidence,
+	}
+
+	if p.IsZero() {
+		return nil
+	}
+
+	return p
+}
+
+// SetProvenance assigns the properties of 'p' to 'd'. If 'p' is nil any existing provenance is removed.
+func (d *GeoreferenceDepicted) SetProvenance(p *Provenance) {
+
+	if p == nil {
+		p = &Provenance{}
+	}
+
+	d.Author = p.Author
+	d.Created = p.Created
+	d.Citation = p.Citation
+	d.Note = p.Note
+	d.Confidence = p.Confidence
+}
+
+// UnmarshalJSON decodes 'body' in to 'd' ensuring that a non-empty label is present.
+func (d *GeoreferenceDepicted) UnmarshalJSON(body []byte) error {
+
+	// Use an alias type so that we don't recurse back in to this method
+
+	type depicted GeoreferenceDepicted
+
+	var tmp depicted
+
+	err := json.Unmarshal(body, &tmp)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal georeference depicted, %w", err)
+	}
+
+	if tmp.Label == "" {
+		return fmt.Errorf("Georeference depicted is missing %s property", geo.RESERVED_GEOREFERENCE_LABEL)
+	}
+
+	if tmp.Depicts == nil {
+		tmp.Depicts = make([]int64, 0)
+	}
+
+	new_d := GeoreferenceDepicted(tmp)
+	p := new_d.Provenance()
+
+	if p != nil {
+
+		err := p.Validate()
+
+		if err != nil {
+			return fmt.Errorf("Georeference depicted has invalid provenance, %w", err)
+		}
+	}
+
+	*d = new_d
+	return nil
+}
+
+// String returns a string representation of 'd'.
+func (d *GeoreferenceDepicted) String() string {
+	return fmt.Sprintf("%s: %v", d.Label, d.Depicts)
+}
+
+// DepictionGeoreferences defines the georeferencing properties stored in a depiction (image) record.
+type DepictionGeoreferences struct {
+	// Depicted is the list of labeled georeferences for the depiction.
+	Depicted []*GeoreferenceDepicted `json:"georef:depicted"`
+	// BelongsTo is the unique set of Who's On First IDs (and their ancestors) referenced by the depiction.
+	BelongsTo []int64 `json:"georef:whosonfirst_belongsto"`
+}
+
+// SubjectGeoreferences defines the georeferencing properties stored in a subject (object) record.
+type SubjectGeoreferences struct {
+	// Depicted is a dictionary of Who's On First IDs keyed by georeference label for all the depictions of the subject.
+	Depicted map[string][]int64 `json:"georef:depicted"`
+	// Depictions is the list of georeferenced depiction IDs for the subject.
+	Depictions []int64 `json:"georef:depictions"`
+	// BelongsTo is the unique set of Who's On First IDs (and their ancestors) referenced by all the depictions of the subject.
+	BelongsTo []int64 `json:"georef:whosonfirst_belongsto"`
+	// GeotagDepictions is the list of geotagged depiction IDs for the subject.
+	GeotagDepictions []int64 `json:"geotag:depictions"`
+}
+
+// LoadDepictionGeoreferences returns a `DepictionGeoreferences` instance derived from the properties in 'body'.
+func LoadDepictionGeoreferences(body []byte) (*DepictionGeoreferences, error) {
+
+	depicted, err := LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	belongsto, err := LoadGeoreferenceBelongsTo(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &DepictionGeoreferences{
+		Depicted:  depicted,
+		BelongsTo: belongsto,
+	}
+
+	return refs, nil
+}
+
+// LoadSubjectGeoreferences returns a `SubjectGeoreferences` instance derived from the properties in 'body'.
+func LoadSubjectGeoreferences(body []byte) (*SubjectGeoreferences, error) {
+
+	depicted, err := LoadSubjectGeoreferenceDepicted(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	depictions, err := LoadGeoreferenceDepictions(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	belongsto, err := LoadGeoreferenceBelongsTo(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	geotag_depictions, err := LoadGeotagDepictions(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &SubjectGeoreferences{
+		Depicted:         depicted,
+		Depictions:       depictions,
+		BelongsTo:        belongsto,
+		GeotagDepictions: geotag_depictions,
+	}
+
+	return refs, nil
+}
+
+// LoadGeoreferenceDepicted returns the list of `GeoreferenceDepicted` instances stored in the `georef:depicted`
+// property of a depiction record. If the property is absent or null an empty list is returned.
+func LoadGeoreferenceDepicted(body []byte) ([]*GeoreferenceDepicted, error) {
+
+	path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)
+	rsp := gjson.GetBytes(body, path)
+
+	depicted := make([]*GeoreferenceDepicted, 0)
+
+	if !rsp.Exists() || rsp.Type == gjson.Null {
+		return depicted, nil
+	}
+
+	if !rsp.IsArray() {
+		return nil, fmt.Errorf("Invalid %s property, expected a list but got %s", geo.RESERVED_GEOREFERENCE_DEPICTED, rsp.Type)
+	}
+
+	for idx, r := range rsp.Array() {
+
+		if !r.IsObject() {
+			return nil, fmt.Errorf("Invalid %s property, item at offset %d is not a dictionary", geo.RESERVED_GEOREFERENCE_DEPICTED, idx)
+		}
+
+		label_rsp := r.Get(geo.RESERVED_GEOREFERENCE_LABEL)
+
+		if label_rsp.Type != gjson.String || label_rsp.String() == "" {
+			return nil, fmt.Errorf("Invalid %s property, item at offset %d has missing or invalid %s property", geo.RESERVED_GEOREFERENCE_DEPICTED, idx, geo.RESERVED_GEOREFERENCE_LABEL)
+		}
+
+		ids, err := int64sFromResult(r.Get(geo.RESERVED_WOF_DEPICTS))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s property, item at offset %d has invalid %s property, %w", geo.RESERVED_GEOREFERENCE_DEPICTED, idx, geo.RESERVED_WOF_DEPICTS, err)
+		}
+
+		d := &GeoreferenceDepicted{
+			Label:   label_rsp.String(),
+			Depicts: ids,
+		}
+
+		p, err := provenanceFromResult(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s property, item at offset %d has invalid provenance, %w", geo.RESERVED_GEOREFERENCE_DEPICTED, idx, err)
+		}
+
+		d.SetProvenance(p)
+
+		depicted = append(depicted, d)
+	}
+
+	return depicted, nil
+}
+
+// LoadSubjectGeoreferenceDepicted returns the dictionary of Who's On First IDs, keyed by georeference label, stored
+// in the `georef:depicted` property of a subject record. If the property is absent or null an empty dictionary is returned.
+func LoadSubjectGeoreferenceDepicted(body []byte) (map[string][]int64, error) {
+
+	path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)
+	rsp := gjson.GetBytes(body, path)
+
+	depicted := make(map[string][]int64)
+
+	if !rsp.Exists() || rsp.Type == gjson.Null {
+		return depicted, nil
+	}
+
+	if !rsp.IsObject() {
+		return nil, fmt.Errorf("Invalid %s property, expected a dictionary but got %s", geo.RESERVED_GEOREFERENCE_DEPICTED, rsp.Type)
+	}
+
+	for k, v := range rsp.Map() {
+
+		ids, err := int64sFromResult(v)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s property, label '%s' has invalid IDs, %w", geo.RESERVED_GEOREFERENCE_DEPICTED, k, err)
+		}
+
+		depicted[k] = ids
+	}
+
+	return depicted, nil
+}
+
+// LoadGeoreferenceDepictions returns the list of depiction IDs stored in the `georef:depictions` property of a
+// subject record. If the property is absent an empty list is returned.
+func LoadGeoreferenceDepictions(body []byte) ([]int64, error) {
+
+	path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTIONS)
+	rsp := gjson.GetBytes(body, path)
+
+	ids, err := int64sFromResult(rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s property, %w", geo.RESERVED_GEOREFERENCE_DEPICTIONS, err)
+	}
+
+	return ids, nil
+}
+
+// LoadGeoreferenceBelongsTo returns the list of Who's On First IDs stored in the `georef:whosonfirst_belongsto`
+// property of a depiction or subject record. If the property is absent an empty list is returned.
+func LoadGeoreferenceBelongsTo(body []byte) ([]int64, error) {
+
+	path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_BELONGSTO)
+	rsp := gjson.GetBytes(body, path)
+
+	ids, err := int64sFromResult(rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s property, %w", geo.RESERVED_GEOREFERENCE_BELONGSTO, err)
+	}
+
+	return ids, nil
+}
+
+// LoadGeotagDepictions returns the list of depiction IDs stored in the `geotag:depictions` property of a subject
+// record. If the property is absent an empty list is returned.
+func LoadGeotagDepictions(body []byte) ([]int64, error) {
+
+	path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOTAG_SUBJECT_DEPICTIONS)
+	rsp := gjson.GetBytes(body, path)
+
+	ids, err := int64sFromResult(rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s property, %w", geo.RESERVED_GEOTAG_SUBJECT_DEPICTIONS, err)
+	}
+
+	return ids, nil
+}
+
+// IsSubjectRecord returns a boolean value indicating whether 'body' is a subject (object) record. A record is considered
+// to be a subject if it has a (non-null) `georef:depictions` or `geotag:depictions` property or its `georef:depicted`
+// property is a dictionary.
+func IsSubjectRecord(body []byte) bool {
+
+	for _, k := range []string{geo.RESERVED_GEOREFERENCE_DEPICTIONS, geo.RESERVED_GEOTAG_SUBJECT_DEPICTIONS} {
+
+		rsp := gjson.GetBytes(body, fmt.Sprintf("properties.%s", k))
+
+		if rsp.Exists() && rsp.Type != gjson.Null {
+			return true
+		}
+	}
+
+	return gjson.GetBytes(body, fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)).IsObject()
+}
+
+// provenanceFromResult returns the `Provenance` derived from the (optional) provenance properties in 'rsp', which is
+// expected to be a single `georef:depicted` entry. If none of the properties are present nil is returned.
+func provenanceFromResult(rsp gjson.Result) (*Provenance, error) {
+
+	p := new(Provenance)
+
+	for _, k := range []string{geo.RESERVED_GEOREFERENCE_AUTHOR, geo.RESERVED_GEOREFERENCE_CITATION, geo.RESERVED_GEOREFERENCE_NOTE} {
+
+		v := rsp.Get(k)
+
+		if !v.Exists() {
+			continue
+		}
+
+		if v.Type != gjson.String {
+			return nil, fmt.Errorf("%s property is not a string (%s)", k, v.Type)
+		}
+
+		switch k {
+		case geo.RESERVED_GEOREFERENCE_AUTHOR:
+			p.Author = v.String()
+		case geo.RESERVED_GEOREFERENCE_CITATION:
+			p.Citation = v.String()
+		case geo.RESERVED_GEOREFERENCE_NOTE:
+			p.Note = v.String()
+		}
+	}
+
+	created_rsp := rsp.Get(geo.RESERVED_GEOREFERENCE_CREATED)
+
+	if created_rsp.Exists() {
+
+		if created_rsp.Type != gjson.Number || float64(created_rsp.Int()) != created_rsp.Float() {
+			return nil, fmt.Errorf("%s property is not an integer", geo.RESERVED_GEOREFERENCE_CREATED)
+		}
+
+		p.Created = created_rsp.Int()
+	}
+
+	confidence_rsp := rsp.Get(geo.RESERVED_GEOREFERENCE_CONFIDENCE)
+
+	if confidence_rsp.Exists() {
+
+		if confidence_rsp.Type != gjson.Number {
+			return nil, fmt.Errorf("%s property is not a number (%s)", geo.RESERVED_GEOREFERENCE_CONFIDENCE, confidence_rsp.Type)
+		}
+
+		confidence := confidence_rsp.Float()
+		p.Confidence = &confidence
+	}
+
+	if p.IsZero() {
+		return nil, nil
+	}
+
+	err := p.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// int64sFromResult returns the unique list of integers contained in 'rsp' which is expected to be a list of numbers.
+// A missing or null result will return an empty list.
+func int64sFromResult(rsp gjson.Result) ([]int64, error) {
+
+	ids := make([]int64, 0)
+
+	if !rsp.Exists() || rsp.Type == gjson.Null {
+		return ids, nil
+	}
+
+	if !rsp.IsArray() {
+		return nil, fmt.Errorf("Expected a list but got %s", rsp.Type)
+	}
+
+	for idx, i := range rsp.Array() {
+
+		if i.Type != gjson.Number {
+			return nil, fmt.Errorf("Item at offset %d is not a number (%s)", idx, i.Type)
+		}
+
+		id := i.Int()
+
+		if float64(id) != i.Float() {
+			return nil, fmt.Errorf("Item at offset %d is not an integer", idx)
+		}
+
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
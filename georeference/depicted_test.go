@@ -0,0 +1,213 @@
+package georeference
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLoadGeoreferenceDepicted(t *testing.T) {
+
+	body := []byte(`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263]},{"georef:label":"sfomuseum:flightcover_to","wof:depicts":[890413117,101932003]}]}}`)
+
+	depicted, err := LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		t.Fatalf("Failed to load georeference depicted, %v", err)
+	}
+
+	if len(depicted) != 2 {
+		t.Fatalf("Expected 2 depicted entries, but got %d", len(depicted))
+	}
+
+	if depicted[1].Label != "sfomuseum:flightcover_to" {
+		t.Fatalf("Unexpected label for second entry, %s", depicted[1].Label)
+	}
+
+	if len(depicted[1].Depicts) != 2 {
+		t.Fatalf("Expected 2 IDs for second entry, but got %d", len(depicted[1].Depicts))
+	}
+
+	// Missing property
+
+	depicted, err = LoadGeoreferenceDepicted([]byte(`{"properties":{}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to load missing georeference depicted, %v", err)
+	}
+
+	if len(depicted) != 0 {
+		t.Fatalf("Expected 0 depicted entries, but got %d", len(depicted))
+	}
+
+	// Null property
+
+	depicted, err = LoadGeoreferenceDepicted([]byte(`{"properties":{"georef:depicted":null}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to load null georeference depicted, %v", err)
+	}
+
+	if len(depicted) != 0 {
+		t.Fatalf("Expected 0 depicted entries, but got %d", len(depicted))
+	}
+}
+
+func TestLoadGeoreferenceDepictedMalformed(t *testing.T) {
+
+	tests := []string{
+		`{"properties":{"georef:depicted":{"sfomuseum:depicts":[102025263]}}}`,
+		`{"properties":{"georef:depicted":["sfomuseum:depicts"]}}`,
+		`{"properties":{"georef:depicted":[{"wof:depicts":[102025263]}]}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":1234,"wof:depicts":[102025263]}]}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":102025263}]}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":["102025263"]}]}}`,
+	}
+
+	for _, str_body := range tests {
+
+		_, err := LoadGeoreferenceDepicted([]byte(str_body))
+
+		if err == nil {
+			t.Fatalf("Expected malformed record to fail: %s", str_body)
+		}
+	}
+}
+
+func TestLoadSubjectGeoreferences(t *testing.T) {
+
+	body := []byte(`{"properties":{"georef:depicted":{"sfomuseum:depicts":[102025263]},"georef:depictions":[1897903961],"georef:whosonfirst_belongsto":[102025263,85632293],"geotag:depictions":[1880245775]}}`)
+
+	refs, err := LoadSubjectGeoreferences(body)
+
+	if err != nil {
+		t.Fatalf("Failed to load subject georeferences, %v", err)
+	}
+
+	if len(refs.Depicted["sfomuseum:depicts"]) != 1 {
+		t.Fatalf("Unexpected depicted value, %v", refs.Depicted)
+	}
+
+	if len(refs.Depictions) != 1 || refs.Depictions[0] != 1897903961 {
+		t.Fatalf("Unexpected depictions value, %v", refs.Depictions)
+	}
+
+	if len(refs.BelongsTo) != 2 {
+		t.Fatalf("Unexpected belongs to value, %v", refs.BelongsTo)
+	}
+
+	if len(refs.GeotagDepictions) != 1 || refs.GeotagDepictions[0] != 1880245775 {
+		t.Fatalf("Unexpected geotag depictions value, %v", refs.GeotagDepictions)
+	}
+
+	refs, err = LoadSubjectGeoreferences([]byte(`{"properties":{"georef:depicted":null}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to load null subject georeferences, %v", err)
+	}
+
+	if len(refs.Depicted) != 0 {
+		t.Fatalf("Unexpected depicted value, %v", refs.Depicted)
+	}
+
+	for _, str_body := range []string{
+		`{"properties":{"georef:depictions":{"a":1}}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263]}]}}`,
+		`{"properties":{"geotag:depictions":"1880245775"}}`,
+	} {
+
+		_, err = LoadSubjectGeoreferences([]byte(str_body))
+
+		if err == nil {
+			t.Fatalf("Expected malformed record to fail: %s", str_body)
+		}
+	}
+}
+
+func TestIsSubjectRecord(t *testing.T) {
+
+	tests := map[string]bool{
+		`{"properties":{"georef:depictions":[1897903961]}}`:                                           true,
+		`{"properties":{"geotag:depictions":[1880245775]}}`:                                           true,
+		`{"properties":{"georef:depicted":{"sfomuseum:depicts":[102025263]}}}`:                        true,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[1]}]}}`: false,
+		`{"properties":{"georef:depictions":null,"georef:depicted":null}}`:                            false,
+		`{"properties":{}}`: false,
+	}
+
+	for str_body, expected := range tests {
+
+		if IsSubjectRecord([]byte(str_body)) != expected {
+			t.Fatalf("Expected IsSubjectRecord to return %t for %s", expected, str_body)
+		}
+	}
+}
+
+func TestGeoreferenceDepictedJSON(t *testing.T) {
+
+	d := &GeoreferenceDepicted{
+		Label:   "sfomuseum:depicts",
+		Depicts: []int64{102025263},
+	}
+
+	enc, err := json.Marshal(d)
+
+	if err != nil {
+		t.Fatalf("Failed to marshal depicted, %v", err)
+	}
+
+	expected := `{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263]}`
+
+	if string(enc) != expected {
+		t.Fatalf("Unexpected encoding, %s", string(enc))
+	}
+
+	var d2 *GeoreferenceDepicted
+
+	err = json.Unmarshal(enc, &d2)
+
+	if err != nil {
+		t.Fatalf("Failed to unmarshal depicted, %v", err)
+	}
+
+	if d2.Label != d.Label || len(d2.Depicts) != 1 || d2.Depicts[0] != d.Depicts[0] {
+		t.Fatalf("Unexpected round-trip result, %v", d2)
+	}
+
+	err = json.Unmarshal([]byte(`{"wof:depicts":[1]}`), &d2)
+
+	if err == nil {
+		t.Fatalf("Expected missing label to fail")
+	}
+}
+
+func TestLoadGeoreferenceDepictedProvenance(t *testing.T) {
+
+	body := []byte(`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263],"georef:author":"alice","georef:created":1700000000,"georef:confidence":0.5}]}}`)
+
+	depicted, err := LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		t.Fatalf("Failed to load georeference depicted, %v", err)
+	}
+
+	p := depicted[0].Provenance()
+
+	if p == nil || p.Author != "alice" || p.Created != 1700000000 || p.Confidence == nil || *p.Confidence != 0.5 {
+		t.Fatalf("Unexpected provenance, %v", p)
+	}
+
+	tests := []string{
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263],"georef:author":1234}]}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263],"georef:created":"yesterday"}]}}`,
+		`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263],"georef:confidence":1.5}]}}`,
+	}
+
+	for _, str_body := range tests {
+
+		_, err := LoadGeoreferenceDepicted([]byte(str_body))
+
+		if err == nil {
+			t.Fatalf("Expected invalid provenance to fail: %s", str_body)
+		}
+	}
+}
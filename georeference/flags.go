@@ -1,15 +1,30 @@
 package georeference
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/gazetteer"
 )
 
+// NameResolver is an interface for resolving place names to Who's On First IDs.
+type NameResolver interface {
+	// ResolveName returns the Who's On First ID for a place name (for example "Sydney, AU") or an error if the name can not be resolved unambiguously.
+	ResolveName(context.Context, string) (int64, error)
+}
+
 // MultiKeyValueStringsToReferences converts a list of `multi.KeyValueString` key-value pairs in to a list of `Reference` instances.
 func MultiKeyValueStringsToReferences(kv_references multi.KeyValueString) ([]*Reference, error) {
+	return MultiKeyValueStringsToReferencesWithResolver(context.Background(), kv_references, nil)
+}
+
+// MultiKeyValueStringsToReferencesWithResolver converts a list of `multi.KeyValueString` key-value pairs in to a list of `Reference`
+// instances. Values are expected to be a comma-separated list of Who's On First IDs or a place name prefixed by "name:" (for example
+// "name:Bangkok" or "name:Sydney, AU") which will be resolved to a Who's On First ID using 'resolver'.
+func MultiKeyValueStringsToReferencesWithResolver(ctx context.Context, kv_references multi.KeyValueString, resolver NameResolver) ([]*Reference, error) {
 
 	refs := make([]*Reference, len(kv_references))
 
@@ -20,37 +35,71 @@ func MultiKeyValueStringsToReferences(kv_references multi.KeyValueString) ([]*Re
 
 		label := k
 
-		str_ids := strings.Split(v, ",")
+		ids, err := parseReferenceValue(ctx, v, resolver)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse value for '%s', %w", label, err)
+		}
+
+		r := &Reference{
+			Ids:      ids,
+			Label:    label,
+			AltLabel: label,
+		}
+
+		refs[refs_idx] = r
+	}
+
+	return refs, nil
+}
+
+// HasNameReferences returns a boolean value indicating whether any of the values in 'kv_references' are place names
+// (prefixed by "name:") that need to be resolved.
+func HasNameReferences(kv_references multi.KeyValueString) bool {
 
-		ids := make([]int64, len(str_ids))
+	for _, kv := range kv_references {
 
-		for ids_idx, str_id := range str_ids {
+		if strings.HasPrefix(kv.Value().(string), gazetteer.NAME_PREFIX) {
+			return true
+		}
+	}
+
+	return false
+}
 
-			id, err := strconv.ParseInt(str_id, 10, 64)
+func parseReferenceValue(ctx context.Context, v string, resolver NameResolver) ([]int64, error) {
 
-			if err != nil {
-				return nil, fmt.Errorf("Failed to parse ID '%s', %w", str_id, err)
-			}
+	if strings.HasPrefix(v, gazetteer.NAME_PREFIX) {
 
-			ids[ids_idx] = id
+		if resolver == nil {
+			return nil, fmt.Errorf("Unable to resolve '%s', no name resolver defined", v)
 		}
 
-		// START OF make me better, more nuanced
+		name := strings.TrimPrefix(v, gazetteer.NAME_PREFIX)
 
-		alt_label := strings.Replace(label, ":", "_", -1)
-		alt_label = strings.Replace(alt_label, ".", "_", -1)
-		alt_label = strings.Replace(alt_label, " ", "_", -1)
+		id, err := resolver.ResolveName(ctx, name)
 
-		// END OF make me better, more nuanced
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve name '%s', %w", name, err)
+		}
 
-		r := &Reference{
-			Ids:      ids,
-			Label:    label,
-			AltLabel: label,
+		return []int64{id}, nil
+	}
+
+	str_ids := strings.Split(v, ",")
+
+	ids := make([]int64, len(str_ids))
+
+	for ids_idx, str_id := range str_ids {
+
+		id, err := strconv.ParseInt(str_id, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse ID '%s', %w", str_id, err)
 		}
 
-		refs[refs_idx] = r
+		ids[ids_idx] = id
 	}
 
-	return refs, nil
+	return ids, nil
 }
@@ -0,0 +1,86 @@
+package georeference
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/sfomuseum/go-sfomuseum-geo/gazetteer"
+)
+
+func TestMultiKeyValueStringsToReferencesWithResolver(t *testing.T) {
+
+	ctx := context.Background()
+
+	idx := gazetteer.NewIndex()
+
+	records := []string{
+		`{"properties":{"wof:id":102025263,"wof:name":"Bangkok","wof:placetype":"locality","wof:country":"TH"}}`,
+		`{"properties":{"wof:id":101932003,"wof:name":"Sydney","wof:placetype":"locality","wof:country":"AU"}}`,
+		`{"properties":{"wof:id":101736545,"wof:name":"Sydney","wof:placetype":"locality","wof:country":"CA"}}`,
+	}
+
+	for _, body := range records {
+
+		err := idx.Add(ctx, []byte(body))
+
+		if err != nil {
+			t.Fatalf("Failed to add record, %v", err)
+		}
+	}
+
+	var kv multi.KeyValueString
+
+	for _, str_kv := range []string{"sfomuseum:depicts=name:Bangkok", "sfomuseum:flightcover_to=name:Sydney, AU", "sfomuseum:flightcover_to=890413117"} {
+
+		err := kv.Set(str_kv)
+
+		if err != nil {
+			t.Fatalf("Failed to set %s, %v", str_kv, err)
+		}
+	}
+
+	if !HasNameReferences(kv) {
+		t.Fatalf("Expected name references")
+	}
+
+	refs, err := MultiKeyValueStringsToReferencesWithResolver(ctx, kv, idx)
+
+	if err != nil {
+		t.Fatalf("Failed to derive references, %v", err)
+	}
+
+	if len(refs) != 3 {
+		t.Fatalf("Expected 3 references, got %d", len(refs))
+	}
+
+	if refs[0].Label != "sfomuseum:depicts" || !slices.Equal(refs[0].Ids, []int64{102025263}) {
+		t.Fatalf("Unexpected first reference, %s", refs[0])
+	}
+
+	if refs[1].AltLabel != "sfomuseum:flightcover_to" || !slices.Equal(refs[1].Ids, []int64{101932003}) {
+		t.Fatalf("Unexpected second reference, %s", refs[1])
+	}
+
+	if refs[2].AltLabel != "sfomuseum:flightcover_to" || !slices.Equal(refs[2].Ids, []int64{890413117}) {
+		t.Fatalf("Unexpected third reference, %s", refs[2])
+	}
+
+	// Ambiguous names and missing resolvers fail
+
+	var ambiguous_kv multi.KeyValueString
+	ambiguous_kv.Set("sfomuseum:depicts=name:Sydney")
+
+	_, err = MultiKeyValueStringsToReferencesWithResolver(ctx, ambiguous_kv, idx)
+
+	if err == nil {
+		t.Fatalf("Expected ambiguous name to fail")
+	}
+
+	_, err = MultiKeyValueStringsToReferences(kv)
+
+	if err == nil {
+		t.Fatalf("Expected name reference without resolver to fail")
+	}
+}
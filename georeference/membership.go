@@ -0,0 +1,231 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
+)
+
+// DEFAULT_MEMBERSHIP_URI is the default URI used to create a `SubjectMembership` instance. It reads the list of
+// depictions (images) for a subject (object) from the `millsfield:images` property.
+const DEFAULT_MEMBERSHIP_URI string = "property://?path=millsfield:images"
+
+// The list of properties used by the "depictions://" `SubjectMembership` scheme. Note that `georef:depictions` is written by
+// `RecompileGeorefencesForSubject` so this scheme is only suitable for re-deriving the georeferences of depictions already
+// recorded by a previous recompile. Depictions missing from the last recompile will never be found.
+var DEPICTIONS_MEMBERSHIP_PROPERTIES = []string{
+	geo.RESERVED_GEOREFERENCE_DEPICTIONS,
+	geo.RESERVED_GEOTAG_SUBJECT_DEPICTIONS,
+}
+
+// SubjectMembership defines an interface for deriving the list of depictions (for example images) belonging to a subject (for example an object).
+type SubjectMembership interface {
+	// Depictions returns the list of depiction IDs for the subject record in 'subject_body'.
+	Depictions(ctx context.Context, subject_body []byte) ([]int64, error)
+}
+
+// NewSubjectMembership returns a new `SubjectMembership` instance derived from 'uri'. Valid schemes are:
+//
+//	property://?path={PROPERTY}[&path={PROPERTY}]  Read depiction IDs from one or more (relative to "properties") property paths of the subject.
+//	depictions://                                  Read depiction IDs from the `georef:depictions` and `geotag:depictions` properties of the subject.
+//	                                               This scheme is not suitable for recompiling subjects from scratch (see DEPICTIONS_MEMBERSHIP_PROPERTIES).
+//	parent://?iterator-uri={URI}&source={SOURCE}   Derive depiction IDs from all the records whose `wof:parent_id` property is equal to the
+//	                                               subject's ID. Records are read using a whosonfirst/go-whosonfirst-iterate/v3.Iterator instance
+//	                                               (default is "repo://") for one or more 'source' parameters.
+func NewSubjectMembership(ctx context.Context, uri string) (SubjectMembership, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse membership URI, %w", err)
+	}
+
+	q := u.Query()
+
+	switch u.Scheme {
+	case "property":
+
+		paths := q["path"]
+
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("Missing ?path= parameter")
+		}
+
+		return NewPropertyMembership(paths...), nil
+
+	case "depictions":
+
+		return NewPropertyMembership(DEPICTIONS_MEMBERSHIP_PROPERTIES...), nil
+
+	case "parent":
+
+		iterator_uri := q.Get("iterator-uri")
+
+		if iterator_uri == "" {
+			iterator_uri = "repo://"
+		}
+
+		sources := q["source"]
+
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("Missing ?source= parameter")
+		}
+
+		return NewParentMembershipFromIterator(ctx, iterator_uri, sources...)
+
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported membership scheme '%s'", u.Scheme)
+	}
+}
+
+// PropertyMembership implements the `SubjectMembership` interface by reading depiction IDs from one or more subject properties.
+type PropertyMembership struct {
+	paths []string
+}
+
+// NewPropertyMembership returns a new `PropertyMembership` instance which reads depiction IDs from 'paths', where each path
+// is relative to a subject's "properties" dictionary.
+func NewPropertyMembership(paths ...string) *PropertyMembership {
+
+	m := &PropertyMembership{
+		paths: paths,
+	}
+
+	return m
+}
+
+// Depictions returns the unique list of depiction IDs in any of the properties defined by 'm', in the order they are encountered.
+func (m *PropertyMembership) Depictions(ctx context.Context, subject_body []byte) ([]int64, error) {
+
+	depictions := make([]int64, 0)
+
+	for _, path := range m.paths {
+
+		rsp := gjson.GetBytes(subject_body, fmt.Sprintf("properties.%s", path))
+
+		ids, err := int64sFromResult(rsp)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s property, %w", path, err)
+		}
+
+		for _, id := range ids {
+
+			if id > 0 && !slices.Contains(depictions, id) {
+				depictions = append(depictions, id)
+			}
+		}
+	}
+
+	return depictions, nil
+}
+
+// ParentMembership implements the `SubjectMembership` interface by looking up depictions whose `wof:parent_id` property is equal to a subject's ID.
+type ParentMembership struct {
+	children map[int64][]int64
+	mu       *sync.RWMutex
+}
+
+// NewParentMembership returns a new, empty, `ParentMembership` instance.
+func NewParentMembership() *ParentMembership {
+
+	m := &ParentMembership{
+		children: make(map[int64][]int64),
+		mu:       new(sync.RWMutex),
+	}
+
+	return m
+}
+
+// NewParentMembershipFromIterator returns a new `ParentMembership` instance populated with all the records emitted by a
+// `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived from 'iterator_uri' for 'sources'.
+func NewParentMembershipFromIterator(ctx context.Context, iterator_uri string, sources ...string) (*ParentMembership, error) {
+
+	m := NewParentMembership()
+
+	iter, err := iterate.NewIterator(ctx, iterator_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new iterator, %w", err)
+	}
+
+	defer iter.Close()
+
+	for rec, err := range iter.Iterate(ctx, sources...) {
+
+		if err != nil {
+			return nil, fmt.Errorf("Iterator signaled an error, %w", err)
+		}
+
+		body, err := io.ReadAll(rec.Body)
+		rec.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", rec.Path, err)
+		}
+
+		if strings.Contains(rec.Path, "-alt-") {
+			continue
+		}
+
+		err = m.Add(ctx, body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to add %s, %w", rec.Path, err)
+		}
+	}
+
+	slog.Debug("Indexed depictions by parent ID", "parents", len(m.children))
+	return m, nil
+}
+
+// Add adds the depiction record in 'body' to 'm' keyed by its `wof:parent_id` property. Records without a (positive) parent ID are ignored.
+func (m *ParentMembership) Add(ctx context.Context, body []byte) error {
+
+	id, err := properties.Id(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive ID, %w", err)
+	}
+
+	parent_id := gjson.GetBytes(body, "properties.wof:parent_id").Int()
+
+	if parent_id <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(m.children[parent_id], id) {
+		m.children[parent_id] = append(m.children[parent_id], id)
+		slices.Sort(m.children[parent_id])
+	}
+
+	return nil
+}
+
+// Depictions returns the sorted list of depiction IDs whose `wof:parent_id` property is equal to the ID of 'subject_body'.
+func (m *ParentMembership) Depictions(ctx context.Context, subject_body []byte) ([]int64, error) {
+
+	subject_id, err := properties.Id(subject_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive subject ID, %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.children[subject_id]), nil
+}
@@ -0,0 +1,163 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+func TestPropertyMembership(t *testing.T) {
+
+	ctx := context.Background()
+
+	body := []byte(`{"properties":{"wof:id":1,"millsfield:images":[3,2],"georef:depictions":[2,4],"geotag:depictions":[5,4]}}`)
+
+	tests := map[string][]int64{
+		DEFAULT_MEMBERSHIP_URI: []int64{3, 2},
+		"property://?path=georef:depictions&path=millsfield:images": []int64{2, 4, 3},
+		"depictions://": []int64{2, 4, 5},
+	}
+
+	for uri, expected := range tests {
+
+		m, err := NewSubjectMembership(ctx, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create membership for %s, %v", uri, err)
+		}
+
+		depictions, err := m.Depictions(ctx, body)
+
+		if err != nil {
+			t.Fatalf("Failed to derive depictions for %s, %v", uri, err)
+		}
+
+		if !slices.Equal(depictions, expected) {
+			t.Fatalf("Unexpected depictions for %s, expected %v but got %v", uri, expected, depictions)
+		}
+	}
+
+	m := NewPropertyMembership("millsfield:images")
+
+	_, err := m.Depictions(ctx, []byte(`{"properties":{"millsfield:images":1}}`))
+
+	if err == nil {
+		t.Fatalf("Expected invalid property to fail")
+	}
+
+	for _, uri := range []string{"property://", "parent://", "bogus://"} {
+
+		_, err := NewSubjectMembership(ctx, uri)
+
+		if err == nil {
+			t.Fatalf("Expected %s to fail", uri)
+		}
+	}
+}
+
+func TestParentMembership(t *testing.T) {
+
+	ctx := context.Background()
+
+	path_media, err := filepath.Abs("../fixtures/sfomuseum-data-media-collection")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	m, err := NewSubjectMembership(ctx, fmt.Sprintf("parent://?source=%s", path_media))
+
+	if err != nil {
+		t.Fatalf("Failed to create membership, %v", err)
+	}
+
+	depictions, err := m.Depictions(ctx, []byte(`{"properties":{"wof:id":1511907389}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to derive depictions, %v", err)
+	}
+
+	if !slices.Equal(depictions, []int64{1527829811, 1527829813, 1527829815}) {
+		t.Fatalf("Unexpected depictions, %v", depictions)
+	}
+}
+
+func TestRecompileGeorefencesForSubjectMembership(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+	}
+
+	_, err := AssignReferences(ctx, opts, 1527829813, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	subject_body, err := wof_reader.LoadBytes(ctx, opts.SubjectReader, 1511907389)
+
+	if err != nil {
+		t.Fatalf("Failed to load subject, %v", err)
+	}
+
+	// A membership which yields no depictions
+
+	recompile_opts := &RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          opts.DepictionReader,
+		SFOMuseumReader:          opts.SFOMuseumReader,
+		WhosOnFirstReader:        opts.WhosOnFirstReader,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		Membership:               NewPropertyMembership("example:images"),
+	}
+
+	_, new_body, err := RecompileGeorefencesForSubject(ctx, recompile_opts, subject_body)
+
+	if err != nil {
+		t.Fatalf("Failed to recompile subject, %v", err)
+	}
+
+	if len(gjson.GetBytes(new_body, "properties.georef:depictions").Array()) != 0 {
+		t.Fatalf("Expected no depictions, %s", gjson.GetBytes(new_body, "properties.georef:depictions").String())
+	}
+
+	// Depictions derived from wof:parent_id
+
+	parent_membership := NewParentMembership()
+
+	for _, id := range []int64{1527829811, 1527829813, 1527829815} {
+
+		body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, id)
+
+		if err != nil {
+			t.Fatalf("Failed to load depiction %d, %v", id, err)
+		}
+
+		err = parent_membership.Add(ctx, body)
+
+		if err != nil {
+			t.Fatalf("Failed to add depiction %d, %v", id, err)
+		}
+	}
+
+	recompile_opts.Membership = parent_membership
+
+	_, new_body, err = RecompileGeorefencesForSubject(ctx, recompile_opts, subject_body)
+
+	if err != nil {
+		t.Fatalf("Failed to recompile subject, %v", err)
+	}
+
+	depictions := intArray(gjson.GetBytes(new_body, "properties.georef:depictions"))
+
+	if !slices.Equal(depictions, []int64{1527829813}) {
+		t.Fatalf("Unexpected depictions, %v", depictions)
+	}
+}
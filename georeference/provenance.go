@@ -0,0 +1,181 @@
+package georeference
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+)
+
+// Provenance defines information about who asserted a georeference, when and on what evidence.
+type Provenance struct {
+	// Author is the name of the person (or process) who asserted the georeference.
+	Author string `json:"author,omitempty"`
+	// Created is the Unix timestamp when the georeference was asserted.
+	Created int64 `json:"created,omitempty"`
+	// Citation is an optional reference to the source or evidence for the georeference.
+	Citation string `json:"citation,omitempty"`
+	// Note is an optional free-form note about the georeference.
+	Note string `json:"note,omitempty"`
+	// Confidence is an optional value between 0.0 and 1.0 indicating how confident the author is in the georeference.
+	// It is a pointer so that a confidence of 0.0 can be distinguished from no confidence at all.
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// IsZero returns a boolean value indicating whether none of the properties in 'p' have been set.
+func (p *Provenance) IsZero() bool {
+	return p.Author == "" && p.Created == 0 && p.Citation == "" && p.Note == "" && p.Confidence == nil
+}
+
+// Validate returns an error if any of the properties in 'p' are invalid.
+func (p *Provenance) Validate() error {
+
+	if p.Confidence != nil && (*p.Confidence < 0 || *p.Confidence > 1) {
+		return fmt.Errorf("Invalid confidence value (%f), must be between 0.0 and 1.0", *p.Confidence)
+	}
+
+	if p.Created < 0 {
+		return fmt.Errorf("Invalid created value (%d)", p.Created)
+	}
+
+	return nil
+}
+
+// Properties returns a dictionary of the (non-empty) properties in 'p' keyed by their reserved `georef:` property name.
+// It is safe to call this method on a nil instance in which case an empty dictionary is returned.
+func (p *Provenance) Properties() map[string]any {
+
+	props := make(map[string]any)
+
+	if p == nil {
+		return props
+	}
+
+	if p.Author != "" {
+		props[geo.RESERVED_GEOREFERENCE_AUTHOR] = p.Author
+	}
+
+	if p.Created != 0 {
+		props[geo.RESERVED_GEOREFERENCE_CREATED] = p.Created
+	}
+
+	if p.Citation != "" {
+		props[geo.RESERVED_GEOREFERENCE_CITATION] = p.Citation
+	}
+
+	if p.Note != "" {
+		props[geo.RESERVED_GEOREFERENCE_NOTE] = p.Note
+	}
+
+	if p.Confidence != nil {
+		props[geo.RESERVED_GEOREFERENCE_CONFIDENCE] = *p.Confidence
+	}
+
+	return props
+}
+
+// Clone returns a copy of 'p'.
+func (p *Provenance) Clone() *Provenance {
+
+	c := *p
+
+	if p.Confidence != nil {
+		confidence := *p.Confidence
+		c.Confidence = &confidence
+	}
+
+	return &c
+}
+
+// DepictionProvenance defines the provenance for a labeled georeference in a single depiction, as rolled up
+// in to the `georef:provenance` property of a subject record.
+type DepictionProvenance struct {
+	// DepictionId is the ID of the depiction (image) in which the georeference was asserted.
+	DepictionId int64 `json:"georef:depiction"`
+	// Author is the name of the person (or process) who asserted the georeference.
+	Author string `json:"georef:author,omitempty"`
+	// Created is the Unix timestamp when the georeference was asserted.
+	Created int64 `json:"georef:created,omitempty"`
+	// Citation is an optional reference to the source or evidence for the georeference.
+	Citation string `json:"georef:citation,omitempty"`
+	// Note is an optional free-form note about the georeference.
+	Note string `json:"georef:note,omitempty"`
+	// Confidence is an optional value between 0.0 and 1.0 indicating how confident the author is in the georeference.
+	Confidence *float64 `json:"georef:confidence,omitempty"`
+}
+
+// appendDepictionProvenance appends a new `DepictionProvenance` instance derived from 'depiction_id' and 'p' to 'entries'
+// unless 'p' is nil or 'entries' already contains an entry for 'depiction_id'.
+func appendDepictionProvenance(entries []*DepictionProvenance, depiction_id int64, p *Provenance) []*DepictionProvenance {
+
+	if p == nil {
+		return entries
+	}
+
+	for _, e := range entries {
+
+		if e.DepictionId == depiction_id {
+			return entries
+		}
+	}
+
+	e := &DepictionProvenance{
+		DepictionId: depiction_id,
+		Author:      p.Author,
+		Created:     p.Created,
+		Citation:    p.Citation,
+		Note:        p.Note,
+		Confidence:  p.Confidence,
+	}
+
+	return append(entries, e)
+}
+
+// resolveProvenance returns the `Provenance` to record for 'r'. If 'r' has its own provenance it is used, with 'author'
+// and 'now' filling in any missing author or created values. Otherwise, if 'existing' is the current entry for the same
+// label with the same IDs, its provenance is preserved. Otherwise a new provenance is derived from 'author' and 'now'.
+func resolveProvenance(r *Reference, existing *GeoreferenceDepicted, author string, now time.Time) *Provenance {
+
+	if r.Provenance != nil && !r.Provenance.IsZero() {
+
+		p := r.Provenance.Clone()
+
+		if p.Author == "" {
+			p.Author = author
+		}
+
+		if p.Created == 0 {
+			p.Created = now.Unix()
+		}
+
+		return p
+	}
+
+	if existing != nil && sameIds(existing.Depicts, r.Ids) {
+		return existing.Provenance()
+	}
+
+	p := &Provenance{
+		Author:  author,
+		Created: now.Unix(),
+	}
+
+	return p
+}
+
+func sameIds(a []int64, b []int64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, id := range a {
+
+		if !slices.Contains(b, id) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,284 @@
+package georeference
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/tidwall/gjson"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+func TestProvenanceValidate(t *testing.T) {
+
+	valid := []*Provenance{
+		&Provenance{},
+		&Provenance{Author: "alice", Confidence: confidence(0.5)},
+		&Provenance{Created: 1700000000, Confidence: confidence(1.0)},
+	}
+
+	for _, p := range valid {
+
+		err := p.Validate()
+
+		if err != nil {
+			t.Fatalf("Expected %v to validate, %v", p, err)
+		}
+	}
+
+	invalid := []*Provenance{
+		&Provenance{Confidence: confidence(-0.1)},
+		&Provenance{Confidence: confidence(1.1)},
+		&Provenance{Created: -1},
+	}
+
+	for _, p := range invalid {
+
+		err := p.Validate()
+
+		if err == nil {
+			t.Fatalf("Expected %v to fail validation", p)
+		}
+	}
+}
+
+func TestProvenanceZeroConfidence(t *testing.T) {
+
+	p := &Provenance{Confidence: confidence(0.0)}
+
+	if p.IsZero() {
+		t.Fatalf("Expected provenance with a confidence of 0.0 not to be zero")
+	}
+
+	v, ok := p.Properties()[geo.RESERVED_GEOREFERENCE_CONFIDENCE]
+
+	if !ok || v != 0.0 {
+		t.Fatalf("Expected confidence of 0.0 to be recorded, %v", p.Properties())
+	}
+
+	d := new(GeoreferenceDepicted)
+	d.SetProvenance(p)
+
+	enc, err := json.Marshal(d)
+
+	if err != nil {
+		t.Fatalf("Failed to marshal depicted, %v", err)
+	}
+
+	if !gjson.GetBytes(enc, geo.RESERVED_GEOREFERENCE_CONFIDENCE).Exists() {
+		t.Fatalf("Expected encoded depicted to include confidence, %s", string(enc))
+	}
+
+	depicted, err := LoadGeoreferenceDepicted([]byte(`{"properties":{"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263],"georef:confidence":0}]}}`))
+
+	if err != nil {
+		t.Fatalf("Failed to load georeference depicted, %v", err)
+	}
+
+	p = depicted[0].Provenance()
+
+	if p == nil || p.Confidence == nil || *p.Confidence != 0.0 {
+		t.Fatalf("Expected confidence of 0.0 to be loaded, %v", p)
+	}
+}
+
+func TestProvenanceProperties(t *testing.T) {
+
+	var p *Provenance
+
+	if len(p.Properties()) != 0 {
+		t.Fatalf("Expected nil provenance to return empty properties")
+	}
+
+	p = &Provenance{Author: "alice", Created: 1700000000, Confidence: confidence(0.75)}
+	props := p.Properties()
+
+	if len(props) != 3 {
+		t.Fatalf("Expected 3 properties, got %v", props)
+	}
+
+	if props[geo.RESERVED_GEOREFERENCE_AUTHOR] != "alice" {
+		t.Fatalf("Unexpected author property, %v", props)
+	}
+
+	if _, ok := props[geo.RESERVED_GEOREFERENCE_CITATION]; ok {
+		t.Fatalf("Did not expect empty citation property, %v", props)
+	}
+}
+
+func TestResolveProvenance(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+
+	existing := &GeoreferenceDepicted{
+		Label:   "sfomuseum:depicts",
+		Depicts: []int64{102025263},
+		Author:  "alice",
+		Created: 1600000000,
+	}
+
+	// Same IDs, no new provenance: keep the existing provenance
+
+	p := resolveProvenance(&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}}, existing, "bob", now)
+
+	if p.Author != "alice" || p.Created != 1600000000 {
+		t.Fatalf("Expected existing provenance to be preserved, %v", p)
+	}
+
+	// Different IDs: new provenance
+
+	p = resolveProvenance(&Reference{Label: "sfomuseum:depicts", Ids: []int64{101932003}}, existing, "bob", now)
+
+	if p.Author != "bob" || p.Created != now.Unix() {
+		t.Fatalf("Expected new provenance, %v", p)
+	}
+
+	// Explicit provenance: author and created are filled in if missing
+
+	r := &Reference{
+		Label:      "sfomuseum:depicts",
+		Ids:        []int64{102025263},
+		Provenance: &Provenance{Citation: "Caption on reverse", Confidence: confidence(0.5)},
+	}
+
+	p = resolveProvenance(r, existing, "bob", now)
+
+	if p.Author != "bob" || p.Created != now.Unix() || p.Citation != "Caption on reverse" || p.Confidence == nil || *p.Confidence != 0.5 {
+		t.Fatalf("Unexpected explicit provenance, %v", p)
+	}
+
+	if r.Provenance.Author != "" {
+		t.Fatalf("Reference provenance was modified")
+	}
+}
+
+func TestAddReferencesProvenance(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+	opts.Author = "alice"
+
+	depiction_id := int64(1527829811)
+
+	from_ref := &Reference{
+		Label: "sfomuseum:flightcover_from",
+		Ids:   []int64{101932003},
+		Provenance: &Provenance{
+			Citation:   "Postmark",
+			Confidence: confidence(0.9),
+		},
+	}
+
+	_, err := AddReferences(ctx, opts, depiction_id, from_ref)
+
+	if err != nil {
+		t.Fatalf("Failed to add first reference, %v", err)
+	}
+
+	opts.Author = "bob"
+
+	r, err := AddReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}})
+
+	if err != nil {
+		t.Fatalf("Failed to add second reference, %v", err)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	depiction_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	depicted, err := LoadGeoreferenceDepicted(depiction_body)
+
+	if err != nil {
+		t.Fatalf("Failed to load depicted, %v", err)
+	}
+
+	lookup := make(map[string]*GeoreferenceDepicted)
+
+	for _, d := range depicted {
+		lookup[d.Label] = d
+	}
+
+	from_p := lookup["sfomuseum:flightcover_from"].Provenance()
+
+	if from_p == nil || from_p.Author != "alice" || from_p.Citation != "Postmark" || from_p.Confidence == nil || *from_p.Confidence != 0.9 {
+		t.Fatalf("Expected flightcover_from provenance to be preserved, %v", from_p)
+	}
+
+	to_p := lookup["sfomuseum:flightcover_to"].Provenance()
+
+	if to_p == nil || to_p.Author != "bob" || to_p.Created == 0 {
+		t.Fatalf("Unexpected flightcover_to provenance, %v", to_p)
+	}
+
+	// Subject roll-up
+
+	subject_path := fmt.Sprintf("properties.%s.sfomuseum:flightcover_from.0", geo.RESERVED_GEOREFERENCE_PROVENANCE)
+	var subject_rsp gjson.Result
+
+	for _, f := range gjson.GetBytes(body, "features").Array() {
+
+		rsp := f.Get(subject_path)
+
+		if rsp.Exists() {
+			subject_rsp = rsp
+			break
+		}
+	}
+
+	if !subject_rsp.Exists() {
+		t.Fatalf("Expected subject to have rolled up provenance, %s", string(body))
+	}
+
+	if subject_rsp.Get(geo.RESERVED_GEOREFERENCE_DEPICTION).Int() != depiction_id || subject_rsp.Get(geo.RESERVED_GEOREFERENCE_AUTHOR).String() != "alice" {
+		t.Fatalf("Unexpected subject provenance, %s", subject_rsp.String())
+	}
+
+	// Changing the IDs for a label replaces its provenance
+
+	_, err = AddReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{102025263}})
+
+	if err != nil {
+		t.Fatalf("Failed to update first reference, %v", err)
+	}
+
+	depiction_body, err = wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	depicted, err = LoadGeoreferenceDepicted(depiction_body)
+
+	if err != nil {
+		t.Fatalf("Failed to load depicted, %v", err)
+	}
+
+	for _, d := range depicted {
+
+		if d.Label != "sfomuseum:flightcover_from" {
+			continue
+		}
+
+		p := d.Provenance()
+
+		if p == nil || p.Author != "bob" || p.Citation != "" {
+			t.Fatalf("Expected flightcover_from provenance to be replaced, %v", p)
+		}
+	}
+}
+
+func confidence(v float64) *float64 {
+	return &v
+}
@@ -2,8 +2,11 @@ package georeference
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
 )
 
 // type Reference is a struct that encapusulates data about a place being georeferenced.
@@ -14,6 +17,8 @@ type Reference struct {
 	Label string `json:"label"`
 	// AltLabel is the alternate geometry label to use for the class of georeference.
 	AltLabel string `json:"alt_label"`
+	// Provenance is the optional information about who asserted the georeference, when and on what evidence.
+	Provenance *Provenance `json:"provenance,omitempty"`
 }
 
 func (r *Reference) String() string {
@@ -26,3 +31,44 @@ func (r *Reference) String() string {
 
 	return fmt.Sprintf("%s: %s", r.Label, strings.Join(str_ids, ","))
 }
+
+// referenceLabels returns the list of labels for 'refs'.
+func referenceLabels(refs []*Reference) []string {
+
+	labels := make([]string, len(refs))
+
+	for idx, r := range refs {
+		labels[idx] = r.Label
+	}
+
+	return labels
+}
+
+// referencePlaces returns the unique list of names (`wof:name`) of the places referenced by 'refs' using the record
+// bodies in 'ref_bodies'. IDs without a corresponding body, or whose body has no name, are skipped.
+func referencePlaces(refs []*Reference, ref_bodies map[int64][]byte) []string {
+
+	places := make([]string, 0)
+
+	for _, r := range refs {
+
+		for _, id := range r.Ids {
+
+			body, exists := ref_bodies[id]
+
+			if !exists {
+				continue
+			}
+
+			name, err := properties.Name(body)
+
+			if err != nil || slices.Contains(places, name) {
+				continue
+			}
+
+			places = append(places, name)
+		}
+	}
+
+	return places
+}
@@ -0,0 +1,213 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
+)
+
+// RefreshReferences re-assigns the existing references (stored in the `georef:depicted` property) for each of 'depiction_ids'
+// using `AssignReferences` so that any hierarchies and centroids derived from the Who's On First records they reference are
+// brought up to date. Subject (parent) records are not updated as each depiction is refreshed. Instead each affected subject
+// is recompiled, once, after all of its depictions have been refreshed. Depictions without any existing references are skipped.
+// The response is a single `geo_writers.UpdateResult` combining all the records that were updated.
+func RefreshReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_ids ...int64) (*geo_writers.UpdateResult, error) {
+
+	logger := slog.Default()
+	logger = logger.With("action", "refresh georeferences")
+
+	// Create the reader cache once so that it is shared by all the depictions and subjects being refreshed
+
+	if opts.ReaderCacheURI != "" {
+
+		whosonfirst_reader, sfomuseum_reader, log_stats, err := cacheReaders(ctx, opts.ReaderCacheURI, opts.WhosOnFirstReader, opts.SFOMuseumReader)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer log_stats()
+
+		cached_opts := *opts
+		cached_opts.WhosOnFirstReader = whosonfirst_reader
+		cached_opts.SFOMuseumReader = sfomuseum_reader
+		cached_opts.ReaderCacheURI = ""
+
+		opts = &cached_opts
+	}
+
+	results := make([]*geo_writers.UpdateResult, 0)
+
+	// The (ordered) list of subjects to recompile and the updated depictions for each
+	subject_ids := make([]int64, 0)
+	skip_lists := make(map[int64]map[int64]*SkipListItem)
+
+	seen := make([]int64, 0)
+
+	for _, depiction_id := range depiction_ids {
+
+		if slices.Contains(seen, depiction_id) {
+			continue
+		}
+
+		seen = append(seen, depiction_id)
+
+		logger := logger.With("depiction id", depiction_id)
+
+		refs, err := ExistingReferences(ctx, opts, depiction_id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(refs) == 0 {
+			logger.Warn("Depiction has no existing references, skipping")
+			continue
+		}
+
+		logger.Debug("Refresh references", "count", len(refs))
+
+		rsp, subject_id, skip_item, err := assignReferences(ctx, opts, depiction_id, true, refs...)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to refresh references for depiction %d, %w", depiction_id, err)
+		}
+
+		results = append(results, rsp)
+
+		_, exists := skip_lists[subject_id]
+
+		if !exists {
+			subject_ids = append(subject_ids, subject_id)
+			skip_lists[subject_id] = make(map[int64]*SkipListItem)
+		}
+
+		skip_lists[subject_id][depiction_id] = skip_item
+	}
+
+	for _, subject_id := range subject_ids {
+
+		rsp, err := recompileSubject(ctx, opts, subject_id, skip_lists[subject_id])
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to recompile subject %d, %w", subject_id, err)
+		}
+
+		if rsp != nil {
+			results = append(results, rsp)
+		}
+	}
+
+	return CombineResults(opts.DryRun, results...), nil
+}
+
+// RecompileSubject recompiles the georeferences for 'subject_id' from all of its depictions and writes the subject, using the
+// same writers (and transaction) as `AssignReferences`, if it has changed. If the subject has not changed an empty
+// `geo_writers.UpdateResult` is returned.
+func RecompileSubject(ctx context.Context, opts *AssignReferencesOptions, subject_id int64) (*geo_writers.UpdateResult, error) {
+
+	rsp, err := recompileSubject(ctx, opts, subject_id, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp == nil {
+
+		rsp = geo_writers.NewUpdateResult()
+
+		if opts.DryRun {
+			rsp.Plan = plan.NewPlan()
+		}
+	}
+
+	return rsp, nil
+}
+
+// recompileSubject recompiles the georeferences for 'subject_id' using the updated depictions in 'skip_list' and writes the
+// subject if it has changed. It returns nil if the subject has not changed.
+func recompileSubject(ctx context.Context, opts *AssignReferencesOptions, subject_id int64, skip_list map[int64]*SkipListItem) (*geo_writers.UpdateResult, error) {
+
+	logger := slog.Default()
+	logger = logger.With("action", "refresh georeferences")
+	logger = logger.With("subject id", subject_id)
+
+	subject_body, err := wof_reader.LoadBytes(ctx, opts.SubjectReader, subject_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load subject, %w", err)
+	}
+
+	recompile_opts := &RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          opts.DepictionReader,
+		SFOMuseumReader:          opts.SFOMuseumReader,
+		WhosOnFirstReader:        opts.WhosOnFirstReader,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		SkipList:                 skip_list,
+		Membership:               opts.SubjectMembership,
+		Concurrency:              opts.Concurrency,
+	}
+
+	has_changed, new_body, err := RecompileGeorefencesForSubject(ctx, recompile_opts, subject_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to recompile georeferences for subject, %w", err)
+	}
+
+	logger.Debug("Has subject changed", "changes", has_changed, "depictions", len(skip_list))
+
+	if !has_changed {
+		return nil, nil
+	}
+
+	github_opts := &github.UpdateWriterURIOptions{
+		Author:        opts.Author,
+		WhosOnFirstId: subject_id,
+		Action:        github.GeoreferenceAction,
+		SubjectId:     subject_id,
+		Templates:     opts.GitHubTemplates,
+	}
+
+	var update_plan *plan.Plan
+
+	if opts.DryRun {
+		update_plan = plan.NewPlan()
+	}
+
+	writers_opts := &geo_writers.CreateWritersOptions{
+		SubjectWriterURI:    opts.SubjectWriterURI,
+		DepictionWriterURI:  opts.DepictionWriterURI,
+		GithubWriterOptions: github_opts,
+		Plan:                update_plan,
+		DepictionReader:     opts.DepictionReader,
+		SubjectReader:       opts.SubjectReader,
+		GitHubAPIURL:        opts.GitHubAPIURL,
+	}
+
+	writers, err := geo_writers.CreateWriters(ctx, writers_opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create writers, %w", err)
+	}
+
+	_, err = wof_writer.WriteBytes(ctx, writers.SubjectMultiWriter, new_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write subject update, %w", err)
+	}
+
+	err = writers.Commit(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to commit writes, %w", err)
+	}
+
+	return writers.Result(ctx)
+}
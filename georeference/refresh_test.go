@@ -0,0 +1,230 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func TestRefreshReferences(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	path_fixtures, err := filepath.Abs("../fixtures/whosonfirst-data-admin")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	root := t.TempDir()
+
+	err = os.CopyFS(root, os.DirFS(path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to copy whosonfirst-data-admin fixtures, %v", err)
+	}
+
+	wof_reader, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	opts.WhosOnFirstReader = wof_reader
+
+	// Two depictions for the same subject (1511907389) and one for another (1897902471)
+
+	assignments := map[int64][]*Reference{
+		1527829811: []*Reference{
+			&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}},
+			&Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{102025263}},
+		},
+		1527829813: []*Reference{
+			&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+		},
+		1897903961: []*Reference{
+			&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+		},
+	}
+
+	for depiction_id, refs := range assignments {
+
+		_, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+		if err != nil {
+			t.Fatalf("Failed to assign references for %d, %v", depiction_id, err)
+		}
+	}
+
+	// Update the hierarchy for Bangkok upstream
+
+	bangkok_path := filepath.Join(root, "data/102/025/263/102025263.geojson")
+
+	bangkok_body, err := os.ReadFile(bangkok_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read Bangkok, %v", err)
+	}
+
+	bangkok_body, err = sjson.SetBytes(bangkok_body, "properties.wof:hierarchy.0.county_id", 1234)
+
+	if err != nil {
+		t.Fatalf("Failed to update Bangkok, %v", err)
+	}
+
+	err = os.WriteFile(bangkok_path, bangkok_body, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write Bangkok, %v", err)
+	}
+
+	// Dry run first
+
+	opts.DryRun = true
+
+	r, err := RefreshReferences(ctx, opts, 1527829811, 1527829813, 1897903961, 1527829813)
+
+	if err != nil {
+		t.Fatalf("Failed to refresh references (dry run), %v", err)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	subject_changes := 0
+
+	for _, c := range gjson.GetBytes(body, "changes").Array() {
+
+		if c.Get("role").String() == "subject" {
+			subject_changes += 1
+		}
+	}
+
+	if subject_changes != 2 {
+		t.Fatalf("Expected 2 subject changes in plan, got %d: %s", subject_changes, string(body))
+	}
+
+	opts.DryRun = false
+
+	r, err = RefreshReferences(ctx, opts, 1527829811, 1527829813, 1897903961)
+
+	if err != nil {
+		t.Fatalf("Failed to refresh references, %v", err)
+	}
+
+	body, err = r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	counts := make(map[int64]int)
+	alt_counts := make(map[int64]int)
+
+	for _, f := range gjson.GetBytes(body, "features").Array() {
+
+		id := f.Get("properties.wof:id").Int()
+
+		// Alt files share the ID of their depiction
+
+		if f.Get("properties.src:alt_label").Exists() {
+			alt_counts[id] += 1
+			continue
+		}
+
+		counts[id] += 1
+
+		county_ids := intArray(f.Get("properties.wof:hierarchy.#.county_id"))
+
+		if !slices.Contains(county_ids, 1234) {
+			t.Fatalf("Expected %d to be updated with refreshed hierarchy, got %s", id, f.Get("properties.wof:hierarchy").String())
+		}
+	}
+
+	for _, id := range []int64{1511907389, 1897902471, 1527829811, 1527829813, 1897903961} {
+
+		if counts[id] != 1 {
+			t.Fatalf("Expected %d to be written once, got %d", id, counts[id])
+		}
+	}
+
+	for _, id := range []int64{1527829811, 1527829813, 1897903961} {
+
+		if alt_counts[id] == 0 {
+			t.Fatalf("Expected alt files for %d to be included in the response", id)
+		}
+	}
+
+	// Depictions without references are skipped
+
+	r, err = RefreshReferences(ctx, opts, 1527827539)
+
+	if err != nil {
+		t.Fatalf("Failed to refresh depiction without references, %v", err)
+	}
+
+	body, err = r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	if len(gjson.GetBytes(body, "features").Array()) != 0 {
+		t.Fatalf("Expected no updates, %s", string(body))
+	}
+}
+
+func TestRecompileSubject(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+	}
+
+	_, err := AssignReferences(ctx, opts, 1527829813, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+
+		r, err := RecompileSubject(ctx, opts, 1511907389)
+
+		if err != nil {
+			t.Fatalf("Failed to recompile subject, %v", err)
+		}
+
+		for _, rec := range r.Records {
+
+			if rec.Role != plan.SubjectRole || rec.Id != 1511907389 {
+				t.Fatalf("Unexpected record, %v", rec)
+			}
+
+			// Recompiling a second time should not change anything
+			if i > 0 && rec.Changed {
+				t.Fatalf("Did not expect subject to change when recompiled again")
+			}
+		}
+	}
+
+	_, err = RecompileSubject(ctx, opts, 1)
+
+	if err == nil {
+		t.Fatalf("Expected recompiling an unknown subject to fail")
+	}
+}
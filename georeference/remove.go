@@ -2,8 +2,52 @@ package georeference
 
 import (
 	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
 )
 
-func RemoveAllReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64) ([]byte, error) {
+// RemoveAllReferences removes all the georeferences for 'depiction_id'.
+func RemoveAllReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64) (*geo_writers.UpdateResult, error) {
 	return AssignReferences(ctx, opts, depiction_id)
 }
+
+// RemoveReferences removes the georeferences matching 'labels' for 'depiction_id' leaving any other existing
+// georeferences in place. The remaining set of references is then assigned using `AssignReferences`. If none of 'labels'
+// are present nothing is written and an empty `geo_writers.UpdateResult` is returned.
+func RemoveReferences(ctx context.Context, opts *AssignReferencesOptions, depiction_id int64, labels ...string) (*geo_writers.UpdateResult, error) {
+
+	existing_refs, err := ExistingReferences(ctx, opts, depiction_id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]*Reference, 0)
+
+	for _, r := range existing_refs {
+
+		if slices.Contains(labels, r.Label) {
+			continue
+		}
+
+		refs = append(refs, r)
+	}
+
+	if len(refs) == len(existing_refs) {
+
+		slog.Warn("None of the labels to remove are present in depiction, nothing to do", "depiction id", depiction_id, "labels", labels)
+
+		rsp := geo_writers.NewUpdateResult()
+
+		if opts.DryRun {
+			rsp.Plan = plan.NewPlan()
+		}
+
+		return rsp, nil
+	}
+
+	return AssignReferences(ctx, opts, depiction_id, refs...)
+}
@@ -0,0 +1,143 @@
+package georeference
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+func TestRemoveReferences(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1527829811)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}},
+		&Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}},
+	}
+
+	_, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	r, err := RemoveReferences(ctx, opts, depiction_id, "sfomuseum:flightcover_to")
+
+	if err != nil {
+		t.Fatalf("Failed to remove reference, %v", err)
+	}
+
+	fc_body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	// Subject, depiction and deprecated alt file
+
+	deprecated_rsp := gjson.GetBytes(fc_body, `features.#(properties.src:alt_label=="georef_sfomuseum_flightcover_to").properties.edtf:deprecated`)
+
+	if !deprecated_rsp.Exists() {
+		t.Fatalf("Expected deprecated alt feature in feature collection, %s", string(fc_body))
+	}
+
+	alt_r, err := opts.DepictionReader.Read(ctx, "152/782/981/1/1527829811-alt-georef_sfomuseum_flightcover_to.geojson")
+
+	if err != nil {
+		t.Fatalf("Failed to read deprecated alt file, %v", err)
+	}
+
+	defer alt_r.Close()
+
+	alt_body, err := io.ReadAll(alt_r)
+
+	if err != nil {
+		t.Fatalf("Failed to read deprecated alt file body, %v", err)
+	}
+
+	if properties.Deprecated(alt_body) == "" {
+		t.Fatalf("Expected alt file to be deprecated")
+	}
+
+	if gjson.GetBytes(alt_body, "geometry.type").String() != "MultiPoint" {
+		t.Fatalf("Expected deprecated alt file to preserve its geometry")
+	}
+
+	body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	depicted, err := LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		t.Fatalf("Failed to load depicted, %v", err)
+	}
+
+	if len(depicted) != 1 || depicted[0].Label != "sfomuseum:flightcover_from" {
+		t.Fatalf("Unexpected georeferences after removal, %v", depicted)
+	}
+
+	alt_geoms, err := properties.AltGeometries(body)
+
+	if err != nil {
+		t.Fatalf("Failed to derive alt geometries, %v", err)
+	}
+
+	if slices.Contains(alt_geoms, "georef_sfomuseum_flightcover_to") {
+		t.Fatalf("Removed reference is still listed in src:geom_alt, %v", alt_geoms)
+	}
+
+	if !slices.Contains(alt_geoms, "georef_sfomuseum_flightcover_from") {
+		t.Fatalf("Remaining reference is missing from src:geom_alt, %v", alt_geoms)
+	}
+}
+
+func TestRemoveReferencesNoMatch(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1527829811)
+
+	_, err := AssignReferences(ctx, opts, depiction_id, &Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}})
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	before, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	r, err := RemoveReferences(ctx, opts, depiction_id, "sfomuseum:flightcover_to")
+
+	if err != nil {
+		t.Fatalf("Failed to remove reference, %v", err)
+	}
+
+	if len(r.Records) != 0 {
+		t.Fatalf("Expected no records to be written, got %d", len(r.Records))
+	}
+
+	after, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		t.Fatalf("Failed to load depiction, %v", err)
+	}
+
+	if string(before) != string(after) {
+		t.Fatalf("Expected depiction to be unchanged")
+	}
+}
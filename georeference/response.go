@@ -0,0 +1,31 @@
+package georeference
+
+import (
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+)
+
+// CombineResults merges the results returned by multiple calls to `AssignReferences` (or `AddReferences`,
+// `RemoveReferences`, etc.) in to a single `geo_writers.UpdateResult`. If 'dry_run' is true the combined result
+// will always contain a (possibly empty) `plan.Plan` combining the plans for each result. Any reference validations
+// (stored in the `RESERVED_VALIDATIONS_KEY` member) are merged.
+func CombineResults(dry_run bool, results ...*geo_writers.UpdateResult) *geo_writers.UpdateResult {
+
+	combined := geo_writers.NewUpdateResult()
+
+	if dry_run {
+		combined.Plan = plan.NewPlan()
+	}
+
+	validations := make([]*ReferenceValidation, 0)
+
+	for _, r := range results {
+		combined.Merge(r)
+		validations = append(validations, ResultValidations(r)...)
+	}
+
+	delete(combined.Members, RESERVED_VALIDATIONS_KEY)
+	AppendValidations(combined, validations...)
+
+	return combined
+}
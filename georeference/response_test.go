@@ -0,0 +1,73 @@
+package georeference
+
+import (
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/tidwall/gjson"
+)
+
+func TestCombineResults(t *testing.T) {
+
+	r_1 := geo_writers.NewUpdateResult()
+	r_1.Add(&geo_writers.UpdateRecord{Id: 1, Role: plan.SubjectRole, URI: "a.geojson", Body: []byte(`{"type":"Feature","id":1,"properties":{"wof:id":1},"geometry":{"type":"Point","coordinates":[0,0]}}`)})
+
+	r_2 := geo_writers.NewUpdateResult()
+	r_2.Add(&geo_writers.UpdateRecord{Id: 2, Role: plan.DepictionRole, URI: "b.geojson", Body: []byte(`{"type":"Feature","id":2,"properties":{"wof:id":2},"geometry":{"type":"Point","coordinates":[1,1]}}`)})
+	r_2.Add(&geo_writers.UpdateRecord{Id: 3, Role: plan.DepictionRole, URI: "c.geojson", Body: []byte(`{"type":"Feature","id":3,"properties":{"wof:id":3},"geometry":{"type":"Point","coordinates":[2,2]}}`)})
+
+	AppendValidations(r_1, &ReferenceValidation{Id: 1002})
+	AppendValidations(r_2, &ReferenceValidation{Id: 1003})
+
+	body, err := CombineResults(false, r_1, r_2).Response()
+
+	if err != nil {
+		t.Fatalf("Failed to combine feature collections, %v", err)
+	}
+
+	if gjson.GetBytes(body, "features.#").Int() != 3 {
+		t.Fatalf("Expected 3 features, %s", string(body))
+	}
+
+	if gjson.GetBytes(body, RESERVED_VALIDATIONS_KEY+".#").Int() != 2 {
+		t.Fatalf("Expected 2 validations, %s", string(body))
+	}
+
+	p_1 := geo_writers.NewUpdateResult()
+	p_1.Plan = plan.NewPlan()
+	p_1.Plan.Add(&plan.Change{Id: 1, Role: plan.DepictionRole, URI: "a.geojson", Action: plan.UpdateAction})
+
+	p_2 := geo_writers.NewUpdateResult()
+	p_2.Plan = plan.NewPlan()
+	p_2.Plan.Add(&plan.Change{Id: 2, Role: plan.SubjectRole, URI: "b.geojson", Action: plan.UpdateAction})
+
+	body, err = CombineResults(true, p_1, p_2).Response()
+
+	if err != nil {
+		t.Fatalf("Failed to combine plans, %v", err)
+	}
+
+	if gjson.GetBytes(body, "changes.#").Int() != 2 {
+		t.Fatalf("Expected 2 changes, %s", string(body))
+	}
+
+	body, err = CombineResults(true).Response()
+
+	if err != nil {
+		t.Fatalf("Failed to combine empty results, %v", err)
+	}
+
+	if !gjson.GetBytes(body, "changes").Exists() {
+		t.Fatalf("Expected empty dry run to return a plan, %s", string(body))
+	}
+
+	bad := geo_writers.NewUpdateResult()
+	bad.Add(&geo_writers.UpdateRecord{Id: 4, URI: "d.geojson", Body: []byte(`{`)})
+
+	_, err = CombineResults(false, bad).Response()
+
+	if err == nil {
+		t.Fatalf("Expected invalid record to fail")
+	}
+}
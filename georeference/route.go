@@ -0,0 +1,90 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/sfomuseum/go-sfomuseum-geo/alt"
+	"github.com/sfomuseum/go-sfomuseum-geo/geometry"
+)
+
+// FLIGHT_ROUTE_ALT_LABEL is the alternate geometry label for the great circle route derived from flight cover references.
+const FLIGHT_ROUTE_ALT_LABEL string = "georef_flight_route"
+
+// FLIGHT_ROUTE_LABELS is the ordered list of reference labels used to derive a flight route. A route is
+// traced through the IDs for each label, in the order the IDs are defined, and in the order of the labels.
+var FLIGHT_ROUTE_LABELS = []string{
+	"sfomuseum:flightcover_from",
+	"sfomuseum:flightcover_via",
+	"sfomuseum:flightcover_to",
+}
+
+// IsFlightRouteLabel returns a boolean value indicating whether 'label' is one of `FLIGHT_ROUTE_LABELS`.
+func IsFlightRouteLabel(label string) bool {
+	return slices.Contains(FLIGHT_ROUTE_LABELS, label)
+}
+
+// DeriveFlightRouteAltFeature returns a new `alt.WhosOnFirstAltFeature` instance for depiction 'depiction_id' whose geometry
+// is the great circle route through the points in 'route_points', a dictionary of points keyed by their reference label.
+// A route is only derived if there are points for both the first ("from") and last ("to") labels in `FLIGHT_ROUTE_LABELS`;
+// if not then the method will return nil (and no error).
+func DeriveFlightRouteAltFeature(ctx context.Context, depiction_id int64, depiction_repo string, src_geom string, route_points map[string][]orb.Point, route_ids map[string][]int64) (*alt.WhosOnFirstAltFeature, error) {
+
+	from_label := FLIGHT_ROUTE_LABELS[0]
+	to_label := FLIGHT_ROUTE_LABELS[len(FLIGHT_ROUTE_LABELS)-1]
+
+	if len(route_points[from_label]) == 0 || len(route_points[to_label]) == 0 {
+		return nil, nil
+	}
+
+	points := make([]orb.Point, 0)
+
+	alt_props := map[string]any{
+		"wof:id":        depiction_id,
+		"wof:repo":      depiction_repo,
+		"src:alt_label": FLIGHT_ROUTE_ALT_LABEL,
+		"src:geom":      src_geom,
+	}
+
+	for _, label := range FLIGHT_ROUTE_LABELS {
+
+		pts, ok := route_points[label]
+
+		if !ok {
+			continue
+		}
+
+		points = append(points, pts...)
+		alt_props[label] = route_ids[label]
+	}
+
+	// A route needs somewhere to go
+
+	distinct := make([]orb.Point, 0)
+
+	for _, pt := range points {
+		distinct = geometry.AddPointIfNotExist(distinct, pt)
+	}
+
+	if len(distinct) < 2 {
+		return nil, nil
+	}
+
+	route_geom, err := geometry.DeriveGreatCircleRoute(points...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive great circle route, %w", err)
+	}
+
+	alt_feature := &alt.WhosOnFirstAltFeature{
+		Type:       "Feature",
+		Id:         depiction_id,
+		Properties: alt_props,
+		Geometry:   geojson.NewGeometry(route_geom),
+	}
+
+	return alt_feature, nil
+}
@@ -0,0 +1,91 @@
+package georeference
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAssignReferencesFlightRoute(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	depiction_id := int64(1527829811)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:flightcover_from", Ids: []int64{101932003}},
+		&Reference{Label: "sfomuseum:flightcover_to", Ids: []int64{890413117}},
+	}
+
+	_, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	route_path := "152/782/981/1/1527829811-alt-georef_flight_route.geojson"
+
+	route_body, err := readTestFile(ctx, opts.DepictionReader, route_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read flight route alt file, %v", err)
+	}
+
+	geom_type := gjson.GetBytes(route_body, "geometry.type").String()
+
+	if geom_type != "LineString" {
+		t.Fatalf("Expected LineString geometry for flight route, got %s", geom_type)
+	}
+
+	if gjson.GetBytes(route_body, "properties.sfomuseum:flightcover_from.0").Int() != 101932003 {
+		t.Fatalf("Flight route is missing from reference, %s", string(route_body))
+	}
+
+	depiction_body, err := readTestFile(ctx, opts.DepictionReader, "152/782/981/1/1527829811.geojson")
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	if gjson.GetBytes(depiction_body, "geometry.type").String() != "MultiPoint" {
+		t.Fatalf("Expected depiction geometry to remain a MultiPoint")
+	}
+
+	if len(gjson.GetBytes(depiction_body, "geometry.coordinates").Array()) != 2 {
+		t.Fatalf("Expected depiction MultiPoint to contain only the referenced points, %s", gjson.GetBytes(depiction_body, "geometry").String())
+	}
+
+	found := false
+
+	for _, r := range gjson.GetBytes(depiction_body, "properties.src:geom_alt").Array() {
+
+		if r.String() == FLIGHT_ROUTE_ALT_LABEL {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		t.Fatalf("Expected src:geom_alt to contain %s", FLIGHT_ROUTE_ALT_LABEL)
+	}
+
+	// Removing the destination should deprecate the route
+
+	_, err = RemoveReferences(ctx, opts, depiction_id, "sfomuseum:flightcover_to")
+
+	if err != nil {
+		t.Fatalf("Failed to remove reference, %v", err)
+	}
+
+	route_body, err = readTestFile(ctx, opts.DepictionReader, route_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read flight route alt file, %v", err)
+	}
+
+	if !gjson.GetBytes(route_body, "properties.edtf:deprecated").Exists() {
+		t.Fatalf("Expected flight route to be deprecated")
+	}
+}
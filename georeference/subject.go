@@ -1,6 +1,7 @@
 package georeference
 
 import (
+	"cmp"
 	"context"
 	"crypto/md5"
 	"encoding/json"
@@ -13,6 +14,7 @@ import (
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/sfomuseum/go-sfomuseum-geo/fanout"
 	"github.com/sfomuseum/go-sfomuseum-geo/geometry"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
@@ -26,9 +28,8 @@ import (
 type SkipListItem struct {
 	// The geometry of the depiction
 	Geometry orb.Geometry
-	// The georef:depicted map for the depiction
-	// Sad-face pending a properly typed thing in assign.go
-	Depicted []map[string]any
+	// The georef:depicted entries for the depiction
+	Depicted []*GeoreferenceDepicted
 }
 
 // RecompileGeorefencesForSubjectOptions defines configuration options for invoking
@@ -44,6 +45,15 @@ type RecompileGeorefencesForSubjectOptions struct {
 	DefaultGeometryFeatureId int64
 	// SkipList is a dicitionary of pre-determined georeferencing information keyed by the depiction (image) ID in question.
 	SkipList map[int64]*SkipListItem
+	// Membership is used to derive the list of depictions (images) for the subject. If nil the `millsfield:images` property
+	// of the subject is used. Depictions in 'SkipList' are always considered to be members of the subject.
+	Membership SubjectMembership
+	// ReaderCacheURI is an optional `cache.NewCache` URI. If defined the `WhosOnFirstReader` and `SFOMuseumReader` readers are
+	// placed behind a cache created for each call to `RecompileGeorefencesForSubject`.
+	ReaderCacheURI string
+	// Concurrency is the maximum number of records to read at once. If zero (or less) the limit defined by
+	// `fanout.WithLimit` for the context, or `fanout.DEFAULT_LIMIT`, is used.
+	Concurrency int
 }
 
 // RecompileGeorefencesForSubject rebuilds all the revelent "georef:" properties for a subject (object) derived
@@ -55,7 +65,7 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 	subject_depicted_key := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)
 	subject_depictions_key := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTIONS)
 	subject_belongsto_key := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_BELONGSTO)
-	geotag_depicted_key := fmt.Sprintf("properties.%s", geo.RESERVED_GEOTAG_DEPICTIONS)
+	subject_provenance_key := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_PROVENANCE)
 
 	logger := slog.Default()
 
@@ -67,6 +77,31 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 
 	logger = logger.With("subject id", subject_id)
 
+	if opts.ReaderCacheURI != "" {
+
+		whosonfirst_reader, sfomuseum_reader, log_stats, err := cacheReaders(ctx, opts.ReaderCacheURI, opts.WhosOnFirstReader, opts.SFOMuseumReader)
+
+		if err != nil {
+			return false, nil, err
+		}
+
+		defer log_stats()
+
+		cached_opts := *opts
+		cached_opts.WhosOnFirstReader = whosonfirst_reader
+		cached_opts.SFOMuseumReader = sfomuseum_reader
+		cached_opts.ReaderCacheURI = ""
+
+		opts = &cached_opts
+	}
+
+	// Ensure that any other functions which process records concurrently (for example geometry.DeriveMultiPointFromIds)
+	// apply the same limit
+
+	if opts.Concurrency > 0 {
+		ctx = fanout.WithLimit(ctx, opts.Concurrency)
+	}
+
 	count_skiplist := 0
 
 	if opts.SkipList != nil {
@@ -89,6 +124,9 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 	subject_depictions := make([]int64, 0)
 	subject_belongsto := make([]int64, 0)
 
+	// The provenance of each georeference label keyed by label
+	subject_provenance := make(map[string][]*DepictionProvenance)
+
 	type image_ref struct {
 		// The depiction of the subject
 		depiction int64
@@ -96,24 +134,40 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 		label string
 		// The place being depicted
 		place_id int64
+		// The provenance of the georeference label in the depiction
+		provenance *Provenance
+	}
+
+	membership := opts.Membership
+
+	if membership == nil {
+		membership = NewPropertyMembership("millsfield:images")
+	}
+
+	images_list, err := membership.Depictions(ctx, subject_body)
+
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to derive depictions for subject, %w", err)
 	}
 
-	im_done_ch := make(chan bool)
-	im_err_ch := make(chan error)
-	im_ref_ch := make(chan image_ref)
+	skiplist_ids := make([]int64, 0)
 
-	im_remaining := 0
+	for image_id, _ := range opts.SkipList {
 
-	// Replace with georeferences array defined above?
+		if !slices.Contains(images_list, image_id) {
+			skiplist_ids = append(skiplist_ids, image_id)
+		}
+	}
 
-	images_rsp := gjson.GetBytes(subject_body, "properties.millsfield:images")
-	images_list := images_rsp.Array()
+	slices.Sort(skiplist_ids)
+	images_list = append(images_list, skiplist_ids...)
 
 	logger.Debug("Process images for subject", "count", len(images_list))
 
-	for _, r := range images_list {
+	// The list of images which are not in the skip list and need to be loaded
+	to_load := make([]int64, 0)
 
-		image_id := r.Int()
+	for _, image_id := range images_list {
 
 		logger.Debug("Derive georef details from image", "id", image_id)
 
@@ -131,11 +185,10 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 
 			for _, d := range skiplist_item.Depicted {
 
-				// Sad-face type assertions pending a properly typed thing in assign.go
-				label := d[geo.RESERVED_GEOREFERENCE_LABEL].(string)
-				ids := d[geo.RESERVED_WOF_DEPICTS].([]int64)
+				label := d.Label
+				subject_provenance[label] = appendDepictionProvenance(subject_provenance[label], image_id, d.Provenance())
 
-				for _, place_id := range ids {
+				for _, place_id := range d.Depicts {
 
 					depicted_ids, exists := subject_depicted[label]
 
@@ -158,87 +211,80 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 			continue
 		}
 
-		im_remaining += 1
-		logger.Debug("Schedule image for processing", "image id", image_id, "count", im_remaining)
+		logger.Debug("Schedule image for processing", "image id", image_id)
+		to_load = append(to_load, image_id)
+	}
 
-		go func(image_id int64) {
+	// Load the remaining images, at most opts.Concurrency at once, and derive their (geo)refs
 
-			defer func() {
-				im_done_ch <- true
-			}()
+	images_refs, err := fanout.Map(ctx, opts.Concurrency, to_load, func(ctx context.Context, image_id int64) ([]image_ref, error) {
 
-			logger.Debug("Load image", "image id", image_id)
+		logger.Debug("Load image", "image id", image_id)
 
-			image_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, image_id)
+		image_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, image_id)
 
-			if err != nil {
-				im_err_ch <- fmt.Errorf("Failed to read image ID %d, %w", image_id, err)
-				return
-			}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read image ID %d, %w", image_id, err)
+		}
 
-			georefs_path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED)
-			georefs_rsp := gjson.GetBytes(image_body, georefs_path)
+		depicted, err := LoadGeoreferenceDepicted(image_body)
 
-			logger.Debug("Depictions for image", "image_id", image_id, "key", geo.RESERVED_GEOREFERENCE_DEPICTED, "count", len(georefs_rsp.Array()))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load georeferences for image ID %d, %w", image_id, err)
+		}
 
-			for _, r := range georefs_rsp.Array() {
+		logger.Debug("Depictions for image", "image_id", image_id, "key", geo.RESERVED_GEOREFERENCE_DEPICTED, "count", len(depicted))
 
-				label := r.Get(geo.RESERVED_GEOREFERENCE_LABEL).String()
-				ids := r.Get(geo.RESERVED_WOF_DEPICTS)
+		refs := make([]image_ref, 0)
 
-				for _, i := range ids.Array() {
-					place_id := i.Int()
-					logger.Debug("Dispatch image", "image", image_id, "key", label, "place", place_id)
-					im_ref_ch <- image_ref{label: label, place_id: place_id, depiction: image_id}
-				}
+		for _, d := range depicted {
+
+			for _, place_id := range d.Depicts {
+				logger.Debug("Dispatch image", "image", image_id, "key", d.Label, "place", place_id)
+				refs = append(refs, image_ref{label: d.Label, place_id: place_id, depiction: image_id, provenance: d.Provenance()})
 			}
+		}
 
-		}(image_id)
-	}
+		return refs, nil
+	})
 
-	// Wait...
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to denormalize georeference properties, %w", err)
+	}
 
-	if im_remaining > 0 {
+	for _, refs := range images_refs {
 
-		logger.Debug("Waiting for image processing to complete", "remaining", im_remaining)
+		for _, ref := range refs {
 
-		for im_remaining > 0 {
-			select {
-			case <-im_done_ch:
-				im_remaining -= 1
-				logger.Debug("Image processing completed", "remaining", im_remaining)
-			case err := <-im_err_ch:
-				return false, nil, fmt.Errorf("Failed to denormalize georeference properties, %w", err)
-			case ref := <-im_ref_ch:
+			label := ref.label
+			depiction_id := ref.depiction
+			place_id := ref.place_id
 
-				label := ref.label
-				depiction_id := ref.depiction
-				place_id := ref.place_id
+			logger.Debug("Update subject (geo)refs for image", "id", place_id, "label", label)
 
-				logger.Debug("Update subject (geo)refs for image", "id", place_id, "label", label)
+			if !slices.Contains(subject_depictions, depiction_id) {
+				subject_depictions = append(subject_depictions, depiction_id)
+			}
 
-				if !slices.Contains(subject_depictions, depiction_id) {
-					subject_depictions = append(subject_depictions, depiction_id)
-				}
+			subject_provenance[label] = appendDepictionProvenance(subject_provenance[label], depiction_id, ref.provenance)
 
-				// Update wof:references for subject
-				// subject_belongsto_lookup.Store(id, true)
+			// Update wof:references for subject
+			// subject_belongsto_lookup.Store(id, true)
 
-				depicted_ids, exists := subject_depicted[label]
+			depicted_ids, exists := subject_depicted[label]
 
-				if !exists {
-					depicted_ids = make([]int64, 0)
-				}
+			if !exists {
+				depicted_ids = make([]int64, 0)
+			}
 
-				if !slices.Contains(depicted_ids, place_id) {
-					depicted_ids = append(depicted_ids, place_id)
-				}
+			if !slices.Contains(depicted_ids, place_id) {
+				depicted_ids = append(depicted_ids, place_id)
+			}
 
-				subject_depicted[label] = depicted_ids
+			subject_depicted[label] = depicted_ids
 
-				if !slices.Contains(subject_belongsto, place_id) {
-					subject_belongsto = append(subject_belongsto, place_id)
-				}
+			if !slices.Contains(subject_belongsto, place_id) {
+				subject_belongsto = append(subject_belongsto, place_id)
 			}
 		}
 	}
@@ -246,6 +292,20 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 	subject_updates[subject_depicted_key] = subject_depicted
 	subject_updates[subject_depictions_key] = subject_depictions
 
+	// Only assign georef:provenance if there is provenance to record or if there is
+	// an existing property that needs to be cleared.
+
+	for _, entries := range subject_provenance {
+
+		slices.SortFunc(entries, func(a *DepictionProvenance, b *DepictionProvenance) int {
+			return cmp.Compare(a.DepictionId, b.DepictionId)
+		})
+	}
+
+	if len(subject_provenance) > 0 || gjson.GetBytes(subject_body, subject_provenance_key).Exists() {
+		subject_updates[subject_provenance_key] = subject_provenance
+	}
+
 	// START OF inflate belongs to array to include ancestors and derived deduplicated hierarchies
 
 	logger.Debug("Inflate belongs to and derive hierarchies")
@@ -253,57 +313,43 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 	combined_belongsto_map := new(sync.Map)
 	combined_hiers_map := new(sync.Map)
 
-	done_ch := make(chan bool)
-	err_ch := make(chan error)
-
-	for _, id := range subject_belongsto {
-
-		go func(id int64) {
+	// Records which can not be loaded are logged but do not cause the subject to fail
 
-			defer func() {
-				done_ch <- true
-			}()
+	err = fanout.Each(ctx, opts.Concurrency, subject_belongsto, func(ctx context.Context, id int64) error {
 
-			logger.Debug("Inflate belongs and derive hierarchy for georeference", "belongsto id", id)
+		logger.Debug("Inflate belongs and derive hierarchy for georeference", "belongsto id", id)
 
-			belongsto_body, err := wof_reader.LoadBytes(ctx, opts.WhosOnFirstReader, id)
+		belongsto_body, err := wof_reader.LoadBytes(ctx, opts.WhosOnFirstReader, id)
 
-			if err != nil {
-				combined_belongsto_map.Store(id, true)
-				err_ch <- fmt.Errorf("Failed to load record for %d, %w", id, err)
-				return
-			}
-
-			belongsto_hiers := properties.Hierarchies(belongsto_body)
+		if err != nil {
+			combined_belongsto_map.Store(id, true)
+			logger.Warn("Failed to inflate belongs to", "error", fmt.Errorf("Failed to load record for %d, %w", id, err))
+			return nil
+		}
 
-			for _, h := range belongsto_hiers {
+		belongsto_hiers := properties.Hierarchies(belongsto_body)
 
-				enc_h, err := json.Marshal(h)
+		for _, h := range belongsto_hiers {
 
-				if err != nil {
-					logger.Error("Failed to marshal hierarchy", "error", err)
-				} else {
-					md5_h := fmt.Sprintf("%x", md5.Sum(enc_h))
-					combined_hiers_map.Store(md5_h, h)
-				}
+			enc_h, err := json.Marshal(h)
 
-				for _, h_id := range h {
-					combined_belongsto_map.Store(h_id, true)
-				}
+			if err != nil {
+				logger.Error("Failed to marshal hierarchy", "error", err)
+			} else {
+				md5_h := fmt.Sprintf("%x", md5.Sum(enc_h))
+				combined_hiers_map.Store(md5_h, h)
 			}
 
-		}(id)
-	}
+			for _, h_id := range h {
+				combined_belongsto_map.Store(h_id, true)
+			}
+		}
 
-	remaining := len(subject_belongsto)
+		return nil
+	})
 
-	for remaining > 0 {
-		select {
-		case <-done_ch:
-			remaining -= 1
-		case err := <-err_ch:
-			logger.Warn("Failed to inflate belongs to", "error", err)
-		}
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to inflate belongs to, %w", err)
 	}
 
 	combined_hiers := make([]map[string]int64, 0)
@@ -335,11 +381,13 @@ func RecompileGeorefencesForSubject(ctx context.Context, opts *RecompileGeorefen
 
 	// Read geotag pointers from subject file
 
-	geotag_depicted_rsp := gjson.GetBytes(subject_body, geotag_depicted_key)
+	geotag_depictions, err := LoadGeotagDepictions(subject_body)
 
-	for _, r := range geotag_depicted_rsp.Array() {
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to load geotag depictions for subject, %w", err)
+	}
 
-		id := r.Int()
+	for _, id := range geotag_depictions {
 
 		if !slices.Contains(geom_ids, id) {
 			logger.Debug("Add subject geom ID (geotag) to lookup", "id", id)
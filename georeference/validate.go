@@ -0,0 +1,327 @@
+package georeference
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader/v2"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+)
+
+// RESERVED_VALIDATIONS_KEY is the top-level key in the response returned by `AssignReferences` (and friends) under which
+// the list of `ReferenceValidation` records describing any non-current references is stored.
+const RESERVED_VALIDATIONS_KEY string = "georef:validations"
+
+// MAX_SUPERSESSION_DEPTH is the maximum number of `wof:superseded_by` links that will be followed for a single reference.
+const MAX_SUPERSESSION_DEPTH int = 10
+
+// ValidationMode defines how references to non-current (deprecated, superseded or not current) Who's On First records are handled.
+type ValidationMode string
+
+const (
+	// ValidationWarn records, and logs, references to non-current records but otherwise stores them as-is. This is the default mode.
+	ValidationWarn ValidationMode = "warn"
+	// ValidationFollow follows `wof:superseded_by` chains and replaces superseded references with the current record. References
+	// to deprecated or not current records which have not been superseded are recorded and stored as-is.
+	ValidationFollow ValidationMode = "follow"
+	// ValidationRefuse returns an error if any reference is to a non-current record.
+	ValidationRefuse ValidationMode = "refuse"
+)
+
+// Reference validation statuses
+const (
+	// StatusSuperseded signals that a record has a non-empty `wof:superseded_by` property.
+	StatusSuperseded string = "superseded"
+	// StatusDeprecated signals that a record has a non-empty `edtf:deprecated` property.
+	StatusDeprecated string = "deprecated"
+	// StatusNotCurrent signals that a record has a `mz:is_current` property equal to 0.
+	StatusNotCurrent string = "not_current"
+)
+
+// Reference validation actions
+const (
+	// ActionWarned signals that a non-current reference was recorded but stored as-is.
+	ActionWarned string = "warned"
+	// ActionFollowed signals that a superseded reference was replaced by the record it was (eventually) superseded by.
+	ActionFollowed string = "followed"
+	// ActionRefused signals that a non-current reference caused the update to fail.
+	ActionRefused string = "refused"
+)
+
+// ParseValidationMode returns the `ValidationMode` for 'str_mode'. An empty string returns `ValidationWarn`.
+func ParseValidationMode(str_mode string) (ValidationMode, error) {
+
+	switch ValidationMode(strings.ToLower(str_mode)) {
+	case "", ValidationWarn:
+		return ValidationWarn, nil
+	case ValidationFollow:
+		return ValidationFollow, nil
+	case ValidationRefuse:
+		return ValidationRefuse, nil
+	default:
+		return "", fmt.Errorf("Invalid validation mode '%s'", str_mode)
+	}
+}
+
+// ReferenceValidation records a reference to a non-current Who's On First record and what was done about it.
+type ReferenceValidation struct {
+	// The label of the reference.
+	Label string `json:"georef:label"`
+	// The Who's On First ID that was referenced.
+	Id int64 `json:"wof:id"`
+	// The list of statuses (`StatusSuperseded`, `StatusDeprecated`, `StatusNotCurrent`) for the record.
+	Status []string `json:"status"`
+	// The action taken (`ActionWarned`, `ActionFollowed`, `ActionRefused`).
+	Action string `json:"action"`
+	// The Who's On First ID that was stored in place of 'Id'. This is only set if 'Action' is `ActionFollowed`.
+	ResolvedId int64 `json:"resolved_id,omitempty"`
+	// The list of Who's On First IDs, starting with 'Id', followed to derive 'ResolvedId'.
+	Chain []int64 `json:"chain,omitempty"`
+}
+
+// String returns a human-readable representation of 'v'.
+func (v *ReferenceValidation) String() string {
+
+	str := fmt.Sprintf("%s %d (%s) %s", v.Label, v.Id, strings.Join(v.Status, ","), v.Action)
+
+	if v.ResolvedId != 0 {
+		str = fmt.Sprintf("%s to %d", str, v.ResolvedId)
+	}
+
+	return str
+}
+
+// ValidationError is the error returned when one or more references are refused.
+type ValidationError struct {
+	// The list of references that were refused.
+	Validations []*ReferenceValidation
+}
+
+// Error returns a string representation of 'e'.
+func (e *ValidationError) Error() string {
+
+	refused := make([]string, len(e.Validations))
+
+	for idx, v := range e.Validations {
+		refused[idx] = v.String()
+	}
+
+	return fmt.Sprintf("One or more references are not current: %s", strings.Join(refused, "; "))
+}
+
+// AppendValidations assigns 'validations' to the `RESERVED_VALIDATIONS_KEY` member of 'r', appending them to any existing
+// validations. If 'validations' is empty 'r' is left unchanged.
+func AppendValidations(r *geo_writers.UpdateResult, validations ...*ReferenceValidation) {
+
+	if len(validations) == 0 {
+		return
+	}
+
+	if r.Members == nil {
+		r.Members = make(map[string]any)
+	}
+
+	all_validations := append(ResultValidations(r), validations...)
+	r.Members[RESERVED_VALIDATIONS_KEY] = all_validations
+}
+
+// ResultValidations returns the list of `ReferenceValidation` records assigned to the `RESERVED_VALIDATIONS_KEY` member of 'r'.
+func ResultValidations(r *geo_writers.UpdateResult) []*ReferenceValidation {
+
+	validations, ok := r.Members[RESERVED_VALIDATIONS_KEY].([]*ReferenceValidation)
+
+	if !ok {
+		return make([]*ReferenceValidation, 0)
+	}
+
+	return slices.Clone(validations)
+}
+
+// recordStatus returns the list of statuses for the Who's On First record in 'body' and the list of IDs it has been superseded by.
+func recordStatus(body []byte) ([]string, []int64) {
+
+	status := make([]string, 0)
+	superseded_by := make([]int64, 0)
+
+	for _, r := range gjson.GetBytes(body, "properties.wof:superseded_by").Array() {
+
+		id := r.Int()
+
+		if id > 0 && !slices.Contains(superseded_by, id) {
+			superseded_by = append(superseded_by, id)
+		}
+	}
+
+	if len(superseded_by) > 0 {
+		status = append(status, StatusSuperseded)
+	}
+
+	deprecated_rsp := gjson.GetBytes(body, "properties.edtf:deprecated")
+
+	if deprecated_rsp.Exists() && deprecated_rsp.String() != "" {
+		status = append(status, StatusDeprecated)
+	}
+
+	current_rsp := gjson.GetBytes(body, "properties.mz:is_current")
+
+	if current_rsp.Exists() && current_rsp.Int() == 0 {
+		status = append(status, StatusNotCurrent)
+	}
+
+	return status, superseded_by
+}
+
+// ValidateReferences checks that each of the Who's On First IDs in 'refs', read from 'r', are current records and handles those
+// that are not according to 'mode'. It returns a new list of `Reference` instances (with superseded IDs replaced if 'mode' is
+// `ValidationFollow`), the list of `ReferenceValidation` records describing what was done and a dictionary of the record bodies
+// that were read, keyed by ID, so they don't need to be read again. If 'mode' is `ValidationRefuse` and any record is not current
+// a `ValidationError` is returned.
+func ValidateReferences(ctx context.Context, r reader.Reader, mode ValidationMode, refs ...*Reference) ([]*Reference, []*ReferenceValidation, map[int64][]byte, error) {
+
+	logger := slog.Default()
+
+	if mode == "" {
+		mode = ValidationWarn
+	}
+
+	bodies := make(map[int64][]byte)
+
+	load := func(id int64) ([]byte, error) {
+
+		if body, ok := bodies[id]; ok {
+			return body, nil
+		}
+
+		body, err := wof_reader.LoadBytes(ctx, r, id)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read record for WOF ID %d, %w", id, err)
+		}
+
+		bodies[id] = body
+		return body, nil
+	}
+
+	validated := make([]*Reference, len(refs))
+	validations := make([]*ReferenceValidation, 0)
+	refused := make([]*ReferenceValidation, 0)
+
+	for ref_idx, ref := range refs {
+
+		new_ref := *ref
+		new_ref.Ids = make([]int64, 0)
+
+		for _, id := range ref.Ids {
+
+			body, err := load(id)
+
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			status, superseded_by := recordStatus(body)
+
+			if len(status) == 0 {
+
+				if !slices.Contains(new_ref.Ids, id) {
+					new_ref.Ids = append(new_ref.Ids, id)
+				}
+
+				continue
+			}
+
+			v := &ReferenceValidation{
+				Label:  ref.Label,
+				Id:     id,
+				Status: status,
+				Action: ActionWarned,
+			}
+
+			resolved_id := id
+
+			switch mode {
+			case ValidationRefuse:
+
+				v.Action = ActionRefused
+				refused = append(refused, v)
+
+			case ValidationFollow:
+
+				if len(superseded_by) == 0 {
+					break
+				}
+
+				chain, err := followSupersession(id, superseded_by, load)
+
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("Failed to follow supersession chain for %d (%s), %w", id, ref.Label, err)
+				}
+
+				resolved_id = chain[len(chain)-1]
+
+				v.Action = ActionFollowed
+				v.ResolvedId = resolved_id
+				v.Chain = chain
+			}
+
+			logger.Warn("Reference is not current", "label", ref.Label, "id", id, "status", status, "action", v.Action, "resolved id", resolved_id)
+			validations = append(validations, v)
+
+			if !slices.Contains(new_ref.Ids, resolved_id) {
+				new_ref.Ids = append(new_ref.Ids, resolved_id)
+			}
+		}
+
+		validated[ref_idx] = &new_ref
+	}
+
+	if len(refused) > 0 {
+		return nil, nil, nil, &ValidationError{Validations: refused}
+	}
+
+	return validated, validations, bodies, nil
+}
+
+// followSupersession follows the `wof:superseded_by` chain starting with 'id' (which has been superseded by 'superseded_by')
+// until it reaches a record which has not been superseded and returns the list of IDs in the chain. Chains which split (a record
+// superseded by more than one record), loop or exceed `MAX_SUPERSESSION_DEPTH` return an error.
+func followSupersession(id int64, superseded_by []int64, load func(int64) ([]byte, error)) ([]int64, error) {
+
+	chain := []int64{
+		id,
+	}
+
+	for len(superseded_by) > 0 {
+
+		if len(superseded_by) > 1 {
+			return nil, fmt.Errorf("%d has been superseded by multiple records (%v)", chain[len(chain)-1], superseded_by)
+		}
+
+		next_id := superseded_by[0]
+
+		if slices.Contains(chain, next_id) {
+			return nil, fmt.Errorf("Supersession chain %v loops back to %d", chain, next_id)
+		}
+
+		chain = append(chain, next_id)
+
+		if len(chain) > MAX_SUPERSESSION_DEPTH {
+			return nil, fmt.Errorf("Supersession chain %v exceeds maximum depth", chain)
+		}
+
+		body, err := load(next_id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, superseded_by = recordStatus(body)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,308 @@
+package georeference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// writeValidationRecord writes a minimal Who's On First record for 'id', with the additional properties in
+// 'props', to the "data" directory of 'root'.
+func writeValidationRecord(t *testing.T, root string, id int64, props string) {
+
+	rel_path, err := uri.Id2RelPath(id)
+
+	if err != nil {
+		t.Fatalf("Failed to derive path for %d, %v", id, err)
+	}
+
+	path := filepath.Join(root, "data", rel_path)
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create directory for %d, %v", id, err)
+	}
+
+	body := fmt.Sprintf(`{"type":"Feature","properties":{"wof:id":%d,"wof:name":"Place %d","wof:placetype":"locality","wof:repo":"whosonfirst-data-admin"%s},"geometry":{"type":"Point","coordinates":[100.5,13.75]}}`, id, id, props)
+
+	err = os.WriteFile(path, []byte(body), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write record for %d, %v", id, err)
+	}
+}
+
+func setupValidationReader(t *testing.T) reader.Reader {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	writeValidationRecord(t, root, 1001, ``)
+	writeValidationRecord(t, root, 1002, `,"mz:is_current":0,"wof:superseded_by":[1003]`)
+	writeValidationRecord(t, root, 1003, `,"mz:is_current":0,"wof:superseded_by":[1001]`)
+	writeValidationRecord(t, root, 1004, `,"mz:is_current":0,"edtf:deprecated":"2024-01-01"`)
+	writeValidationRecord(t, root, 1005, `,"wof:superseded_by":[1001,1003]`)
+	writeValidationRecord(t, root, 1006, `,"wof:superseded_by":[1007]`)
+	writeValidationRecord(t, root, 1007, `,"wof:superseded_by":[1006]`)
+
+	r, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	return r
+}
+
+func TestParseValidationMode(t *testing.T) {
+
+	tests := map[string]ValidationMode{
+		"":       ValidationWarn,
+		"warn":   ValidationWarn,
+		"FOLLOW": ValidationFollow,
+		"refuse": ValidationRefuse,
+	}
+
+	for str_mode, expected := range tests {
+
+		mode, err := ParseValidationMode(str_mode)
+
+		if err != nil {
+			t.Fatalf("Failed to parse '%s', %v", str_mode, err)
+		}
+
+		if mode != expected {
+			t.Fatalf("Expected '%s' to be %s, got %s", str_mode, expected, mode)
+		}
+	}
+
+	_, err := ParseValidationMode("ignore")
+
+	if err == nil {
+		t.Fatalf("Expected invalid mode to fail")
+	}
+}
+
+func TestValidateReferencesFollow(t *testing.T) {
+
+	ctx := context.Background()
+	r := setupValidationReader(t)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{1002, 1004}},
+		&Reference{Label: "sfomuseum:from", Ids: []int64{1001}},
+	}
+
+	validated, validations, bodies, err := ValidateReferences(ctx, r, ValidationFollow, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to validate references, %v", err)
+	}
+
+	if !slices.Equal(validated[0].Ids, []int64{1001, 1004}) {
+		t.Fatalf("Unexpected IDs for first reference: %v", validated[0].Ids)
+	}
+
+	if !slices.Equal(validated[1].Ids, []int64{1001}) {
+		t.Fatalf("Unexpected IDs for second reference: %v", validated[1].Ids)
+	}
+
+	if !slices.Equal(refs[0].Ids, []int64{1002, 1004}) {
+		t.Fatalf("Expected input references to be left unchanged, got %v", refs[0].Ids)
+	}
+
+	if len(validations) != 2 {
+		t.Fatalf("Expected 2 validations, got %d", len(validations))
+	}
+
+	followed := validations[0]
+
+	if followed.Action != ActionFollowed || followed.ResolvedId != 1001 || !slices.Equal(followed.Chain, []int64{1002, 1003, 1001}) {
+		t.Fatalf("Unexpected validation for superseded record: %v", followed)
+	}
+
+	warned := validations[1]
+
+	if warned.Action != ActionWarned || !slices.Equal(warned.Status, []string{StatusDeprecated, StatusNotCurrent}) {
+		t.Fatalf("Unexpected validation for deprecated record: %v", warned)
+	}
+
+	for _, id := range []int64{1001, 1002, 1003, 1004} {
+
+		_, ok := bodies[id]
+
+		if !ok {
+			t.Fatalf("Expected body for %d to be cached", id)
+		}
+	}
+}
+
+func TestValidateReferencesWarn(t *testing.T) {
+
+	ctx := context.Background()
+	r := setupValidationReader(t)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{1002}},
+	}
+
+	validated, validations, _, err := ValidateReferences(ctx, r, "", refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to validate references, %v", err)
+	}
+
+	if !slices.Equal(validated[0].Ids, []int64{1002}) {
+		t.Fatalf("Expected superseded record to be stored as-is, got %v", validated[0].Ids)
+	}
+
+	if len(validations) != 1 || validations[0].Action != ActionWarned {
+		t.Fatalf("Expected a single warning, got %v", validations)
+	}
+}
+
+func TestValidateReferencesRefuse(t *testing.T) {
+
+	ctx := context.Background()
+	r := setupValidationReader(t)
+
+	refs := []*Reference{
+		&Reference{Label: "sfomuseum:depicts", Ids: []int64{1001, 1004}},
+	}
+
+	_, _, _, err := ValidateReferences(ctx, r, ValidationRefuse, refs...)
+
+	var validation_err *ValidationError
+
+	if !errors.As(err, &validation_err) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	if len(validation_err.Validations) != 1 || validation_err.Validations[0].Id != 1004 {
+		t.Fatalf("Unexpected refused references: %v", validation_err)
+	}
+
+	_, _, _, err = ValidateReferences(ctx, r, ValidationRefuse, &Reference{Label: "sfomuseum:depicts", Ids: []int64{1001}})
+
+	if err != nil {
+		t.Fatalf("Expected current references to be accepted, %v", err)
+	}
+}
+
+func TestValidateReferencesInvalidChains(t *testing.T) {
+
+	ctx := context.Background()
+	r := setupValidationReader(t)
+
+	for _, id := range []int64{1005, 1006} {
+
+		_, _, _, err := ValidateReferences(ctx, r, ValidationFollow, &Reference{Label: "sfomuseum:depicts", Ids: []int64{id}})
+
+		if err == nil {
+			t.Fatalf("Expected supersession chain for %d to fail", id)
+		}
+	}
+}
+
+func TestAssignReferencesValidation(t *testing.T) {
+
+	ctx := context.Background()
+	opts := setupAssignReferencesOptions(t)
+
+	path_fixtures, err := filepath.Abs("../fixtures/whosonfirst-data-admin")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	root := t.TempDir()
+
+	err = os.CopyFS(root, os.DirFS(path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to copy whosonfirst-data-admin fixtures, %v", err)
+	}
+
+	// A (fictional) record superseded by Bangkok
+	writeValidationRecord(t, root, 1002, `,"mz:is_current":0,"wof:superseded_by":[102025263]`)
+
+	wof_reader, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	opts.WhosOnFirstReader = wof_reader
+	opts.ReferenceValidation = ValidationFollow
+
+	depiction_id := int64(1897903961)
+
+	refs := []*Reference{
+		&Reference{
+			Label: "sfomuseum:depicts",
+			Ids:   []int64{1002},
+		},
+	}
+
+	r, err := AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	validations_rsp := gjson.GetBytes(body, RESERVED_VALIDATIONS_KEY)
+
+	if len(validations_rsp.Array()) != 1 || validations_rsp.Get("0.resolved_id").Int() != 102025263 {
+		t.Fatalf("Unexpected validations, %s", validations_rsp.String())
+	}
+
+	depicts_rsp := gjson.GetBytes(body, "features.1.properties.georef:depicted.0.wof:depicts")
+
+	if !slices.Contains(intArray(depicts_rsp), 102025263) || slices.Contains(intArray(depicts_rsp), 1002) {
+		t.Fatalf("Expected superseded reference to be replaced, got %s", depicts_rsp.String())
+	}
+
+	combined, err := CombineResults(false, r, r).Response()
+
+	if err != nil {
+		t.Fatalf("Failed to combine results, %v", err)
+	}
+
+	if len(gjson.GetBytes(combined, RESERVED_VALIDATIONS_KEY).Array()) != 2 {
+		t.Fatalf("Expected combined response to contain 2 validations, %s", gjson.GetBytes(combined, RESERVED_VALIDATIONS_KEY).String())
+	}
+
+	opts.ReferenceValidation = ValidationRefuse
+
+	_, err = AssignReferences(ctx, opts, depiction_id, refs...)
+
+	if err == nil {
+		t.Fatalf("Expected superseded reference to be refused")
+	}
+}
+
+func intArray(rsp gjson.Result) []int64 {
+
+	ids := make([]int64, 0)
+
+	for _, r := range rsp.Array() {
+		ids = append(ids, r.Int())
+	}
+
+	return ids
+}
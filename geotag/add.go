@@ -14,7 +14,9 @@ import (
 	"github.com/sfomuseum/go-sfomuseum-geo"
 	"github.com/sfomuseum/go-sfomuseum-geo/alt"
 	"github.com/sfomuseum/go-sfomuseum-geo/geometry"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
 	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
 	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-ioutil"
@@ -42,6 +44,15 @@ type AddGeotagDepictionOptions struct {
 	WhosOnFirstReader reader.Reader
 	// The name of the person (or process) updating a depiction.
 	Author string
+	// GitHubAPIURL is an optional base URL for the GitHub API used when `githubapi-pr://` writers are grouped in to a
+	// single coordinated change set. If empty the default GitHub API endpoint is used.
+	GitHubAPIURL string
+	// GitHubTemplates are optional `text/template` templates used to derive the commit messages, pull request titles and
+	// descriptions and branch names for GitHub writers. If nil the default templates are used.
+	GitHubTemplates *github.Templates
+	// DryRun is a boolean flag signaling that no data should be written. Instead a JSON-encoded `plan.Plan` describing
+	// the records that would be written, and how they would change, is returned.
+	DryRun bool
 }
 
 // AddGeotagDepiction will update the geometries and relevant properties for SFOM/WOF records 'depiction_id' and 'subject_id' using
@@ -74,8 +85,9 @@ type AddGeotagDepictionOptions struct {
 // After exporting and writing the depiction record a new alternate geometry (`geotag-fov`) is created for the depiction.
 // - Its geometry is assigned the field of view (line string) of the 'geotag_f' feature.
 //
-// Finally the alternate geometry is exported and written (to `opts.DepictionWriter`).
-func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, update *Depiction) ([]byte, error) {
+// Finally the alternate geometry is exported and written (to `opts.DepictionWriter`). The `geo_writers.UpdateResult` returned lists
+// the subject, depiction and alternate geometry records that were written (or, if 'opts.DryRun' is true, that would be written).
+func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, update *Depiction) (*geo_writers.UpdateResult, error) {
 
 	depiction_id := update.DepictionId
 	geotag_f := update.Feature
@@ -96,12 +108,24 @@ func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, up
 		Author:        opts.Author,
 		WhosOnFirstId: depiction_id,
 		Action:        github.GeotagAction,
+		Templates:     opts.GitHubTemplates,
+	}
+
+	var update_plan *plan.Plan
+
+	if opts.DryRun {
+		logger.Debug("Dry run enabled, record changes to plan")
+		update_plan = plan.NewPlan()
 	}
 
 	writers_opts := &geo_writers.CreateWritersOptions{
 		SubjectWriterURI:    opts.SubjectWriterURI,
 		DepictionWriterURI:  opts.DepictionWriterURI,
 		GithubWriterOptions: github_opts,
+		Plan:                update_plan,
+		DepictionReader:     opts.DepictionReader,
+		SubjectReader:       opts.SubjectReader,
+		GitHubAPIURL:        opts.GitHubAPIURL,
 	}
 
 	// See notes in writers/writers.go for why this returns both "Writer" and "MultiWriter" instances (for now)
@@ -197,17 +221,20 @@ func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, up
 		depiction_id,
 	}
 
-	depictions_rsp := gjson.GetBytes(subject_body, "properties.geotag:depictions")
+	geotag_depictions, err := georeference.LoadGeotagDepictions(subject_body)
 
-	for _, r := range depictions_rsp.Array() {
-		id := r.Int()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load geotag depictions for subject, %w", err)
+	}
+
+	for _, id := range geotag_depictions {
 
 		if !slices.Contains(subject_depictions, id) {
 			subject_depictions = append(subject_depictions, id)
 		}
 	}
 
-	subject_updates["properties.geotag:depictions"] = subject_depictions
+	subject_updates[fmt.Sprintf("properties.%s", geo.RESERVED_GEOTAG_SUBJECT_DEPICTIONS)] = subject_depictions
 
 	// Update the subject geometry
 
@@ -316,18 +343,10 @@ func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, up
 		}
 	}
 
-	subject_geom_ids := make([]int64, 0)
-
-	georefs_path := fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTIONS)
-	georefs_rsp := gjson.GetBytes(subject_body, georefs_path)
+	subject_geom_ids, err := georeference.LoadGeoreferenceDepictions(subject_body)
 
-	for _, r := range georefs_rsp.Array() {
-
-		r_id := r.Int()
-
-		if !slices.Contains(subject_geom_ids, r_id) {
-			subject_geom_ids = append(subject_geom_ids, r_id)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load georeference depictions for subject record %d, %w", subject_id, err)
 	}
 
 	// derive subject_geom_ids from georeferernces...
@@ -616,44 +635,22 @@ func AddGeotagDepiction(ctx context.Context, opts *AddGeotagDepictionOptions, up
 		return nil, fmt.Errorf("Failed to write alt file %s, %w", alt_uri, err)
 	}
 
-	// Close the depiction and subject writers - this is a no-op for many writer but
-	// required for things like the githubapi-tree:// and githubapi-pr:// writers.
+	// Commit all the staged writes and close the depiction and subject writers. Nothing is
+	// persisted until this point. Closing is a no-op for many writer but required for things
+	// like the githubapi-tree:// and githubapi-pr:// writers.
 
-	err = writers.DepictionMultiWriter.Close(ctx)
+	err = writers.Commit(ctx)
 
 	if err != nil {
-		logger.Error("Failed to close depiction writer", "error", err)
-		return nil, fmt.Errorf("Failed to close depiction writer, %w", err)
+		logger.Error("Failed to commit writes", "error", err)
+		return nil, fmt.Errorf("Failed to commit writes, %w", err)
 	}
 
-	err = writers.SubjectMultiWriter.Close(ctx)
-
-	if err != nil {
-		logger.Error("Failed to close subject writer", "error", err)
-		return nil, fmt.Errorf("Failed to close subject writer, %w", err)
-	}
-
-	//
-
-	fc, err := writers.AsFeatureCollection()
-
-	if err != nil {
-		return nil, fmt.Errorf("Failed to derive feature collection, %w", err)
-	}
-
-	new_alt_f, err := geojson.UnmarshalFeature(alt_body)
-
-	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal feature from alt body, %w", err)
-	}
-
-	fc.Append(new_alt_f)
-
-	fc_body, err := fc.MarshalJSON()
+	r, err := writers.Result(ctx)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to marshal feature collection, %w", err)
+		return nil, fmt.Errorf("Failed to derive update result, %w", err)
 	}
 
-	return fc_body, nil
+	return r, nil
 }
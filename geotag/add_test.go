@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	geojson "github.com/sfomuseum/go-geojson-geotag/v2"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
 )
@@ -89,12 +90,22 @@ func TestUpdateDepiction(t *testing.T) {
 		SubjectWriterURI:   obj_writer_uri,
 	}
 
-	body, err := AddGeotagDepiction(ctx, opts, update)
+	r, err := AddGeotagDepiction(ctx, opts, update)
 
 	if err != nil {
 		t.Fatalf("Failed to update depiction, %v", err)
 	}
 
+	if len(r.Records) != 3 || r.Records[2].Role != plan.AltRole || r.Records[2].AltLabel != GEOTAG_LABEL {
+		t.Fatalf("Expected subject, depiction and alt records, %v", r.Records)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
 	features_rsp := gjson.GetBytes(body, "features")
 
 	count_features := len(features_rsp.Array())
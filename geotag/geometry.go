@@ -8,6 +8,7 @@ import (
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-sfomuseum-geo/geometry"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
@@ -113,11 +114,13 @@ func DeriveGeometryForSubject(ctx context.Context, opts *DeriveGeometryForSubjec
 		return nil, fmt.Errorf("Failed to derive georeference coordinates, %w", err)
 	}
 
-	geotag_rsp := gjson.GetBytes(body, "properties.geotag:depictions")
+	geotag_depictions, err := georeference.LoadGeotagDepictions(body)
 
-	for _, id_rsp := range geotag_rsp.Array() {
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load geotag depictions, %w", err)
+	}
 
-		depiction_id := id_rsp.Int()
+	for _, depiction_id := range geotag_depictions {
 
 		depiction_body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
 
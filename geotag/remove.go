@@ -3,20 +3,19 @@ package geotag
 import (
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
 	"strings"
-	"time"
 
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/sfomuseum/go-sfomuseum-geo/alt"
 	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
 	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
 	"github.com/tidwall/gjson"
 	"github.com/whosonfirst/go-reader/v2"
 	"github.com/whosonfirst/go-whosonfirst-export/v3"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
-	"github.com/whosonfirst/go-whosonfirst-uri"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 )
 
@@ -35,9 +34,18 @@ type RemoveGeotagDepictionOptions struct {
 	DefaultGeometry *geojson.Geometry
 	// A valid whosonfirst/go-reader.Reader instance for reading "parent" features. This includes general Who's On First IDs.
 	WhosOnFirstReader reader.Reader
+	// GitHubAPIURL is an optional base URL for the GitHub API used when `githubapi-pr://` writers are grouped in to a
+	// single coordinated change set. If empty the default GitHub API endpoint is used.
+	GitHubAPIURL string
+	// GitHubTemplates are optional `text/template` templates used to derive the commit messages, pull request titles and
+	// descriptions and branch names for GitHub writers. If nil the default templates are used.
+	GitHubTemplates *github.Templates
+	// DryRun is a boolean flag signaling that no data should be written. Instead a JSON-encoded `plan.Plan` describing
+	// the records that would be written, and how they would change, is returned.
+	DryRun bool
 }
 
-func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptions, update *Depiction) ([]byte, error) {
+func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptions, update *Depiction) (*geo_writers.UpdateResult, error) {
 
 	depiction_id := update.DepictionId
 
@@ -51,12 +59,24 @@ func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptio
 		Author:        opts.Author,
 		WhosOnFirstId: depiction_id,
 		Action:        github.GeotagAction,
+		Templates:     opts.GitHubTemplates,
+	}
+
+	var update_plan *plan.Plan
+
+	if opts.DryRun {
+		logger.Debug("Dry run enabled, record changes to plan")
+		update_plan = plan.NewPlan()
 	}
 
 	writers_opts := &geo_writers.CreateWritersOptions{
 		SubjectWriterURI:    opts.SubjectWriterURI,
 		DepictionWriterURI:  opts.DepictionWriterURI,
 		GithubWriterOptions: github_opts,
+		Plan:                update_plan,
+		DepictionReader:     opts.DepictionReader,
+		SubjectReader:       opts.SubjectReader,
+		GitHubAPIURL:        opts.GitHubAPIURL,
 	}
 
 	// See notes in writers/writers.go for why this returns both "Writer" and "MultiWriter" instances (for now)
@@ -136,56 +156,13 @@ func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptio
 		depiction_update["properties.src:geom_alt"] = alt_geoms
 
 		// Deprecate alt geom
-		// START OF put me in a function somewhere...
 
 		logger.Debug("Deprecated alt geom", "label", fov_label)
 
-		alt_args, err := uri.NewAlternateURIArgsFromAltLabel(fov_label)
-
-		if err != nil {
-			return nil, fmt.Errorf("Failed to derive URI args from geotag-fov alt label, %w", err)
-		}
-
-		alt_uri, err := uri.Id2RelPath(depiction_id, alt_args)
-
-		if err != nil {
-			return nil, fmt.Errorf("Failed to derive alt URI for depiction, %w", err)
-		}
-
-		// START OF whosonfirst/go-whosonfirst-reader doesn't know how to work with alt files
-
-		alt_r, err := opts.DepictionReader.Read(ctx, alt_uri)
-
-		if err != nil {
-			return nil, fmt.Errorf("Failed to open alt URI for reading, %w", err)
-		}
-
-		defer alt_r.Close()
-
-		alt_body, err := io.ReadAll(alt_r)
-
-		if err != nil {
-			return nil, fmt.Errorf("Failed to load alt file, %w", err)
-		}
-
-		// END OF whosonfirst/go-whosonfirst-reader doesn't know how to work with alt files
-
-		now := time.Now()
-
-		alt_updates := map[string]any{
-			"properties.edtf:deprecated": now.Format("2006-01-02"),
-		}
-
-		new_alt_body, err := export.AssignProperties(ctx, alt_body, alt_updates)
-
-		if err != nil {
-			return nil, fmt.Errorf("Failed to assign properties to alt depiction, %w", err)
-		}
-
-		_, new_alt_body, err = export.Export(ctx, new_alt_body)
+		new_alt_body, err := alt.DeprecateAltFeature(ctx, opts.DepictionReader, depiction_id, fov_label)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to export alt depiction, %w", err)
+			return nil, fmt.Errorf("Failed to deprecate alt depiction, %w", err)
 		}
 
 		_, err = wof_writer.WriteBytes(ctx, writers.DepictionWriter, new_alt_body)
@@ -193,8 +170,6 @@ func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptio
 		if err != nil {
 			return nil, fmt.Errorf("Failed to write changes for alt depiction, %w", err)
 		}
-
-		// END OF put me in a function somewhere...
 	}
 
 	// Update depiction geometry
@@ -338,36 +313,24 @@ func RemoveGeotagDepiction(ctx context.Context, opts *RemoveGeotagDepictionOptio
 		return nil, fmt.Errorf("Failed to write changes for subject, %w", err)
 	}
 
-	// Close the depiction and subject writers - this is a no-op for many writer but
-	// required for things like the githubapi-tree:// and githubapi-pr:// writers.
-
-	err = writers.DepictionMultiWriter.Close(ctx)
-
-	if err != nil {
-		logger.Error("Failed to close depiction writer", "error", err)
-		return nil, fmt.Errorf("Failed to close depiction writer, %w", err)
-	}
+	// Commit all the staged writes and close the depiction and subject writers. Nothing is
+	// persisted until this point. Closing is a no-op for many writer but required for things
+	// like the githubapi-tree:// and githubapi-pr:// writers.
 
-	err = writers.SubjectMultiWriter.Close(ctx)
+	err = writers.Commit(ctx)
 
 	if err != nil {
-		logger.Error("Failed to close subject writer", "error", err)
-		return nil, fmt.Errorf("Failed to close subject writer, %w", err)
+		logger.Error("Failed to commit writes", "error", err)
+		return nil, fmt.Errorf("Failed to commit writes, %w", err)
 	}
 
-	// Return GeoJSON FeatureCollection with updated features (depiction, subject)
-
-	fc, err := writers.AsFeatureCollection()
-
-	if err != nil {
-		return nil, fmt.Errorf("Failed to derive feature collection, %w", err)
-	}
+	// Return the updated features (depiction, subject)
 
-	fc_body, err := fc.MarshalJSON()
+	r, err := writers.Result(ctx)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to marshal feature collection, %w", err)
+		return nil, fmt.Errorf("Failed to derive update result, %w", err)
 	}
 
-	return fc_body, nil
+	return r, nil
 }
@@ -0,0 +1,787 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v74/github"
+	"github.com/whosonfirst/go-writer/v3"
+	"golang.org/x/oauth2"
+)
+
+// DEFAULT_BASE_BRANCH is the default branch that change set pull requests are created against.
+const DEFAULT_BASE_BRANCH string = "main"
+
+// IsPullRequestURI returns a boolean value indicating whether 'writer_uri' is a `githubapi-pr://` URI.
+func IsPullRequestURI(writer_uri string) bool {
+	return strings.HasPrefix(writer_uri, GITHUBAPI_PR_SCHEME+"://")
+}
+
+// IsBufferedURI returns a boolean value indicating whether 'writer_uri' is a `githubapi-branch://`, `githubapi-pr://` or
+// `githubapi-tree://` URI. These writers buffer all their writes in memory and only publish them when they are closed.
+func IsBufferedURI(writer_uri string) bool {
+
+	for _, scheme := range []string{GITHUBAPI_BRANCH_SCHEME, GITHUBAPI_PR_SCHEME, GITHUBAPI_TREE_SCHEME} {
+
+		if strings.HasPrefix(writer_uri, scheme+"://") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ChangeSetOptions is a struct containing configuration details for the `NewChangeSet` method.
+type ChangeSetOptions struct {
+	// The Who's On First ID of the record that triggered the change set.
+	WhosOnFirstId int64
+	// The name of the person to associate with commits and pull requests. If empty the `pr-author` parameter of the first
+	// writer URI, or the name (or login) of the user associated with its access token, is used.
+	Author string
+	// The email address of the person to associate with commits. If empty the `pr-email` parameter of the first writer URI,
+	// or the email address of the user associated with its access token, or "{Author}@localhost" is used.
+	Email string
+	// The action being performed.
+	Action Action
+	// An optional base URL for the GitHub API. If empty the default GitHub API endpoint is used.
+	APIURL string
+	// The Who's On First ID of the subject (parent) record of 'WhosOnFirstId', if known.
+	SubjectId int64
+	// The list of labels being updated, if any.
+	Labels []string
+	// The names of the places being referenced, if any.
+	Places []string
+	// Optional templates used to derive commit messages, pull request titles and descriptions and the branch name. If nil
+	// the default templates are used.
+	Templates *Templates
+}
+
+// PullRequest defines a pull request opened by a `ChangeSet`.
+type PullRequest struct {
+	// The owner of the repository the pull request was opened in.
+	Owner string `json:"owner"`
+	// The name of the repository the pull request was opened in.
+	Repo string `json:"repo"`
+	// The pull request number.
+	Number int `json:"number"`
+	// The URL of the pull request.
+	URL string `json:"url"`
+}
+
+// String returns the "{OWNER}/{REPO}#{NUMBER}" representation of 'pr'.
+func (pr *PullRequest) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+// ChangeSet groups all the writes, across one or more GitHub repositories, for a single operation so that they
+// are committed to a shared branch name in each repository and opened as pull requests whose descriptions link
+// to one another. Writes are buffered in memory and nothing is published until every writer created by the change
+// set has been closed (or `Publish` is invoked explicitly).
+type ChangeSet struct {
+	branch        string
+	title         string
+	description   string
+	templates     *Templates
+	tctx          *TemplateContext
+	author        string
+	email         string
+	api_url       string
+	repos         []*changeSetRepo
+	open          int
+	published     bool
+	discarded     bool
+	pull_requests []*PullRequest
+	mu            *sync.Mutex
+}
+
+// changeSetRepo is a repository written to by a change set. Commits are made to 'pr_owner'/'pr_repo' (which is the same as
+// 'owner'/'repo' unless the pull request is opened from a fork) and pull requests are opened against 'owner'/'repo'.
+type changeSetRepo struct {
+	owner       string
+	repo        string
+	pr_owner    string
+	pr_repo     string
+	base_branch string
+	ensure_repo bool
+	client      *gh.Client
+	entries     []*gh.TreeEntry
+}
+
+// head returns the "head" value for pull requests opened from 'r'.
+func (r *changeSetRepo) head(branch string) string {
+
+	if r.pr_owner != r.owner {
+		return fmt.Sprintf("%s:%s", r.pr_owner, branch)
+	}
+
+	return branch
+}
+
+// NewChangeSet returns a new `ChangeSet` instance derived from 'opts'.
+func NewChangeSet(ctx context.Context, opts *ChangeSetOptions) (*ChangeSet, error) {
+
+	tctx := newTemplateContext(opts.Author, opts.Action, opts.WhosOnFirstId, opts.SubjectId, opts.Labels, opts.Places)
+
+	rendered, err := opts.Templates.Render(tctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to render templates, %w", err)
+	}
+
+	cs := &ChangeSet{
+		branch:        rendered.Branch,
+		title:         rendered.PullRequestTitle,
+		description:   rendered.PullRequestDescription,
+		templates:     opts.Templates,
+		tctx:          tctx,
+		author:        opts.Author,
+		email:         opts.Email,
+		api_url:       opts.APIURL,
+		repos:         make([]*changeSetRepo, 0),
+		pull_requests: make([]*PullRequest, 0),
+		mu:            new(sync.Mutex),
+	}
+
+	return cs, nil
+}
+
+// Branch returns the name of the branch that all the writes in 'cs' will be committed to.
+func (cs *ChangeSet) Branch() string {
+	return cs.branch
+}
+
+// PullRequests returns the list of pull requests opened by 'cs'. It will be empty until 'cs' has been published.
+func (cs *ChangeSet) PullRequests() []*PullRequest {
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.pull_requests
+}
+
+// NewWriter returns a new `writer.Writer` instance whose writes are added to 'cs'. 'writer_uri' is expected to be
+// a `githubapi-pr://{OWNER}/{REPO}?access_token={TOKEN}` URI with optional `branch` (the branch to open pull requests
+// against) and `prefix` parameters. As with `whosonfirst/go-writer-github` the optional `pr-owner` and `pr-repo` parameters
+// define the repository (for example a fork) that changes are committed to, `ensure-repo` signals that the repository should
+// be forked from {OWNER}/{REPO} if it does not exist and `pr-author` and `pr-email` are used if the change set has no author
+// or email address. Any `pr-branch`, `pr-title` or `pr-description` parameters are ignored in favour of the change set's own
+// values. Writers for the same repositories and base branch share a single pull request.
+func (cs *ChangeSet) NewWriter(ctx context.Context, writer_uri string) (writer.Writer, error) {
+
+	u, err := url.Parse(writer_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	if u.Scheme != GITHUBAPI_PR_SCHEME {
+		return nil, fmt.Errorf("Unsupported scheme '%s'", u.Scheme)
+	}
+
+	owner := u.Host
+	repo := strings.TrimLeft(u.Path, "/")
+
+	if owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("Invalid owner or repo")
+	}
+
+	q := u.Query()
+
+	token := q.Get("access_token")
+
+	if token == "" {
+		return nil, fmt.Errorf("Missing access token")
+	}
+
+	base_branch := q.Get("branch")
+
+	if base_branch == "" {
+		base_branch = DEFAULT_BASE_BRANCH
+	}
+
+	pr_owner := q.Get("pr-owner")
+
+	if pr_owner == "" {
+		pr_owner = owner
+	}
+
+	pr_repo := q.Get("pr-repo")
+
+	if pr_repo == "" {
+		pr_repo = repo
+	}
+
+	ensure_repo := false
+
+	str_ensure_repo := q.Get("ensure-repo")
+
+	if str_ensure_repo != "" {
+
+		v, err := strconv.ParseBool(str_ensure_repo)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ensure-repo parameter, %w", err)
+		}
+
+		ensure_repo = v
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.published {
+		return nil, fmt.Errorf("Change set has already been published")
+	}
+
+	if cs.discarded {
+		return nil, fmt.Errorf("Change set has been discarded")
+	}
+
+	var cs_repo *changeSetRepo
+
+	for _, r := range cs.repos {
+
+		if r.owner == owner && r.repo == repo && r.pr_owner == pr_owner && r.pr_repo == pr_repo && r.base_branch == base_branch {
+			cs_repo = r
+			break
+		}
+	}
+
+	if cs_repo == nil {
+
+		client, err := cs.newClient(ctx, token)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create GitHub client, %w", err)
+		}
+
+		cs_repo = &changeSetRepo{
+			owner:       owner,
+			repo:        repo,
+			pr_owner:    pr_owner,
+			pr_repo:     pr_repo,
+			base_branch: base_branch,
+			ensure_repo: ensure_repo,
+			client:      client,
+			entries:     make([]*gh.TreeEntry, 0),
+		}
+
+		cs.repos = append(cs.repos, cs_repo)
+	}
+
+	cs_repo.ensure_repo = cs_repo.ensure_repo || ensure_repo
+
+	err = cs.resolveAuthor(ctx, cs_repo.client, q.Get("pr-author"), q.Get("pr-email"))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve author, %w", err)
+	}
+
+	cs.open += 1
+
+	wr := &ChangeSetWriter{
+		changeset: cs,
+		repo:      cs_repo,
+		prefix:    q.Get("prefix"),
+	}
+
+	return wr, nil
+}
+
+// resolveAuthor assigns the author and email address for 'cs' if they are not already set. 'pr_author' and 'pr_email' are
+// used if present, otherwise the name (or login) and email address of the user associated with 'client' are used. If there is
+// still no email address "{AUTHOR}@localhost" is used. It is expected that the caller has locked 'cs'.
+func (cs *ChangeSet) resolveAuthor(ctx context.Context, client *gh.Client, pr_author string, pr_email string) error {
+
+	if cs.author == "" {
+		cs.author = pr_author
+	}
+
+	if cs.email == "" {
+		cs.email = pr_email
+	}
+
+	if cs.author != "" && cs.email != "" {
+		return nil
+	}
+
+	if cs.author == "" {
+
+		user, _, err := client.Users.Get(ctx, "")
+
+		if err != nil {
+			return fmt.Errorf("Failed to retrieve user for access token, %w", err)
+		}
+
+		cs.author = user.GetName()
+
+		if cs.author == "" {
+			cs.author = user.GetLogin()
+		}
+
+		if cs.author == "" {
+			return fmt.Errorf("Unable to derive author from access token")
+		}
+
+		if cs.email == "" {
+			cs.email = user.GetEmail()
+		}
+	}
+
+	if cs.email == "" {
+		cs.email = fmt.Sprintf("%s@localhost", cs.author)
+	}
+
+	return nil
+}
+
+func (cs *ChangeSet) newClient(ctx context.Context, token string) (*gh.Client, error) {
+
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+
+	tc := oauth2.NewClient(ctx, ts)
+	client := gh.NewClient(tc)
+
+	if cs.api_url != "" {
+
+		api_u, err := url.Parse(cs.api_url)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse API URL, %w", err)
+		}
+
+		if !strings.HasSuffix(api_u.Path, "/") {
+			api_u.Path = api_u.Path + "/"
+		}
+
+		client.BaseURL = api_u
+	}
+
+	return client, nil
+}
+
+func (cs *ChangeSet) add(repo *changeSetRepo, e *gh.TreeEntry) error {
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.published {
+		return fmt.Errorf("Change set has already been published")
+	}
+
+	if cs.discarded {
+		return fmt.Errorf("Change set has been discarded")
+	}
+
+	repo.entries = append(repo.entries, e)
+	return nil
+}
+
+// release is invoked when a `ChangeSetWriter` is closed. Once all the writers have been closed the change set is published.
+func (cs *ChangeSet) release(ctx context.Context) error {
+
+	cs.mu.Lock()
+	cs.open -= 1
+	open := cs.open
+	cs.mu.Unlock()
+
+	if open > 0 {
+		return nil
+	}
+
+	return cs.Publish(ctx)
+}
+
+// Discard drops all the writes in 'cs' so that nothing is published when its writers are closed. It is a no-op if 'cs'
+// has already been published.
+func (cs *ChangeSet) Discard(ctx context.Context) error {
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.published {
+		return nil
+	}
+
+	cs.discarded = true
+
+	for _, r := range cs.repos {
+		r.entries = nil
+	}
+
+	return nil
+}
+
+// Publish commits the writes for each repository in 'cs' to a new branch (whose name is the same in every repository),
+// opens a pull request for each branch and then updates the description of each pull request with links to all the other
+// pull requests in the change set. Repositories without any writes are skipped. A change set can only be published once.
+func (cs *ChangeSet) Publish(ctx context.Context) error {
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.published || cs.discarded {
+		return nil
+	}
+
+	cs.published = true
+
+	logger := slog.Default()
+	logger = logger.With("branch", cs.branch)
+
+	repos := make([]*changeSetRepo, 0)
+
+	for _, r := range cs.repos {
+
+		if len(r.entries) > 0 {
+			repos = append(repos, r)
+		}
+	}
+
+	for _, r := range repos {
+
+		logger.Debug("Commit change set", "owner", r.owner, "repo", r.repo, "count", len(r.entries))
+
+		err := cs.commit(ctx, r)
+
+		if err != nil {
+			return fmt.Errorf("Failed to commit change set to %s/%s (pull requests opened: %s), %w", r.owner, r.repo, cs.pullRequestsString(), err)
+		}
+	}
+
+	for _, r := range repos {
+
+		new_pr := &gh.NewPullRequest{
+			Title:               gh.Ptr(cs.title),
+			Head:                gh.Ptr(r.head(cs.branch)),
+			Base:                gh.Ptr(r.base_branch),
+			Body:                gh.Ptr(cs.description),
+			MaintainerCanModify: gh.Ptr(true),
+		}
+
+		gh_pr, _, err := r.client.PullRequests.Create(ctx, r.owner, r.repo, new_pr)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create pull request for %s/%s (pull requests opened: %s), %w", r.owner, r.repo, cs.pullRequestsString(), err)
+		}
+
+		pr := &PullRequest{
+			Owner:  r.owner,
+			Repo:   r.repo,
+			Number: gh_pr.GetNumber(),
+			URL:    gh_pr.GetHTMLURL(),
+		}
+
+		logger.Info("Opened pull request", "pull request", pr.String(), "url", pr.URL)
+		cs.pull_requests = append(cs.pull_requests, pr)
+	}
+
+	if len(cs.pull_requests) < 2 {
+		return nil
+	}
+
+	// Now that all the pull requests exist cross-link them
+
+	for idx, r := range repos {
+
+		pr := cs.pull_requests[idx]
+
+		body := cs.crossLinkedDescription(pr)
+
+		update := &gh.PullRequest{
+			Body: gh.Ptr(body),
+		}
+
+		_, _, err := r.client.PullRequests.Edit(ctx, r.owner, r.repo, pr.Number, update)
+
+		if err != nil {
+			return fmt.Errorf("Failed to update description for pull request %s, %w", pr.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// commit creates (or reuses) the change set branch in 'r' and commits all of its entries in a single commit. If the
+// commit repository for 'r' is a fork which does not exist, and 'r.ensure_repo' is true, it is created first.
+func (cs *ChangeSet) commit(ctx context.Context, r *changeSetRepo) error {
+
+	if r.ensure_repo {
+
+		err := cs.ensureRepo(ctx, r)
+
+		if err != nil {
+			return fmt.Errorf("Failed to ensure repo, %w", err)
+		}
+	}
+
+	base_ref := fmt.Sprintf("refs/heads/%s", r.base_branch)
+	pr_ref := fmt.Sprintf("refs/heads/%s", cs.branch)
+
+	ref, _, err := r.client.Git.GetRef(ctx, r.pr_owner, r.pr_repo, pr_ref)
+
+	if err != nil {
+
+		if !isNotFound(err) {
+			return fmt.Errorf("Failed to retrieve branch '%s', %w", cs.branch, err)
+		}
+
+		base, _, err := r.client.Git.GetRef(ctx, r.pr_owner, r.pr_repo, base_ref)
+
+		if err != nil {
+			return fmt.Errorf("Failed to retrieve base branch '%s', %w", r.base_branch, err)
+		}
+
+		new_ref := &gh.Reference{
+			Ref: gh.Ptr(pr_ref),
+			Object: &gh.GitObject{
+				SHA: base.Object.SHA,
+			},
+		}
+
+		ref, _, err = r.client.Git.CreateRef(ctx, r.pr_owner, r.pr_repo, new_ref)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create branch, %w", err)
+		}
+	}
+
+	parent_sha := ref.GetObject().GetSHA()
+
+	tree, _, err := r.client.Git.CreateTree(ctx, r.pr_owner, r.pr_repo, parent_sha, r.entries)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create tree, %w", err)
+	}
+
+	parent, _, err := r.client.Git.GetCommit(ctx, r.pr_owner, r.pr_repo, parent_sha)
+
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve parent commit, %w", err)
+	}
+
+	paths := make([]string, len(r.entries))
+
+	for idx, e := range r.entries {
+		paths[idx] = e.GetPath()
+	}
+
+	tctx := *cs.tctx
+	tctx.Path = strings.Join(paths, ", ")
+
+	msg, err := cs.templates.RenderCommitMessage(&tctx)
+
+	if err != nil {
+		return fmt.Errorf("Failed to render commit message, %w", err)
+	}
+
+	now := time.Now()
+
+	commit := &gh.Commit{
+		Author: &gh.CommitAuthor{
+			Date:  &gh.Timestamp{Time: now},
+			Name:  gh.Ptr(cs.author),
+			Email: gh.Ptr(cs.email),
+		},
+		Message: gh.Ptr(msg),
+		Tree:    tree,
+		Parents: []*gh.Commit{parent},
+	}
+
+	new_commit, _, err := r.client.Git.CreateCommit(ctx, r.pr_owner, r.pr_repo, commit, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create commit, %w", err)
+	}
+
+	ref.Object.SHA = new_commit.SHA
+
+	_, _, err = r.client.Git.UpdateRef(ctx, r.pr_owner, r.pr_repo, ref, false)
+
+	if err != nil {
+		return fmt.Errorf("Failed to update branch, %w", err)
+	}
+
+	return nil
+}
+
+// ensureRepo ensures that the commit repository for 'r' exists, forking it from 'r.owner'/'r.repo' (and renaming the fork
+// if necessary) if it does not.
+func (cs *ChangeSet) ensureRepo(ctx context.Context, r *changeSetRepo) error {
+
+	_, _, err := r.client.Repositories.Get(ctx, r.pr_owner, r.pr_repo)
+
+	if err == nil {
+		return nil
+	}
+
+	if !isNotFound(err) {
+		return fmt.Errorf("Failed to retrieve %s/%s, %w", r.pr_owner, r.pr_repo, err)
+	}
+
+	if r.pr_owner == r.owner {
+		return fmt.Errorf("Can not fork %s/%s to the same owner", r.owner, r.repo)
+	}
+
+	fork_opts := &gh.RepositoryCreateForkOptions{
+		Organization: r.pr_owner,
+	}
+
+	_, _, err = r.client.Repositories.CreateFork(ctx, r.owner, r.repo, fork_opts)
+
+	if err != nil {
+
+		var accepted_err *gh.AcceptedError
+
+		if !errors.As(err, &accepted_err) {
+			return fmt.Errorf("Failed to create fork, %w", err)
+		}
+	}
+
+	if r.pr_repo != r.repo {
+
+		update := &gh.Repository{
+			Name: gh.Ptr(r.pr_repo),
+		}
+
+		_, _, err := r.client.Repositories.Edit(ctx, r.pr_owner, r.repo, update)
+
+		if err != nil {
+			return fmt.Errorf("Failed to rename fork, %w", err)
+		}
+	}
+
+	return nil
+}
+
+// crossLinkedDescription returns the description for 'pr' with links to all the other pull requests in the change set.
+func (cs *ChangeSet) crossLinkedDescription(pr *PullRequest) string {
+
+	var sb strings.Builder
+
+	sb.WriteString(cs.description)
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf("This pull request is part of change set `%s`. Related pull requests:\n", cs.branch))
+
+	for _, other := range cs.pull_requests {
+
+		if other == pr {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("\n* %s %s", other.String(), other.URL))
+	}
+
+	return sb.String()
+}
+
+func (cs *ChangeSet) pullRequestsString() string {
+
+	if len(cs.pull_requests) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(cs.pull_requests))
+
+	for idx, pr := range cs.pull_requests {
+		names[idx] = pr.String()
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// ChangeSetWriter implements the `whosonfirst/go-writer/v3.Writer` interface and adds writes to a `ChangeSet` instance.
+type ChangeSetWriter struct {
+	changeset *ChangeSet
+	repo      *changeSetRepo
+	prefix    string
+	closed    bool
+}
+
+// Write adds the body in 'fh' for 'path' to the underlying `ChangeSet` instance.
+func (wr *ChangeSetWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read body for %s, %w", path, err)
+	}
+
+	e := &gh.TreeEntry{
+		Path:    gh.Ptr(wr.WriterURI(ctx, path)),
+		Type:    gh.Ptr("blob"),
+		Content: gh.Ptr(string(body)),
+		Mode:    gh.Ptr("100644"),
+	}
+
+	err = wr.changeset.add(wr.repo, e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns 'path' relative to the writer's (optional) prefix.
+func (wr *ChangeSetWriter) WriterURI(ctx context.Context, path string) string {
+
+	if wr.prefix != "" {
+		return filepath.Join(wr.prefix, path)
+	}
+
+	return path
+}
+
+// Flush is a no-op. Writes are only published once all the writers in the underlying `ChangeSet` have been closed.
+func (wr *ChangeSetWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close marks the writer as closed. If it is the last open writer in the underlying `ChangeSet` then the change set is published.
+func (wr *ChangeSetWriter) Close(ctx context.Context) error {
+
+	if wr.closed {
+		return nil
+	}
+
+	wr.closed = true
+	return wr.changeset.release(ctx)
+}
+
+// Discard drops all the writes in the underlying `ChangeSet` and marks the writer as closed without publishing anything.
+func (wr *ChangeSetWriter) Discard(ctx context.Context) error {
+
+	wr.closed = true
+	return wr.changeset.Discard(ctx)
+}
+
+// SetLogger is a no-op.
+func (wr *ChangeSetWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
+
+// isNotFound returns a boolean value indicating whether 'err' is a GitHub API "404 Not Found" error.
+func isNotFound(err error) bool {
+
+	var rsp_err *gh.ErrorResponse
+
+	if !errors.As(err, &rsp_err) || rsp_err.Response == nil {
+		return false
+	}
+
+	return rsp_err.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,488 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/github/githubtest"
+)
+
+func TestChangeSet(t *testing.T) {
+
+	ctx := context.Background()
+
+	server := githubtest.NewServer("sfomuseum-data/sfomuseum-data-media-collection", "sfomuseum-data/sfomuseum-data-collection")
+	defer server.Close()
+
+	opts := &ChangeSetOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeoreferenceAction,
+		APIURL:        server.URL,
+	}
+
+	cs, err := NewChangeSet(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create change set, %v", err)
+	}
+
+	depiction_wr, err := cs.NewWriter(ctx, "githubapi-pr://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&prefix=data")
+
+	if err != nil {
+		t.Fatalf("Failed to create depiction writer, %v", err)
+	}
+
+	subject_wr, err := cs.NewWriter(ctx, "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret&prefix=data")
+
+	if err != nil {
+		t.Fatalf("Failed to create subject writer, %v", err)
+	}
+
+	writes := map[string]string{
+		"189/790/396/1/1897903961.geojson":                              "depiction",
+		"189/790/396/1/1897903961-alt-georef-sfomuseum_depicts.geojson": "alt",
+	}
+
+	for path, body := range writes {
+
+		_, err := depiction_wr.Write(ctx, path, bytes.NewReader([]byte(body)))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	_, err = subject_wr.Write(ctx, "189/790/247/1/1897902471.geojson", bytes.NewReader([]byte("subject")))
+
+	if err != nil {
+		t.Fatalf("Failed to write subject, %v", err)
+	}
+
+	err = depiction_wr.Close(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to close depiction writer, %v", err)
+	}
+
+	if len(cs.PullRequests()) != 0 {
+		t.Fatalf("Change set published before all writers were closed")
+	}
+
+	err = subject_wr.Close(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to close subject writer, %v", err)
+	}
+
+	prs := cs.PullRequests()
+
+	if len(prs) != 2 {
+		t.Fatalf("Expected 2 pull requests, got %d", len(prs))
+	}
+
+	expected_files := map[string]int{
+		"sfomuseum-data/sfomuseum-data-media-collection": 2,
+		"sfomuseum-data/sfomuseum-data-collection":       1,
+	}
+
+	for idx, pr := range prs {
+
+		name := fmt.Sprintf("%s/%s", pr.Owner, pr.Repo)
+		repo := server.Repo(name)
+
+		if len(repo.PullRequests) != 1 {
+			t.Fatalf("Expected 1 pull request in %s, got %d", name, len(repo.PullRequests))
+		}
+
+		gh_pr := repo.PullRequests[0]
+
+		if gh_pr.Head != cs.Branch() || gh_pr.Base != DEFAULT_BASE_BRANCH {
+			t.Fatalf("Unexpected head or base for %s, %s %s", name, gh_pr.Head, gh_pr.Base)
+		}
+
+		other := prs[(idx+1)%2]
+
+		if !strings.Contains(gh_pr.Body, other.String()) || !strings.Contains(gh_pr.Body, other.URL) {
+			t.Fatalf("Expected description for %s to link to %s, %s", name, other.String(), gh_pr.Body)
+		}
+
+		if strings.Contains(gh_pr.Body, pr.String()) {
+			t.Fatalf("Did not expect description for %s to link to itself, %s", name, gh_pr.Body)
+		}
+
+		files, err := server.Files(name, cs.Branch())
+
+		if err != nil {
+			t.Fatalf("Failed to retrieve files for %s, %v", name, err)
+		}
+
+		if len(files) != expected_files[name] {
+			t.Fatalf("Expected %d files in %s, got %d", expected_files[name], name, len(files))
+		}
+
+		for _, f := range files {
+
+			if !strings.HasPrefix(f.Path, "data/") {
+				t.Fatalf("Expected file to be written with prefix, %s", f.Path)
+			}
+		}
+
+		if len(repo.Commits) != 2 {
+			t.Fatalf("Expected a single commit for change set in %s, got %d commits", name, len(repo.Commits)-1)
+		}
+
+		for _, c := range repo.Commits {
+
+			if c.Message == "initial commit" {
+				continue
+			}
+
+			if !strings.HasPrefix(c.Message, "[alice] update georeference data for data/") || strings.Count(c.Message, "data/") != len(files) {
+				t.Fatalf("Expected commit message in %s to list committed files, %s", name, c.Message)
+			}
+		}
+	}
+
+	// Once published a change set is done
+
+	_, err = cs.NewWriter(ctx, "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret")
+
+	if err == nil {
+		t.Fatalf("Expected new writer for published change set to fail")
+	}
+}
+
+func TestChangeSetSharedRepo(t *testing.T) {
+
+	ctx := context.Background()
+
+	server := githubtest.NewServer("sfomuseum-data/sfomuseum-data-collection")
+	defer server.Close()
+
+	opts := &ChangeSetOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeotagAction,
+		APIURL:        server.URL,
+	}
+
+	cs, err := NewChangeSet(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create change set, %v", err)
+	}
+
+	uri := "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret"
+
+	for _, path := range []string{"a.geojson", "b.geojson"} {
+
+		wr, err := cs.NewWriter(ctx, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create writer, %v", err)
+		}
+
+		_, err = wr.Write(ctx, path, bytes.NewReader([]byte(path)))
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+
+		defer wr.Close(ctx)
+	}
+
+	err = cs.Publish(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to publish change set, %v", err)
+	}
+
+	prs := cs.PullRequests()
+
+	if len(prs) != 1 {
+		t.Fatalf("Expected 1 pull request, got %d", len(prs))
+	}
+
+	files, err := server.Files("sfomuseum-data/sfomuseum-data-collection", cs.Branch())
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve files, %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
+	}
+}
+
+// publishTestChangeSet creates a new change set for 'opts', writes "a.geojson" using a writer created from 'uri' and then
+// closes the writer (publishing the change set).
+func publishTestChangeSet(ctx context.Context, opts *ChangeSetOptions, uri string) (*ChangeSet, error) {
+
+	cs, err := NewChangeSet(ctx, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wr, err := cs.NewWriter(ctx, uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = wr.Write(ctx, "a.geojson", bytes.NewReader([]byte("a")))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, wr.Close(ctx)
+}
+
+// headCommit returns the commit at the head of 'branch' in 'repo'.
+func headCommit(repo *githubtest.Repo, branch string) *githubtest.Commit {
+	return repo.Commits[repo.Refs["refs/heads/"+branch]]
+}
+
+func TestChangeSetAuthor(t *testing.T) {
+
+	ctx := context.Background()
+
+	name := "sfomuseum-data/sfomuseum-data-collection"
+
+	tests := []struct {
+		uri   string
+		name  string
+		email string
+		user  *githubtest.User
+	}{
+		{
+			uri:   "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret",
+			name:  "bob",
+			email: "bob@localhost",
+			user:  &githubtest.User{Login: "bob"},
+		},
+		{
+			uri:   "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret",
+			name:  "Bob",
+			email: "bob@example.com",
+			user:  &githubtest.User{Login: "bob", Name: "Bob", Email: "bob@example.com"},
+		},
+		{
+			uri:   "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret&pr-author=carol&pr-email=carol@example.com",
+			name:  "carol",
+			email: "carol@example.com",
+			user:  &githubtest.User{Login: "bob"},
+		},
+	}
+
+	for _, test := range tests {
+
+		server := githubtest.NewServer(name)
+		defer server.Close()
+
+		server.User = test.user
+
+		opts := &ChangeSetOptions{
+			WhosOnFirstId: 1897903961,
+			Action:        GeotagAction,
+			APIURL:        server.URL,
+		}
+
+		cs, err := publishTestChangeSet(ctx, opts, test.uri)
+
+		if err != nil {
+			t.Fatalf("Failed to publish change set without author for %s, %v", test.uri, err)
+		}
+
+		c := headCommit(server.Repo(name), cs.Branch())
+
+		if c.AuthorName != test.name || c.AuthorEmail != test.email {
+			t.Fatalf("Unexpected commit author for %s, %s <%s>", test.uri, c.AuthorName, c.AuthorEmail)
+		}
+	}
+}
+
+func TestChangeSetFork(t *testing.T) {
+
+	ctx := context.Background()
+
+	server := githubtest.NewServer("sfomuseum-data/sfomuseum-data-collection")
+	defer server.Close()
+
+	opts := &ChangeSetOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeotagAction,
+		APIURL:        server.URL,
+	}
+
+	uri := "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret&pr-owner=alice&pr-repo=collection&ensure-repo=true"
+
+	cs, err := publishTestChangeSet(ctx, opts, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to publish change set, %v", err)
+	}
+
+	_, err = server.Files("sfomuseum-data/sfomuseum-data-collection", cs.Branch())
+
+	if err == nil {
+		t.Fatalf("Did not expect branch in upstream repository")
+	}
+
+	files, err := server.Files("alice/collection", cs.Branch())
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve files from fork, %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file in fork, got %d", len(files))
+	}
+
+	prs := server.Repo("sfomuseum-data/sfomuseum-data-collection").PullRequests
+
+	if len(prs) != 1 || prs[0].Head != "alice:"+cs.Branch() {
+		t.Fatalf("Expected pull request from fork in upstream repository, %v", prs)
+	}
+
+	cs, err = NewChangeSet(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create change set, %v", err)
+	}
+
+	_, err = cs.NewWriter(ctx, "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret&ensure-repo=maybe")
+
+	if err == nil {
+		t.Fatalf("Expected invalid ensure-repo parameter to fail")
+	}
+}
+
+func TestChangeSetBranchError(t *testing.T) {
+
+	ctx := context.Background()
+
+	name := "sfomuseum-data/sfomuseum-data-collection"
+
+	server := githubtest.NewServer(name)
+	defer server.Close()
+
+	opts := &ChangeSetOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeotagAction,
+		APIURL:        server.URL,
+		Templates: &Templates{
+			Branch: "geotag-{{ .WhosOnFirstId }}",
+		},
+	}
+
+	server.Fail("GET", "/repos/sfomuseum-data/sfomuseum-data-collection/git/ref/heads/geotag-1897903961", 500)
+
+	_, err := publishTestChangeSet(ctx, opts, "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret")
+
+	if err == nil {
+		t.Fatalf("Expected failure to retrieve branch to fail")
+	}
+
+	if _, exists := server.Repo(name).Refs["refs/heads/geotag-1897903961"]; exists {
+		t.Fatalf("Did not expect branch to be created when it could not be retrieved")
+	}
+}
+
+func TestIsPullRequestURI(t *testing.T) {
+
+	tests := map[string]bool{
+		"githubapi-pr://sfomuseum-data/sfomuseum-data-collection": true,
+		"githubapi://sfomuseum-data/sfomuseum-data-collection":    false,
+		"repo:///usr/local/data/sfomuseum-data-collection":        false,
+	}
+
+	for uri, expected := range tests {
+
+		if IsPullRequestURI(uri) != expected {
+			t.Fatalf("Unexpected result for %s", uri)
+		}
+	}
+}
+
+func TestChangeSetDiscard(t *testing.T) {
+
+	ctx := context.Background()
+
+	name := "sfomuseum-data/sfomuseum-data-collection"
+
+	server := githubtest.NewServer(name)
+	defer server.Close()
+
+	opts := &ChangeSetOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeoreferenceAction,
+		APIURL:        server.URL,
+	}
+
+	cs, err := NewChangeSet(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create change set, %v", err)
+	}
+
+	wr, err := cs.NewWriter(ctx, "githubapi-pr://sfomuseum-data/sfomuseum-data-collection?access_token=s33kret")
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	_, err = wr.Write(ctx, "a.geojson", bytes.NewReader([]byte("a")))
+
+	if err != nil {
+		t.Fatalf("Failed to write, %v", err)
+	}
+
+	err = wr.(*ChangeSetWriter).Discard(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to discard writer, %v", err)
+	}
+
+	err = wr.Close(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to close discarded writer, %v", err)
+	}
+
+	if len(cs.PullRequests()) != 0 || len(server.Repo(name).PullRequests) != 0 {
+		t.Fatalf("Did not expect discarded change set to be published")
+	}
+
+	_, err = wr.Write(ctx, "b.geojson", bytes.NewReader([]byte("b")))
+
+	if err == nil {
+		t.Fatalf("Expected write to discarded change set to fail")
+	}
+}
+
+func TestIsBufferedURI(t *testing.T) {
+
+	tests := map[string]bool{
+		"githubapi-pr://sfomuseum-data/sfomuseum-data-collection":     true,
+		"githubapi-tree://sfomuseum-data/sfomuseum-data-collection":   true,
+		"githubapi-branch://sfomuseum-data/sfomuseum-data-collection": true,
+		"githubapi://sfomuseum-data/sfomuseum-data-collection":        false,
+		"repo:///usr/local/data/sfomuseum-data-collection":            false,
+	}
+
+	for uri, expected := range tests {
+
+		if IsBufferedURI(uri) != expected {
+			t.Fatalf("Unexpected result for %s", uri)
+		}
+	}
+}
@@ -0,0 +1,704 @@
+// Package githubtest provides a minimal in-memory fake of the GitHub API, sufficient for testing the
+// user, fork, branch, tree, commit and pull request operations performed by `github.ChangeSet`.
+package githubtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// File is a file committed to a `Repo`.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Commit is a commit created in a `Repo`.
+type Commit struct {
+	SHA         string
+	Message     string
+	Parents     []string
+	Tree        string
+	AuthorName  string
+	AuthorEmail string
+}
+
+// User is the user associated with the access token used to call a `Server`.
+type User struct {
+	Login string
+	Name  string
+	Email string
+}
+
+// PullRequest is a pull request opened in a `Repo`.
+type PullRequest struct {
+	Number int
+	Title  string
+	Head   string
+	Base   string
+	Body   string
+}
+
+// Repo is the state of a single repository in a fake GitHub API.
+type Repo struct {
+	// Refs is a dictionary of commit SHAs keyed by ref name (for example "refs/heads/main").
+	Refs map[string]string
+	// Commits is a dictionary of commits keyed by their SHA.
+	Commits map[string]*Commit
+	// Trees is a dictionary of the files in each tree keyed by the tree's SHA.
+	Trees map[string][]*File
+	// PullRequests is the list of pull requests opened in the repository.
+	PullRequests []*PullRequest
+}
+
+// Server is an in-memory fake of the GitHub API.
+type Server struct {
+	*httptest.Server
+	// User is the user returned for the authenticated user. It may be modified before any requests are made.
+	User     *User
+	repos    map[string]*Repo
+	failures map[string]int
+	mu       *sync.Mutex
+}
+
+// NewServer returns a new `Server` instance with an empty repository, containing a single commit on a "main"
+// branch, for each of 'repos' (which are expected to be "{OWNER}/{REPO}" strings). Callers are expected to invoke
+// the `Close` method when they are finished.
+func NewServer(repos ...string) *Server {
+
+	s := &Server{
+		User:     &User{Login: "githubtest"},
+		repos:    make(map[string]*Repo),
+		failures: make(map[string]int),
+		mu:       new(sync.Mutex),
+	}
+
+	for _, name := range repos {
+
+		r := newRepo()
+
+		tree_sha := digest("tree", name)
+		commit_sha := digest("commit", name)
+
+		r.Trees[tree_sha] = make([]*File, 0)
+		r.Commits[commit_sha] = &Commit{SHA: commit_sha, Message: "initial commit", Tree: tree_sha}
+		r.Refs["refs/heads/main"] = commit_sha
+
+		s.repos[name] = r
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /user", s.handleGetUser)
+	mux.HandleFunc("GET /repos/{owner}/{repo}", s.handleGetRepo)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}", s.handleEditRepo)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/forks", s.handleCreateFork)
+
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/ref/{ref...}", s.handleGetRef)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/refs", s.handleCreateRef)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/git/refs/{ref...}", s.handleUpdateRef)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/trees", s.handleCreateTree)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/commits/{sha}", s.handleGetCommit)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/commits", s.handleCreateCommit)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls", s.handleCreatePullRequest)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/pulls/{number}", s.handleEditPullRequest)
+
+	s.Server = httptest.NewServer(s.failureHandler(mux))
+	return s
+}
+
+// Fail causes requests for 'method' and 'path' (for example "GET" and "/repos/{OWNER}/{REPO}/git/ref/heads/{BRANCH}") to
+// fail with HTTP status code 'status'.
+func (s *Server) Fail(method string, path string, status int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method+" "+path] = status
+}
+
+func (s *Server) failureHandler(next http.Handler) http.Handler {
+
+	fn := func(w http.ResponseWriter, req *http.Request) {
+
+		s.mu.Lock()
+		status, fail := s.failures[req.Method+" "+req.URL.Path]
+		s.mu.Unlock()
+
+		if fail {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func newRepo() *Repo {
+
+	r := &Repo{
+		Refs:         make(map[string]string),
+		Commits:      make(map[string]*Commit),
+		Trees:        make(map[string][]*File),
+		PullRequests: make([]*PullRequest, 0),
+	}
+
+	return r
+}
+
+// Repo returns the state of repository 'name' ("{OWNER}/{REPO}") or nil if it does not exist.
+func (s *Server) Repo(name string) *Repo {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.repos[name]
+}
+
+// Files returns the files in the tree for the commit at the head of 'branch' in repository 'name'.
+func (s *Server) Files(name string, branch string) ([]*File, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[name]
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown repo")
+	}
+
+	sha, ok := r.Refs["refs/heads/"+branch]
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown branch")
+	}
+
+	return r.Trees[r.Commits[sha].Tree], nil
+}
+
+func (s *Server) repo(w http.ResponseWriter, req *http.Request) *Repo {
+
+	name := fmt.Sprintf("%s/%s", req.PathValue("owner"), req.PathValue("repo"))
+
+	r, ok := s.repos[name]
+
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return nil
+	}
+
+	return r
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"login": s.User.Login,
+		"name":  s.User.Name,
+		"email": s.User.Email,
+	})
+}
+
+func (s *Server) handleGetRepo(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repo(w, req) == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, repoResponse(req.PathValue("owner"), req.PathValue("repo")))
+}
+
+// handleCreateFork copies the state of a repository to a new repository, with the same name, belonging to the
+// "organization" in the request. Like the GitHub API it responds with a "202 Accepted" status.
+func (s *Server) handleCreateFork(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		Organization string `json:"organization"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil || body.Organization == "" {
+		http.Error(w, "Missing organization", http.StatusBadRequest)
+		return
+	}
+
+	fork := newRepo()
+
+	for k, v := range r.Refs {
+		fork.Refs[k] = v
+	}
+
+	for k, v := range r.Commits {
+		fork.Commits[k] = v
+	}
+
+	for k, v := range r.Trees {
+		fork.Trees[k] = v
+	}
+
+	name := req.PathValue("repo")
+	s.repos[fmt.Sprintf("%s/%s", body.Organization, name)] = fork
+
+	writeJSON(w, http.StatusAccepted, repoResponse(body.Organization, name))
+}
+
+// handleEditRepo renames a repository.
+func (s *Server) handleEditRepo(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	owner := req.PathValue("owner")
+	name := req.PathValue("repo")
+
+	if body.Name != "" && body.Name != name {
+		delete(s.repos, fmt.Sprintf("%s/%s", owner, name))
+		s.repos[fmt.Sprintf("%s/%s", owner, body.Name)] = r
+		name = body.Name
+	}
+
+	writeJSON(w, http.StatusOK, repoResponse(owner, name))
+}
+
+func (s *Server) handleGetRef(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	ref := "refs/" + req.PathValue("ref")
+	sha, ok := r.Refs[ref]
+
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, refResponse(ref, sha))
+}
+
+func (s *Server) handleCreateRef(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, exists := r.Refs[body.Ref]; exists {
+		http.Error(w, "Reference already exists", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, exists := r.Commits[body.SHA]; !exists {
+		http.Error(w, "Object does not exist", http.StatusUnprocessableEntity)
+		return
+	}
+
+	r.Refs[body.Ref] = body.SHA
+	writeJSON(w, http.StatusCreated, refResponse(body.Ref, body.SHA))
+}
+
+func (s *Server) handleUpdateRef(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	ref := "refs/" + req.PathValue("ref")
+
+	if _, exists := r.Refs[ref]; !exists {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		SHA string `json:"sha"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, exists := r.Commits[body.SHA]; !exists {
+		http.Error(w, "Object does not exist", http.StatusUnprocessableEntity)
+		return
+	}
+
+	r.Refs[ref] = body.SHA
+	writeJSON(w, http.StatusOK, refResponse(ref, body.SHA))
+}
+
+func (s *Server) handleCreateTree(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		BaseTree string  `json:"base_tree"`
+		Tree     []*File `json:"tree"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Like the GitHub API base_tree may be a commit (or a tree) SHA
+
+	base_sha := body.BaseTree
+
+	if c, ok := r.Commits[base_sha]; ok {
+		base_sha = c.Tree
+	}
+
+	files := make([]*File, 0)
+	lookup := make(map[string]*File)
+
+	for _, f := range r.Trees[base_sha] {
+		lookup[f.Path] = f
+		files = append(files, f)
+	}
+
+	for _, f := range body.Tree {
+
+		if existing, ok := lookup[f.Path]; ok {
+			existing.Content = f.Content
+			continue
+		}
+
+		lookup[f.Path] = f
+		files = append(files, f)
+	}
+
+	sha := digest("tree", strconv.Itoa(len(r.Trees)), body.BaseTree)
+	r.Trees[sha] = files
+
+	writeJSON(w, http.StatusCreated, map[string]any{"sha": sha})
+}
+
+func (s *Server) handleGetCommit(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	c, ok := r.Commits[req.PathValue("sha")]
+
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, commitResponse(c))
+}
+
+func (s *Server) handleCreateCommit(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+		Author  *struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, exists := r.Trees[body.Tree]; !exists {
+		http.Error(w, "Tree does not exist", http.StatusUnprocessableEntity)
+		return
+	}
+
+	sha := digest("commit", strconv.Itoa(len(r.Commits)), body.Tree)
+
+	c := &Commit{
+		SHA:     sha,
+		Message: body.Message,
+		Parents: body.Parents,
+		Tree:    body.Tree,
+	}
+
+	if body.Author != nil {
+		c.AuthorName = body.Author.Name
+		c.AuthorEmail = body.Author.Email
+	}
+
+	r.Commits[sha] = c
+	writeJSON(w, http.StatusCreated, commitResponse(c))
+}
+
+func (s *Server) handleCreatePullRequest(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	var body struct {
+		Title string `json:"title"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+		Body  string `json:"body"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Heads in other repositories (forks) are "{OWNER}:{BRANCH}" strings
+
+	head_repo := r
+	head_branch := body.Head
+
+	if owner, branch, is_fork := strings.Cut(body.Head, ":"); is_fork {
+
+		head_repo = nil
+		head_branch = branch
+
+		for name, other := range s.repos {
+
+			if strings.HasPrefix(name, owner+"/") {
+
+				if _, exists := other.Refs["refs/heads/"+branch]; exists {
+					head_repo = other
+					break
+				}
+			}
+		}
+	}
+
+	if head_repo == nil {
+		http.Error(w, "Unknown head", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, exists := head_repo.Refs["refs/heads/"+head_branch]; !exists {
+		http.Error(w, "Unknown head", http.StatusUnprocessableEntity)
+		return
+	}
+
+	pr := &PullRequest{
+		Number: len(r.PullRequests) + 1,
+		Title:  body.Title,
+		Head:   body.Head,
+		Base:   body.Base,
+		Body:   body.Body,
+	}
+
+	r.PullRequests = append(r.PullRequests, pr)
+	writeJSON(w, http.StatusCreated, s.pullRequestResponse(req, pr))
+}
+
+func (s *Server) handleEditPullRequest(w http.ResponseWriter, req *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(w, req)
+
+	if r == nil {
+		return
+	}
+
+	number, err := strconv.Atoi(req.PathValue("number"))
+
+	if err != nil || number < 1 || number > len(r.PullRequests) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Body *string `json:"body"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pr := r.PullRequests[number-1]
+
+	if body.Body != nil {
+		pr.Body = *body.Body
+	}
+
+	writeJSON(w, http.StatusOK, s.pullRequestResponse(req, pr))
+}
+
+func (s *Server) pullRequestResponse(req *http.Request, pr *PullRequest) map[string]any {
+
+	html_url := fmt.Sprintf("%s/%s/%s/pull/%d", s.URL, req.PathValue("owner"), req.PathValue("repo"), pr.Number)
+
+	return map[string]any{
+		"number":   pr.Number,
+		"title":    pr.Title,
+		"body":     pr.Body,
+		"html_url": html_url,
+	}
+}
+
+func repoResponse(owner string, name string) map[string]any {
+
+	return map[string]any{
+		"name":      name,
+		"full_name": fmt.Sprintf("%s/%s", owner, name),
+		"owner": map[string]any{
+			"login": owner,
+		},
+	}
+}
+
+func refResponse(ref string, sha string) map[string]any {
+
+	return map[string]any{
+		"ref": ref,
+		"object": map[string]any{
+			"type": "commit",
+			"sha":  sha,
+		},
+	}
+}
+
+func commitResponse(c *Commit) map[string]any {
+
+	parents := make([]map[string]any, len(c.Parents))
+
+	for idx, sha := range c.Parents {
+		parents[idx] = map[string]any{"sha": sha}
+	}
+
+	return map[string]any{
+		"sha":     c.SHA,
+		"message": c.Message,
+		"tree":    map[string]any{"sha": c.Tree},
+		"parents": parents,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+func digest(parts ...string) string {
+
+	h := sha1.New()
+
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,222 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DEFAULT_COMMIT_MESSAGE_TEMPLATE is the default `text/template` template used to derive commit messages.
+const DEFAULT_COMMIT_MESSAGE_TEMPLATE string = "[{{ .Author }}] update {{ .Action }} data for {{ .Path }}"
+
+// DEFAULT_PULL_REQUEST_TITLE_TEMPLATE is the default `text/template` template used to derive pull request titles.
+const DEFAULT_PULL_REQUEST_TITLE_TEMPLATE string = "[{{ .Author }}] update {{ .Action }} data for {{ .WhosOnFirstId }}"
+
+// DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE is the default `text/template` template used to derive pull request descriptions.
+const DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE string = DEFAULT_PULL_REQUEST_TITLE_TEMPLATE
+
+// DEFAULT_BRANCH_TEMPLATE is the default `text/template` template used to derive branch names.
+const DEFAULT_BRANCH_TEMPLATE string = "{{ .Author }}-{{ .Timestamp }}-{{ .Action }}-{{ .WhosOnFirstId }}"
+
+// TemplateContext is the struct passed to each of the templates defined in `Templates`. It describes the operation
+// being committed.
+type TemplateContext struct {
+	// The name of the person (or process) performing the operation.
+	Author string
+	// The action being performed.
+	Action Action
+	// The Who's On First ID of the record that triggered the operation (for example a depiction).
+	WhosOnFirstId int64
+	// The Who's On First ID of the subject (parent) record of 'WhosOnFirstId', if known.
+	SubjectId int64
+	// The list of labels (for example georeference labels) being updated, if any.
+	Labels []string
+	// The names of the places being referenced, if any.
+	Places []string
+	// The Unix timestamp when the template context was created.
+	Timestamp int64
+	// The path (or comma-separated list of paths) of the file(s) being committed. This is only meaningful for commit messages.
+	// For `githubapi://` writers, which format a commit message for each file they write, it is replaced by the path of that file.
+	// For other writers, whose paths are not known until they are committed, it is the string value of 'WhosOnFirstId'.
+	Path string
+}
+
+// Templates defines `text/template` templates for the commit messages, pull request titles and descriptions and branch
+// names created by GitHub writers. Each template is passed a `TemplateContext` instance. Empty values are replaced by their
+// corresponding `DEFAULT_` template.
+type Templates struct {
+	// The template used to derive commit messages.
+	CommitMessage string
+	// The template used to derive pull request titles.
+	PullRequestTitle string
+	// The template used to derive pull request descriptions.
+	PullRequestDescription string
+	// The template used to derive branch names.
+	Branch string
+}
+
+// RenderedTemplates contains the output of each of the templates defined by a `Templates` instance.
+type RenderedTemplates struct {
+	CommitMessage          string
+	PullRequestTitle       string
+	PullRequestDescription string
+	Branch                 string
+}
+
+var re_slug = regexp.MustCompile(`[^a-z0-9\-_\.]+`)
+
+// templateFuncs are the additional functions available to templates.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"slug": func(s string) string {
+		s = re_slug.ReplaceAllString(strings.ToLower(s), "-")
+		return strings.Trim(s, "-")
+	},
+}
+
+// DefaultTemplates returns a new `Templates` instance containing the default templates.
+func DefaultTemplates() *Templates {
+
+	t := &Templates{
+		CommitMessage:          DEFAULT_COMMIT_MESSAGE_TEMPLATE,
+		PullRequestTitle:       DEFAULT_PULL_REQUEST_TITLE_TEMPLATE,
+		PullRequestDescription: DEFAULT_PULL_REQUEST_DESCRIPTION_TEMPLATE,
+		Branch:                 DEFAULT_BRANCH_TEMPLATE,
+	}
+
+	return t
+}
+
+// Validate ensures that each template defined by 't' can be parsed.
+func (t *Templates) Validate() error {
+
+	for name, t_str := range t.templates() {
+
+		_, err := parseTemplate(name, t_str)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Render renders each template defined by 't' using 'tctx'. If 't' is nil the default templates are used.
+func (t *Templates) Render(tctx *TemplateContext) (*RenderedTemplates, error) {
+
+	rendered := make(map[string]string)
+
+	for name, t_str := range t.templates() {
+
+		str, err := renderTemplate(name, t_str, tctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rendered[name] = str
+	}
+
+	r := &RenderedTemplates{
+		CommitMessage:          rendered["commit message"],
+		PullRequestTitle:       rendered["pull request title"],
+		PullRequestDescription: rendered["pull request description"],
+		Branch:                 rendered["branch"],
+	}
+
+	return r, nil
+}
+
+// RenderCommitMessage renders the commit message template defined by 't' using 'tctx'. If 't' is nil the default template is used.
+func (t *Templates) RenderCommitMessage(tctx *TemplateContext) (string, error) {
+	return renderTemplate("commit message", t.templates()["commit message"], tctx)
+}
+
+// templates returns the templates defined by 't', keyed by name, substituting default templates for empty values.
+func (t *Templates) templates() map[string]string {
+
+	d := DefaultTemplates()
+
+	if t == nil {
+		t = d
+	}
+
+	choose := func(t_str string, default_str string) string {
+
+		if t_str == "" {
+			return default_str
+		}
+
+		return t_str
+	}
+
+	templates := map[string]string{
+		"commit message":           choose(t.CommitMessage, d.CommitMessage),
+		"pull request title":       choose(t.PullRequestTitle, d.PullRequestTitle),
+		"pull request description": choose(t.PullRequestDescription, d.PullRequestDescription),
+		"branch":                   choose(t.Branch, d.Branch),
+	}
+
+	return templates
+}
+
+func parseTemplate(name string, t_str string) (*template.Template, error) {
+
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(t_str)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse %s template, %w", name, err)
+	}
+
+	return t, nil
+}
+
+func renderTemplate(name string, t_str string, tctx *TemplateContext) (string, error) {
+
+	t, err := parseTemplate(name, t_str)
+
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = t.Execute(&buf, tctx)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to render %s template, %w", name, err)
+	}
+
+	str := strings.TrimSpace(buf.String())
+
+	if str == "" {
+		return "", fmt.Errorf("Rendered %s template is empty", name)
+	}
+
+	return str, nil
+}
+
+// newTemplateContext returns a new `TemplateContext` instance for 'author', 'action', 'id', 'subject_id', 'labels' and 'places'
+// whose timestamp is the current time.
+func newTemplateContext(author string, action Action, id int64, subject_id int64, labels []string, places []string) *TemplateContext {
+
+	now := time.Now()
+
+	tctx := &TemplateContext{
+		Author:        author,
+		Action:        action,
+		WhosOnFirstId: id,
+		SubjectId:     subject_id,
+		Labels:        labels,
+		Places:        places,
+		Timestamp:     now.Unix(),
+	}
+
+	return tctx
+}
@@ -0,0 +1,106 @@
+package github
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTemplatesRender(t *testing.T) {
+
+	tctx := &TemplateContext{
+		Author:        "alice",
+		Action:        GeoreferenceAction,
+		WhosOnFirstId: 1897903961,
+		SubjectId:     1897902471,
+		Labels:        []string{"sfomuseum:depicts", "sfomuseum:flight_route"},
+		Places:        []string{"San Francisco", "Bangkok"},
+		Timestamp:     1700000000,
+		Path:          "189/790/396/1/1897903961.geojson",
+	}
+
+	// The nil and empty templates should produce the same values as the default templates
+
+	for _, templates := range []*Templates{nil, &Templates{}, DefaultTemplates()} {
+
+		rendered, err := templates.Render(tctx)
+
+		if err != nil {
+			t.Fatalf("Failed to render templates, %v", err)
+		}
+
+		if rendered.CommitMessage != "[alice] update georeference data for 189/790/396/1/1897903961.geojson" {
+			t.Fatalf("Unexpected commit message, %s", rendered.CommitMessage)
+		}
+
+		if rendered.PullRequestTitle != "[alice] update georeference data for 1897903961" {
+			t.Fatalf("Unexpected pull request title, %s", rendered.PullRequestTitle)
+		}
+
+		if rendered.PullRequestDescription != rendered.PullRequestTitle {
+			t.Fatalf("Unexpected pull request description, %s", rendered.PullRequestDescription)
+		}
+
+		if rendered.Branch != "alice-1700000000-georeference-1897903961" {
+			t.Fatalf("Unexpected branch, %s", rendered.Branch)
+		}
+	}
+
+	templates := &Templates{
+		CommitMessage:    "{{ .Action }}: {{ join .Labels \", \" }} ({{ .SubjectId }})",
+		PullRequestTitle: "Update {{ join .Places \" and \" }}",
+		Branch:           "{{ .Author | upper }}/{{ slug (index .Places 0) }}-{{ .WhosOnFirstId }}",
+	}
+
+	err := templates.Validate()
+
+	if err != nil {
+		t.Fatalf("Failed to validate templates, %v", err)
+	}
+
+	rendered, err := templates.Render(tctx)
+
+	if err != nil {
+		t.Fatalf("Failed to render templates, %v", err)
+	}
+
+	expected := map[string]string{
+		rendered.CommitMessage:          "georeference: sfomuseum:depicts, sfomuseum:flight_route (1897902471)",
+		rendered.PullRequestTitle:       "Update San Francisco and Bangkok",
+		rendered.PullRequestDescription: "[alice] update georeference data for 1897903961",
+		rendered.Branch:                 "ALICE/san-francisco-1897903961",
+	}
+
+	for v, e := range expected {
+
+		if v != e {
+			t.Fatalf("Expected '%s', got '%s'", e, v)
+		}
+	}
+
+	invalid := []*Templates{
+		&Templates{Branch: "{{ .Author "},
+		&Templates{PullRequestTitle: "{{ .Unknown }}"},
+		&Templates{CommitMessage: "{{ if false }}{{ end }}"},
+	}
+
+	for idx, templates := range invalid {
+
+		_, err := templates.Render(tctx)
+
+		if err == nil {
+			t.Fatalf("Expected invalid template at offset %d to fail", idx)
+		}
+	}
+
+	err = invalid[0].Validate()
+
+	if err == nil {
+		t.Fatalf("Expected template with syntax error to fail validation")
+	}
+
+	msg, err := invalid[1].RenderCommitMessage(tctx)
+
+	if err != nil || msg != fmt.Sprintf("[alice] update georeference data for %s", tctx.Path) {
+		t.Fatalf("Unexpected commit message, %s %v", msg, err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"time"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 type Action int
@@ -26,12 +28,59 @@ const (
 	GeotagAction
 )
 
+// GITHUBAPI_SCHEME is the URI scheme for `whosonfirst/go-writer-github` writers which commit each file separately.
+const GITHUBAPI_SCHEME string = "githubapi"
+
+// GITHUBAPI_BRANCH_SCHEME is the URI scheme for `whosonfirst/go-writer-github` writers which commit all their files to a new branch.
+const GITHUBAPI_BRANCH_SCHEME string = "githubapi-branch"
+
+// GITHUBAPI_PR_SCHEME is the URI scheme for `whosonfirst/go-writer-github` pull request writers.
+const GITHUBAPI_PR_SCHEME string = "githubapi-pr"
+
+// GITHUBAPI_TREE_SCHEME is the URI scheme for `whosonfirst/go-writer-github` writers which commit all their files in a single commit.
+const GITHUBAPI_TREE_SCHEME string = "githubapi-tree"
+
+// writerURIParameters maps each GitHub writer scheme to the function used to assign its URI parameters.
+var writerURIParameters = map[string]func(*UpdateWriterURIOptions, url.Values) error{
+	GITHUBAPI_SCHEME:        assignAPIParameters,
+	GITHUBAPI_BRANCH_SCHEME: assignBranchParameters,
+	GITHUBAPI_PR_SCHEME:     assignPullRequestParameters,
+	GITHUBAPI_TREE_SCHEME:   assignTreeParameters,
+}
+
+// commitPathPlaceholder is the value assigned to `TemplateContext.Path` when rendering commit message templates for
+// `githubapi://` writers. It is replaced by the "%s" placeholder the writer expects after any other "%" characters in
+// the rendered message have been escaped.
+const commitPathPlaceholder string = "{{{path}}}"
+
+// UpdateWriterURIOptions defines configuration details for the `UpdateWriterURI` method.
 type UpdateWriterURIOptions struct {
+	// The Who's On First ID of the record that triggered the update.
 	WhosOnFirstId int64
-	Author        string
-	Action        Action
+	// The name of the person (or process) performing the update.
+	Author string
+	// The action being performed.
+	Action Action
+	// The Who's On First ID of the subject (parent) record of 'WhosOnFirstId', if known.
+	SubjectId int64
+	// The list of labels being updated, if any.
+	Labels []string
+	// The names of the places being referenced, if any.
+	Places []string
+	// Optional templates used to derive commit messages, pull request titles and descriptions and branch names. If nil
+	// the default templates are used.
+	Templates *Templates
 }
 
+// TemplateContext returns a new `TemplateContext` instance derived from 'opts'.
+func (opts *UpdateWriterURIOptions) TemplateContext() *TemplateContext {
+	return newTemplateContext(opts.Author, opts.Action, opts.WhosOnFirstId, opts.SubjectId, opts.Labels, opts.Places)
+}
+
+// UpdateWriterURI assigns the commit message, author, branch and pull request details derived from 'opts' and its templates
+// to 'writer_uri' if its scheme is one of the GitHub writer schemes registered by `whosonfirst/go-writer-github` (see
+// `SupportedWriterSchemes`). Any existing values for those parameters are replaced. Other parameters, and other URIs, are
+// left unchanged.
 func UpdateWriterURI(ctx context.Context, opts *UpdateWriterURIOptions, writer_uri string) (string, error) {
 
 	wr_u, err := url.Parse(writer_uri)
@@ -40,47 +89,133 @@ func UpdateWriterURI(ctx context.Context, opts *UpdateWriterURIOptions, writer_u
 		return "", fmt.Errorf("Failed to parse URI, %w", err)
 	}
 
-	switch wr_u.Scheme {
+	assign_params, exists := writerURIParameters[wr_u.Scheme]
 
-	case "githubapi":
+	if !exists {
+		return wr_u.String(), nil
+	}
 
-		update_msg := fmt.Sprintf("[%s] update %s data for ", opts.Author, opts.Action)
-		update_msg = update_msg + "%s" // I wish I knew how to include a literal '%s' in fmt.Sprintf...
+	wr_q := wr_u.Query()
 
-		wr_q := wr_u.Query()
+	err = assign_params(opts, wr_q)
 
-		wr_q.Del("new")
-		wr_q.Del("update")
+	if err != nil {
+		return "", fmt.Errorf("Failed to assign parameters for %s writer, %w", wr_u.Scheme, err)
+	}
 
-		wr_q.Set("new", update_msg)
-		wr_q.Set("update", update_msg)
+	wr_u.RawQuery = wr_q.Encode()
 
-		// branch...
-		wr_u.RawQuery = wr_q.Encode()
+	return wr_u.String(), nil
+}
 
-	case "githubapi-pr":
+// SupportedWriterSchemes returns the sorted list of writer URI schemes that `UpdateWriterURI` assigns parameters to.
+func SupportedWriterSchemes() []string {
 
-		title := fmt.Sprintf("[%s] update %s data for %d", opts.Author, opts.Action, opts.WhosOnFirstId)
-		description := title
+	schemes := make([]string, 0, len(writerURIParameters))
 
-		now := time.Now()
-		ts := now.Unix()
+	for s := range writerURIParameters {
+		schemes = append(schemes, s)
+	}
 
-		branch := fmt.Sprintf("%s-%d-%s-%d", opts.Author, ts, opts.Action, opts.WhosOnFirstId)
+	slices.Sort(schemes)
+	return schemes
+}
 
-		wr_q := wr_u.Query()
+// assignAPIParameters assigns the "new" and "update" commit message parameters for `githubapi://` writers. These writers
+// commit each file separately, formatting the message with the path of the file.
+func assignAPIParameters(opts *UpdateWriterURIOptions, q url.Values) error {
 
-		wr_q.Del("pr-branch")
-		wr_q.Del("pr-title")
-		wr_q.Del("pr-description")
+	tctx := opts.TemplateContext()
+	tctx.Path = commitPathPlaceholder
 
-		wr_q.Set("pr-branch", branch)
-		wr_q.Set("pr-title", title)
-		wr_q.Set("pr-description", description)
+	msg, err := opts.Templates.RenderCommitMessage(tctx)
 
-		// branch...
-		wr_u.RawQuery = wr_q.Encode()
+	if err != nil {
+		return err
 	}
 
-	return wr_u.String(), nil
+	// The writer formats the message with fmt.Sprintf so escape anything else that looks like a verb
+
+	msg = strings.ReplaceAll(msg, "%", "%%")
+	msg = strings.ReplaceAll(msg, commitPathPlaceholder, "%s")
+
+	q.Set("new", msg)
+	q.Set("update", msg)
+
+	return nil
+}
+
+// assignBranchParameters assigns the "to-branch", "description" (commit message) and "author" parameters for `githubapi-branch://`
+// writers. These writers commit all their files to a new branch in a single commit.
+func assignBranchParameters(opts *UpdateWriterURIOptions, q url.Values) error {
+
+	rendered, err := opts.Templates.Render(opts.multiFileTemplateContext())
+
+	if err != nil {
+		return err
+	}
+
+	q.Set("to-branch", rendered.Branch)
+	q.Set("description", rendered.CommitMessage)
+
+	if opts.Author != "" {
+		q.Set("author", opts.Author)
+	}
+
+	return nil
+}
+
+// assignTreeParameters assigns the "description" (commit message) and "author" parameters for `githubapi-tree://` writers. These
+// writers commit all their files in a single commit to their base branch unless a "to-branch" parameter is present, in which
+// case it is replaced by the rendered branch template.
+func assignTreeParameters(opts *UpdateWriterURIOptions, q url.Values) error {
+
+	rendered, err := opts.Templates.Render(opts.multiFileTemplateContext())
+
+	if err != nil {
+		return err
+	}
+
+	if q.Has("to-branch") {
+		q.Set("to-branch", rendered.Branch)
+	}
+
+	q.Set("description", rendered.CommitMessage)
+
+	if opts.Author != "" {
+		q.Set("author", opts.Author)
+	}
+
+	return nil
+}
+
+// assignPullRequestParameters assigns the "pr-branch", "pr-title", "pr-description" and "pr-author" parameters for
+// `githubapi-pr://` writers. These writers use the pull request description as their commit message.
+func assignPullRequestParameters(opts *UpdateWriterURIOptions, q url.Values) error {
+
+	rendered, err := opts.Templates.Render(opts.multiFileTemplateContext())
+
+	if err != nil {
+		return err
+	}
+
+	q.Set("pr-branch", rendered.Branch)
+	q.Set("pr-title", rendered.PullRequestTitle)
+	q.Set("pr-description", rendered.PullRequestDescription)
+
+	if opts.Author != "" {
+		q.Set("pr-author", opts.Author)
+	}
+
+	return nil
+}
+
+// multiFileTemplateContext returns a new `TemplateContext` for writers which commit all their files at once, and whose paths
+// are not known in advance, so its `Path` property is the string value of 'opts.WhosOnFirstId'.
+func (opts *UpdateWriterURIOptions) multiFileTemplateContext() *TemplateContext {
+
+	tctx := opts.TemplateContext()
+	tctx.Path = strconv.FormatInt(opts.WhosOnFirstId, 10)
+
+	return tctx
 }
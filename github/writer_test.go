@@ -0,0 +1,278 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	writer_github "github.com/whosonfirst/go-writer-github/v3"
+)
+
+func TestUpdateWriterURI(t *testing.T) {
+
+	ctx := context.Background()
+
+	opts := &UpdateWriterURIOptions{
+		WhosOnFirstId: 1897903961,
+		Author:        "alice",
+		Action:        GeotagAction,
+	}
+
+	// Non-GitHub URIs are returned unchanged
+
+	for _, uri := range []string{"repo:///usr/local/data/sfomuseum-data-media-collection", "fs:///usr/local/data"} {
+
+		new_uri, err := UpdateWriterURI(ctx, opts, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to update %s, %v", uri, err)
+		}
+
+		if new_uri != uri {
+			t.Fatalf("Expected %s to be unchanged, got %s", uri, new_uri)
+		}
+	}
+
+	new_uri, err := UpdateWriterURI(ctx, opts, "githubapi://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&new=Created+%25s")
+
+	if err != nil {
+		t.Fatalf("Failed to update githubapi URI, %v", err)
+	}
+
+	u, err := url.Parse(new_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to parse updated URI, %v", err)
+	}
+
+	q := u.Query()
+
+	for _, k := range []string{"new", "update"} {
+
+		if q.Get(k) != "[alice] update geotag data for %s" {
+			t.Fatalf("Unexpected value for %s parameter, %s", k, q.Get(k))
+		}
+	}
+
+	if q.Get("access_token") != "s33kret" {
+		t.Fatalf("Expected access token to be preserved")
+	}
+
+	// Percent signs in rendered messages are escaped so they survive the writer's call to fmt.Sprintf
+
+	opts.Templates = &Templates{
+		CommitMessage:    "100% {{ .Action }} for {{ .Path }}",
+		PullRequestTitle: "{{ .Author }}: {{ .WhosOnFirstId }}",
+		Branch:           "geotag/{{ .WhosOnFirstId }}",
+	}
+
+	new_uri, err = UpdateWriterURI(ctx, opts, "githubapi://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret")
+
+	if err != nil {
+		t.Fatalf("Failed to update githubapi URI, %v", err)
+	}
+
+	u, _ = url.Parse(new_uri)
+	msg := u.Query().Get("update")
+
+	if fmt.Sprintf(msg, "a.geojson") != "100% geotag for a.geojson" {
+		t.Fatalf("Unexpected commit message template, %s", msg)
+	}
+
+	new_uri, err = UpdateWriterURI(ctx, opts, "githubapi-pr://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&pr-title=old")
+
+	if err != nil {
+		t.Fatalf("Failed to update githubapi-pr URI, %v", err)
+	}
+
+	u, _ = url.Parse(new_uri)
+	q = u.Query()
+
+	if q.Get("pr-title") != "alice: 1897903961" || q.Get("pr-branch") != "geotag/1897903961" {
+		t.Fatalf("Unexpected pull request parameters, %s", new_uri)
+	}
+
+	if !strings.HasPrefix(q.Get("pr-description"), "[alice] update geotag data for 1897903961") {
+		t.Fatalf("Expected default pull request description, %s", q.Get("pr-description"))
+	}
+
+	opts.Templates = &Templates{
+		Branch: "{{ .Missing }}",
+	}
+
+	_, err = UpdateWriterURI(ctx, opts, "githubapi-pr://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret")
+
+	if err == nil {
+		t.Fatalf("Expected invalid template to fail")
+	}
+}
+
+func TestSupportedWriterSchemes(t *testing.T) {
+
+	expected := []string{
+		writer_github.GITHUBAPI_SCHEME,
+		writer_github.GITHUBAPI_BRANCH_SCHEME,
+		writer_github.GITHUBAPI_PR_SCHEME,
+		writer_github.GITHUBAPI_TREE_SCHEME,
+	}
+
+	slices.Sort(expected)
+
+	if !slices.Equal(SupportedWriterSchemes(), expected) {
+		t.Fatalf("Expected %v, got %v", expected, SupportedWriterSchemes())
+	}
+}
+
+func TestUpdateWriterURISchemes(t *testing.T) {
+
+	ctx := context.Background()
+
+	opts := &UpdateWriterURIOptions{
+		WhosOnFirstId: 1897903961,
+		SubjectId:     1897902471,
+		Author:        "alice",
+		Action:        GeoreferenceAction,
+		Templates: &Templates{
+			Branch: "georef-{{ .SubjectId }}-{{ .WhosOnFirstId }}",
+		},
+	}
+
+	tests := []struct {
+		uri      string
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			uri: "githubapi://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&branch=dev&prefix=data",
+			expected: map[string]string{
+				"new":    "[alice] update georeference data for %s",
+				"update": "[alice] update georeference data for %s",
+			},
+		},
+		{
+			uri: "githubapi-branch://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&branch=dev&prefix=data&to-branch=old&merge=true&remove-on-merge=true&email=alice@example.com",
+			expected: map[string]string{
+				"to-branch":       "georef-1897902471-1897903961",
+				"description":     "[alice] update georeference data for 1897903961",
+				"author":          "alice",
+				"merge":           "true",
+				"remove-on-merge": "true",
+				"email":           "alice@example.com",
+			},
+		},
+		{
+			uri: "githubapi-tree://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&branch=dev&prefix=data&description=old",
+			expected: map[string]string{
+				"description": "[alice] update georeference data for 1897903961",
+				"author":      "alice",
+			},
+			absent: []string{"to-branch"},
+		},
+		{
+			uri: "githubapi-tree://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&to-branch=old",
+			expected: map[string]string{
+				"to-branch":   "georef-1897902471-1897903961",
+				"description": "[alice] update georeference data for 1897903961",
+				"author":      "alice",
+			},
+		},
+		{
+			uri: "githubapi-pr://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&branch=dev&prefix=data&pr-owner=example&ensure-repo=true",
+			expected: map[string]string{
+				"pr-branch":      "georef-1897902471-1897903961",
+				"pr-title":       "[alice] update georeference data for 1897903961",
+				"pr-description": "[alice] update georeference data for 1897903961",
+				"pr-author":      "alice",
+				"pr-owner":       "example",
+				"ensure-repo":    "true",
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		u, err := url.Parse(test.uri)
+
+		if err != nil {
+			t.Fatalf("Failed to parse %s, %v", test.uri, err)
+		}
+
+		new_uri, err := UpdateWriterURI(ctx, opts, test.uri)
+
+		if err != nil {
+			t.Fatalf("Failed to update %s, %v", test.uri, err)
+		}
+
+		new_u, err := url.Parse(new_uri)
+
+		if err != nil {
+			t.Fatalf("Failed to parse updated URI %s, %v", new_uri, err)
+		}
+
+		if new_u.Scheme != u.Scheme || new_u.Host != u.Host || new_u.Path != u.Path {
+			t.Fatalf("Expected scheme, host and path to be preserved for %s, %s", test.uri, new_uri)
+		}
+
+		q := new_u.Query()
+
+		for k, v := range test.expected {
+
+			if q.Get(k) != v {
+				t.Fatalf("Expected %s parameter to be '%s' for %s, got '%s'", k, v, test.uri, q.Get(k))
+			}
+		}
+
+		for _, k := range test.absent {
+
+			if q.Has(k) {
+				t.Fatalf("Did not expect %s parameter for %s, %s", k, test.uri, new_uri)
+			}
+		}
+
+		// Parameters not assigned by UpdateWriterURI are preserved
+
+		for k, v := range u.Query() {
+
+			_, assigned := test.expected[k]
+
+			if !assigned && q.Get(k) != v[0] {
+				t.Fatalf("Expected %s parameter to be preserved for %s, %s", k, test.uri, new_uri)
+			}
+		}
+
+		// Updating an updated URI is a no-op
+
+		again_uri, err := UpdateWriterURI(ctx, opts, new_uri)
+
+		if err != nil {
+			t.Fatalf("Failed to update %s, %v", new_uri, err)
+		}
+
+		if again_uri != new_uri {
+			t.Fatalf("Expected updated URI to round-trip, %s %s", new_uri, again_uri)
+		}
+	}
+
+	// Writers which default to the token's user are left alone if there is no author
+
+	anon_opts := *opts
+	anon_opts.Author = ""
+
+	for _, uri := range []string{"githubapi-branch://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret&to-branch=old", "githubapi-pr://sfomuseum-data/sfomuseum-data-media-collection?access_token=s33kret"} {
+
+		new_uri, err := UpdateWriterURI(ctx, &anon_opts, uri)
+
+		if err != nil {
+			t.Fatalf("Failed to update %s, %v", uri, err)
+		}
+
+		u, _ := url.Parse(new_uri)
+
+		if u.Query().Has("author") || u.Query().Has("pr-author") {
+			t.Fatalf("Did not expect author parameter, %s", new_uri)
+		}
+	}
+}
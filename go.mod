@@ -4,6 +4,7 @@ go 1.25.0
 
 require (
 	github.com/aws/aws-lambda-go v1.53.0
+	github.com/google/go-github/v74 v74.0.0
 	github.com/paulmach/orb v0.12.0
 	github.com/sfomuseum/go-flags v0.12.1
 	github.com/sfomuseum/go-geojson-geotag/v2 v2.0.0
@@ -24,6 +25,7 @@ require (
 	github.com/whosonfirst/go-writer-github/v3 v3.1.2
 	github.com/whosonfirst/go-writer/v3 v3.1.1
 	gocloud.dev v0.45.0
+	golang.org/x/oauth2 v0.34.0
 )
 
 require (
@@ -77,7 +79,6 @@ require (
 	github.com/go-ini/ini v1.67.0 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
-	github.com/google/go-github/v74 v74.0.0 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/google/wire v0.7.0 // indirect
@@ -109,7 +110,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.40.0 // indirect
 	go.uber.org/ratelimit v0.3.1 // indirect
 	golang.org/x/net v0.48.0 // indirect
-	golang.org/x/oauth2 v0.34.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.32.0 // indirect
 	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
@@ -0,0 +1,135 @@
+// Package testfixtures provides helper methods for tests which read from, or write to, the data stored in the fixtures directory.
+// It imports the "testing" package so it should only be imported by `_test.go` files.
+package testfixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+// DEPICTIONS is the name of the fixtures repository containing depiction (image) records.
+const DEPICTIONS string = "sfomuseum-data-media-collection"
+
+// SUBJECTS is the name of the fixtures repository containing subject (object) records.
+const SUBJECTS string = "sfomuseum-data-collection"
+
+// WHOSONFIRST is the name of the fixtures repository containing Who's On First administrative records.
+const WHOSONFIRST string = "whosonfirst-data-admin"
+
+// ARCHITECTURE is the name of the fixtures repository containing "sfomuseum" architecture records.
+const ARCHITECTURE string = "sfomuseum-data-architecture"
+
+// DEFAULT_GEOMETRY_FEATURE_ID is the ID of the fixtures record to use for deriving a default geometry.
+const DEFAULT_GEOMETRY_FEATURE_ID int64 = 1159396131
+
+// Repos defines the readers and `repo://` URIs for a set of fixtures repositories, some of which have been copied
+// to a temporary directory so that they can be written to.
+type Repos struct {
+	// The `repo://` URI for a writable copy of the depiction fixtures.
+	DepictionURI string
+	// The `repo://` URI for a writable copy of the subject fixtures.
+	SubjectURI string
+	// A reader for 'DepictionURI'.
+	DepictionReader reader.Reader
+	// A reader for 'SubjectURI'.
+	SubjectReader reader.Reader
+	// A reader for the (read-only) Who's On First fixtures.
+	WhosOnFirstReader reader.Reader
+	// A reader for 'DepictionURI', 'SubjectURI' and the (read-only) architecture fixtures.
+	SFOMuseumReader reader.Reader
+}
+
+// Root returns the absolute path of the fixtures directory.
+func Root(t testing.TB) string {
+
+	t.Helper()
+
+	_, path, _, ok := runtime.Caller(0)
+
+	if !ok {
+		t.Fatalf("Failed to derive path for testfixtures package")
+	}
+
+	return filepath.Join(filepath.Dir(path), "..", "..", "fixtures")
+}
+
+// RepoURI returns the `repo://` URI for the repository named 'repo' in the directory 'root'.
+func RepoURI(root string, repo string) string {
+	return fmt.Sprintf("repo://%s", filepath.Join(root, repo))
+}
+
+// Copy copies each of the fixtures repositories named in 'repos' to a new temporary directory, which is removed
+// when the test completes, and returns its path.
+func Copy(t testing.TB, repos ...string) string {
+
+	t.Helper()
+
+	root := Root(t)
+	tmp_dir := t.TempDir()
+
+	for _, repo := range repos {
+
+		err := os.CopyFS(filepath.Join(tmp_dir, repo), os.DirFS(filepath.Join(root, repo)))
+
+		if err != nil {
+			t.Fatalf("Failed to copy %s fixtures, %v", repo, err)
+		}
+	}
+
+	return tmp_dir
+}
+
+// NewReader returns a new `reader.Reader` instance for 'uris'. If there is more than one URI a `reader.MultiReader`
+// instance is returned.
+func NewReader(t testing.TB, uris ...string) reader.Reader {
+
+	t.Helper()
+
+	ctx := context.Background()
+
+	var r reader.Reader
+	var err error
+
+	switch len(uris) {
+	case 1:
+		r, err = reader.NewReader(ctx, uris[0])
+	default:
+		r, err = reader.NewMultiReaderFromURIs(ctx, uris...)
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to create reader for %v, %v", uris, err)
+	}
+
+	return r
+}
+
+// NewRepos copies the depiction and subject fixtures to a new temporary directory and returns a new `Repos`
+// instance for those copies and the read-only Who's On First and architecture fixtures.
+func NewRepos(t testing.TB) *Repos {
+
+	t.Helper()
+
+	root := Root(t)
+	tmp_dir := Copy(t, DEPICTIONS, SUBJECTS)
+
+	depiction_uri := RepoURI(tmp_dir, DEPICTIONS)
+	subject_uri := RepoURI(tmp_dir, SUBJECTS)
+
+	repos := &Repos{
+		DepictionURI:      depiction_uri,
+		SubjectURI:        subject_uri,
+		DepictionReader:   NewReader(t, depiction_uri),
+		SubjectReader:     NewReader(t, subject_uri),
+		WhosOnFirstReader: NewReader(t, RepoURI(root, WHOSONFIRST)),
+		SFOMuseumReader:   NewReader(t, depiction_uri, subject_uri, RepoURI(root, ARCHITECTURE)),
+	}
+
+	return repos
+}
@@ -0,0 +1,417 @@
+// Package lint provides methods for checking that georeference and geotag data in depiction (image) and subject (object)
+// records, and their alternate geometry files, are internally consistent.
+package lint
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// RULE_MISSING_ALT_FILE is the rule for a `src:geom_alt` label which has no corresponding alternate geometry file.
+const RULE_MISSING_ALT_FILE string = "missing_alt_file"
+
+// RULE_UNLISTED_ALT_FILE is the rule for a `georef_*` alternate geometry file which is not listed in its record's `src:geom_alt` property.
+const RULE_UNLISTED_ALT_FILE string = "unlisted_alt_file"
+
+// RULE_MISSING_SUBJECT_REFERENCE is the rule for a depiction reference which is absent from its subject's `georef:depicted` property.
+const RULE_MISSING_SUBJECT_REFERENCE string = "missing_subject_reference"
+
+// RULE_INVALID_GEOREFERENCES is the rule for a record whose `georef:depicted` property can not be parsed.
+const RULE_INVALID_GEOREFERENCES string = "invalid_georeferences"
+
+// RULE_GEOTAG_SUBJECT_MISMATCH is the rule for a depiction whose `geotag:subject` property is not equal to its `wof:parent_id` property.
+const RULE_GEOTAG_SUBJECT_MISMATCH string = "geotag_subject_mismatch"
+
+// RULE_SUBJECT_GEOMETRY_MISMATCH is the rule for a subject whose geometry is not the same as the geometry produced by `georeference.RecompileGeorefencesForSubject`.
+const RULE_SUBJECT_GEOMETRY_MISMATCH string = "subject_geometry_mismatch"
+
+// Violation defines a single inconsistency in a record.
+type Violation struct {
+	// The name of the rule being violated.
+	Rule string `json:"rule"`
+	// The ID of the record being reported.
+	Id int64 `json:"id"`
+	// The role of the record being reported, if known.
+	Role plan.Role `json:"role,omitempty"`
+	// The path of the record (or alternate geometry file) being reported, if known.
+	Path string `json:"path,omitempty"`
+	// A human-readable description of the violation.
+	Message string `json:"message"`
+}
+
+// LinterOptions defines configuration options for the `Linter` instance.
+type LinterOptions struct {
+	// RecompileOptions, if not nil, are used to recompile the georeferences for each subject and compare the resulting
+	// geometry with the subject's current geometry. If nil this check is skipped.
+	RecompileOptions *georeference.RecompileGeorefencesForSubjectOptions
+}
+
+// Linter checks the consistency of the records (and alternate geometry files) added to it.
+type Linter struct {
+	options *LinterOptions
+	// The records added to the linter keyed by ID
+	records map[int64]*record
+	// The labels of the alternate geometry files added to the linter keyed by ID and then label, whose value is the path of the file
+	alt_files map[int64]map[string]string
+	// Violations which can be determined from a single record
+	violations []*Violation
+	mu         *sync.Mutex
+}
+
+// record is the subset of a record's properties needed to perform checks across records.
+type record struct {
+	path       string
+	parent_id  int64
+	is_subject bool
+	geom_alt   []string
+	// The list of Who's On First IDs for each georeference label
+	depicted map[string][]int64
+}
+
+// role returns the `plan.Role` of 'r'.
+func (r *record) role() plan.Role {
+
+	if r.is_subject {
+		return plan.SubjectRole
+	}
+
+	return plan.DepictionRole
+}
+
+// NewLinter returns a new `Linter` instance configured by 'opts'.
+func NewLinter(opts *LinterOptions) *Linter {
+
+	if opts == nil {
+		opts = &LinterOptions{}
+	}
+
+	l := &Linter{
+		options:    opts,
+		records:    make(map[int64]*record),
+		alt_files:  make(map[int64]map[string]string),
+		violations: make([]*Violation, 0),
+		mu:         new(sync.Mutex),
+	}
+
+	return l
+}
+
+// AddFromIterator adds all the records emitted by a `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived
+// from 'iterator_uri' for 'sources' to 'l'.
+func (l *Linter) AddFromIterator(ctx context.Context, iterator_uri string, sources ...string) error {
+
+	iter, err := iterate.NewIterator(ctx, iterator_uri)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create new iterator, %w", err)
+	}
+
+	defer iter.Close()
+
+	for rec, err := range iter.Iterate(ctx, sources...) {
+
+		if err != nil {
+			return fmt.Errorf("Iterator signaled an error, %w", err)
+		}
+
+		body, err := io.ReadAll(rec.Body)
+		rec.Body.Close()
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", rec.Path, err)
+		}
+
+		err = l.Add(ctx, rec.Path, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to add %s, %w", rec.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Add adds the record (or alternate geometry file) in 'body', read from 'path', to 'l'. Checks which can be performed
+// using only 'body' are run immediately. Checks which span multiple records are run by the `Report` method.
+func (l *Linter) Add(ctx context.Context, path string, body []byte) error {
+
+	id, uri_args, err := uri.ParseURI(path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to parse path, %w", err)
+	}
+
+	if uri_args.IsAlternate {
+
+		label, err := uri_args.AltGeom.String()
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive alt label, %w", err)
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		_, exists := l.alt_files[id]
+
+		if !exists {
+			l.alt_files[id] = make(map[string]string)
+		}
+
+		l.alt_files[id][label] = path
+		return nil
+	}
+
+	r := &record{
+		path:       path,
+		parent_id:  gjson.GetBytes(body, "properties.wof:parent_id").Int(),
+		is_subject: georeference.IsSubjectRecord(body),
+		geom_alt:   make([]string, 0),
+		depicted:   make(map[string][]int64),
+	}
+
+	for _, a := range gjson.GetBytes(body, "properties.src:geom_alt").Array() {
+		r.geom_alt = append(r.geom_alt, a.String())
+	}
+
+	violations := make([]*Violation, 0)
+
+	depicted, err := loadDepicted(body, r.is_subject)
+
+	if err != nil {
+
+		v := &Violation{
+			Rule:    RULE_INVALID_GEOREFERENCES,
+			Id:      id,
+			Role:    r.role(),
+			Path:    path,
+			Message: err.Error(),
+		}
+
+		violations = append(violations, v)
+
+	} else {
+		r.depicted = depicted
+	}
+
+	geotag_subject_rsp := gjson.GetBytes(body, fmt.Sprintf("properties.%s", geo.RESERVED_GEOTAG_SUBJECT))
+
+	if !r.is_subject && geotag_subject_rsp.Exists() && geotag_subject_rsp.Int() != r.parent_id {
+
+		v := &Violation{
+			Rule:    RULE_GEOTAG_SUBJECT_MISMATCH,
+			Id:      id,
+			Role:    plan.DepictionRole,
+			Path:    path,
+			Message: fmt.Sprintf("geotag:subject (%d) does not match wof:parent_id (%d)", geotag_subject_rsp.Int(), r.parent_id),
+		}
+
+		violations = append(violations, v)
+	}
+
+	// Subjects whose georef:depicted property is absent (or null) have never been georeferenced
+
+	depicted_rsp := gjson.GetBytes(body, fmt.Sprintf("properties.%s", geo.RESERVED_GEOREFERENCE_DEPICTED))
+	has_depicted := depicted_rsp.Exists() && depicted_rsp.Type != gjson.Null
+
+	if r.is_subject && has_depicted && l.options.RecompileOptions != nil {
+
+		v := l.checkSubjectGeometry(ctx, id, path, body)
+
+		if v != nil {
+			violations = append(violations, v)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records[id] = r
+	l.violations = append(l.violations, violations...)
+
+	return nil
+}
+
+// checkSubjectGeometry recompiles the georeferences for the subject in 'body' and returns a `Violation` if the
+// resulting geometry differs from the subject's current geometry, or if the two can not be compared.
+func (l *Linter) checkSubjectGeometry(ctx context.Context, id int64, path string, body []byte) *Violation {
+
+	v := &Violation{
+		Rule: RULE_SUBJECT_GEOMETRY_MISMATCH,
+		Id:   id,
+		Role: plan.SubjectRole,
+		Path: path,
+	}
+
+	_, new_body, err := georeference.RecompileGeorefencesForSubject(ctx, l.options.RecompileOptions, body)
+
+	if err != nil {
+		v.Message = fmt.Sprintf("Failed to recompile georeferences, %v", err)
+		return v
+	}
+
+	current_geom, err := geojson.UnmarshalGeometry([]byte(gjson.GetBytes(body, "geometry").Raw))
+
+	if err != nil {
+		v.Message = fmt.Sprintf("Failed to unmarshal geometry, %v", err)
+		return v
+	}
+
+	expected_geom, err := geojson.UnmarshalGeometry([]byte(gjson.GetBytes(new_body, "geometry").Raw))
+
+	if err != nil {
+		v.Message = fmt.Sprintf("Failed to unmarshal recompiled geometry, %v", err)
+		return v
+	}
+
+	if orb.Equal(current_geom.Geometry(), expected_geom.Geometry()) {
+		return nil
+	}
+
+	v.Message = fmt.Sprintf("Subject geometry (%s) does not match recompiled geometry (%s)", current_geom.Geometry().GeoJSONType(), expected_geom.Geometry().GeoJSONType())
+	return v
+}
+
+// Report runs the checks which span multiple records and returns a `Report` containing all the violations found
+// for the records added to 'l'. Depictions whose subject has not been added to 'l' are not checked against their subject.
+func (l *Linter) Report(ctx context.Context) *Report {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	violations := slices.Clone(l.violations)
+
+	for id, r := range l.records {
+
+		alt_files := l.alt_files[id]
+
+		for _, label := range r.geom_alt {
+
+			_, exists := alt_files[label]
+
+			if !exists {
+
+				v := &Violation{
+					Rule:    RULE_MISSING_ALT_FILE,
+					Id:      id,
+					Role:    r.role(),
+					Path:    r.path,
+					Message: fmt.Sprintf("src:geom_alt lists '%s' but there is no alternate geometry file", label),
+				}
+
+				violations = append(violations, v)
+			}
+		}
+
+		if r.is_subject {
+			continue
+		}
+
+		subject, exists := l.records[r.parent_id]
+
+		if !exists {
+			slog.Debug("Subject not found, skipping subject checks", "id", id, "subject id", r.parent_id)
+			continue
+		}
+
+		for label, ids := range r.depicted {
+
+			for _, ref_id := range ids {
+
+				if !slices.Contains(subject.depicted[label], ref_id) {
+
+					v := &Violation{
+						Rule:    RULE_MISSING_SUBJECT_REFERENCE,
+						Id:      id,
+						Role:    plan.DepictionRole,
+						Path:    r.path,
+						Message: fmt.Sprintf("Reference %d (%s) is missing from subject %d georef:depicted property", ref_id, label, r.parent_id),
+					}
+
+					violations = append(violations, v)
+				}
+			}
+		}
+	}
+
+	for id, alt_files := range l.alt_files {
+
+		r, exists := l.records[id]
+
+		for label, path := range alt_files {
+
+			if !strings.HasPrefix(label, georeference.GEOREF_ALT_PREFIX) {
+				continue
+			}
+
+			if exists && slices.Contains(r.geom_alt, label) {
+				continue
+			}
+
+			v := &Violation{
+				Rule:    RULE_UNLISTED_ALT_FILE,
+				Id:      id,
+				Path:    path,
+				Message: fmt.Sprintf("Alternate geometry file '%s' is not listed in src:geom_alt", label),
+			}
+
+			if !exists {
+				v.Message = fmt.Sprintf("Alternate geometry file '%s' has no corresponding record", label)
+			}
+
+			violations = append(violations, v)
+		}
+	}
+
+	slices.SortFunc(violations, func(a, b *Violation) int {
+		return cmp.Or(
+			cmp.Compare(a.Id, b.Id),
+			cmp.Compare(a.Rule, b.Rule),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+
+	rpt := &Report{
+		Records:    len(l.records),
+		Violations: violations,
+	}
+
+	return rpt
+}
+
+// loadDepicted returns the list of Who's On First IDs for each georeference label in the `georef:depicted` property of 'body'.
+func loadDepicted(body []byte, is_subject bool) (map[string][]int64, error) {
+
+	if is_subject {
+		return georeference.LoadSubjectGeoreferenceDepicted(body)
+	}
+
+	depicted, err := georeference.LoadGeoreferenceDepicted(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[string][]int64)
+
+	for _, d := range depicted {
+		lookup[d.Label] = append(lookup[d.Label], d.Depicts...)
+	}
+
+	return lookup, nil
+}
@@ -0,0 +1,214 @@
+package lint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+var test_records = map[string]string{
+	// A depiction with a missing alt file, an unlisted alt file, a reference missing from its subject and a mismatched geotag:subject
+	"data/100/1/1001.geojson":                              `{"properties":{"wof:id":1001,"wof:parent_id":2001,"geotag:subject":2002,"src:geom_alt":["georef_sfomuseum_depicts","georef_sfomuseum_missing"],"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263,101932003]}]}}`,
+	"data/100/1/1001-alt-georef_sfomuseum_depicts.geojson": `{"properties":{"wof:id":1001,"src:alt_label":"georef_sfomuseum_depicts"}}`,
+	"data/100/1/1001-alt-georef_sfomuseum_extra.geojson":   `{"properties":{"wof:id":1001,"src:alt_label":"georef_sfomuseum_extra"}}`,
+	// A consistent depiction
+	"data/100/2/1002.geojson":                `{"properties":{"wof:id":1002,"wof:parent_id":2001,"geotag:subject":2001,"src:geom_alt":["geotag-fov"],"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263]}]}}`,
+	"data/100/2/1002-alt-geotag-fov.geojson": `{"properties":{"wof:id":1002,"src:alt_label":"geotag-fov"}}`,
+	// A depiction with a malformed georef:depicted property
+	"data/100/3/1003.geojson": `{"properties":{"wof:id":1003,"wof:parent_id":2001,"georef:depicted":"sfomuseum:depicts"}}`,
+	// A depiction with a null georef:depicted property
+	"data/100/4/1004.geojson": `{"properties":{"wof:id":1004,"wof:parent_id":2001,"georef:depicted":null}}`,
+	// The subject
+	"data/200/1/2001.geojson": `{"properties":{"wof:id":2001,"georef:depictions":[1001,1002],"georef:depicted":{"sfomuseum:depicts":[102025263]}}}`,
+	// An alt file without a record
+	"data/300/1/3001-alt-georef_sfomuseum_depicts.geojson": `{"properties":{"wof:id":3001}}`,
+}
+
+func TestLinter(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	for rel_path, body := range test_records {
+
+		path := filepath.Join(root, rel_path)
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create directory for %s, %v", rel_path, err)
+		}
+
+		err = os.WriteFile(path, []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", rel_path, err)
+		}
+	}
+
+	l := NewLinter(nil)
+
+	err := l.AddFromIterator(ctx, "repo://", root)
+
+	if err != nil {
+		t.Fatalf("Failed to add records, %v", err)
+	}
+
+	rpt := l.Report(ctx)
+
+	if rpt.Records != 5 {
+		t.Fatalf("Expected 5 records, got %d", rpt.Records)
+	}
+
+	found := make([]string, len(rpt.Violations))
+
+	for idx, v := range rpt.Violations {
+		found[idx] = fmt.Sprintf("%d %s", v.Id, v.Rule)
+	}
+
+	expected := []string{
+		"1001 geotag_subject_mismatch",
+		"1001 missing_alt_file",
+		"1001 missing_subject_reference",
+		"1001 unlisted_alt_file",
+		"1003 invalid_georeferences",
+		"3001 unlisted_alt_file",
+	}
+
+	if !slices.Equal(found, expected) {
+		t.Fatalf("Unexpected violations, %v", found)
+	}
+
+	for _, v := range rpt.Violations {
+
+		if v.Rule == RULE_MISSING_ALT_FILE && v.Role != plan.DepictionRole {
+			t.Fatalf("Expected missing alt file violation to have depiction role, %v", v)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err = rpt.WriteJSON(&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to write JSON report, %v", err)
+	}
+
+	if gjson.GetBytes(buf.Bytes(), "violations.#").Int() != 6 {
+		t.Fatalf("Unexpected JSON report, %s", buf.String())
+	}
+
+	buf.Reset()
+
+	err = rpt.WriteText(&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to write text report, %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "Checked 5 records, found 6 violations") || !strings.Contains(buf.String(), "unlisted_alt_file (2)") {
+		t.Fatalf("Unexpected text report, %s", buf.String())
+	}
+}
+
+func TestLinterSubjectGeometry(t *testing.T) {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../fixtures")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	depiction_uri := fmt.Sprintf("repo://%s/sfomuseum-data-media-collection", path_fixtures)
+	subject_uri := fmt.Sprintf("repo://%s/sfomuseum-data-collection", path_fixtures)
+
+	depiction_reader, err := reader.NewReader(ctx, depiction_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create depiction reader, %v", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s/whosonfirst-data-admin", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	sfomuseum_reader, err := reader.NewMultiReaderFromURIs(ctx, depiction_uri, subject_uri, fmt.Sprintf("repo://%s/sfomuseum-data-architecture", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create sfomuseum reader, %v", err)
+	}
+
+	recompile_opts := &georeference.RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          depiction_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		DefaultGeometryFeatureId: 1159396131,
+	}
+
+	rel_path := "data/189/790/247/1/1897902471.geojson"
+
+	body, err := os.ReadFile(filepath.Join(path_fixtures, "sfomuseum-data-collection", rel_path))
+
+	if err != nil {
+		t.Fatalf("Failed to read subject, %v", err)
+	}
+
+	_, body, err = georeference.RecompileGeorefencesForSubject(ctx, recompile_opts, body)
+
+	if err != nil {
+		t.Fatalf("Failed to recompile subject, %v", err)
+	}
+
+	opts := &LinterOptions{
+		RecompileOptions: recompile_opts,
+	}
+
+	l := NewLinter(opts)
+
+	err = l.Add(ctx, rel_path, body)
+
+	if err != nil {
+		t.Fatalf("Failed to add subject, %v", err)
+	}
+
+	rpt := l.Report(ctx)
+
+	if len(rpt.Violations) != 0 {
+		t.Fatalf("Expected recompiled subject to have no violations, %v", rpt.Violations[0])
+	}
+
+	body, err = sjson.SetRawBytes(body, "geometry", []byte(`{"type":"Point","coordinates":[0,0]}`))
+
+	if err != nil {
+		t.Fatalf("Failed to update geometry, %v", err)
+	}
+
+	l = NewLinter(opts)
+
+	err = l.Add(ctx, rel_path, body)
+
+	if err != nil {
+		t.Fatalf("Failed to add subject, %v", err)
+	}
+
+	rpt = l.Report(ctx)
+
+	if len(rpt.Violations) != 1 || rpt.Violations[0].Rule != RULE_SUBJECT_GEOMETRY_MISMATCH {
+		t.Fatalf("Expected subject geometry mismatch, %v", rpt.Violations)
+	}
+}
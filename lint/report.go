@@ -0,0 +1,81 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Report defines the outcome of linting a set of records.
+type Report struct {
+	// The number of records (not including alternate geometry files) which were checked.
+	Records int `json:"records"`
+	// The list of violations found, sorted by record ID and then rule.
+	Violations []*Violation `json:"violations"`
+}
+
+// WriteJSON writes a JSON-encoded representation of 'rpt' to 'wr'.
+func (rpt *Report) WriteJSON(wr io.Writer) error {
+
+	enc := json.NewEncoder(wr)
+	err := enc.Encode(rpt)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode report, %w", err)
+	}
+
+	return nil
+}
+
+// WriteText writes a human-readable representation of 'rpt', with violations grouped by rule, to 'wr'.
+func (rpt *Report) WriteText(wr io.Writer) error {
+
+	_, err := fmt.Fprintf(wr, "Checked %d records, found %d violations\n", rpt.Records, len(rpt.Violations))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write report, %w", err)
+	}
+
+	rules := make([]string, 0)
+	by_rule := make(map[string][]*Violation)
+
+	for _, v := range rpt.Violations {
+
+		_, exists := by_rule[v.Rule]
+
+		if !exists {
+			rules = append(rules, v.Rule)
+		}
+
+		by_rule[v.Rule] = append(by_rule[v.Rule], v)
+	}
+
+	for _, rule := range rules {
+
+		_, err := fmt.Fprintf(wr, "\n%s (%d)\n", rule, len(by_rule[rule]))
+
+		if err != nil {
+			return fmt.Errorf("Failed to write report, %w", err)
+		}
+
+		for _, v := range by_rule[rule] {
+
+			_, err := fmt.Fprintf(wr, "  %d\t%s\n", v.Id, v.Message)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write report, %w", err)
+			}
+
+			if v.Path != "" {
+
+				_, err := fmt.Fprintf(wr, "  \t%s\n", v.Path)
+
+				if err != nil {
+					return fmt.Errorf("Failed to write report, %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
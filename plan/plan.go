@@ -0,0 +1,237 @@
+// Package plan provides methods for recording the changes that an update to depiction, subject and alternate geometry
+// records would make without persisting those changes.
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
+
+// Role is a string label describing the role a record plays in an update.
+type Role string
+
+const (
+	// DepictionRole denotes a depiction (for example an image) record.
+	DepictionRole Role = "depiction"
+	// SubjectRole denotes a subject (for example an object) record.
+	SubjectRole Role = "subject"
+	// AltRole denotes an alternate geometry record for a depiction.
+	AltRole Role = "alt"
+)
+
+// Action is a string label describing what would happen to a record.
+type Action string
+
+const (
+	// CreateAction denotes a record that does not exist yet and would be created.
+	CreateAction Action = "create"
+	// UpdateAction denotes an existing record that would be updated.
+	UpdateAction Action = "update"
+)
+
+// IGNORE_PROPERTIES is the list of properties which are always updated when a record is exported
+// and which are excluded from property diffs.
+var IGNORE_PROPERTIES = []string{
+	"wof:lastmodified",
+}
+
+// PropertyDiff describes the difference for a single property between a record's current and planned state.
+type PropertyDiff struct {
+	// The name of the property (relative to the "properties" dictionary).
+	Property string `json:"property"`
+	// The current value of the property. This will be `nil` if the property does not exist.
+	Previous any `json:"previous"`
+	// The planned value of the property. This will be `nil` if the property would be removed.
+	Current any `json:"current"`
+}
+
+// Change describes the planned changes for a single record.
+type Change struct {
+	// The Who's On First ID of the record.
+	Id int64 `json:"id"`
+	// The role of the record in the update.
+	Role Role `json:"role"`
+	// The alternate geometry label of the record, if it is an alternate geometry.
+	AltLabel string `json:"alt_label,omitempty"`
+	// The (relative) URI that the record would be written to.
+	URI string `json:"uri"`
+	// Whether the record would be created or updated.
+	Action Action `json:"action"`
+	// The list of property differences between the current and planned record.
+	Properties []*PropertyDiff `json:"properties"`
+	// A boolean flag indicating whether the geometry of the record would change.
+	GeometryChanged bool `json:"geometry_changed"`
+}
+
+// Changed returns a boolean value indicating whether 'c' contains any property or geometry changes.
+func (c *Change) Changed() bool {
+	return c.GeometryChanged || len(c.Properties) > 0
+}
+
+// Plan is a structured list of the records that would be written by an update.
+type Plan struct {
+	// The list of planned changes, in the order they would be written.
+	Changes []*Change `json:"changes"`
+	mu      *sync.RWMutex
+}
+
+// NewPlan returns a new (empty) `Plan` instance.
+func NewPlan() *Plan {
+
+	p := &Plan{
+		Changes: make([]*Change, 0),
+		mu:      new(sync.RWMutex),
+	}
+
+	return p
+}
+
+// Add appends 'c' to the list of changes in 'p'. If 'p' already contains a change for the same URI it will be replaced.
+func (p *Plan) Add(c *Change) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for idx, existing := range p.Changes {
+
+		if existing.URI == c.URI {
+			p.Changes[idx] = c
+			return
+		}
+	}
+
+	p.Changes = append(p.Changes, c)
+}
+
+// Diff returns the list of property differences between 'previous' and 'current' as well as a boolean flag indicating
+// whether their geometries differ. If 'previous' is nil every property in 'current' is considered new.
+func Diff(previous []byte, current []byte) ([]*PropertyDiff, bool, error) {
+
+	if len(current) == 0 || !gjson.ValidBytes(current) {
+		return nil, false, fmt.Errorf("Invalid current body")
+	}
+
+	if len(previous) > 0 && !gjson.ValidBytes(previous) {
+		return nil, false, fmt.Errorf("Invalid previous body")
+	}
+
+	prev_props := gjson.GetBytes(previous, "properties").Map()
+	curr_props := gjson.GetBytes(current, "properties").Map()
+
+	keys := make([]string, 0)
+
+	for k, _ := range prev_props {
+		keys = append(keys, k)
+	}
+
+	for k, _ := range curr_props {
+
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+
+	diffs := make([]*PropertyDiff, 0)
+
+	for _, k := range keys {
+
+		if slices.Contains(IGNORE_PROPERTIES, k) {
+			continue
+		}
+
+		prev_rsp, prev_ok := prev_props[k]
+		curr_rsp, curr_ok := curr_props[k]
+
+		var prev_v any
+		var curr_v any
+
+		if prev_ok {
+			prev_v = prev_rsp.Value()
+		}
+
+		if curr_ok {
+			curr_v = curr_rsp.Value()
+		}
+
+		if prev_ok == curr_ok && reflect.DeepEqual(prev_v, curr_v) {
+			continue
+		}
+
+		d := &PropertyDiff{
+			Property: k,
+			Previous: prev_v,
+			Current:  curr_v,
+		}
+
+		diffs = append(diffs, d)
+	}
+
+	geom_changed := geometryChanged(previous, current)
+	return diffs, geom_changed, nil
+}
+
+// String returns a human-readable summary of the changes in 'p'.
+func (p *Plan) String() string {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var buf bytes.Buffer
+
+	for _, c := range p.Changes {
+
+		label := string(c.Role)
+
+		if c.AltLabel != "" {
+			label = fmt.Sprintf("%s (%s)", label, c.AltLabel)
+		}
+
+		fmt.Fprintf(&buf, "%s %s %d %s\n", strings.ToUpper(string(c.Action)), label, c.Id, c.URI)
+
+		for _, d := range c.Properties {
+			fmt.Fprintf(&buf, "\t%s: %s -> %s\n", d.Property, encodeValue(d.Previous), encodeValue(d.Current))
+		}
+
+		if c.GeometryChanged {
+			fmt.Fprintf(&buf, "\tgeometry changed\n")
+		}
+	}
+
+	return buf.String()
+}
+
+func geometryChanged(previous []byte, current []byte) bool {
+
+	prev_rsp := gjson.GetBytes(previous, "geometry")
+	curr_rsp := gjson.GetBytes(current, "geometry")
+
+	if prev_rsp.Exists() != curr_rsp.Exists() {
+		return true
+	}
+
+	return !reflect.DeepEqual(prev_rsp.Value(), curr_rsp.Value())
+}
+
+func encodeValue(v any) string {
+
+	if v == nil {
+		return "null"
+	}
+
+	enc, err := json.Marshal(v)
+
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(enc)
+}
@@ -0,0 +1,76 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+
+	previous := []byte(`{"type":"Feature","properties":{"wof:id":1,"wof:name":"a","wof:lastmodified":1,"wof:tags":["x"]},"geometry":{"type":"Point","coordinates":[0,0]}}`)
+	current := []byte(`{"type":"Feature","properties":{"wof:id":1,"wof:name":"b","wof:lastmodified":2,"wof:tags":["x"],"wof:repo":"r"},"geometry":{"type":"Point","coordinates":[0,0]}}`)
+
+	diffs, geom_changed, err := Diff(previous, current)
+
+	if err != nil {
+		t.Fatalf("Failed to derive diff, %v", err)
+	}
+
+	if geom_changed {
+		t.Fatalf("Did not expect geometry to have changed")
+	}
+
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 property diffs, got %d", len(diffs))
+	}
+
+	if diffs[0].Property != "wof:name" || diffs[0].Previous != "a" || diffs[0].Current != "b" {
+		t.Fatalf("Unexpected diff for wof:name, %v", diffs[0])
+	}
+
+	if diffs[1].Property != "wof:repo" || diffs[1].Previous != nil || diffs[1].Current != "r" {
+		t.Fatalf("Unexpected diff for wof:repo, %v", diffs[1])
+	}
+
+	current = []byte(`{"type":"Feature","properties":{"wof:id":1},"geometry":{"type":"Point","coordinates":[1,1]}}`)
+
+	diffs, geom_changed, err = Diff(nil, current)
+
+	if err != nil {
+		t.Fatalf("Failed to derive diff for new record, %v", err)
+	}
+
+	if !geom_changed {
+		t.Fatalf("Expected geometry to have changed for new record")
+	}
+
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 property diff for new record, got %d", len(diffs))
+	}
+
+	_, _, err = Diff(nil, []byte(`{`))
+
+	if err == nil {
+		t.Fatalf("Expected invalid body to fail")
+	}
+}
+
+func TestPlanAdd(t *testing.T) {
+
+	p := NewPlan()
+
+	p.Add(&Change{Id: 1, Role: DepictionRole, URI: "000/000/000/1/1.geojson", Action: UpdateAction})
+	p.Add(&Change{Id: 1, Role: DepictionRole, URI: "000/000/000/1/1.geojson", Action: UpdateAction, GeometryChanged: true})
+
+	if len(p.Changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(p.Changes))
+	}
+
+	if !p.Changes[0].Changed() {
+		t.Fatalf("Expected change to have been replaced")
+	}
+
+	if !strings.Contains(p.String(), "UPDATE depiction 1") {
+		t.Fatalf("Unexpected string value, %s", p.String())
+	}
+}
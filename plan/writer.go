@@ -0,0 +1,134 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// PlanWriter implements the `whosonfirst/go-writer/v3.Writer` interface and records the changes that would
+// be made by each write, relative to the current record read from a `whosonfirst/go-reader/v2.Reader` instance,
+// in a `Plan` instance rather than persisting them.
+type PlanWriter struct {
+	plan   *Plan
+	role   Role
+	reader reader.Reader
+}
+
+// NewPlanWriter returns a new `PlanWriter` instance which will record changes for records with role 'role' in 'p'
+// comparing them against the current records read from 'r'. Writes for alternate geometry files are recorded with
+// the `AltRole` role.
+func NewPlanWriter(ctx context.Context, p *Plan, role Role, r reader.Reader) (writer.Writer, error) {
+
+	wr := &PlanWriter{
+		plan:   p,
+		role:   role,
+		reader: r,
+	}
+
+	return wr, nil
+}
+
+// Write records the differences between the body in 'fh' and the current record for 'path' in the underlying `Plan` instance.
+func (wr *PlanWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read body for %s, %w", path, err)
+	}
+
+	id, uri_args, err := uri.ParseURI(path)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse %s, %w", path, err)
+	}
+
+	role := wr.role
+	alt_label := ""
+
+	if uri_args.IsAlternate {
+
+		role = AltRole
+
+		alt_label, err = uri_args.AltGeom.String()
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to derive alt label for %s, %w", path, err)
+		}
+	}
+
+	action := UpdateAction
+
+	var previous []byte
+
+	exists, err := wr.reader.Exists(ctx, path)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to determine whether %s exists, %w", path, err)
+	}
+
+	if exists {
+
+		r, err := wr.reader.Read(ctx, path)
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		defer r.Close()
+
+		previous, err = io.ReadAll(r)
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to read body for %s, %w", path, err)
+		}
+
+	} else {
+		action = CreateAction
+	}
+
+	diffs, geom_changed, err := Diff(previous, body)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to derive changes for %s, %w", path, err)
+	}
+
+	c := &Change{
+		Id:              id,
+		Role:            role,
+		AltLabel:        alt_label,
+		URI:             path,
+		Action:          action,
+		Properties:      diffs,
+		GeometryChanged: geom_changed,
+	}
+
+	wr.plan.Add(c)
+	return int64(len(body)), nil
+}
+
+// WriterURI returns 'path'.
+func (wr *PlanWriter) WriterURI(ctx context.Context, path string) string {
+	return path
+}
+
+// Flush is a no-op to satisfy the `whosonfirst/go-writer/v3.Writer` interface.
+func (wr *PlanWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op to satisfy the `whosonfirst/go-writer/v3.Writer` interface.
+func (wr *PlanWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+// SetLogger is a no-op to satisfy the `whosonfirst/go-writer/v3.Writer` interface.
+func (wr *PlanWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
@@ -0,0 +1,428 @@
+// Package repair provides methods for fixing inconsistencies between depiction (image) records, their alternate
+// geometry files and their subject (object) records.
+package repair
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/sfomuseum/go-sfomuseum-geo/geotag"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	geo_writers "github.com/sfomuseum/go-sfomuseum-geo/writers"
+	"github.com/whosonfirst/go-whosonfirst-export/v3"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader/v2"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
+)
+
+// DepictionInspection defines the inconsistencies found for a depiction record.
+type DepictionInspection struct {
+	// The ID of the depiction.
+	Id int64
+	// The ID of the depiction's subject (parent).
+	SubjectId int64
+	// The depiction's existing references, derived from its `georef:depicted` property.
+	References []*georeference.Reference
+	// The alternate geometry labels (listed in `src:geom_alt` or derived from 'References') which have no alternate geometry file.
+	Missing []string
+	// The alternate geometry labels listed in `src:geom_alt` which have no alternate geometry file and can not be regenerated.
+	Dangling []string
+}
+
+// Regenerate returns a boolean value indicating whether the depiction's alternate geometry files should be regenerated from its references.
+func (i *DepictionInspection) Regenerate() bool {
+	return len(i.References) > 0 && len(i.Missing) > 0
+}
+
+// Prune returns a boolean value indicating whether dangling labels should be pruned from the depiction's `src:geom_alt` property
+// without regenerating its alternate geometry files.
+func (i *DepictionInspection) Prune() bool {
+	return len(i.References) == 0 && len(i.Dangling) > 0
+}
+
+// InspectDepiction returns a `DepictionInspection` describing the alternate geometry files that are missing for 'depiction_id'.
+func InspectDepiction(ctx context.Context, opts *georeference.AssignReferencesOptions, depiction_id int64) (*DepictionInspection, error) {
+
+	body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load depiction %d, %w", depiction_id, err)
+	}
+
+	subject_id, err := properties.ParentId(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive subject (parent) ID for depiction %d, %w", depiction_id, err)
+	}
+
+	refs, err := georeference.ExistingReferences(ctx, opts, depiction_id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := properties.AltGeometries(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive alt geometries for depiction %d, %w", depiction_id, err)
+	}
+
+	// The labels which can be regenerated from the depiction's references
+
+	expected := make([]string, 0)
+
+	for _, r := range refs {
+		expected = append(expected, georeference.DeriveAltLabelFromReference(r))
+	}
+
+	labels := slices.Clone(listed)
+
+	for _, label := range expected {
+
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	i := &DepictionInspection{
+		Id:         depiction_id,
+		SubjectId:  subject_id,
+		References: refs,
+		Missing:    make([]string, 0),
+		Dangling:   make([]string, 0),
+	}
+
+	for _, label := range labels {
+
+		exists, err := altFileExists(ctx, opts, depiction_id, label)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			continue
+		}
+
+		i.Missing = append(i.Missing, label)
+
+		if !slices.Contains(expected, label) {
+			i.Dangling = append(i.Dangling, label)
+		}
+	}
+
+	return i, nil
+}
+
+// Repair fixes inconsistencies for 'depiction_ids' and 'subject_ids' writing all changes using the writers defined in 'opts':
+//
+//   - Depictions with references (in their `georef:depicted` property) and missing alternate geometry files are refreshed using
+//     `georeference.RefreshReferences` which regenerates the alternate geometry files and removes dangling `src:geom_alt` labels.
+//   - Dangling `src:geom_alt` labels are removed from depictions without references.
+//   - The subjects of each depiction, and each of 'subject_ids', are recompiled and written if they have changed. Subjects with
+//     georeferences are recompiled using `georeference.RecompileGeorefencesForSubject`. Subjects with only geotag depictions
+//     have their geometry derived using `geotag.DeriveGeometryForSubject`.
+//
+// The response is a single `geo_writers.UpdateResult` combining all the records that were updated.
+func Repair(ctx context.Context, opts *georeference.AssignReferencesOptions, depiction_ids []int64, subject_ids []int64) (*geo_writers.UpdateResult, error) {
+
+	logger := slog.Default()
+	logger = logger.With("action", "repair")
+
+	results := make([]*geo_writers.UpdateResult, 0)
+
+	to_refresh := make([]int64, 0)
+	refreshed_subjects := make([]int64, 0)
+
+	to_recompile := make([]int64, 0)
+
+	seen := make([]int64, 0)
+
+	for _, depiction_id := range depiction_ids {
+
+		if slices.Contains(seen, depiction_id) {
+			continue
+		}
+
+		seen = append(seen, depiction_id)
+
+		i, err := InspectDepiction(ctx, opts, depiction_id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Debug("Inspect depiction", "depiction id", depiction_id, "references", len(i.References), "missing", i.Missing, "dangling", i.Dangling)
+
+		switch {
+		case i.Regenerate():
+
+			to_refresh = append(to_refresh, depiction_id)
+
+			if !slices.Contains(refreshed_subjects, i.SubjectId) {
+				refreshed_subjects = append(refreshed_subjects, i.SubjectId)
+			}
+
+		case i.Prune():
+
+			rsp, err := pruneDepiction(ctx, opts, depiction_id, i.Dangling)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to prune depiction %d, %w", depiction_id, err)
+			}
+
+			if rsp != nil {
+				results = append(results, rsp)
+			}
+		}
+
+		if !slices.Contains(to_recompile, i.SubjectId) {
+			to_recompile = append(to_recompile, i.SubjectId)
+		}
+	}
+
+	if len(to_refresh) > 0 {
+
+		logger.Info("Regenerate alt files for depictions", "count", len(to_refresh))
+
+		refresh_opts := *opts
+		refresh_opts.PruneMissingAltFiles = true
+
+		rsp, err := georeference.RefreshReferences(ctx, &refresh_opts, to_refresh...)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to refresh depictions, %w", err)
+		}
+
+		results = append(results, rsp)
+	}
+
+	for _, subject_id := range subject_ids {
+
+		if !slices.Contains(to_recompile, subject_id) {
+			to_recompile = append(to_recompile, subject_id)
+		}
+	}
+
+	for _, subject_id := range to_recompile {
+
+		// Subjects will have already been recompiled when their depictions were refreshed
+
+		if slices.Contains(refreshed_subjects, subject_id) {
+			continue
+		}
+
+		rsp, err := recompileSubject(ctx, opts, subject_id)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to recompile subject %d, %w", subject_id, err)
+		}
+
+		if rsp != nil {
+			results = append(results, rsp)
+		}
+	}
+
+	return georeference.CombineResults(opts.DryRun, results...), nil
+}
+
+// pruneDepiction removes 'labels' from the `src:geom_alt` property of 'depiction_id' and writes the depiction if it has changed.
+// It returns nil if the depiction has not changed.
+func pruneDepiction(ctx context.Context, opts *georeference.AssignReferencesOptions, depiction_id int64, labels []string) (*geo_writers.UpdateResult, error) {
+
+	body, err := wof_reader.LoadBytes(ctx, opts.DepictionReader, depiction_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load depiction, %w", err)
+	}
+
+	listed, err := properties.AltGeometries(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive alt geometries, %w", err)
+	}
+
+	geom_alt := make([]string, 0)
+
+	for _, label := range listed {
+
+		if !slices.Contains(labels, label) {
+			geom_alt = append(geom_alt, label)
+		}
+	}
+
+	updates := map[string]any{
+		"properties.src:geom_alt": geom_alt,
+	}
+
+	has_changed, new_body, err := export.AssignPropertiesIfChanged(ctx, body, updates)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to assign properties, %w", err)
+	}
+
+	if !has_changed {
+		return nil, nil
+	}
+
+	slog.Debug("Prune dangling alt labels", "depiction id", depiction_id, "labels", labels)
+
+	return writeRecord(ctx, opts, depiction_id, github.GeoreferenceAction, plan.DepictionRole, new_body)
+}
+
+// recompileSubject recompiles the georeferences (or geotag geometry) for 'subject_id' and writes the subject if it has changed.
+// It returns nil if the subject has not changed.
+func recompileSubject(ctx context.Context, opts *georeference.AssignReferencesOptions, subject_id int64) (*geo_writers.UpdateResult, error) {
+
+	body, err := wof_reader.LoadBytes(ctx, opts.SubjectReader, subject_id)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load subject, %w", err)
+	}
+
+	refs, err := georeference.LoadSubjectGeoreferences(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load georeferences for subject, %w", err)
+	}
+
+	if len(refs.Depicted) == 0 && len(refs.GeotagDepictions) > 0 {
+
+		geom_opts := &geotag.DeriveGeometryForSubjectOptions{
+			WhosOnFirstReader: opts.WhosOnFirstReader,
+			DepictionReader:   opts.DepictionReader,
+		}
+
+		geom, err := geotag.DeriveGeometryForSubject(ctx, geom_opts, body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive geotag geometry for subject, %w", err)
+		}
+
+		if geom == nil {
+			return nil, nil
+		}
+
+		updates := map[string]any{
+			"geometry": geom,
+		}
+
+		has_changed, new_body, err := export.AssignPropertiesIfChanged(ctx, body, updates)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to assign geometry for subject, %w", err)
+		}
+
+		if !has_changed {
+			return nil, nil
+		}
+
+		return writeRecord(ctx, opts, subject_id, github.GeotagAction, plan.SubjectRole, new_body)
+	}
+
+	recompile_opts := &georeference.RecompileGeorefencesForSubjectOptions{
+		DepictionReader:          opts.DepictionReader,
+		SFOMuseumReader:          opts.SFOMuseumReader,
+		WhosOnFirstReader:        opts.WhosOnFirstReader,
+		DefaultGeometryFeatureId: opts.DefaultGeometryFeatureId,
+		Membership:               opts.SubjectMembership,
+		ReaderCacheURI:           opts.ReaderCacheURI,
+	}
+
+	has_changed, new_body, err := georeference.RecompileGeorefencesForSubject(ctx, recompile_opts, body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to recompile georeferences for subject, %w", err)
+	}
+
+	if !has_changed {
+		return nil, nil
+	}
+
+	return writeRecord(ctx, opts, subject_id, github.GeoreferenceAction, plan.SubjectRole, new_body)
+}
+
+// writeRecord writes 'body' to the depiction or subject writer (defined by 'role') derived from 'opts'. It returns
+// a JSON-encoded `plan.Plan` if 'opts.DryRun' is true or a GeoJSON FeatureCollection containing 'body' otherwise.
+func writeRecord(ctx context.Context, opts *georeference.AssignReferencesOptions, id int64, action github.Action, role plan.Role, body []byte) (*geo_writers.UpdateResult, error) {
+
+	github_opts := &github.UpdateWriterURIOptions{
+		Author:        opts.Author,
+		WhosOnFirstId: id,
+		Action:        action,
+		Templates:     opts.GitHubTemplates,
+	}
+
+	var update_plan *plan.Plan
+
+	if opts.DryRun {
+		update_plan = plan.NewPlan()
+	}
+
+	writers_opts := &geo_writers.CreateWritersOptions{
+		SubjectWriterURI:    opts.SubjectWriterURI,
+		DepictionWriterURI:  opts.DepictionWriterURI,
+		GithubWriterOptions: github_opts,
+		Plan:                update_plan,
+		DepictionReader:     opts.DepictionReader,
+		SubjectReader:       opts.SubjectReader,
+		GitHubAPIURL:        opts.GitHubAPIURL,
+	}
+
+	writers, err := geo_writers.CreateWriters(ctx, writers_opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create writers, %w", err)
+	}
+
+	switch role {
+	case plan.DepictionRole:
+		_, err = wof_writer.WriteBytes(ctx, writers.DepictionMultiWriter, body)
+	default:
+		_, err = wof_writer.WriteBytes(ctx, writers.SubjectMultiWriter, body)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write %s %d, %w", role, id, err)
+	}
+
+	err = writers.Commit(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to commit writes, %w", err)
+	}
+
+	return writers.Result(ctx)
+}
+
+// altFileExists returns a boolean value indicating whether the alternate geometry file labeled 'label' for 'depiction_id' exists.
+func altFileExists(ctx context.Context, opts *georeference.AssignReferencesOptions, depiction_id int64, label string) (bool, error) {
+
+	alt_uri_geom := &uri.AltGeom{
+		Source: label,
+	}
+
+	alt_uri_args := &uri.URIArgs{
+		IsAlternate: true,
+		AltGeom:     alt_uri_geom,
+	}
+
+	alt_uri, err := uri.Id2RelPath(depiction_id, alt_uri_args)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to derive rel path for alt file, %w", err)
+	}
+
+	exists, err := opts.DepictionReader.Exists(ctx, alt_uri)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to determine whether %s exists, %w", alt_uri, err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,255 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func setupRepairOptions(t *testing.T) (*georeference.AssignReferencesOptions, string) {
+
+	ctx := context.Background()
+
+	path_fixtures, err := filepath.Abs("../fixtures")
+
+	if err != nil {
+		t.Fatalf("Failed to derive absolute path, %v", err)
+	}
+
+	tmp_dir := t.TempDir()
+
+	for _, repo := range []string{"sfomuseum-data-media-collection", "sfomuseum-data-collection"} {
+
+		err := os.CopyFS(filepath.Join(tmp_dir, repo), os.DirFS(filepath.Join(path_fixtures, repo)))
+
+		if err != nil {
+			t.Fatalf("Failed to copy %s fixtures, %v", repo, err)
+		}
+	}
+
+	depiction_uri := fmt.Sprintf("repo://%s/sfomuseum-data-media-collection", tmp_dir)
+	subject_uri := fmt.Sprintf("repo://%s/sfomuseum-data-collection", tmp_dir)
+
+	depiction_reader, err := reader.NewReader(ctx, depiction_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create depiction reader, %v", err)
+	}
+
+	subject_reader, err := reader.NewReader(ctx, subject_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create subject reader, %v", err)
+	}
+
+	whosonfirst_reader, err := reader.NewReader(ctx, fmt.Sprintf("repo://%s/whosonfirst-data-admin", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create whosonfirst reader, %v", err)
+	}
+
+	sfomuseum_reader, err := reader.NewMultiReaderFromURIs(ctx, depiction_uri, subject_uri, fmt.Sprintf("repo://%s/sfomuseum-data-architecture", path_fixtures))
+
+	if err != nil {
+		t.Fatalf("Failed to create sfomuseum reader, %v", err)
+	}
+
+	opts := &georeference.AssignReferencesOptions{
+		DepictionReader:          depiction_reader,
+		SubjectReader:            subject_reader,
+		WhosOnFirstReader:        whosonfirst_reader,
+		SFOMuseumReader:          sfomuseum_reader,
+		DefaultGeometryFeatureId: 1159396131,
+		DepictionWriterURI:       depiction_uri,
+		SubjectWriterURI:         subject_uri,
+	}
+
+	return opts, tmp_dir
+}
+
+func updateRecord(t *testing.T, path string, key string, value any) {
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read %s, %v", path, err)
+	}
+
+	body, err = sjson.SetBytes(body, key, value)
+
+	if err != nil {
+		t.Fatalf("Failed to update %s, %v", path, err)
+	}
+
+	err = os.WriteFile(path, body, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+}
+
+func TestRepair(t *testing.T) {
+
+	ctx := context.Background()
+	opts, root := setupRepairOptions(t)
+
+	refs := []*georeference.Reference{
+		&georeference.Reference{Label: "sfomuseum:depicts", Ids: []int64{102025263}},
+	}
+
+	_, err := georeference.AssignReferences(ctx, opts, 1527829813, refs...)
+
+	if err != nil {
+		t.Fatalf("Failed to assign references, %v", err)
+	}
+
+	// Delete the alt file for a depiction with references and add dangling labels
+
+	depiction_root := filepath.Join(root, "sfomuseum-data-media-collection/data/152/782/981/3")
+	alt_path := filepath.Join(depiction_root, "1527829813-alt-georef_sfomuseum_depicts.geojson")
+
+	err = os.Remove(alt_path)
+
+	if err != nil {
+		t.Fatalf("Failed to remove alt file, %v", err)
+	}
+
+	updateRecord(t, filepath.Join(depiction_root, "1527829813.geojson"), "properties.src:geom_alt", []string{"georef_sfomuseum_depicts", "georef_sfomuseum_bogus", "geotag-fov"})
+
+	// Add a dangling label to a depiction without references
+
+	other_path := filepath.Join(root, "sfomuseum-data-media-collection/data/152/782/981/5/1527829815.geojson")
+	updateRecord(t, other_path, "properties.src:geom_alt", []string{"geotag-fov"})
+
+	i, err := InspectDepiction(ctx, opts, 1527829813)
+
+	if err != nil {
+		t.Fatalf("Failed to inspect depiction, %v", err)
+	}
+
+	if !i.Regenerate() || !slices.Equal(i.Dangling, []string{"georef_sfomuseum_bogus", "geotag-fov"}) {
+		t.Fatalf("Unexpected inspection, %v", i)
+	}
+
+	// Dry run
+
+	opts.DryRun = true
+
+	r, err := Repair(ctx, opts, []int64{1527829813, 1527829815}, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to repair (dry run), %v", err)
+	}
+
+	body, err := r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	if len(gjson.GetBytes(body, "changes").Array()) == 0 {
+		t.Fatalf("Expected dry run to record changes, %s", string(body))
+	}
+
+	_, err = os.Stat(alt_path)
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected dry run not to write alt file")
+	}
+
+	opts.DryRun = false
+
+	_, err = Repair(ctx, opts, []int64{1527829813, 1527829815}, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to repair, %v", err)
+	}
+
+	_, err = os.Stat(alt_path)
+
+	if err != nil {
+		t.Fatalf("Expected alt file to be regenerated, %v", err)
+	}
+
+	depiction_body, err := os.ReadFile(filepath.Join(depiction_root, "1527829813.geojson"))
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	geom_alt := gjson.GetBytes(depiction_body, "properties.src:geom_alt").Array()
+
+	if len(geom_alt) != 1 || geom_alt[0].String() != "georef_sfomuseum_depicts" {
+		t.Fatalf("Unexpected src:geom_alt after repair, %v", geom_alt)
+	}
+
+	other_body, err := os.ReadFile(other_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read depiction, %v", err)
+	}
+
+	if len(gjson.GetBytes(other_body, "properties.src:geom_alt").Array()) != 0 {
+		t.Fatalf("Expected dangling label to be pruned, %s", gjson.GetBytes(other_body, "properties.src:geom_alt").Raw)
+	}
+
+	// Nothing left to repair for depictions
+
+	i, err = InspectDepiction(ctx, opts, 1527829813)
+
+	if err != nil {
+		t.Fatalf("Failed to inspect depiction, %v", err)
+	}
+
+	if i.Regenerate() || i.Prune() {
+		t.Fatalf("Expected depiction to be repaired, %v", i)
+	}
+
+	// Out-of-date subject
+
+	subject_path := filepath.Join(root, "sfomuseum-data-collection/data/151/190/738/9/1511907389.geojson")
+
+	subject_body, err := os.ReadFile(subject_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read subject, %v", err)
+	}
+
+	expected_geom := gjson.GetBytes(subject_body, "geometry").Raw
+
+	updateRecord(t, subject_path, "geometry", map[string]any{"type": "Point", "coordinates": []float64{0, 0}})
+
+	r, err = Repair(ctx, opts, nil, []int64{1511907389})
+
+	if err != nil {
+		t.Fatalf("Failed to repair subject, %v", err)
+	}
+
+	body, err = r.Response()
+
+	if err != nil {
+		t.Fatalf("Failed to derive response, %v", err)
+	}
+
+	if len(gjson.GetBytes(body, "features").Array()) != 1 {
+		t.Fatalf("Expected subject to be updated, %s", string(body))
+	}
+
+	subject_body, err = os.ReadFile(subject_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read subject, %v", err)
+	}
+
+	if gjson.GetBytes(subject_body, "geometry").Raw != expected_geom {
+		t.Fatalf("Unexpected subject geometry after repair, %s", gjson.GetBytes(subject_body, "geometry").Raw)
+	}
+}
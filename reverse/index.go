@@ -0,0 +1,481 @@
+// Package reverse provides methods for building and querying a persistent reverse index of the Who's On First
+// places referenced by depiction and subject records, by way of their `georef:` and `geotag:` properties.
+package reverse
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-sfomuseum-geo"
+	"github.com/sfomuseum/go-sfomuseum-geo/georeference"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v3"
+)
+
+// The list of properties that are inspected for references to Who's On First places.
+var REFERENCE_PROPERTIES = []string{
+	geo.RESERVED_GEOREFERENCE_DEPICTED,
+	geo.RESERVED_GEOREFERENCE_BELONGSTO,
+	geo.RESERVED_GEOTAG_BELONGSTO,
+	geo.RESERVED_GEOTAG_CAMERA,
+	geo.RESERVED_GEOTAG_TARGET,
+}
+
+// Role is a string label describing the role a record plays in the index.
+type Role string
+
+const (
+	// DepictionRole denotes a depiction (for example an image) record.
+	DepictionRole Role = "depiction"
+	// SubjectRole denotes a subject (for example an object) record.
+	SubjectRole Role = "subject"
+)
+
+// Referrer is a depiction or subject record which references one or more Who's On First places.
+type Referrer struct {
+	// The unique ID of the depiction or subject record.
+	Id int64 `json:"wof:id"`
+	// The role (`DepictionRole` or `SubjectRole`) of the record.
+	Role Role `json:"role"`
+	// The subject ID for depiction records (derived from its `wof:parent_id` property) or the record's own ID for subject records.
+	SubjectId int64 `json:"subject_id"`
+	// The repository the record belongs to.
+	Repo string `json:"wof:repo,omitempty"`
+	// The list of properties by which the record references a given place. This is only populated by the `Lookup` method.
+	Properties []string `json:"properties,omitempty"`
+}
+
+// Index is a reverse index mapping Who's On First place IDs to the depiction and subject records that reference them.
+type Index struct {
+	// Referrers is the dictionary of all the records in the index, keyed by ID.
+	Referrers map[int64]*Referrer `json:"referrers"`
+	// Places is a dictionary mapping Who's On First place IDs to a dictionary of the records that reference them
+	// (keyed by record ID) and the list of properties they are referenced by.
+	Places map[int64]map[int64][]string `json:"places"`
+	mu     *sync.RWMutex
+}
+
+// NewIndex returns a new, empty, `Index` instance.
+func NewIndex() *Index {
+
+	idx := &Index{
+		Referrers: make(map[int64]*Referrer),
+		Places:    make(map[int64]map[int64][]string),
+		mu:        new(sync.RWMutex),
+	}
+
+	return idx
+}
+
+// NewIndexFromIterator returns a new `Index` instance populated with all the records emitted by a
+// `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived from 'iterator_uri' for 'sources'.
+func NewIndexFromIterator(ctx context.Context, iterator_uri string, sources ...string) (*Index, error) {
+
+	idx := NewIndex()
+
+	err := idx.IndexIterator(ctx, iterator_uri, sources...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// ReadIndex returns a new `Index` instance from the JSON-encoded data in 'r' (as produced by the `Write` method).
+func ReadIndex(r io.Reader) (*Index, error) {
+
+	idx := NewIndex()
+
+	dec := json.NewDecoder(r)
+	err := dec.Decode(idx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode index, %w", err)
+	}
+
+	if idx.Referrers == nil {
+		idx.Referrers = make(map[int64]*Referrer)
+	}
+
+	if idx.Places == nil {
+		idx.Places = make(map[int64]map[int64][]string)
+	}
+
+	return idx, nil
+}
+
+// ReadIndexFromPath returns a new `Index` instance from the JSON-encoded data stored in 'path'.
+func ReadIndexFromPath(path string) (*Index, error) {
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer r.Close()
+
+	return ReadIndex(r)
+}
+
+// Write writes a JSON-encoded representation of 'idx' to 'wr'.
+func (idx *Index) Write(wr io.Writer) error {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	enc := json.NewEncoder(wr)
+	err := enc.Encode(idx)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode index, %w", err)
+	}
+
+	return nil
+}
+
+// WriteToPath writes a JSON-encoded representation of 'idx' to 'path'. The index is written to a temporary file
+// first which is then renamed to 'path' so that an existing index is never left partially written.
+func (idx *Index) WriteToPath(path string) error {
+
+	tmp_path := fmt.Sprintf("%s.tmp", path)
+
+	wr, err := os.Create(tmp_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create %s, %w", tmp_path, err)
+	}
+
+	err = idx.Write(wr)
+
+	if err != nil {
+		wr.Close()
+		os.Remove(tmp_path)
+		return err
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return fmt.Errorf("Failed to close %s, %w", tmp_path, err)
+	}
+
+	err = os.Rename(tmp_path, path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to rename %s, %w", tmp_path, err)
+	}
+
+	return nil
+}
+
+// IndexIterator adds all the records emitted by a `whosonfirst/go-whosonfirst-iterate/v3.Iterator` instance derived
+// from 'iterator_uri' for 'sources' to 'idx'. Alternate geometry files are skipped.
+func (idx *Index) IndexIterator(ctx context.Context, iterator_uri string, sources ...string) error {
+
+	iter, err := iterate.NewIterator(ctx, iterator_uri)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create new iterator, %w", err)
+	}
+
+	defer iter.Close()
+
+	for rec, err := range iter.Iterate(ctx, sources...) {
+
+		if err != nil {
+			return fmt.Errorf("Iterator signaled an error, %w", err)
+		}
+
+		body, err := io.ReadAll(rec.Body)
+		rec.Body.Close()
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", rec.Path, err)
+		}
+
+		if strings.Contains(rec.Path, "-alt-") {
+			continue
+		}
+
+		err = idx.Add(ctx, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to index %s, %w", rec.Path, err)
+		}
+	}
+
+	slog.Debug("Indexed references", "records", idx.Count())
+	return nil
+}
+
+// Add adds the depiction or subject record in 'body' to 'idx', replacing any existing entries for that record. Records
+// which do not reference any Who's On First places are removed from the index. A record is considered to be a subject if
+// it has a `georef:depictions` or `geotag:depictions` property or its `georef:depicted` property is a dictionary; otherwise
+// it is considered to be a depiction.
+func (idx *Index) Add(ctx context.Context, body []byte) error {
+
+	id_rsp := gjson.GetBytes(body, "properties.wof:id")
+
+	if !id_rsp.Exists() {
+		return fmt.Errorf("Record is missing wof:id property")
+	}
+
+	id := id_rsp.Int()
+	is_subject := georeference.IsSubjectRecord(body)
+
+	refs := referencedPlaces(id, body, is_subject)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	r := &Referrer{
+		Id:        id,
+		Role:      DepictionRole,
+		SubjectId: gjson.GetBytes(body, "properties.wof:parent_id").Int(),
+		Repo:      gjson.GetBytes(body, "properties.wof:repo").String(),
+	}
+
+	if is_subject {
+		r.Role = SubjectRole
+		r.SubjectId = id
+	}
+
+	idx.Referrers[id] = r
+
+	for place_id, props := range refs {
+
+		_, ok := idx.Places[place_id]
+
+		if !ok {
+			idx.Places[place_id] = make(map[int64][]string)
+		}
+
+		idx.Places[place_id][id] = props
+	}
+
+	return nil
+}
+
+// Remove removes all the entries for the record with ID 'id' from 'idx'.
+func (idx *Index) Remove(id int64) {
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int64) {
+
+	_, ok := idx.Referrers[id]
+
+	if !ok {
+		return
+	}
+
+	delete(idx.Referrers, id)
+
+	for place_id, referrers := range idx.Places {
+
+		delete(referrers, id)
+
+		if len(referrers) == 0 {
+			delete(idx.Places, place_id)
+		}
+	}
+}
+
+// Count returns the number of depiction and subject records in 'idx'.
+func (idx *Index) Count() int {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.Referrers)
+}
+
+// Lookup returns the list of depiction and subject records which reference any of 'place_ids', sorted by ID. Each
+// `Referrer` has its 'Properties' field populated with the (unique) list of properties by which it references those places.
+func (idx *Index) Lookup(place_ids ...int64) []*Referrer {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	found := make(map[int64]*Referrer)
+
+	for _, place_id := range place_ids {
+
+		for id, props := range idx.Places[place_id] {
+
+			r, ok := found[id]
+
+			if !ok {
+				ref := *idx.Referrers[id]
+				ref.Properties = make([]string, 0)
+				r = &ref
+				found[id] = r
+			}
+
+			for _, p := range props {
+
+				if !slices.Contains(r.Properties, p) {
+					r.Properties = append(r.Properties, p)
+				}
+			}
+		}
+	}
+
+	referrers := make([]*Referrer, 0)
+
+	for _, r := range found {
+		slices.Sort(r.Properties)
+		referrers = append(referrers, r)
+	}
+
+	slices.SortFunc(referrers, func(a *Referrer, b *Referrer) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return referrers
+}
+
+// Subjects returns the sorted, unique, list of subject IDs affected by changes to 'place_ids'. This includes subject records
+// which reference 'place_ids' directly as well as the subjects of any depiction records which reference them. The result is
+// suitable for passing to the `georef-recompile-subject` tool.
+func (idx *Index) Subjects(place_ids ...int64) []int64 {
+
+	subjects := make([]int64, 0)
+
+	for _, r := range idx.Lookup(place_ids...) {
+
+		if r.SubjectId > 0 && !slices.Contains(subjects, r.SubjectId) {
+			subjects = append(subjects, r.SubjectId)
+		}
+	}
+
+	slices.Sort(subjects)
+	return subjects
+}
+
+// Depictions returns the sorted list of depiction IDs which reference any of 'place_ids'.
+func (idx *Index) Depictions(place_ids ...int64) []int64 {
+
+	depictions := make([]int64, 0)
+
+	for _, r := range idx.Lookup(place_ids...) {
+
+		if r.Role == DepictionRole {
+			depictions = append(depictions, r.Id)
+		}
+	}
+
+	return depictions
+}
+
+// referencedPlaces returns a dictionary mapping each of the Who's On First place IDs referenced by 'body' to the list of
+// properties they are referenced by. Georeference properties which can not be parsed are logged and skipped.
+func referencedPlaces(id int64, body []byte, is_subject bool) map[int64][]string {
+
+	refs := make(map[int64][]string)
+
+	add := func(prop string, place_id int64) {
+
+		if place_id <= 0 || slices.Contains(refs[place_id], prop) {
+			return
+		}
+
+		refs[place_id] = append(refs[place_id], prop)
+	}
+
+	depicted, belongsto, err := loadGeoreferences(body, is_subject)
+
+	if err != nil {
+		slog.Warn("Failed to load georeferences, skipping", "id", id, "error", err)
+	}
+
+	for _, place_id := range depicted {
+		add(geo.RESERVED_GEOREFERENCE_DEPICTED, place_id)
+	}
+
+	for _, place_id := range belongsto {
+		add(geo.RESERVED_GEOREFERENCE_BELONGSTO, place_id)
+	}
+
+	for _, prop := range REFERENCE_PROPERTIES {
+
+		switch prop {
+		case geo.RESERVED_GEOREFERENCE_DEPICTED, geo.RESERVED_GEOREFERENCE_BELONGSTO:
+			continue
+		}
+
+		rsp := gjson.GetBytes(body, fmt.Sprintf("properties.%s", prop))
+
+		if !rsp.Exists() {
+			continue
+		}
+
+		if rsp.IsArray() {
+
+			for _, r := range rsp.Array() {
+				add(prop, r.Int())
+			}
+
+			continue
+		}
+
+		add(prop, rsp.Int())
+	}
+
+	return refs
+}
+
+// loadGeoreferences returns the Who's On First IDs in the `georef:depicted` and `georef:whosonfirst_belongsto`
+// properties of 'body'.
+func loadGeoreferences(body []byte, is_subject bool) ([]int64, []int64, error) {
+
+	depicted := make([]int64, 0)
+
+	if is_subject {
+
+		refs, err := georeference.LoadSubjectGeoreferences(body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, ids := range refs.Depicted {
+			depicted = append(depicted, ids...)
+		}
+
+		return depicted, refs.BelongsTo, nil
+	}
+
+	refs, err := georeference.LoadDepictionGeoreferences(body)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, d := range refs.Depicted {
+		depicted = append(depicted, d.Depicts...)
+	}
+
+	return depicted, refs.BelongsTo, nil
+}
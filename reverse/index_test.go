@@ -0,0 +1,201 @@
+package reverse
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+var test_records = map[int64]string{
+	// A georeferenced depiction (image)
+	1897903961: `{"properties":{"wof:id":1897903961,"wof:parent_id":1897902471,"wof:repo":"sfomuseum-data-media-collection","georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[102025263]}],"georef:whosonfirst_belongsto":[102025263,85632293]}}`,
+	// Its subject (object)
+	1897902471: `{"properties":{"wof:id":1897902471,"wof:parent_id":1159162825,"wof:repo":"sfomuseum-data-collection","georef:depictions":[1897903961],"georef:depicted":{"sfomuseum:depicts":[102025263]},"georef:whosonfirst_belongsto":[102025263,85632293]}}`,
+	// A geotagged depiction (image) whose subject has not been indexed
+	1527827539: `{"properties":{"wof:id":1527827539,"wof:parent_id":1511948573,"wof:repo":"sfomuseum-data-media-collection","geotag:whosonfirst_camera":102087579,"geotag:whosonfirst_target":85922583,"geotag:whosonfirst_belongsto":[102087579,85922583,85632293]}}`,
+	// A record with no references (and a null georef:depicted property)
+	1527829813: `{"properties":{"wof:id":1527829813,"wof:parent_id":1511907389,"georef:depicted":null}}`,
+}
+
+func newTestIndex(t *testing.T) *Index {
+
+	ctx := context.Background()
+	idx := NewIndex()
+
+	for id, body := range test_records {
+
+		err := idx.Add(ctx, []byte(body))
+
+		if err != nil {
+			t.Fatalf("Failed to add %d, %v", id, err)
+		}
+	}
+
+	return idx
+}
+
+func TestIndexLookup(t *testing.T) {
+
+	idx := newTestIndex(t)
+
+	if idx.Count() != 3 {
+		t.Fatalf("Expected 3 records, got %d", idx.Count())
+	}
+
+	referrers := idx.Lookup(102025263)
+
+	if len(referrers) != 2 {
+		t.Fatalf("Expected 2 referrers for 102025263, got %d", len(referrers))
+	}
+
+	subject := referrers[0]
+
+	if subject.Id != 1897902471 || subject.Role != SubjectRole || subject.SubjectId != 1897902471 {
+		t.Fatalf("Unexpected subject referrer, %v", subject)
+	}
+
+	depiction := referrers[1]
+
+	if depiction.Id != 1897903961 || depiction.Role != DepictionRole || depiction.SubjectId != 1897902471 {
+		t.Fatalf("Unexpected depiction referrer, %v", depiction)
+	}
+
+	if !slices.Equal(depiction.Properties, []string{"georef:depicted", "georef:whosonfirst_belongsto"}) {
+		t.Fatalf("Unexpected properties for depiction, %v", depiction.Properties)
+	}
+
+	camera := idx.Lookup(102087579)
+
+	if len(camera) != 1 || !slices.Equal(camera[0].Properties, []string{"geotag:whosonfirst_belongsto", "geotag:whosonfirst_camera"}) {
+		t.Fatalf("Unexpected referrers for 102087579, %v", camera)
+	}
+
+	if !slices.Equal(idx.Subjects(102025263), []int64{1897902471}) {
+		t.Fatalf("Unexpected subjects for 102025263, %v", idx.Subjects(102025263))
+	}
+
+	if !slices.Equal(idx.Subjects(85632293), []int64{1511948573, 1897902471}) {
+		t.Fatalf("Unexpected subjects for 85632293, %v", idx.Subjects(85632293))
+	}
+
+	if !slices.Equal(idx.Depictions(85632293), []int64{1527827539, 1897903961}) {
+		t.Fatalf("Unexpected depictions for 85632293, %v", idx.Depictions(85632293))
+	}
+
+	if len(idx.Lookup(1234)) != 0 {
+		t.Fatalf("Expected no referrers for unknown place")
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+
+	ctx := context.Background()
+	idx := newTestIndex(t)
+
+	// Replace Bangkok with Sydney
+
+	updated := `{"properties":{"wof:id":1897903961,"wof:parent_id":1897902471,"georef:depicted":[{"georef:label":"sfomuseum:depicts","wof:depicts":[101932003]}]}}`
+
+	err := idx.Add(ctx, []byte(updated))
+
+	if err != nil {
+		t.Fatalf("Failed to update record, %v", err)
+	}
+
+	if !slices.Equal(idx.Depictions(102025263), []int64{}) {
+		t.Fatalf("Expected stale references to be removed, %v", idx.Depictions(102025263))
+	}
+
+	if !slices.Equal(idx.Depictions(101932003), []int64{1897903961}) {
+		t.Fatalf("Expected updated references, %v", idx.Depictions(101932003))
+	}
+
+	idx.Remove(1897903961)
+
+	if len(idx.Lookup(101932003)) != 0 {
+		t.Fatalf("Expected removed record to be absent")
+	}
+
+	err = idx.Add(ctx, []byte(`{"properties":{}}`))
+
+	if err == nil {
+		t.Fatalf("Expected record without wof:id to fail")
+	}
+}
+
+func TestIndexReadWrite(t *testing.T) {
+
+	idx := newTestIndex(t)
+
+	path := filepath.Join(t.TempDir(), "index.json")
+
+	err := idx.WriteToPath(path)
+
+	if err != nil {
+		t.Fatalf("Failed to write index, %v", err)
+	}
+
+	idx2, err := ReadIndexFromPath(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read index, %v", err)
+	}
+
+	if idx2.Count() != idx.Count() {
+		t.Fatalf("Expected %d records, got %d", idx.Count(), idx2.Count())
+	}
+
+	if !slices.Equal(idx2.Subjects(85632293), idx.Subjects(85632293)) {
+		t.Fatalf("Unexpected subjects after round trip, %v", idx2.Subjects(85632293))
+	}
+
+	_, err = ReadIndex(bytes.NewReader([]byte(`{`)))
+
+	if err == nil {
+		t.Fatalf("Expected invalid index to fail")
+	}
+}
+
+func TestIndexIterator(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	for id, body := range test_records {
+
+		rel_path, err := uri.Id2RelPath(id)
+
+		if err != nil {
+			t.Fatalf("Failed to derive path for %d, %v", id, err)
+		}
+
+		path := filepath.Join(root, "data", rel_path)
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			t.Fatalf("Failed to create directory for %d, %v", id, err)
+		}
+
+		err = os.WriteFile(path, []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %d, %v", id, err)
+		}
+	}
+
+	idx, err := NewIndexFromIterator(ctx, "repo://", root)
+
+	if err != nil {
+		t.Fatalf("Failed to create index, %v", err)
+	}
+
+	if !slices.Equal(idx.Subjects(102025263), []int64{1897902471}) {
+		t.Fatalf("Unexpected subjects for 102025263, %v", idx.Subjects(102025263))
+	}
+}
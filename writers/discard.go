@@ -0,0 +1,129 @@
+package writers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// Discarder is the interface implemented by writers which buffer their writes in memory and only publish them when
+// they are closed (for example the `githubapi-pr://` and `githubapi-tree://` writers).
+type Discarder interface {
+	// Discard drops any buffered writes without publishing them. Once a writer has been discarded its `Close` method
+	// is a no-op.
+	Discard(context.Context) error
+}
+
+// Discard drops any writes buffered by 'wr' if it implements the `Discarder` interface. It returns a boolean value
+// indicating whether 'wr' was discarded. Writers which do not buffer their writes have already persisted them and are
+// left untouched.
+func Discard(ctx context.Context, wr writer.Writer) (bool, error) {
+
+	d, ok := wr.(Discarder)
+
+	if !ok {
+		return false, nil
+	}
+
+	err := d.Discard(ctx)
+
+	if err != nil {
+		return true, fmt.Errorf("Failed to discard writer, %w", err)
+	}
+
+	return true, nil
+}
+
+// NewWriter returns a new `writer.Writer` instance for 'writer_uri'. If 'writer_uri' is a GitHub writer URI whose writes
+// are buffered until the writer is closed (see `github.IsBufferedURI`) then the writer is wrapped in a `BufferedWriter`
+// instance so that its writes can be discarded.
+func NewWriter(ctx context.Context, writer_uri string) (writer.Writer, error) {
+
+	wr, err := writer.NewWriter(ctx, writer_uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if github.IsBufferedURI(writer_uri) {
+		wr = NewBufferedWriter(wr)
+	}
+
+	return wr, nil
+}
+
+// BufferedWriter implements the `whosonfirst/go-writer/v3.Writer` and `Discarder` interfaces for an underlying writer
+// which buffers its writes until it is closed.
+type BufferedWriter struct {
+	writer    writer.Writer
+	discarded bool
+	mu        *sync.Mutex
+}
+
+// NewBufferedWriter returns a new `BufferedWriter` instance wrapping 'wr', which is expected to buffer its writes
+// until it is closed.
+func NewBufferedWriter(wr writer.Writer) *BufferedWriter {
+
+	buffered_wr := &BufferedWriter{
+		writer: wr,
+		mu:     new(sync.Mutex),
+	}
+
+	return buffered_wr
+}
+
+// Write writes the body in 'fh' for 'path' to the underlying writer. It returns an error if 'wr' has been discarded.
+func (wr *BufferedWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.discarded {
+		return 0, fmt.Errorf("Writer has been discarded")
+	}
+
+	return wr.writer.Write(ctx, path, fh)
+}
+
+// WriterURI returns the value of the underlying writer's `WriterURI` method.
+func (wr *BufferedWriter) WriterURI(ctx context.Context, path string) string {
+	return wr.writer.WriterURI(ctx, path)
+}
+
+// Flush is a no-op. Buffered writes are only published when 'wr' is closed.
+func (wr *BufferedWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes, and publishes the buffered writes of, the underlying writer unless 'wr' has been discarded.
+func (wr *BufferedWriter) Close(ctx context.Context) error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.discarded {
+		return nil
+	}
+
+	return wr.writer.Close(ctx)
+}
+
+// Discard marks 'wr' as discarded so that the underlying writer is never closed and its buffered writes are never published.
+func (wr *BufferedWriter) Discard(ctx context.Context) error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	wr.discarded = true
+	return nil
+}
+
+// SetLogger assigns 'logger' to the underlying writer.
+func (wr *BufferedWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return wr.writer.SetLogger(ctx, logger)
+}
@@ -0,0 +1,129 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+type recordedWrite struct {
+	path string
+	body []byte
+}
+
+// recordingWriter implements the `whosonfirst/go-writer/v3.Writer` interface and keeps an in-memory copy of
+// each body written, keyed by path, so that it can be parroted back to the calling application. Unlike the
+// `writer.IOWriter` instances it replaces each write is kept separately rather than being concatenated. If
+// the recording writer wraps another writer each body is written to that writer before being recorded.
+type recordingWriter struct {
+	mu     *sync.Mutex
+	writes []*recordedWrite
+	writer writer.Writer
+}
+
+// newRecordingWriter returns a new `recordingWriter` instance. If 'wr' is not nil each body is written to
+// it before being recorded.
+func newRecordingWriter(wr writer.Writer) *recordingWriter {
+
+	rec_wr := &recordingWriter{
+		mu:     new(sync.Mutex),
+		writes: make([]*recordedWrite, 0),
+		writer: wr,
+	}
+
+	return rec_wr
+}
+
+// Write records the body in 'fh' for 'path'. If 'path' has already been written its body is replaced.
+func (wr *recordingWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read body for %s, %w", path, err)
+	}
+
+	if wr.writer != nil {
+
+		_, err := wr.writer.Write(ctx, path, bytes.NewReader(body))
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	for _, w := range wr.writes {
+
+		if w.path == path {
+			w.body = body
+			return int64(len(body)), nil
+		}
+	}
+
+	wr.writes = append(wr.writes, &recordedWrite{
+		path: path,
+		body: body,
+	})
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns the value of the wrapped writer's `WriterURI` method or 'path' if there is no wrapped writer.
+func (wr *recordingWriter) WriterURI(ctx context.Context, path string) string {
+
+	if wr.writer != nil {
+		return wr.writer.WriterURI(ctx, path)
+	}
+
+	return path
+}
+
+// Flush invokes the wrapped writer's `Flush` method, if present.
+func (wr *recordingWriter) Flush(ctx context.Context) error {
+
+	if wr.writer != nil {
+		return wr.writer.Flush(ctx)
+	}
+
+	return nil
+}
+
+// Close invokes the wrapped writer's `Close` method, if present.
+func (wr *recordingWriter) Close(ctx context.Context) error {
+
+	if wr.writer != nil {
+		return wr.writer.Close(ctx)
+	}
+
+	return nil
+}
+
+// SetLogger assigns 'logger' to the wrapped writer, if present.
+func (wr *recordingWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+
+	if wr.writer != nil {
+		return wr.writer.SetLogger(ctx, logger)
+	}
+
+	return nil
+}
+
+// Writes returns the list of bodies recorded by 'wr' in the order their paths were first written.
+func (wr *recordingWriter) Writes() []*recordedWrite {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	writes := make([]*recordedWrite, len(wr.writes))
+	copy(writes, wr.writes)
+
+	return writes
+}
@@ -0,0 +1,196 @@
+package writers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/sjson"
+)
+
+// UpdateRecord describes a single record written (or, for dry runs, that would be written) by an update.
+type UpdateRecord struct {
+	// The Who's On First ID of the record.
+	Id int64 `json:"id"`
+	// The role of the record in the update.
+	Role plan.Role `json:"role"`
+	// The alternate geometry label of the record, if it is an alternate geometry.
+	AltLabel string `json:"alt_label,omitempty"`
+	// A boolean flag indicating whether the record was created or any of its properties (other than `plan.IGNORE_PROPERTIES`)
+	// or its geometry changed. If the prior body of the record could not be determined this is always true.
+	Changed bool `json:"changed"`
+	// The URI the record was written to, as reported by the underlying writer's `WriterURI` method.
+	URI string `json:"uri"`
+	// The new body of the record.
+	Body json.RawMessage `json:"body"`
+}
+
+// UpdateResult describes the outcome of an update (for example `georeference.AssignReferences` or `geotag.AddGeotagDepiction`).
+type UpdateResult struct {
+	// The list of records written (or, for dry runs, that would be written) in the order they are included in the
+	// FeatureCollection returned by the `FeatureCollection` method.
+	Records []*UpdateRecord `json:"records"`
+	// The `plan.Plan` describing the changes that would have been made, if the update was a dry run.
+	Plan *plan.Plan `json:"plan,omitempty"`
+	// The list of pull requests opened by the update, if any.
+	PullRequests []*github.PullRequest `json:"pull_requests,omitempty"`
+	// Members is an optional dictionary of additional top-level members (for example reference validations) to include
+	// in the output of the `FeatureCollection` and `Response` methods.
+	Members map[string]any `json:"members,omitempty"`
+}
+
+// NewUpdateResult returns a new (empty) `UpdateResult` instance.
+func NewUpdateResult() *UpdateResult {
+
+	r := &UpdateResult{
+		Records:      make([]*UpdateRecord, 0),
+		PullRequests: make([]*github.PullRequest, 0),
+		Members:      make(map[string]any),
+	}
+
+	return r
+}
+
+// DryRun returns a boolean value indicating whether 'r' describes a dry run.
+func (r *UpdateResult) DryRun() bool {
+	return r.Plan != nil
+}
+
+// Add appends 'records' to 'r'. If 'r' already contains a record with the same URI it will be replaced.
+func (r *UpdateResult) Add(records ...*UpdateRecord) {
+
+	for _, rec := range records {
+
+		idx := slices.IndexFunc(r.Records, func(existing *UpdateRecord) bool {
+			return existing.URI == rec.URI
+		})
+
+		if idx == -1 {
+			r.Records = append(r.Records, rec)
+		} else {
+			r.Records[idx] = rec
+		}
+	}
+}
+
+// Changed returns the list of records in 'r' whose `Changed` flag is true.
+func (r *UpdateResult) Changed() []*UpdateRecord {
+
+	changed := make([]*UpdateRecord, 0)
+
+	for _, rec := range r.Records {
+
+		if rec.Changed {
+			changed = append(changed, rec)
+		}
+	}
+
+	return changed
+}
+
+// Merge adds the records, pull requests and members in 'other' to 'r'. If 'other' describes a dry run its plan
+// changes are added to the plan for 'r'. Members with the same key are replaced.
+func (r *UpdateResult) Merge(other *UpdateResult) {
+
+	r.Add(other.Records...)
+
+	if other.Plan != nil {
+
+		if r.Plan == nil {
+			r.Plan = plan.NewPlan()
+		}
+
+		for _, c := range other.Plan.Changes {
+			r.Plan.Add(c)
+		}
+	}
+
+	r.PullRequests = append(r.PullRequests, other.PullRequests...)
+
+	if r.Members == nil {
+		r.Members = make(map[string]any)
+	}
+
+	for k, v := range other.Members {
+		r.Members[k] = v
+	}
+}
+
+// FeatureCollection returns a GeoJSON FeatureCollection containing the body of each record in 'r', in order, with
+// any members assigned as top-level (foreign) members.
+func (r *UpdateResult) FeatureCollection() (*geojson.FeatureCollection, error) {
+
+	fc := geojson.NewFeatureCollection()
+
+	for _, rec := range r.Records {
+
+		f, err := geojson.UnmarshalFeature(rec.Body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal feature for %s, %w", rec.URI, err)
+		}
+
+		fc.Append(f)
+	}
+
+	if len(r.Members) > 0 {
+		fc.ExtraMembers = geojson.Properties(r.Members)
+	}
+
+	return fc, nil
+}
+
+// Response returns the JSON-encoded response for 'r'. If 'r' describes a dry run this is its `plan.Plan`. Otherwise it is
+// the FeatureCollection returned by the `FeatureCollection` method. In both cases any members are included as top-level keys.
+func (r *UpdateResult) Response() ([]byte, error) {
+
+	if r.Plan == nil {
+
+		fc, err := r.FeatureCollection()
+
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := fc.MarshalJSON()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal feature collection, %w", err)
+		}
+
+		return body, nil
+	}
+
+	body, err := json.Marshal(r.Plan)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal plan, %w", err)
+	}
+
+	for k, v := range r.Members {
+
+		body, err = sjson.SetBytes(body, k, v)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to assign %s member, %w", k, err)
+		}
+	}
+
+	return body, nil
+}
+
+// Log writes a summary of each record and pull request in 'r' to 'logger' at the `slog.LevelInfo` level.
+func (r *UpdateResult) Log(logger *slog.Logger) {
+
+	for _, rec := range r.Records {
+		logger.Info("Record updated", "id", rec.Id, "role", rec.Role, "alt label", rec.AltLabel, "changed", rec.Changed, "uri", rec.URI, "dry run", r.DryRun())
+	}
+
+	for _, pr := range r.PullRequests {
+		logger.Info("Pull request opened", "pull request", pr.String(), "url", pr.URL)
+	}
+}
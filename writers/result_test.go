@@ -0,0 +1,172 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader/v2"
+)
+
+func TestWritersResult(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	subject_body := []byte(`{"type":"Feature","properties":{"wof:id":1,"wof:lastmodified":1},"geometry":{"type":"Point","coordinates":[0,0]}}`)
+	new_subject_body := []byte(`{"type":"Feature","properties":{"wof:id":1,"wof:lastmodified":2},"geometry":{"type":"Point","coordinates":[0,0]}}`)
+	depiction_body := []byte(`{"type":"Feature","properties":{"wof:id":2},"geometry":{"type":"Point","coordinates":[1,1]}}`)
+	alt_body := []byte(`{"type":"Feature","properties":{"wof:id":2,"src:alt_label":"georef_sfomuseum_depicts"},"geometry":{"type":"Point","coordinates":[1,1]}}`)
+
+	err := os.WriteFile(filepath.Join(root, "1.geojson"), subject_body, 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write fixture, %v", err)
+	}
+
+	r, err := reader.NewReader(ctx, fmt.Sprintf("fs://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	alt_path := "2-alt-georef_sfomuseum_depicts.geojson"
+
+	for _, dry_run := range []bool{true, false} {
+
+		writers_opts := &CreateWritersOptions{
+			DepictionWriterURI: fmt.Sprintf("fs://%s", root),
+			SubjectWriterURI:   fmt.Sprintf("fs://%s", root),
+			DepictionReader:    r,
+			SubjectReader:      r,
+		}
+
+		if dry_run {
+			writers_opts.Plan = plan.NewPlan()
+		}
+
+		writers, err := CreateWriters(ctx, writers_opts)
+
+		if err != nil {
+			t.Fatalf("Failed to create writers, %v", err)
+		}
+
+		// Written in the reverse order they are expected in the result
+
+		_, err = writers.DepictionWriter.Write(ctx, alt_path, bytes.NewReader(alt_body))
+
+		if err != nil {
+			t.Fatalf("Failed to write alt, %v", err)
+		}
+
+		_, err = writers.DepictionMultiWriter.Write(ctx, "2.geojson", bytes.NewReader(depiction_body))
+
+		if err != nil {
+			t.Fatalf("Failed to write depiction, %v", err)
+		}
+
+		_, err = writers.SubjectMultiWriter.Write(ctx, "1.geojson", bytes.NewReader(new_subject_body))
+
+		if err != nil {
+			t.Fatalf("Failed to write subject, %v", err)
+		}
+
+		err = writers.Commit(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to commit writes, %v", err)
+		}
+
+		result, err := writers.Result(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to derive result, %v", err)
+		}
+
+		if result.DryRun() != dry_run {
+			t.Fatalf("Unexpected dry run flag for result")
+		}
+
+		expected := []struct {
+			id       int64
+			role     plan.Role
+			alt      string
+			changed  bool
+			dry_path string
+		}{
+			{1, plan.SubjectRole, "", false, "1.geojson"},
+			{2, plan.DepictionRole, "", true, "2.geojson"},
+			{2, plan.AltRole, "georef_sfomuseum_depicts", true, alt_path},
+		}
+
+		if len(result.Records) != len(expected) {
+			t.Fatalf("Expected %d records, got %d", len(expected), len(result.Records))
+		}
+
+		for idx, e := range expected {
+
+			rec := result.Records[idx]
+
+			if rec.Id != e.id || rec.Role != e.role || rec.AltLabel != e.alt || rec.Changed != e.changed {
+				t.Fatalf("Unexpected record at offset %d (dry run %t), %v", idx, dry_run, rec)
+			}
+
+			if dry_run && rec.URI != e.dry_path {
+				t.Fatalf("Unexpected URI for record at offset %d, %s", idx, rec.URI)
+			}
+		}
+
+		alt_records, err := writers.AltRecords(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to derive alt records, %v", err)
+		}
+
+		alt_rec, exists := alt_records["georef_sfomuseum_depicts"]
+
+		if len(alt_records) != 1 || !exists || !bytes.Equal(alt_rec.Body, alt_body) {
+			t.Fatalf("Unexpected alt records, %v", alt_records)
+		}
+
+		if len(result.Changed()) != 2 {
+			t.Fatalf("Expected 2 changed records, got %d", len(result.Changed()))
+		}
+
+		result.Members["example:key"] = "hello"
+
+		body, err := result.Response()
+
+		if err != nil {
+			t.Fatalf("Failed to derive response, %v", err)
+		}
+
+		if gjson.GetBytes(body, "example:key").String() != "hello" {
+			t.Fatalf("Expected response to include member, %s", string(body))
+		}
+
+		if dry_run {
+
+			if gjson.GetBytes(body, "changes.#").Int() != 3 {
+				t.Fatalf("Expected plan with 3 changes, %s", string(body))
+			}
+
+			_, err := os.Stat(filepath.Join(root, "2.geojson"))
+
+			if !os.IsNotExist(err) {
+				t.Fatalf("Expected dry run not to write depiction")
+			}
+
+			continue
+		}
+
+		if gjson.GetBytes(body, "features.#").Int() != 3 || gjson.GetBytes(body, "features.0.properties.wof:id").Int() != 1 {
+			t.Fatalf("Unexpected feature collection, %s", string(body))
+		}
+	}
+}
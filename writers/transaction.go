@@ -0,0 +1,456 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// Transaction stages writes, destined for one or more `whosonfirst/go-writer/v3.Writer` instances, in memory
+// so that they can be committed together once every record has been computed and exported. If any write fails
+// during commit then the transaction will attempt to compensate by restoring the prior bodies of the writes which
+// have already landed. Anything which can not be restored is reported in the `CommitError` that is returned.
+type Transaction struct {
+	mu        *sync.Mutex
+	staged    []*stagedWrite
+	writers   []writer.Writer
+	committed bool
+}
+
+type stagedWrite struct {
+	path   string
+	body   []byte
+	writer writer.Writer
+	reader reader.Reader
+	prior  []byte
+	exists bool
+}
+
+// CommitError is the error returned by `Transaction.Commit` if one or more writes failed. It details exactly
+// which staged writes were persisted, which were restored to their prior state and which were never attempted.
+type CommitError struct {
+	// Failed is the path of the write that failed. It will be empty if the failure occurred closing a writer.
+	Failed string
+	// Landed is the list of paths which were written and are still in place (they were not or could not be restored).
+	Landed []string
+	// Restored is the list of paths which were written and then restored to their prior bodies.
+	Restored []string
+	// Unconfirmed is the list of paths which were handed to a writer whose `Close` method failed. Whether or not they were persisted is unknown.
+	Unconfirmed []string
+	// Discarded is the list of paths which were written to a writer that buffers its writes (see `Discarder`) and then discarded without being published.
+	Discarded []string
+	// Pending is the list of paths which were never written.
+	Pending []string
+	// Err is the underlying error which caused the commit to fail.
+	Err error
+}
+
+// Error returns a string representation of 'e' listing the paths which were landed, restored, unconfirmed and pending.
+func (e *CommitError) Error() string {
+
+	msg := fmt.Sprintf("Failed to commit transaction, %v", e.Err)
+
+	if e.Failed != "" {
+		msg = fmt.Sprintf("Failed to commit transaction writing %s, %v", e.Failed, e.Err)
+	}
+
+	details := []string{
+		fmt.Sprintf("landed: [%s]", strings.Join(e.Landed, ",")),
+		fmt.Sprintf("restored: [%s]", strings.Join(e.Restored, ",")),
+		fmt.Sprintf("unconfirmed: [%s]", strings.Join(e.Unconfirmed, ",")),
+		fmt.Sprintf("discarded: [%s]", strings.Join(e.Discarded, ",")),
+		fmt.Sprintf("pending: [%s]", strings.Join(e.Pending, ",")),
+	}
+
+	return fmt.Sprintf("%s (%s)", msg, strings.Join(details, " "))
+}
+
+// Unwrap returns the underlying error which caused the commit to fail.
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransaction returns a new `Transaction` instance.
+func NewTransaction() *Transaction {
+
+	tx := &Transaction{
+		mu:      new(sync.Mutex),
+		staged:  make([]*stagedWrite, 0),
+		writers: make([]writer.Writer, 0),
+	}
+
+	return tx
+}
+
+// NewWriter returns a new `writer.Writer` instance which stages all its writes in 'tx' rather than writing them to 'wr'.
+// Staged writes are written to 'wr' when `Commit` is invoked. If 'r' is not nil it will be used to read the prior body
+// for each path, at commit time, so that it can be restored if the transaction fails.
+func (tx *Transaction) NewWriter(ctx context.Context, wr writer.Writer, r reader.Reader) writer.Writer {
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if writerIndex(tx.writers, wr) == -1 {
+		tx.writers = append(tx.writers, wr)
+	}
+
+	tx_wr := &TransactionWriter{
+		transaction: tx,
+		writer:      wr,
+		reader:      r,
+	}
+
+	return tx_wr
+}
+
+// Staged returns the list of paths which have been staged in 'tx', in the order they were written.
+func (tx *Transaction) Staged() []string {
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	return stagedPaths(tx.staged)
+}
+
+func (tx *Transaction) stage(w *stagedWrite) error {
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.committed {
+		return fmt.Errorf("Transaction has already been committed")
+	}
+
+	tx.staged = append(tx.staged, w)
+	return nil
+}
+
+// Commit writes all the staged writes in 'tx', in the order they were staged, and then closes each of the underlying
+// writers. The prior body for each write is read before anything is written. If a write fails then the writes which have
+// already landed are restored (in reverse order) to their prior bodies and a `CommitError` is returned. Writes which created
+// new records can not be removed using the `writer.Writer` interface and are reported as landed. A transaction can only be
+// committed once.
+func (tx *Transaction) Commit(ctx context.Context) error {
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.committed {
+		return fmt.Errorf("Transaction has already been committed")
+	}
+
+	tx.committed = true
+
+	logger := slog.Default()
+	logger = logger.With("staged", len(tx.staged))
+
+	// Snapshot prior bodies before anything is written
+
+	for _, w := range tx.staged {
+
+		if w.reader == nil {
+			continue
+		}
+
+		exists, prior, err := readPrior(ctx, w.reader, w.path)
+
+		if err != nil {
+			return &CommitError{
+				Pending: stagedPaths(tx.staged),
+				Err:     fmt.Errorf("Failed to read prior body for %s, %w", w.path, err),
+			}
+		}
+
+		w.exists = exists
+		w.prior = prior
+	}
+
+	for idx, w := range tx.staged {
+
+		logger.Debug("Commit staged write", "path", w.path)
+
+		_, err := w.writer.Write(ctx, w.path, bytes.NewReader(w.body))
+
+		if err == nil {
+			continue
+		}
+
+		logger.Error("Failed to commit staged write, rolling back", "path", w.path, "error", err)
+
+		commit_err := tx.rollback(ctx, tx.staged[:idx])
+		commit_err.Failed = w.path
+		commit_err.Pending = stagedPaths(tx.staged[idx+1:])
+		commit_err.Err = err
+
+		return commit_err
+	}
+
+	// Close the underlying writers - this is a no-op for many writer but required for
+	// things like the githubapi-tree:// and githubapi-pr:// writers.
+
+	for idx, wr := range tx.writers {
+
+		err := wr.Close(ctx)
+
+		if err == nil {
+			continue
+		}
+
+		logger.Error("Failed to close writer", "error", err)
+
+		commit_err := &CommitError{
+			Landed:      make([]string, 0),
+			Restored:    make([]string, 0),
+			Unconfirmed: make([]string, 0),
+			Pending:     make([]string, 0),
+			Err:         fmt.Errorf("Failed to close writer, %w", err),
+		}
+
+		for _, w := range tx.staged {
+
+			// Writes for writers which have already been closed have landed. Writes for this
+			// writer, and any writers which have not been closed yet, may or may not have.
+
+			if writerIndex(tx.writers, w.writer) < idx {
+				commit_err.Landed = append(commit_err.Landed, w.path)
+			} else {
+				commit_err.Unconfirmed = append(commit_err.Unconfirmed, w.path)
+			}
+		}
+
+		return commit_err
+	}
+
+	return nil
+}
+
+// rollback restores the prior bodies for 'landed' in reverse order and returns a `CommitError` detailing the
+// paths that were, and were not, restored. Writers which buffer their writes (see `Discarder`) have not published
+// anything yet so their writes are discarded, rather than restored, and those writers are never closed. Restored paths
+// whose writer fails to close are reported as unconfirmed.
+func (tx *Transaction) rollback(ctx context.Context, landed []*stagedWrite) *CommitError {
+
+	logger := slog.Default()
+
+	commit_err := &CommitError{
+		Landed:      make([]string, 0),
+		Restored:    make([]string, 0),
+		Unconfirmed: make([]string, 0),
+		Discarded:   make([]string, 0),
+	}
+
+	// Discard buffered writers first so that nothing they hold is published
+
+	discarded := make([]writer.Writer, 0)
+
+	for _, wr := range tx.writers {
+
+		ok, err := Discard(ctx, wr)
+
+		if !ok {
+			continue
+		}
+
+		if err != nil {
+			logger.Error("Failed to discard writer after rollback", "error", err)
+		}
+
+		discarded = append(discarded, wr)
+	}
+
+	restored := make(map[int][]string)
+
+	for i := len(landed) - 1; i >= 0; i-- {
+
+		w := landed[i]
+
+		if writerIndex(discarded, w.writer) != -1 {
+			logger.Debug("Discarded buffered write", "path", w.path)
+			commit_err.Discarded = append(commit_err.Discarded, w.path)
+			continue
+		}
+
+		if !w.exists {
+			logger.Warn("Unable to restore staged write, no prior body", "path", w.path)
+			commit_err.Landed = append(commit_err.Landed, w.path)
+			continue
+		}
+
+		_, err := w.writer.Write(ctx, w.path, bytes.NewReader(w.prior))
+
+		if err != nil {
+			logger.Error("Failed to restore prior body", "path", w.path, "error", err)
+			commit_err.Landed = append(commit_err.Landed, w.path)
+			continue
+		}
+
+		logger.Debug("Restored prior body", "path", w.path)
+
+		idx := writerIndex(tx.writers, w.writer)
+		restored[idx] = append(restored[idx], w.path)
+	}
+
+	// Restores have been written (above) so close the (unbuffered) writers to make sure they are persisted.
+
+	for idx, wr := range tx.writers {
+
+		if writerIndex(discarded, wr) != -1 {
+			continue
+		}
+
+		err := wr.Close(ctx)
+
+		if err != nil {
+			logger.Error("Failed to close writer after rollback", "error", err)
+			commit_err.Unconfirmed = append(commit_err.Unconfirmed, restored[idx]...)
+			continue
+		}
+
+		commit_err.Restored = append(commit_err.Restored, restored[idx]...)
+	}
+
+	return commit_err
+}
+
+// TransactionWriter implements the `whosonfirst/go-writer/v3.Writer` interface and stages writes in a `Transaction`
+// instance rather than writing them to an underlying writer.
+type TransactionWriter struct {
+	transaction *Transaction
+	writer      writer.Writer
+	reader      reader.Reader
+}
+
+// Write stages the body in 'fh' for 'path' in the underlying `Transaction` instance.
+func (wr *TransactionWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read body for %s, %w", path, err)
+	}
+
+	w := &stagedWrite{
+		path:   path,
+		body:   body,
+		writer: wr.writer,
+		reader: wr.reader,
+	}
+
+	err = wr.transaction.stage(w)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to stage %s, %w", path, err)
+	}
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns the value of the underlying writer's `WriterURI` method.
+func (wr *TransactionWriter) WriterURI(ctx context.Context, path string) string {
+	return wr.writer.WriterURI(ctx, path)
+}
+
+// Flush is a no-op. Staged writes are only published when the underlying `Transaction` is committed.
+func (wr *TransactionWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op. The underlying writer is closed when the underlying `Transaction` is committed.
+func (wr *TransactionWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+// SetLogger assigns 'logger' to the underlying writer.
+func (wr *TransactionWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return wr.writer.SetLogger(ctx, logger)
+}
+
+// priorBody returns the prior body, read at commit time, for the most recent write to 'path' staged by 'tx_wr'. The first
+// boolean value indicates whether the record existed and the last whether the prior body is known at all. It is not
+// known if 'tx' has not been committed or if 'tx_wr' was created without a reader.
+func (tx *Transaction) priorBody(tx_wr *TransactionWriter, path string) (bool, []byte, bool) {
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if !tx.committed {
+		return false, nil, false
+	}
+
+	for i := len(tx.staged) - 1; i >= 0; i-- {
+
+		w := tx.staged[i]
+
+		if w.path != path || w.writer != tx_wr.writer {
+			continue
+		}
+
+		if w.reader == nil {
+			return false, nil, false
+		}
+
+		return w.exists, w.prior, true
+	}
+
+	return false, nil, false
+}
+
+func readPrior(ctx context.Context, r reader.Reader, path string) (bool, []byte, error) {
+
+	exists, err := r.Exists(ctx, path)
+
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to determine whether %s exists, %w", path, err)
+	}
+
+	if !exists {
+		return false, nil, nil
+	}
+
+	fh, err := r.Read(ctx, path)
+
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to open %s for reading, %w", path, err)
+	}
+
+	defer fh.Close()
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	return true, body, nil
+}
+
+func stagedPaths(staged []*stagedWrite) []string {
+
+	paths := make([]string, len(staged))
+
+	for idx, w := range staged {
+		paths[idx] = w.path
+	}
+
+	return paths
+}
+
+func writerIndex(writers []writer.Writer, wr writer.Writer) int {
+
+	for idx, candidate := range writers {
+
+		if candidate == wr {
+			return idx
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,281 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/whosonfirst/go-reader/v2"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// failingWriter is a `writer.Writer` instance whose writes always fail.
+type failingWriter struct {
+	writer.Writer
+}
+
+func (wr *failingWriter) Write(ctx context.Context, path string, fh io.ReadSeeker) (int64, error) {
+	return 0, fmt.Errorf("Intentional failure writing %s", path)
+}
+
+func (wr *failingWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+// closeWriter is a `writer.Writer` instance which records the number of times it is closed and, optionally, fails to close.
+type closeWriter struct {
+	writer.Writer
+	closed int
+	fail   bool
+}
+
+func (wr *closeWriter) Close(ctx context.Context) error {
+
+	wr.closed += 1
+
+	if wr.fail {
+		return fmt.Errorf("Intentional failure closing writer")
+	}
+
+	return wr.Writer.Close(ctx)
+}
+
+func setupTransactionTest(t *testing.T) (string, writer.Writer, reader.Reader) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(root, "a.geojson"), []byte("old"), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write fixture, %v", err)
+	}
+
+	wr, err := writer.NewWriter(ctx, fmt.Sprintf("fs://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	r, err := reader.NewReader(ctx, fmt.Sprintf("fs://%s", root))
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	return root, wr, r
+}
+
+func TestTransactionCommit(t *testing.T) {
+
+	ctx := context.Background()
+
+	root, fs_wr, fs_r := setupTransactionTest(t)
+
+	tx := NewTransaction()
+	tx_wr := tx.NewWriter(ctx, fs_wr, fs_r)
+
+	for _, path := range []string{"a.geojson", "b.geojson"} {
+
+		_, err := tx_wr.Write(ctx, path, bytes.NewReader([]byte("new")))
+
+		if err != nil {
+			t.Fatalf("Failed to stage %s, %v", path, err)
+		}
+	}
+
+	if !slices.Equal(tx.Staged(), []string{"a.geojson", "b.geojson"}) {
+		t.Fatalf("Unexpected staged writes, %v", tx.Staged())
+	}
+
+	body, _ := os.ReadFile(filepath.Join(root, "a.geojson"))
+
+	if string(body) != "old" {
+		t.Fatalf("Staged write was persisted before commit")
+	}
+
+	err := tx.Commit(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to commit transaction, %v", err)
+	}
+
+	for _, path := range []string{"a.geojson", "b.geojson"} {
+
+		body, err := os.ReadFile(filepath.Join(root, path))
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", path, err)
+		}
+
+		if string(body) != "new" {
+			t.Fatalf("Unexpected body for %s, %s", path, string(body))
+		}
+	}
+
+	err = tx.Commit(ctx)
+
+	if err == nil {
+		t.Fatalf("Expected second commit to fail")
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+
+	ctx := context.Background()
+
+	root, fs_wr, fs_r := setupTransactionTest(t)
+
+	tx := NewTransaction()
+
+	depiction_wr := tx.NewWriter(ctx, fs_wr, fs_r)
+	subject_wr := tx.NewWriter(ctx, &failingWriter{}, nil)
+
+	for _, path := range []string{"a.geojson", "b.geojson"} {
+
+		_, err := depiction_wr.Write(ctx, path, bytes.NewReader([]byte("new")))
+
+		if err != nil {
+			t.Fatalf("Failed to stage %s, %v", path, err)
+		}
+	}
+
+	_, err := subject_wr.Write(ctx, "c.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage c.geojson, %v", err)
+	}
+
+	_, err = depiction_wr.Write(ctx, "d.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage d.geojson, %v", err)
+	}
+
+	err = tx.Commit(ctx)
+
+	if err == nil {
+		t.Fatalf("Expected commit to fail")
+	}
+
+	var commit_err *CommitError
+
+	if !errors.As(err, &commit_err) {
+		t.Fatalf("Expected CommitError, got %T", err)
+	}
+
+	if commit_err.Failed != "c.geojson" {
+		t.Fatalf("Unexpected failed path, %s", commit_err.Failed)
+	}
+
+	if !slices.Equal(commit_err.Restored, []string{"a.geojson"}) {
+		t.Fatalf("Unexpected restored paths, %v", commit_err.Restored)
+	}
+
+	if !slices.Equal(commit_err.Landed, []string{"b.geojson"}) {
+		t.Fatalf("Unexpected landed paths, %v", commit_err.Landed)
+	}
+
+	if !slices.Equal(commit_err.Pending, []string{"d.geojson"}) {
+		t.Fatalf("Unexpected pending paths, %v", commit_err.Pending)
+	}
+
+	body, _ := os.ReadFile(filepath.Join(root, "a.geojson"))
+
+	if string(body) != "old" {
+		t.Fatalf("Expected a.geojson to be restored, %s", string(body))
+	}
+
+	_, err = os.Stat(filepath.Join(root, "d.geojson"))
+
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected d.geojson not to be written")
+	}
+}
+
+func TestTransactionRollbackBuffered(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, fs_wr, fs_r := setupTransactionTest(t)
+
+	close_wr := &closeWriter{Writer: fs_wr}
+
+	tx := NewTransaction()
+
+	depiction_wr := tx.NewWriter(ctx, NewBufferedWriter(close_wr), fs_r)
+	subject_wr := tx.NewWriter(ctx, &failingWriter{}, nil)
+
+	_, err := depiction_wr.Write(ctx, "a.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage a.geojson, %v", err)
+	}
+
+	_, err = subject_wr.Write(ctx, "c.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage c.geojson, %v", err)
+	}
+
+	err = tx.Commit(ctx)
+
+	var commit_err *CommitError
+
+	if !errors.As(err, &commit_err) {
+		t.Fatalf("Expected CommitError, got %v", err)
+	}
+
+	if !slices.Equal(commit_err.Discarded, []string{"a.geojson"}) || len(commit_err.Restored) != 0 || len(commit_err.Landed) != 0 {
+		t.Fatalf("Expected buffered write to be discarded, %v", commit_err)
+	}
+
+	if close_wr.closed != 0 {
+		t.Fatalf("Expected buffered writer not to be closed")
+	}
+}
+
+func TestTransactionRollbackCloseError(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, fs_wr, fs_r := setupTransactionTest(t)
+
+	close_wr := &closeWriter{Writer: fs_wr, fail: true}
+
+	tx := NewTransaction()
+
+	depiction_wr := tx.NewWriter(ctx, close_wr, fs_r)
+	subject_wr := tx.NewWriter(ctx, &failingWriter{}, nil)
+
+	_, err := depiction_wr.Write(ctx, "a.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage a.geojson, %v", err)
+	}
+
+	_, err = subject_wr.Write(ctx, "c.geojson", bytes.NewReader([]byte("new")))
+
+	if err != nil {
+		t.Fatalf("Failed to stage c.geojson, %v", err)
+	}
+
+	err = tx.Commit(ctx)
+
+	var commit_err *CommitError
+
+	if !errors.As(err, &commit_err) {
+		t.Fatalf("Expected CommitError, got %v", err)
+	}
+
+	if !slices.Equal(commit_err.Unconfirmed, []string{"a.geojson"}) || len(commit_err.Restored) != 0 {
+		t.Fatalf("Expected restore whose writer failed to close to be unconfirmed, %v", commit_err)
+	}
+}
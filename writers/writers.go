@@ -1,41 +1,51 @@
 package writers
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
-	"log/slog"
 
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-sfomuseum-geo/github"
+	"github.com/sfomuseum/go-sfomuseum-geo/plan"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader/v2"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
 // Writers is a struct which encapsulates a pair of `whosonfirst/go-writer/v3.Writer` instances for
 // writing data to depictions and subjects respectively. One is a simple Writer instance where data
 // is expected to be persisted to. The second is a `writer.MultiWriter` instance which writes to both
-// the default writer and a local in-memory `writer.IOWriter` instance. This allows the `Writers` struct
-// to expose an `AsFeatureCollection` methods which can be invoked to return the update depiction and
-// subject data (to the calling application) without having to query for that data from source. The
-// reason that both the solitary writer and the multi writer are exposed is because there is often the
-// need to write "alternate geometry" files is because the 'whosonfirst/go-whosonfirstwriter/v3.WriteBytes`
+// the default writer and a local in-memory writer which keeps a copy of each record written. This allows
+// the `Writers` struct to expose `Result` and `AsFeatureCollection` methods which can be invoked to return
+// the updated depiction and subject data (to the calling application) without having to query for that data
+// from source. The reason that both the solitary writer and the multi writer are exposed is because there is
+// often the need to write "alternate geometry" files is because the 'whosonfirst/go-whosonfirstwriter/v3.WriteBytes`
 // function which is typically used to wrap writing data does not support alternate geometies. It should
-// and eventually will but for the time being it doesn't.
+// and eventually will but for the time being it doesn't. Alternate geometry files written to the solitary
+// depiction writer are also kept, separately, in memory and are returned by the `AltRecords` and `Result` methods.
+//
+// All writes are staged in memory in a `Transaction` and nothing is persisted until the `Commit` method
+// is invoked. This ensures that depiction, alternate geometry and subject records are only written once
+// they have all been computed and exported and that a failure writing one will not leave the others
+// in an inconsistent state.
 type Writers struct {
-	// A `whosonfirst/go-writer/v3.Writer` instance for writing depiction data to.
+	// A `whosonfirst/go-writer/v3.Writer` instance for writing depiction data, typically alternate geometry files, to.
 	DepictionWriter writer.Writer
-	// A `whosonfirst/go-writer/v3.MultiWriter` instance wrapping both the principal `DepictionWriter` instance and an in-memory `writer.IOWriter` instance for writing depiction data to.
+	// A `whosonfirst/go-writer/v3.MultiWriter` instance wrapping both the principal `DepictionWriter` instance and an in-memory writer for writing depiction data to.
 	DepictionMultiWriter writer.Writer
 	// A `whosonfirst/go-writer/v3.Writer` instance for writing subject data to.
 	SubjectWriter writer.Writer
-	// A `whosonfirst/go-writer/v3.MultiWriter` instance wrapping both the principal `SubjectWriter` instance and an in-memory `writer.IOWriter` instance for writing subject data to.
+	// A `whosonfirst/go-writer/v3.MultiWriter` instance wrapping both the principal `SubjectWriter` instance and an in-memory writer for writing subject data to.
 	SubjectMultiWriter writer.Writer
 
-	depictionBuf       *bytes.Buffer
-	depictionBufWriter *bufio.Writer
-	subjectBuf         *bytes.Buffer
-	subjectBufWriter   *bufio.Writer
+	depictionTarget   writer.Writer
+	subjectTarget     writer.Writer
+	depictionRecorder *recordingWriter
+	subjectRecorder   *recordingWriter
+	altRecorder       *recordingWriter
+	transaction       *Transaction
+	changeset         *github.ChangeSet
+	plan              *plan.Plan
 }
 
 // CreateWritersOptions is a struct containing configuration details for the `CreateWriters` method.
@@ -45,82 +55,169 @@ type CreateWritersOptions struct {
 	// A registered `whosonfirst/go-writer/v3.Writer` URI describing where subject data will be written to.
 	SubjectWriterURI string
 	// An option `github.UpdateWriterURIOptions` struct used to append GitHub API / PR specific data to writers.
+	// If present then any `githubapi-pr://` writer URIs are written to a single coordinated `github.ChangeSet`
+	// so that all the changes for an operation share a branch name and cross-linked pull requests.
 	GithubWriterOptions *github.UpdateWriterURIOptions
+	// An optional base URL for the GitHub API used by coordinated `github.ChangeSet` writers. If empty the
+	// default GitHub API endpoint is used.
+	GitHubAPIURL string
+	// An optional `plan.Plan` instance. If present then `DepictionWriterURI` and `SubjectWriterURI` are ignored
+	// and all writes are recorded in the plan (comparing them against `DepictionReader` and `SubjectReader`) rather
+	// than being persisted.
+	Plan *plan.Plan
+	// A `whosonfirst/go-reader/v2.Reader` instance used to read existing depiction data. Required if `Plan` is not nil.
+	// If present it is also used to read the prior bodies of depiction records so they can be restored if a commit fails.
+	DepictionReader reader.Reader
+	// A `whosonfirst/go-reader/v2.Reader` instance used to read existing subject data. Required if `Plan` is not nil.
+	// If present it is also used to read the prior bodies of subject records so they can be restored if a commit fails.
+	SubjectReader reader.Reader
 }
 
 // CreateWriters will returns a new `Writers` instance derived from 'opts'.
 func CreateWriters(ctx context.Context, opts *CreateWritersOptions) (*Writers, error) {
 
-	depiction_writer_uri := opts.DepictionWriterURI
-	subject_writer_uri := opts.SubjectWriterURI
+	var depiction_writer writer.Writer
+	var subject_writer writer.Writer
+	var changeset *github.ChangeSet
 
-	if opts.GithubWriterOptions != nil {
+	if opts.Plan != nil {
+
+		if opts.DepictionReader == nil || opts.SubjectReader == nil {
+			return nil, fmt.Errorf("Depiction and subject readers are required when creating plan writers")
+		}
 
 		var err error
 
-		depiction_writer_uri, err = github.UpdateWriterURI(ctx, opts.GithubWriterOptions, depiction_writer_uri)
+		depiction_writer, err = plan.NewPlanWriter(ctx, opts.Plan, plan.DepictionRole, opts.DepictionReader)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create new depiction plan writer, %w", err)
+		}
+
+		subject_writer, err = plan.NewPlanWriter(ctx, opts.Plan, plan.SubjectRole, opts.SubjectReader)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to update depiction writer URI, %w", err)
+			return nil, fmt.Errorf("Failed to create new subject plan writer, %w", err)
 		}
 
-		subject_writer_uri, err = github.UpdateWriterURI(ctx, opts.GithubWriterOptions, subject_writer_uri)
+	} else {
+
+		var err error
+
+		depiction_writer, subject_writer, changeset, err = createWriters(ctx, opts)
 
 		if err != nil {
-			return nil, fmt.Errorf("Failed to update subject writer URI, %w", err)
+			return nil, err
 		}
 	}
 
-	depiction_writer, err := writer.NewWriter(ctx, depiction_writer_uri)
+	tx := NewTransaction()
+
+	depiction_writer = tx.NewWriter(ctx, depiction_writer, opts.DepictionReader)
+	subject_writer = tx.NewWriter(ctx, subject_writer, opts.SubjectReader)
+
+	all_writers, err := createWritersWithWriters(ctx, depiction_writer, subject_writer)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create new depiction writer for '%s', %w", depiction_writer_uri, err)
+		return nil, err
+	}
+
+	all_writers.transaction = tx
+	all_writers.changeset = changeset
+	all_writers.plan = opts.Plan
+	return all_writers, nil
+}
+
+func createWriters(ctx context.Context, opts *CreateWritersOptions) (writer.Writer, writer.Writer, *github.ChangeSet, error) {
+
+	var changeset *github.ChangeSet
+
+	if opts.GithubWriterOptions != nil && (github.IsPullRequestURI(opts.DepictionWriterURI) || github.IsPullRequestURI(opts.SubjectWriterURI)) {
+
+		changeset_opts := &github.ChangeSetOptions{
+			WhosOnFirstId: opts.GithubWriterOptions.WhosOnFirstId,
+			Author:        opts.GithubWriterOptions.Author,
+			Action:        opts.GithubWriterOptions.Action,
+			APIURL:        opts.GitHubAPIURL,
+			SubjectId:     opts.GithubWriterOptions.SubjectId,
+			Labels:        opts.GithubWriterOptions.Labels,
+			Places:        opts.GithubWriterOptions.Places,
+			Templates:     opts.GithubWriterOptions.Templates,
+		}
+
+		cs, err := github.NewChangeSet(ctx, changeset_opts)
+
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Failed to create GitHub change set, %w", err)
+		}
+
+		changeset = cs
 	}
 
-	subject_writer, err := writer.NewWriter(ctx, subject_writer_uri)
+	depiction_writer, err := createWriter(ctx, opts, changeset, opts.DepictionWriterURI)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create new subject writer for '%s', %w", subject_writer_uri, err)
+		return nil, nil, nil, fmt.Errorf("Failed to create new depiction writer, %w", err)
 	}
 
-	// START OF hooks to capture updates/writes so we can parrot them back in the method response
-	// We're doing it this way because the code, as written, relies on sfomuseum/go-sfomuseum-writer
-	// which hides the format-and-export stages and modifies the document being written. To account
-	// for this we'll just keep local copies of those updates in *_buf and reference them at the end.
-	// Note that we are not doing this for the alternate geometry feature (alt_body) since are manually
-	// formatting, exporting and writing a byte slice in advance of better support for alternate
-	// geometries in the tooling.
+	subject_writer, err := createWriter(ctx, opts, changeset, opts.SubjectWriterURI)
+
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to create new subject writer, %w", err)
+	}
 
-	// The buffers where we will write updated Feature information
-	var local_depiction_buf bytes.Buffer
-	var local_subject_buf bytes.Buffer
+	return depiction_writer, subject_writer, changeset, nil
+}
 
-	// The io.Writers where we will write updated Feature information
-	local_depiction_buf_writer := bufio.NewWriter(&local_depiction_buf)
-	local_subject_buf_writer := bufio.NewWriter(&local_subject_buf)
+func createWriter(ctx context.Context, opts *CreateWritersOptions, changeset *github.ChangeSet, writer_uri string) (writer.Writer, error) {
 
-	// The writer.Writer where we will write updated Feature information
-	local_depiction_writer, err := writer.NewIOWriterWithWriter(ctx, local_depiction_buf_writer)
+	if changeset != nil && github.IsPullRequestURI(writer_uri) {
+		return changeset.NewWriter(ctx, writer_uri)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("Failed to create IOWriter for depiction, %w", err)
+	if opts.GithubWriterOptions != nil {
+
+		var err error
+
+		writer_uri, err = github.UpdateWriterURI(ctx, opts.GithubWriterOptions, writer_uri)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to update writer URI, %w", err)
+		}
 	}
 
-	local_subject_writer, err := writer.NewIOWriterWithWriter(ctx, local_subject_buf_writer)
+	wr, err := NewWriter(ctx, writer_uri)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create IOWriter for subject, %w", err)
+		return nil, fmt.Errorf("Failed to create new writer for '%s', %w", writer_uri, err)
 	}
 
+	return wr, nil
+}
+
+func createWritersWithWriters(ctx context.Context, depiction_writer writer.Writer, subject_writer writer.Writer) (*Writers, error) {
+
+	// START OF hooks to capture updates/writes so we can parrot them back in the method response
+	// We're doing it this way because the code, as written, relies on sfomuseum/go-sfomuseum-writer
+	// which hides the format-and-export stages and modifies the document being written. To account
+	// for this we'll just keep local copies of those updates in *_recorder and reference them at the end.
+	// Alternate geometry features are manually formatted, exported and written (as a byte slice) to the
+	// solitary depiction writer, in advance of better support for alternate geometries in the tooling, so
+	// that writer is wrapped in its own recorder which keeps each alternate geometry separately.
+
+	local_depiction_recorder := newRecordingWriter(nil)
+	local_subject_recorder := newRecordingWriter(nil)
+	local_alt_recorder := newRecordingWriter(depiction_writer)
+
 	// The writer.MultiWriter(s) where we will write updated Feature information
 
-	depiction_mw, err := writer.NewMultiWriter(ctx, depiction_writer, local_depiction_writer)
+	depiction_mw, err := writer.NewMultiWriter(ctx, depiction_writer, local_depiction_recorder)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create multi writer for depiction, %w", err)
 	}
 
-	subject_mw, err := writer.NewMultiWriter(ctx, subject_writer, local_subject_writer)
+	subject_mw, err := writer.NewMultiWriter(ctx, subject_writer, local_subject_recorder)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create multi writer for subject, %w", err)
@@ -129,44 +226,198 @@ func CreateWriters(ctx context.Context, opts *CreateWritersOptions) (*Writers, e
 	// END OF hooks to capture updates/writes so we can parrot them back in the method response
 
 	all_writers := &Writers{
-		DepictionWriter:      depiction_writer,
+		DepictionWriter:      local_alt_recorder,
 		SubjectWriter:        subject_writer,
 		DepictionMultiWriter: depiction_mw,
 		SubjectMultiWriter:   subject_mw,
-		depictionBufWriter:   local_depiction_buf_writer,
-		subjectBufWriter:     local_subject_buf_writer,
-		// See the part where we're storing points to local_*_buf?
-		// That is important so we can read them later in AsFeatureCollection
-		depictionBuf: &local_depiction_buf,
-		subjectBuf:   &local_subject_buf,
+		depictionTarget:      depiction_writer,
+		subjectTarget:        subject_writer,
+		depictionRecorder:    local_depiction_recorder,
+		subjectRecorder:      local_subject_recorder,
+		altRecorder:          local_alt_recorder,
 	}
 
 	return all_writers, nil
 }
 
-func (writers *Writers) AsFeatureCollection() (*geojson.FeatureCollection, error) {
+// Commit closes the depiction and subject (multi) writers and then commits all the staged writes to their
+// underlying writers. If the commit fails the error returned will be a `CommitError` detailing which writes
+// landed, which were restored and which were never attempted.
+func (writers *Writers) Commit(ctx context.Context) error {
 
-	writers.depictionBufWriter.Flush()
-	writers.subjectBufWriter.Flush()
+	err := writers.DepictionMultiWriter.Close(ctx)
 
-	fc := geojson.NewFeatureCollection()
+	if err != nil {
+		return fmt.Errorf("Failed to close depiction writer, %w", err)
+	}
 
-	new_subject_body, err := geojson.UnmarshalFeature(writers.subjectBuf.Bytes())
+	err = writers.SubjectMultiWriter.Close(ctx)
 
 	if err != nil {
-		slog.Error("Bad subject buffer", "body", string(writers.subjectBuf.Bytes()))
-		return nil, fmt.Errorf("Failed to unmarshal feature from subject buffer, %w", err)
+		return fmt.Errorf("Failed to close subject writer, %w", err)
+	}
+
+	if writers.transaction == nil {
+		return nil
+	}
+
+	return writers.transaction.Commit(ctx)
+}
+
+// PullRequests returns the list of pull requests opened by the coordinated GitHub change set, if present, once
+// the `Commit` method has been invoked.
+func (writers *Writers) PullRequests() []*github.PullRequest {
+
+	if writers.changeset == nil {
+		return nil
 	}
 
-	fc.Append(new_subject_body)
+	return writers.changeset.PullRequests()
+}
+
+// Result returns a new `UpdateResult` instance containing the subject, depiction and alternate geometry records written to
+// 'writers', in that order. Records which were not written (because they did not change) are omitted. If 'writers' was created
+// with a `plan.Plan` instance it is assigned to the result. This method should be invoked after the `Commit` method since the prior
+// body of each record, used to determine whether it changed, is read at commit time.
+func (writers *Writers) Result(ctx context.Context) (*UpdateResult, error) {
+
+	r := NewUpdateResult()
+	r.Plan = writers.plan
 
-	new_depiction_body, err := geojson.UnmarshalFeature(writers.depictionBuf.Bytes())
+	subject_records, err := writers.records(ctx, plan.SubjectRole, writers.subjectTarget, writers.subjectRecorder)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal feature from depiction buffer, %w", err)
+		return nil, err
 	}
 
-	fc.Append(new_depiction_body)
+	depiction_records, err := writers.records(ctx, plan.DepictionRole, writers.depictionTarget, writers.depictionRecorder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	alt_records, err := writers.records(ctx, plan.DepictionRole, writers.depictionTarget, writers.altRecorder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.Add(subject_records...)
+	r.Add(depiction_records...)
+	r.Add(alt_records...)
+
+	pull_requests := writers.PullRequests()
+
+	if pull_requests != nil {
+		r.PullRequests = pull_requests
+	}
+
+	return r, nil
+}
+
+// AltRecords returns the alternate geometry records written to the `DepictionWriter` instance of 'writers', keyed by their
+// alternate geometry label. Like the `Result` method this should be invoked after the `Commit` method.
+func (writers *Writers) AltRecords(ctx context.Context) (map[string]*UpdateRecord, error) {
+
+	alt_records, err := writers.records(ctx, plan.DepictionRole, writers.depictionTarget, writers.altRecorder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[string]*UpdateRecord)
+
+	for _, rec := range alt_records {
+
+		if rec.AltLabel == "" {
+			continue
+		}
+
+		lookup[rec.AltLabel] = rec
+	}
+
+	return lookup, nil
+}
+
+// records returns a new `UpdateRecord` instance for each of the bodies written to 'rec_wr'. Bodies with a `src:alt_label`
+// property are always assigned the `plan.AltRole` role regardless of 'role'.
+func (writers *Writers) records(ctx context.Context, role plan.Role, wr writer.Writer, rec_wr *recordingWriter) ([]*UpdateRecord, error) {
+
+	writes := rec_wr.Writes()
+	records := make([]*UpdateRecord, len(writes))
+
+	for idx, w := range writes {
+
+		rec, err := writers.newRecord(ctx, role, wr, w.path, w.body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records[idx] = rec
+	}
+
+	return records, nil
+}
+
+// AsFeatureCollection returns a GeoJSON FeatureCollection containing the subject and depiction records written to 'writers', in
+// that order. Records which were not written (because they did not change) are omitted.
+func (writers *Writers) AsFeatureCollection(ctx context.Context) (*geojson.FeatureCollection, error) {
+
+	r, err := writers.Result(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FeatureCollection()
+}
+
+func (writers *Writers) newRecord(ctx context.Context, role plan.Role, wr writer.Writer, path string, body []byte) (*UpdateRecord, error) {
+
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("Invalid body for %s", path)
+	}
+
+	id_rsp := gjson.GetBytes(body, "properties.wof:id")
+
+	if !id_rsp.Exists() {
+		return nil, fmt.Errorf("Body for %s is missing wof:id property", path)
+	}
+
+	alt_label := gjson.GetBytes(body, "properties.src:alt_label").String()
+
+	if alt_label != "" {
+		role = plan.AltRole
+	}
+
+	rec := &UpdateRecord{
+		Id:       id_rsp.Int(),
+		Role:     role,
+		AltLabel: alt_label,
+		Changed:  true,
+		URI:      wr.WriterURI(ctx, path),
+		Body:     body,
+	}
+
+	tx_wr, ok := wr.(*TransactionWriter)
+
+	if !ok || writers.transaction == nil {
+		return rec, nil
+	}
+
+	exists, prior, known := writers.transaction.priorBody(tx_wr, path)
+
+	if !known || !exists {
+		return rec, nil
+	}
+
+	diffs, geom_changed, err := plan.Diff(prior, body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive changes for %s, %w", path, err)
+	}
 
-	return fc, nil
+	rec.Changed = geom_changed || len(diffs) > 0
+	return rec, nil
 }